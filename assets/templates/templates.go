@@ -8,3 +8,6 @@ var CommitMessageTmpl string
 
 //go:embed install.sh
 var InstallSH []byte
+
+//go:embed install-packages.sh.tmpl
+var InstallPackagesSHTmpl []byte
@@ -4,11 +4,14 @@ package chezmoi
 // FIXME add builtin support for --symmetric
 
 import (
+	"bufio"
 	"bytes"
+	"errors"
 	"io"
 	"log/slog"
 	"os"
 	"os/exec"
+	"strings"
 
 	"filippo.io/age"
 	"filippo.io/age/armor"
@@ -17,6 +20,8 @@ import (
 	"github.com/twpayne/chezmoi/v2/internal/chezmoilog"
 )
 
+const ageVersionLine = "age-encryption.org/v1\n"
+
 // An AgeEncryption uses age for encryption and decryption. See
 // https://age-encryption.org.
 type AgeEncryption struct {
@@ -34,6 +39,46 @@ type AgeEncryption struct {
 	Symmetric       bool      `json:"symmetric"       mapstructure:"symmetric"       yaml:"symmetric"`
 }
 
+// CiphertextRecipients implements Encryption.CiphertextRecipients. age does
+// not record recipients' public keys in ciphertext, so only the type of each
+// recipient stanza is returned, followed by its tag for stanza types that have
+// one, e.g. ssh-ed25519.
+func (e *AgeEncryption) CiphertextRecipients(ciphertext []byte) ([]string, error) {
+	var ciphertextReader io.Reader = bytes.NewReader(ciphertext)
+	if bytes.HasPrefix(ciphertext, []byte(armor.Header)) {
+		ciphertextReader = armor.NewReader(ciphertextReader)
+	}
+	headerReader := bufio.NewReader(ciphertextReader)
+	switch version, err := headerReader.ReadString('\n'); {
+	case err != nil:
+		return nil, err
+	case version != ageVersionLine:
+		return nil, errors.New("not an age file")
+	}
+	var recipients []string
+	for {
+		line, err := headerReader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(line, "---") {
+			return recipients, nil
+		}
+		stanzaLine, ok := strings.CutPrefix(line, "-> ")
+		if !ok {
+			continue
+		}
+		switch args := strings.Fields(stanzaLine); {
+		case len(args) == 0:
+			return nil, errors.New("invalid age header")
+		case args[0] == "X25519" || args[0] == "scrypt" || len(args) == 1:
+			recipients = append(recipients, args[0])
+		default:
+			recipients = append(recipients, args[0]+" "+args[1])
+		}
+	}
+}
+
 // Decrypt implements Encryption.Decrypt.
 func (e *AgeEncryption) Decrypt(ciphertext []byte) ([]byte, error) {
 	if e.UseBuiltin {
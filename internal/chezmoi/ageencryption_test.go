@@ -264,6 +264,50 @@ func TestBuiltinAgeRecipientsFile(t *testing.T) {
 	})
 }
 
+func TestBuiltinAgeCiphertextRecipients(t *testing.T) {
+	recipient1, identityAbsPath := builtinAgeGenerateKey(t)
+	recipient2, _ := builtinAgeGenerateKey(t)
+
+	for _, tc := range []struct {
+		name               string
+		ageEncryption      *AgeEncryption
+		expectedRecipients []string
+	}{
+		{
+			name: "recipients",
+			ageEncryption: &AgeEncryption{
+				UseBuiltin: true,
+				Identity:   identityAbsPath,
+				Recipients: []string{
+					recipient1.String(),
+					recipient2.String(),
+				},
+			},
+			expectedRecipients: []string{"X25519", "X25519"},
+		},
+		{
+			name: "recipient",
+			ageEncryption: &AgeEncryption{
+				UseBuiltin: true,
+				Identity:   identityAbsPath,
+				Recipient:  recipient1.String(),
+			},
+			expectedRecipients: []string{"X25519"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ciphertext, err := tc.ageEncryption.Encrypt([]byte("plaintext"))
+			assert.NoError(t, err)
+			actualRecipients, err := tc.ageEncryption.CiphertextRecipients(ciphertext)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedRecipients, actualRecipients)
+		})
+	}
+
+	_, err := (&AgeEncryption{}).CiphertextRecipients([]byte("plaintext\n"))
+	assert.Error(t, err)
+}
+
 func builtinAgeGenerateKey(t *testing.T) (*age.X25519Recipient, AbsPath) {
 	t.Helper()
 	identity, err := age.GenerateX25519Identity()
@@ -75,6 +75,49 @@ type FileAttr struct {
 	Template   bool
 }
 
+// attributeNames are the names of the attributes that can be used as prefixes
+// in source names. dot_ and literal_ are not included as they can only remain
+// in target names if they are explicitly escaped with literal_.
+var attributeNames = []string{
+	"after",
+	"before",
+	"create",
+	"empty",
+	"encrypted",
+	"exact",
+	"executable",
+	"external",
+	"modify",
+	"once",
+	"onchange",
+	"private",
+	"readonly",
+	"remove",
+	"run",
+	"symlink",
+}
+
+// LikelyAttribute returns the name of the attribute that the prefix of
+// targetName, up to the first underscore, is likely to have been intended as,
+// if any. This detects attributes in the wrong order, which chezmoi treats as
+// part of the target name, and misspellings of long attribute names, for
+// example privte_ or exectuable_.
+func LikelyAttribute(targetName string) (string, bool) {
+	word, _, ok := strings.Cut(targetName, "_")
+	if !ok {
+		return "", false
+	}
+	for _, attributeName := range attributeNames {
+		switch {
+		case word == attributeName:
+			return attributeName, true
+		case len(attributeName) >= 7 && len(word) >= 6 && editDistance(word, attributeName) <= 1:
+			return attributeName, true
+		}
+	}
+	return "", false
+}
+
 // parseDirAttr parses a single directory name in the source state.
 func parseDirAttr(name string) DirAttr {
 	name, remove := strings.CutPrefix(name, removePrefix)
@@ -366,3 +409,30 @@ func (fa FileAttr) perm() fs.FileMode {
 	}
 	return perm
 }
+
+// editDistance returns the number of single character insertions, deletions,
+// substitutions, and transpositions of adjacent characters needed to change a
+// into b.
+func editDistance(a, b string) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
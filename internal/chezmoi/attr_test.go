@@ -362,3 +362,27 @@ func TestFileAttrPerm(t *testing.T) {
 		assert.Equal(t, tc.expected, tc.fileAttr.perm())
 	}
 }
+
+func TestLikelyAttribute(t *testing.T) {
+	for _, tc := range []struct {
+		targetName        string
+		expectedAttribute string
+	}{
+		{targetName: ".bashrc"},
+		{targetName: "my_script.sh"},
+		{targetName: "remote_hosts"},
+		{targetName: "exec_foo"},
+		{targetName: "private"},
+		{targetName: "private_file", expectedAttribute: "private"},
+		{targetName: "once_install.sh", expectedAttribute: "once"},
+		{targetName: "privte_file", expectedAttribute: "private"},
+		{targetName: "exectuable_file", expectedAttribute: "executable"},
+		{targetName: "readonyl_file", expectedAttribute: "readonly"},
+	} {
+		t.Run(tc.targetName, func(t *testing.T) {
+			actualAttribute, ok := LikelyAttribute(tc.targetName)
+			assert.Equal(t, tc.expectedAttribute != "", ok)
+			assert.Equal(t, tc.expectedAttribute, actualAttribute)
+		})
+	}
+}
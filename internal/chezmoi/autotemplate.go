@@ -16,6 +16,14 @@ type templateVariable struct {
 
 var templateMarkerRx = regexp.MustCompile(`\{{2,}|\}{2,}`)
 
+// autoTemplateChezmoiKeys are the keys in .chezmoi whose values are replaced
+// when generating templates automatically.
+var autoTemplateChezmoiKeys = []string{
+	"homeDir",
+	"hostname",
+	"username",
+}
+
 // autoTemplate converts contents into a template by escaping template markers
 // and replacing values in data with their keys. It returns the template and if
 // any replacements were made.
@@ -0,0 +1,64 @@
+package chezmoi
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// A bandwidthLimiter limits the combined rate at which all the readers that it
+// wraps can be read.
+type bandwidthLimiter struct {
+	sync.Mutex
+	bytesPerSecond int64
+	next           time.Time
+}
+
+// A bandwidthLimitedReadCloser is an io.ReadCloser whose reads are limited by
+// a bandwidthLimiter.
+type bandwidthLimitedReadCloser struct {
+	io.ReadCloser
+	limiter *bandwidthLimiter
+}
+
+// newBandwidthLimiter returns a new bandwidthLimiter that allows
+// bytesPerSecond bytes to be read every second.
+func newBandwidthLimiter(bytesPerSecond int64) *bandwidthLimiter {
+	return &bandwidthLimiter{
+		bytesPerSecond: bytesPerSecond,
+	}
+}
+
+// limitReadCloser returns an io.ReadCloser that reads from r at the rate
+// allowed by l.
+func (l *bandwidthLimiter) limitReadCloser(r io.ReadCloser) io.ReadCloser {
+	return &bandwidthLimitedReadCloser{
+		ReadCloser: r,
+		limiter:    l,
+	}
+}
+
+// wait reserves time for n bytes and waits until the reservation ends.
+func (l *bandwidthLimiter) wait(n int) {
+	l.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	l.next = l.next.Add(time.Duration(n) * time.Second / time.Duration(l.bytesPerSecond))
+	delay := l.next.Sub(now)
+	l.Unlock()
+	time.Sleep(delay)
+}
+
+// Read implements io.Reader.Read.
+func (r *bandwidthLimitedReadCloser) Read(p []byte) (int, error) {
+	// Limit the size of each read to a tenth of a second's worth of data so
+	// that the rate is smooth.
+	if maxLen := max(r.limiter.bytesPerSecond/10, 1); int64(len(p)) > maxLen {
+		p = p[:maxLen]
+	}
+	n, err := r.ReadCloser.Read(p)
+	r.limiter.wait(n)
+	return n, err
+}
@@ -0,0 +1,22 @@
+package chezmoi
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestBandwidthLimiter(t *testing.T) {
+	limiter := newBandwidthLimiter(4096)
+	data := bytes.Repeat([]byte{'a'}, 1024)
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		actualData, err := io.ReadAll(limiter.limitReadCloser(io.NopCloser(bytes.NewReader(data))))
+		assert.NoError(t, err)
+		assert.Equal(t, data, actualData)
+	}
+	assert.True(t, time.Since(start) >= 500*time.Millisecond)
+}
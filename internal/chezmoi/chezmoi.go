@@ -61,15 +61,20 @@ const (
 const (
 	Prefix = ".chezmoi"
 
-	RootName         = Prefix + "root"
-	TemplatesDirName = Prefix + "templates"
-	VersionName      = Prefix + "version"
-	dataName         = Prefix + "data"
-	externalName     = Prefix + "external"
-	externalsDirName = Prefix + "externals"
-	ignoreName       = Prefix + "ignore"
-	removeName       = Prefix + "remove"
-	scriptsDirName   = Prefix + "scripts"
+	ManifestName          = Prefix + "manifest"
+	ManifestSignatureName = ManifestName + ".asc"
+	RootName              = Prefix + "root"
+	TemplatesDirName      = Prefix + "templates"
+	VersionName           = Prefix + "version"
+	addIgnoreName         = Prefix + "addignore"
+	dataName              = Prefix + "data"
+	externalName          = Prefix + "external"
+	externalsDirName      = Prefix + "externals"
+	gpgKeysName           = Prefix + "gpgkeys"
+	ignoreName            = Prefix + "ignore"
+	metadataName          = Prefix + "metadata"
+	removeName            = Prefix + "remove"
+	scriptsDirName        = Prefix + "scripts"
 )
 
 var (
@@ -88,6 +93,8 @@ var knownPrefixedFiles = chezmoiset.New(
 	Prefix+".yaml"+TemplateSuffix,
 	RootName,
 	VersionName,
+	addIgnoreName+TemplateSuffix,
+	addIgnoreName,
 	dataName+".json",
 	dataName+".toml",
 	dataName+".yaml",
@@ -97,8 +104,14 @@ var knownPrefixedFiles = chezmoiset.New(
 	externalName+".toml",
 	externalName+".yaml"+TemplateSuffix,
 	externalName+".yaml",
+	gpgKeysName+".json",
+	gpgKeysName+".toml",
+	gpgKeysName+".yaml",
 	ignoreName+TemplateSuffix,
 	ignoreName,
+	metadataName+".json",
+	metadataName+".toml",
+	metadataName+".yaml",
 	removeName+TemplateSuffix,
 	removeName,
 )
@@ -196,7 +209,7 @@ func SHA256Sum(data []byte) []byte {
 func SuspiciousSourceDirEntry(base string, fileInfo fs.FileInfo, encryptedSuffixes []string) bool {
 	switch fileInfo.Mode().Type() {
 	case 0:
-		if strings.HasPrefix(base, Prefix) && !knownPrefixedFiles.Contains(base) {
+		if strings.HasPrefix(base, Prefix) && !knownPrefixedFiles.Contains(base) && !isKnownPrefixDotFormat(base) {
 			return true
 		}
 		for _, encryptedSuffix := range encryptedSuffixes {
@@ -321,6 +334,16 @@ func isEmpty(data []byte) bool {
 	return len(bytes.TrimSpace(data)) == 0
 }
 
+// isKnownPrefixDotFormat returns true if base is a file with the .chezmoi
+// prefix that can be written in any supported format.
+func isKnownPrefixDotFormat(base string) bool {
+	return isPrefixDotFormat(base, dataName) ||
+		isPrefixDotFormat(base, externalName) ||
+		isPrefixDotFormatDotTmpl(base, externalName) ||
+		isPrefixDotFormat(base, gpgKeysName) ||
+		isPrefixDotFormat(base, metadataName)
+}
+
 // md5Sum returns the MD5 sum of data.
 func md5Sum(data []byte) []byte {
 	md5SumArr := md5.Sum(data) //nolint:gosec
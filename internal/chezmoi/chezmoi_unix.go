@@ -41,3 +41,14 @@ func isPrivate(fileInfo fs.FileInfo) bool {
 func isReadOnly(fileInfo fs.FileInfo) bool {
 	return fileInfo.Mode().Perm()&0o222 == 0
 }
+
+// permEqual returns if perm1 and perm2 are equal.
+func permEqual(perm1, perm2 fs.FileMode) bool {
+	return perm1 == perm2
+}
+
+// validateTargetRelPath returns nil as all target names are valid on non-Windows
+// systems.
+func validateTargetRelPath(targetRelPath RelPath) error {
+	return nil
+}
@@ -55,17 +55,36 @@ func UserHomeDir() (string, error) {
 	return os.UserHomeDir()
 }
 
-// isPrivate returns false on Windows.
+// isPrivate returns if fileInfo has no group or other permissions. On Windows,
+// only a RealSystem that sets private ACLs reports files and directories with
+// restricted access in this way.
 func isPrivate(fileInfo fs.FileInfo) bool {
-	return false
+	return fileInfo.Mode().Perm()&0o77 == 0
 }
 
-// isReadOnly returns false on Windows.
+// isReadOnly returns if fileInfo has the read-only attribute set. On Windows,
+// os.Stat reports files with the read-only attribute as having no write
+// permissions.
 func isReadOnly(fileInfo fs.FileInfo) bool {
-	return false
+	return fileInfo.Mode().Perm()&0o222 == 0
+}
+
+// permEqual returns if perm1 and perm2 are equal as far as Windows can
+// represent them, that is if they are both private or not and both read-only or
+// not.
+func permEqual(perm1, perm2 fs.FileMode) bool {
+	private1, private2 := perm1&0o77 == 0, perm2&0o77 == 0
+	readOnly1, readOnly2 := perm1&0o222 == 0, perm2&0o222 == 0
+	return private1 == private2 && readOnly1 == readOnly2
 }
 
 // isSlash returns if c is a slash character.
 func isSlash(c byte) bool {
 	return c == '\\' || c == '/'
 }
+
+// validateTargetRelPath returns an error if targetRelPath cannot be created on
+// Windows.
+func validateTargetRelPath(targetRelPath RelPath) error {
+	return validateWindowsTargetRelPath(targetRelPath)
+}
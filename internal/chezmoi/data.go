@@ -13,8 +13,19 @@ import (
 	"unicode"
 
 	"github.com/twpayne/go-vfs/v5"
+	"howett.net/plist"
 )
 
+// macOSSystemVersionKeys maps keys in macOS's SystemVersion.plist to the keys
+// printed by sw_vers.
+var macOSSystemVersionKeys = map[string]string{
+	"ProductBuildVersion":       "buildVersion",
+	"ProductName":               "productName",
+	"ProductVersion":            "productVersion",
+	"ProductVersionExtra":       "productVersionExtra",
+	"ProductUserVisibleVersion": "productUserVisibleVersion",
+}
+
 // Kernel returns the kernel information parsed from /proc/sys/kernel.
 func Kernel(fileSystem vfs.FS) (map[string]any, error) {
 	const procSysKernel = "/proc/sys/kernel"
@@ -50,6 +61,28 @@ func Kernel(fileSystem vfs.FS) (map[string]any, error) {
 	return kernel, nil
 }
 
+// MacOSVersion returns the macOS version information, as printed by sw_vers,
+// parsed from /System/Library/CoreServices/SystemVersion.plist.
+func MacOSVersion(fileSystem vfs.FS) (map[string]any, error) {
+	const systemVersionPlist = "/System/Library/CoreServices/SystemVersion.plist"
+
+	data, err := fileSystem.ReadFile(systemVersionPlist)
+	if err != nil {
+		return nil, err
+	}
+	var systemVersion map[string]any
+	if _, err := plist.Unmarshal(data, &systemVersion); err != nil {
+		return nil, fmt.Errorf("%s: %w", systemVersionPlist, err)
+	}
+	macOSVersion := make(map[string]any)
+	for plistKey, key := range macOSSystemVersionKeys {
+		if value, ok := systemVersion[plistKey].(string); ok {
+			macOSVersion[key] = value
+		}
+	}
+	return macOSVersion, nil
+}
+
 // OSRelease returns the operating system identification data as defined by the
 // os-release specification.
 func OSRelease(fileSystem vfs.FS) (map[string]any, error) {
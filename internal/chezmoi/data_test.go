@@ -61,6 +61,40 @@ func TestKernel(t *testing.T) {
 	}
 }
 
+func TestMacOSVersion(t *testing.T) {
+	chezmoitest.WithTestFS(t, map[string]any{
+		"/System/Library/CoreServices/SystemVersion.plist": chezmoitest.JoinLines(
+			`<?xml version="1.0" encoding="UTF-8"?>`,
+			`<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">`,
+			`<plist version="1.0">`,
+			`<dict>`,
+			`	<key>BuildID</key>`,
+			`	<string>0D0C2E9C-6A5C-11EE-B4C2-0AFE3B2D6C1B</string>`,
+			`	<key>ProductBuildVersion</key>`,
+			`	<string>23B81</string>`,
+			`	<key>ProductCopyright</key>`,
+			`	<string>1983-2023 Apple Inc.</string>`,
+			`	<key>ProductName</key>`,
+			`	<string>macOS</string>`,
+			`	<key>ProductUserVisibleVersion</key>`,
+			`	<string>14.1.1</string>`,
+			`	<key>ProductVersion</key>`,
+			`	<string>14.1.1</string>`,
+			`</dict>`,
+			`</plist>`,
+		),
+	}, func(fileSystem vfs.FS) {
+		actual, err := MacOSVersion(fileSystem)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]any{
+			"buildVersion":              "23B81",
+			"productName":               "macOS",
+			"productUserVisibleVersion": "14.1.1",
+			"productVersion":            "14.1.1",
+		}, actual)
+	})
+}
+
 func TestOSRelease(t *testing.T) {
 	for _, tc := range []struct {
 		name     string
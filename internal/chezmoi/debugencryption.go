@@ -21,6 +21,16 @@ func NewDebugEncryption(encryption Encryption, logger *slog.Logger) *DebugEncryp
 	}
 }
 
+// CiphertextRecipients implements Encryption.CiphertextRecipients.
+func (e *DebugEncryption) CiphertextRecipients(ciphertext []byte) ([]string, error) {
+	recipients, err := e.encryption.CiphertextRecipients(ciphertext)
+	chezmoilog.InfoOrError(e.logger, "CiphertextRecipients", err,
+		chezmoilog.FirstFewBytes("ciphertext", ciphertext),
+		slog.Any("recipients", recipients),
+	)
+	return recipients, err
+}
+
 // Decrypt implements Encryption.Decrypt.
 func (e *DebugEncryption) Decrypt(ciphertext []byte) ([]byte, error) {
 	plaintext, err := e.encryption.Decrypt(ciphertext)
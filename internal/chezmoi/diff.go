@@ -110,6 +110,21 @@ func DiffPatch(path RelPath, fromData []byte, fromMode fs.FileMode, toData []byt
 	}, nil
 }
 
+// A DiffRedactFunc returns data, the contents of absPath, with any secrets
+// replaced, and whether the contents of absPath should be hidden completely.
+// absPath is empty for scripts.
+type DiffRedactFunc func(absPath AbsPath, data []byte) ([]byte, bool)
+
+// redactDiffPatch removes the contents of the files from p, so that it only
+// shows that the files differ, like a diff of binary files.
+func redactDiffPatch(p *gitDiffPatch) {
+	for _, filePatch := range p.filePatches {
+		gitDiffFilePatch := filePatch.(*gitDiffFilePatch) //nolint:forcetypeassert
+		gitDiffFilePatch.isBinary = true
+		gitDiffFilePatch.chunks = nil
+	}
+}
+
 // diffChunks returns the
 // github.com/go-git/go-git/v5/plumbing/format/diff.Chunks required to transform
 // from into to.
@@ -14,6 +14,7 @@ type dataType string
 const (
 	dataTypeCommand dataType = "command"
 	dataTypeDir     dataType = "dir"
+	dataTypeError   dataType = "error"
 	dataTypeFile    dataType = "file"
 	dataTypeScript  dataType = "script"
 	dataTypeSymlink dataType = "symlink"
@@ -40,6 +41,13 @@ type dirData struct {
 	Perm fs.FileMode `json:"perm" yaml:"perm"`
 }
 
+// An errorData contains data about a target that could not be dumped.
+type errorData struct {
+	Type  dataType `json:"type"  yaml:"type"`
+	Name  AbsPath  `json:"name"  yaml:"name"`
+	Error string   `json:"error" yaml:"error"`
+}
+
 // A fileData contains data about a file.
 type fileData struct {
 	Type     dataType    `json:"type"     yaml:"type"`
@@ -114,6 +122,16 @@ func (s *DumpSystem) RunScript(scriptname RelPath, dir AbsPath, data []byte, opt
 	return s.setData(scriptnameStr, scriptData)
 }
 
+// SetError records that name could not be dumped because of err, replacing
+// anything already dumped for name.
+func (s *DumpSystem) SetError(name AbsPath, err error) {
+	s.data[name.String()] = &errorData{
+		Type:  dataTypeError,
+		Name:  name,
+		Error: err.Error(),
+	}
+}
+
 // UnderlyingFS implements System.UnderlyingFS.
 func (s *DumpSystem) UnderlyingFS() vfs.FS {
 	return nil
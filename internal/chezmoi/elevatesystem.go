@@ -0,0 +1,153 @@
+package chezmoi
+
+import (
+	"errors"
+	"io/fs"
+	"os/exec"
+	"strconv"
+	"time"
+
+	vfs "github.com/twpayne/go-vfs/v5"
+)
+
+// elevateTouchTimeFormat is the format of times passed to touch -d, which is
+// understood by both GNU and BSD touch.
+const elevateTouchTimeFormat = "2006-01-02T15:04:05Z"
+
+// elevateWriteFileScript atomically replaces $1 with stdin and permissions $2.
+// stdin is written to a temporary file in the same directory as $1, which
+// mktemp creates readable only by its owner, and the temporary file is then
+// renamed over $1, so an interrupted write never leaves $1 truncated. The
+// temporary file is removed if any step fails.
+const elevateWriteFileScript = `tmp=$(mktemp "${1%/*}/.chezmoi.XXXXXXXXXX") || exit
+trap 'rm -f -- "$tmp"' EXIT
+trap 'exit 1' HUP INT TERM
+cat > "$tmp" && chmod -- "$2" "$tmp" && mv -f -- "$tmp" "$1"`
+
+// An ElevateSystem is a System that retries writes that fail with a permission
+// error by running the equivalent shell commands with elevated privileges, for
+// example with sudo or doas. Writes that succeed without elevated privileges
+// are never elevated.
+type ElevateSystem struct {
+	system  System
+	command string
+	args    []string
+}
+
+// NewElevateSystem returns a new ElevateSystem that wraps system and elevates
+// privileges by prefixing commands with command and args.
+func NewElevateSystem(system System, command string, args []string) *ElevateSystem {
+	return &ElevateSystem{
+		system:  system,
+		command: command,
+		args:    args,
+	}
+}
+
+// Chmod implements System.Chmod.
+func (s *ElevateSystem) Chmod(name AbsPath, mode fs.FileMode) error {
+	return s.elevateOnPermissionError(s.system.Chmod(name, mode), nil, "chmod", "--", formatPerm(mode), name)
+}
+
+// Chtimes implements System.Chtimes.
+func (s *ElevateSystem) Chtimes(name AbsPath, atime, mtime time.Time) error {
+	err := s.system.Chtimes(name, atime, mtime)
+	if !errors.Is(err, fs.ErrPermission) {
+		return err
+	}
+	if err := s.elevateOnPermissionError(err, nil, "touch", "-a", "-d", atime.UTC().Format(elevateTouchTimeFormat), "--", name); err != nil {
+		return err
+	}
+	return s.elevateOnPermissionError(err, nil, "touch", "-m", "-d", mtime.UTC().Format(elevateTouchTimeFormat), "--", name)
+}
+
+// Glob implements System.Glob.
+func (s *ElevateSystem) Glob(pattern string) ([]string, error) {
+	return s.system.Glob(pattern)
+}
+
+// Link implements System.Link.
+func (s *ElevateSystem) Link(oldname, newname AbsPath) error {
+	return s.elevateOnPermissionError(s.system.Link(oldname, newname), nil, "ln", "--", oldname, newname)
+}
+
+// Lstat implements System.Lstat.
+func (s *ElevateSystem) Lstat(name AbsPath) (fs.FileInfo, error) {
+	return s.system.Lstat(name)
+}
+
+// Mkdir implements System.Mkdir.
+func (s *ElevateSystem) Mkdir(name AbsPath, perm fs.FileMode) error {
+	return s.elevateOnPermissionError(s.system.Mkdir(name, perm), nil, "mkdir", "-m", formatPerm(perm), "--", name)
+}
+
+// RawPath implements System.RawPath.
+func (s *ElevateSystem) RawPath(path AbsPath) (AbsPath, error) {
+	return s.system.RawPath(path)
+}
+
+// ReadDir implements System.ReadDir.
+func (s *ElevateSystem) ReadDir(name AbsPath) ([]fs.DirEntry, error) {
+	return s.system.ReadDir(name)
+}
+
+// ReadFile implements System.ReadFile.
+func (s *ElevateSystem) ReadFile(name AbsPath) ([]byte, error) {
+	return s.system.ReadFile(name)
+}
+
+// Readlink implements System.Readlink.
+func (s *ElevateSystem) Readlink(name AbsPath) (string, error) {
+	return s.system.Readlink(name)
+}
+
+// Remove implements System.Remove.
+func (s *ElevateSystem) Remove(name AbsPath) error {
+	return s.elevateOnPermissionError(s.system.Remove(name), nil, "rm", "-d", "--", name)
+}
+
+// RemoveAll implements System.RemoveAll.
+func (s *ElevateSystem) RemoveAll(name AbsPath) error {
+	return s.elevateOnPermissionError(s.system.RemoveAll(name), nil, "rm", "-rf", "--", name)
+}
+
+// Rename implements System.Rename.
+func (s *ElevateSystem) Rename(oldpath, newpath AbsPath) error {
+	return s.elevateOnPermissionError(s.system.Rename(oldpath, newpath), nil, "mv", "-f", "--", oldpath, newpath)
+}
+
+// RunCmd implements System.RunCmd.
+func (s *ElevateSystem) RunCmd(cmd *exec.Cmd) error {
+	return s.system.RunCmd(cmd)
+}
+
+// RunScript implements System.RunScript.
+func (s *ElevateSystem) RunScript(scriptname RelPath, dir AbsPath, data []byte, options RunScriptOptions) error {
+	return s.system.RunScript(scriptname, dir, data, options)
+}
+
+// Stat implements System.Stat.
+func (s *ElevateSystem) Stat(name AbsPath) (fs.FileInfo, error) {
+	return s.system.Stat(name)
+}
+
+// UnderlyingFS implements System.UnderlyingFS.
+func (s *ElevateSystem) UnderlyingFS() vfs.FS {
+	return s.system.UnderlyingFS()
+}
+
+// WriteFile implements System.WriteFile.
+func (s *ElevateSystem) WriteFile(filename AbsPath, data []byte, perm fs.FileMode) error {
+	err := s.system.WriteFile(filename, data, perm)
+	return s.elevateOnPermissionError(err, data, "sh", "-c", elevateWriteFileScript, "sh", filename, formatPerm(perm))
+}
+
+// WriteSymlink implements System.WriteSymlink.
+func (s *ElevateSystem) WriteSymlink(oldname string, newname AbsPath) error {
+	return s.elevateOnPermissionError(s.system.WriteSymlink(oldname, newname), nil, "ln", "-sfn", "--", oldname, newname)
+}
+
+// formatPerm returns the permission bits of mode formatted in octal.
+func formatPerm(mode fs.FileMode) string {
+	return strconv.FormatUint(uint64(mode.Perm()), 8)
+}
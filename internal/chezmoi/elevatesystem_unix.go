@@ -0,0 +1,50 @@
+//go:build unix
+
+package chezmoi
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"os/exec"
+
+	"github.com/twpayne/chezmoi/v2/internal/chezmoilog"
+)
+
+// elevateOnPermissionError runs name with args and stdin with elevated
+// privileges if err is a permission error. Otherwise, it returns err.
+func (s *ElevateSystem) elevateOnPermissionError(err error, stdin []byte, name string, args ...any) error {
+	if !errors.Is(err, fs.ErrPermission) {
+		return err
+	}
+	return s.run(stdin, name, args...)
+}
+
+// run runs name with args and stdin with elevated privileges. args may be
+// strings or AbsPaths, which are converted to raw paths.
+func (s *ElevateSystem) run(stdin []byte, name string, args ...any) error {
+	cmdArgs := append(append([]string{}, s.args...), name)
+	for _, arg := range args {
+		switch arg := arg.(type) {
+		case string:
+			cmdArgs = append(cmdArgs, arg)
+		case AbsPath:
+			rawPath, err := s.system.RawPath(arg)
+			if err != nil {
+				return err
+			}
+			cmdArgs = append(cmdArgs, rawPath.String())
+		default:
+			panic(fmt.Sprintf("%T: unsupported argument type", arg))
+		}
+	}
+	cmd := exec.Command(s.command, cmdArgs...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	cmd.Stderr = os.Stderr
+	return chezmoilog.LogCmdRun(slog.Default(), cmd)
+}
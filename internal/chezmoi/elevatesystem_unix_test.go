@@ -0,0 +1,105 @@
+//go:build unix
+
+package chezmoi
+
+import (
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	vfs "github.com/twpayne/go-vfs/v5"
+	"github.com/twpayne/go-vfs/v5/vfst"
+
+	"github.com/twpayne/chezmoi/v2/internal/chezmoitest"
+)
+
+var _ System = &ElevateSystem{}
+
+func TestElevateSystem(t *testing.T) {
+	chezmoitest.WithTestFS(t, map[string]any{
+		"/etc": map[string]any{
+			"file":     "# contents of file\n",
+			"oldfile":  "# contents of oldfile\n",
+			"dir/file": "",
+		},
+	}, func(fileSystem vfs.FS) {
+		// Simulate a system where every write fails with a permission error,
+		// and elevate privileges with env, which runs the command unchanged.
+		system := NewElevateSystem(NewErrorOnWriteSystem(NewRealSystem(fileSystem), fs.ErrPermission), "env", nil)
+
+		// A hard link to /etc/file keeps the old contents only if /etc/file is
+		// replaced, rather than rewritten in place.
+		assert.NoError(t, fileSystem.Link("/etc/file", "/etc/filelink"))
+
+		assert.NoError(t, system.WriteFile(NewAbsPath("/etc/newfile"), []byte("# contents of newfile\n"), 0o600))
+		assert.NoError(t, system.WriteFile(NewAbsPath("/etc/file"), []byte("# new contents of file\n"), 0o644))
+		assert.NoError(t, system.Mkdir(NewAbsPath("/etc/newdir"), 0o700))
+		assert.NoError(t, system.Chmod(NewAbsPath("/etc/newdir"), 0o755))
+		assert.NoError(t, system.Rename(NewAbsPath("/etc/oldfile"), NewAbsPath("/etc/renamedfile")))
+		assert.NoError(t, system.WriteSymlink("file", NewAbsPath("/etc/symlink")))
+		assert.NoError(t, system.WriteSymlink("-file", NewAbsPath("/etc/dashsymlink")))
+		assert.NoError(t, system.RemoveAll(NewAbsPath("/etc/dir")))
+		mtime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		assert.NoError(t, system.Chtimes(NewAbsPath("/etc/file"), mtime, mtime))
+
+		vfst.RunTests(t, fileSystem, "",
+			vfst.TestPath("/etc/newfile",
+				vfst.TestModeIsRegular(),
+				vfst.TestModePerm(0o600),
+				vfst.TestContentsString("# contents of newfile\n"),
+			),
+			vfst.TestPath("/etc/file",
+				vfst.TestModeIsRegular(),
+				vfst.TestModePerm(0o644),
+				vfst.TestContentsString("# new contents of file\n"),
+			),
+			vfst.TestPath("/etc/filelink",
+				vfst.TestContentsString("# contents of file\n"),
+			),
+			vfst.TestPath("/etc/newdir",
+				vfst.TestIsDir(),
+				vfst.TestModePerm(0o755),
+			),
+			vfst.TestPath("/etc/oldfile",
+				vfst.TestDoesNotExist(),
+			),
+			vfst.TestPath("/etc/renamedfile",
+				vfst.TestContentsString("# contents of oldfile\n"),
+			),
+			vfst.TestPath("/etc/symlink",
+				vfst.TestModeType(fs.ModeSymlink),
+				vfst.TestSymlinkTarget("file"),
+			),
+			vfst.TestPath("/etc/dashsymlink",
+				vfst.TestModeType(fs.ModeSymlink),
+				vfst.TestSymlinkTarget("-file"),
+			),
+			vfst.TestPath("/etc/dir",
+				vfst.TestDoesNotExist(),
+			),
+		)
+
+		dirEntries, err := fileSystem.ReadDir("/etc")
+		assert.NoError(t, err)
+		for _, dirEntry := range dirEntries {
+			assert.False(t, strings.HasPrefix(dirEntry.Name(), ".chezmoi."))
+		}
+
+		fileInfo, err := fileSystem.Stat("/etc/file")
+		assert.NoError(t, err)
+		assert.Equal(t, mtime, fileInfo.ModTime().UTC())
+	})
+}
+
+func TestElevateSystemOtherError(t *testing.T) {
+	chezmoitest.WithTestFS(t, map[string]any{
+		"/etc": &vfst.Dir{Perm: 0o755},
+	}, func(fileSystem vfs.FS) {
+		otherErr := errors.New("other error")
+		system := NewElevateSystem(NewErrorOnWriteSystem(NewRealSystem(fileSystem), otherErr), "false", nil)
+		assert.Equal(t, otherErr, system.WriteFile(NewAbsPath("/etc/file"), nil, 0o644))
+	})
+}
@@ -0,0 +1,7 @@
+package chezmoi
+
+// elevateOnPermissionError returns err. Elevating privileges is not supported
+// on Windows.
+func (s *ElevateSystem) elevateOnPermissionError(err error, stdin []byte, name string, args ...any) error {
+	return err
+}
@@ -2,6 +2,7 @@ package chezmoi
 
 // An Encryption encrypts and decrypts files and data.
 type Encryption interface {
+	CiphertextRecipients(ciphertext []byte) ([]string, error)
 	Decrypt(ciphertext []byte) ([]byte, error)
 	DecryptToFile(plaintextAbsPath AbsPath, ciphertext []byte) error
 	Encrypt(plaintext []byte) ([]byte, error)
@@ -16,6 +16,10 @@ type xorEncryption struct {
 
 var _ Encryption = &xorEncryption{}
 
+func (e *xorEncryption) CiphertextRecipients(ciphertext []byte) ([]string, error) {
+	return []string{"xor"}, nil
+}
+
 func (e *xorEncryption) Decrypt(ciphertext []byte) ([]byte, error) {
 	return e.xorWithKey(ciphertext), nil
 }
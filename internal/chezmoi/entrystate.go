@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"io/fs"
 	"log/slog"
-	"runtime"
 
 	"github.com/twpayne/chezmoi/v2/internal/chezmoilog"
 )
@@ -41,7 +40,7 @@ func (s *EntryState) Equal(other *EntryState) bool {
 	if s.Type != other.Type {
 		return false
 	}
-	if runtime.GOOS != "windows" && s.Mode.Perm() != other.Mode.Perm() {
+	if !permEqual(s.Mode.Perm(), other.Mode.Perm()) {
 		return false
 	}
 	return bytes.Equal(s.ContentsSHA256, other.ContentsSHA256)
@@ -24,7 +24,8 @@ type TooOldError struct {
 }
 
 func (e *TooOldError) Error() string {
-	format := "source state requires chezmoi version %s or later, chezmoi is version %s"
+	format := "source state requires chezmoi version %s or later, chezmoi is version %s: " +
+		"upgrade chezmoi, see https://www.chezmoi.io/install/"
 	return fmt.Sprintf(format, e.Need, e.Have)
 }
 
@@ -27,6 +27,9 @@ type ExternalDiffSystem struct {
 	filter         *EntryTypeFilter
 	reverse        bool
 	scriptContents bool
+	redactFunc     DiffRedactFunc
+
+	redactedDestTempDirAbsPath AbsPath
 }
 
 // ExternalDiffSystemOptions are options for NewExternalDiffSystem.
@@ -34,6 +37,7 @@ type ExternalDiffSystemOptions struct {
 	Filter         *EntryTypeFilter
 	Reverse        bool
 	ScriptContents bool
+	RedactFunc     DiffRedactFunc
 }
 
 // NewExternalDiffSystem creates a new ExternalDiffSystem.
@@ -52,16 +56,20 @@ func NewExternalDiffSystem(
 		filter:         options.Filter,
 		reverse:        options.Reverse,
 		scriptContents: options.ScriptContents,
+		redactFunc:     options.RedactFunc,
 	}
 }
 
 // Close frees all resources held by s.
 func (s *ExternalDiffSystem) Close() error {
-	if !s.tempDirAbsPath.Empty() {
-		if err := os.RemoveAll(s.tempDirAbsPath.String()); err != nil && !errors.Is(err, fs.ErrNotExist) {
+	for _, tempDirAbsPath := range []*AbsPath{&s.tempDirAbsPath, &s.redactedDestTempDirAbsPath} {
+		if tempDirAbsPath.Empty() {
+			continue
+		}
+		if err := os.RemoveAll(tempDirAbsPath.String()); err != nil && !errors.Is(err, fs.ErrNotExist) {
 			return err
 		}
-		s.tempDirAbsPath = EmptyAbsPath
+		*tempDirAbsPath = EmptyAbsPath
 	}
 	return nil
 }
@@ -199,6 +207,9 @@ func (s *ExternalDiffSystem) RunScript(scriptname RelPath, dir AbsPath, data []b
 		if !s.scriptContents {
 			toData = nil
 		}
+		if s.redactFunc != nil && toData != nil {
+			toData, _ = s.redactFunc(EmptyAbsPath, toData)
+		}
 		if err := os.WriteFile(targetAbsPath.String(), toData, 0o700); err != nil { //nolint:gosec
 			return err
 		}
@@ -232,11 +243,21 @@ func (s *ExternalDiffSystem) WriteFile(filename AbsPath, data []byte, perm fs.Fi
 			return err
 		}
 
-		// Write the target contents to a file in a temporary directory.
 		targetRelPath, err := filename.TrimDirPrefix(s.destDirAbsPath)
 		if err != nil {
 			return err
 		}
+
+		// Redact both the destination and the target contents before passing
+		// them to the external diff command.
+		targetData := data
+		if s.redactFunc != nil {
+			if destAbsPath, targetData, err = s.redact(filename, destAbsPath, targetRelPath, data); err != nil {
+				return err
+			}
+		}
+
+		// Write the target contents to a file in a temporary directory.
 		tempDirAbsPath, err := s.tempDir()
 		if err != nil {
 			return err
@@ -245,7 +266,7 @@ func (s *ExternalDiffSystem) WriteFile(filename AbsPath, data []byte, perm fs.Fi
 		if err := os.MkdirAll(targetAbsPath.Dir().String(), 0o700); err != nil {
 			return err
 		}
-		if err := os.WriteFile(targetAbsPath.String(), data, perm); err != nil {
+		if err := os.WriteFile(targetAbsPath.String(), targetData, perm); err != nil {
 			return err
 		}
 
@@ -262,6 +283,50 @@ func (s *ExternalDiffSystem) WriteSymlink(oldname string, newname AbsPath) error
 	return s.system.WriteSymlink(oldname, newname)
 }
 
+// redact returns the path of the destination file and the target contents to
+// pass to the external diff command for filename. If redacting changes the
+// destination contents then they are written to a file in a temporary
+// directory. If the contents should be hidden completely then both are
+// replaced by placeholders.
+func (s *ExternalDiffSystem) redact(
+	filename, destAbsPath AbsPath, targetRelPath RelPath, targetData []byte,
+) (AbsPath, []byte, error) {
+	var destData []byte
+	if destAbsPath != devNullAbsPath {
+		var err error
+		if destData, err = os.ReadFile(destAbsPath.String()); err != nil {
+			return EmptyAbsPath, nil, err
+		}
+	}
+	redactedDestData, hideDest := s.redactFunc(filename, destData)
+	redactedTargetData, hideTarget := s.redactFunc(filename, targetData)
+	if hideDest || hideTarget {
+		redactedTargetData = []byte("# target contents of " + targetRelPath.String() + " hidden\n")
+		if destAbsPath != devNullAbsPath {
+			redactedDestData = []byte("# destination contents of " + targetRelPath.String() + " hidden\n")
+		}
+	}
+	if destAbsPath == devNullAbsPath || bytes.Equal(redactedDestData, destData) {
+		return destAbsPath, redactedTargetData, nil
+	}
+
+	if s.redactedDestTempDirAbsPath.Empty() {
+		tempDir, err := os.MkdirTemp("", "chezmoi-diff-destination")
+		if err != nil {
+			return EmptyAbsPath, nil, err
+		}
+		s.redactedDestTempDirAbsPath = NewAbsPath(tempDir)
+	}
+	redactedDestAbsPath := s.redactedDestTempDirAbsPath.Join(targetRelPath)
+	if err := os.MkdirAll(redactedDestAbsPath.Dir().String(), 0o700); err != nil {
+		return EmptyAbsPath, nil, err
+	}
+	if err := os.WriteFile(redactedDestAbsPath.String(), redactedDestData, 0o600); err != nil {
+		return EmptyAbsPath, nil, err
+	}
+	return redactedDestAbsPath, redactedTargetData, nil
+}
+
 // tempDir creates a temporary directory for s if it does not already exist and
 // returns its path.
 func (s *ExternalDiffSystem) tempDir() (AbsPath, error) {
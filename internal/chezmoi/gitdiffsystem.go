@@ -26,6 +26,7 @@ type GitDiffSystem struct {
 	reverse        bool
 	scriptContents bool
 	textConvFunc   TextConvFunc
+	redactFunc     DiffRedactFunc
 	unifiedEncoder *diff.UnifiedEncoder
 }
 
@@ -36,6 +37,7 @@ type GitDiffSystemOptions struct {
 	Reverse        bool
 	ScriptContents bool
 	TextConvFunc   TextConvFunc
+	RedactFunc     DiffRedactFunc
 }
 
 // NewGitDiffSystem returns a new GitDiffSystem. Output is written to w, the
@@ -53,6 +55,7 @@ func NewGitDiffSystem(system System, w io.Writer, dirAbsPath AbsPath, options *G
 		reverse:        options.Reverse,
 		scriptContents: options.ScriptContents,
 		textConvFunc:   options.TextConvFunc,
+		redactFunc:     options.RedactFunc,
 		unifiedEncoder: unifiedEncoder,
 	}
 }
@@ -212,6 +215,9 @@ func (s *GitDiffSystem) RunScript(scriptname RelPath, dir AbsPath, data []byte,
 		if !s.scriptContents {
 			toData = nil
 		}
+		if s.redactFunc != nil && toData != nil {
+			toData, _ = s.redactFunc(EmptyAbsPath, toData)
+		}
 		if s.reverse {
 			fromData, toData = toData, fromData
 			fromMode, toMode = toMode, fromMode
@@ -303,6 +309,14 @@ func (s *GitDiffSystem) encodeDiff(absPath AbsPath, toData []byte, toMode fs.Fil
 		}
 	}
 
+	hide := false
+	if s.redactFunc != nil {
+		var hideFrom, hideTo bool
+		fromData, hideFrom = s.redactFunc(absPath, fromData)
+		toData, hideTo = s.redactFunc(absPath, toData)
+		hide = hideFrom || hideTo
+	}
+
 	if s.reverse {
 		fromData, toData = toData, fromData
 		fromMode, toMode = toMode, fromMode
@@ -312,6 +326,9 @@ func (s *GitDiffSystem) encodeDiff(absPath AbsPath, toData []byte, toMode fs.Fil
 	if err != nil {
 		return err
 	}
+	if hide {
+		redactDiffPatch(diffPatch.(*gitDiffPatch)) //nolint:forcetypeassert
+	}
 
 	return s.unifiedEncoder.Encode(diffPatch)
 }
@@ -9,9 +9,9 @@ import (
 	"golang.org/x/oauth2"
 )
 
-// NewGitHubClient returns a new github.Client configured with an access token
-// and a http client, if available.
-func NewGitHubClient(ctx context.Context, httpClient *http.Client) *github.Client {
+// GitHubAccessToken returns the GitHub access token from the environment, if
+// set.
+func GitHubAccessToken() string {
 	for _, key := range []string{
 		"CHEZMOI_GITHUB_ACCESS_TOKEN",
 		"CHEZMOI_GITHUB_TOKEN",
@@ -19,13 +19,27 @@ func NewGitHubClient(ctx context.Context, httpClient *http.Client) *github.Clien
 		"GITHUB_TOKEN",
 	} {
 		if accessToken := os.Getenv(key); accessToken != "" {
-			httpClient = oauth2.NewClient(
-				context.WithValue(ctx, oauth2.HTTPClient, httpClient),
-				oauth2.StaticTokenSource(&oauth2.Token{
-					AccessToken: accessToken,
-				}))
-			break
+			return accessToken
 		}
 	}
+	return ""
+}
+
+// NewGitHubClient returns a new github.Client configured with an access token
+// from the environment and a http client, if available.
+func NewGitHubClient(ctx context.Context, httpClient *http.Client) *github.Client {
+	return NewGitHubClientWithAccessToken(ctx, httpClient, GitHubAccessToken())
+}
+
+// NewGitHubClientWithAccessToken returns a new github.Client configured with
+// accessToken, if not empty, and a http client, if available.
+func NewGitHubClientWithAccessToken(ctx context.Context, httpClient *http.Client, accessToken string) *github.Client {
+	if accessToken != "" {
+		httpClient = oauth2.NewClient(
+			context.WithValue(ctx, oauth2.HTTPClient, httpClient),
+			oauth2.StaticTokenSource(&oauth2.Token{
+				AccessToken: accessToken,
+			}))
+	}
 	return github.NewClient(httpClient)
 }
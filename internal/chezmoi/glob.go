@@ -7,6 +7,10 @@ import (
 	vfs "github.com/twpayne/go-vfs/v5"
 )
 
+// globMetaChars are the characters that have a special meaning in glob
+// patterns.
+const globMetaChars = `*?[{\`
+
 // A lstatFS implements io/fs.StatFS but uses Lstat instead of Stat.
 type lstatFS struct {
 	wrapped interface {
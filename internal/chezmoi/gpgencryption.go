@@ -1,15 +1,21 @@
 package chezmoi
 
 import (
+	"bytes"
 	"log/slog"
 	"os"
 	"os/exec"
+	"regexp"
 	"runtime"
+	"slices"
+	"strings"
 
 	"github.com/twpayne/chezmoi/v2/internal/chezmoierrors"
 	"github.com/twpayne/chezmoi/v2/internal/chezmoilog"
 )
 
+var gpgPubkeyEncPacketRx = regexp.MustCompile(`^:pubkey enc packet: .*\bkeyid ([0-9A-Fa-f]+)`)
+
 // A GPGEncryption uses gpg for encryption and decryption. See https://gnupg.org/.
 type GPGEncryption struct {
 	Command    string   `json:"command"    mapstructure:"command"    yaml:"command"`
@@ -20,6 +26,29 @@ type GPGEncryption struct {
 	Suffix     string   `json:"suffix"     mapstructure:"suffix"     yaml:"suffix"`
 }
 
+// CiphertextRecipients implements Encryption.CiphertextRecipients. The key ID
+// of each public key that ciphertext is encrypted to is returned, and
+// "symmetric" if it is also encrypted with a passphrase.
+func (e *GPGEncryption) CiphertextRecipients(ciphertext []byte) ([]string, error) {
+	args := append(slices.Clone(e.Args), "--batch", "--list-only", "--list-packets")
+	cmd := exec.Command(e.Command, args...) //nolint:gosec
+	cmd.Stdin = bytes.NewReader(ciphertext)
+	output, err := chezmoilog.LogCmdOutput(slog.Default(), cmd)
+	if err != nil {
+		return nil, err
+	}
+	var recipients []string
+	for _, line := range strings.Split(string(output), "\n") {
+		switch {
+		case gpgPubkeyEncPacketRx.MatchString(line):
+			recipients = append(recipients, gpgPubkeyEncPacketRx.FindStringSubmatch(line)[1])
+		case strings.HasPrefix(line, ":symkey enc packet:"):
+			recipients = append(recipients, "symmetric")
+		}
+	}
+	return recipients, nil
+}
+
 // Decrypt implements Encryption.Decrypt.
 func (e *GPGEncryption) Decrypt(ciphertext []byte) ([]byte, error) {
 	var plaintext []byte
@@ -33,7 +33,7 @@ func TestGPGEncryption(t *testing.T) {
 		},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
-			testEncryption(t, &GPGEncryption{
+			gpgEncryption := &GPGEncryption{
 				Command: command,
 				Args: []string{
 					"--homedir", tempDir,
@@ -43,7 +43,16 @@ func TestGPGEncryption(t *testing.T) {
 				},
 				Recipient: key,
 				Symmetric: tc.symmetric,
-			})
+			}
+
+			testEncryption(t, gpgEncryption)
+
+			ciphertext, err := gpgEncryption.Encrypt([]byte("plaintext"))
+			assert.NoError(t, err)
+			recipients, err := gpgEncryption.CiphertextRecipients(ciphertext)
+			assert.NoError(t, err)
+			assert.Equal(t, 1, len(recipients))
+			assert.Equal(t, tc.symmetric, recipients[0] == "symmetric")
 		})
 	}
 }
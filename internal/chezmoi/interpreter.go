@@ -3,6 +3,7 @@ package chezmoi
 import (
 	"log/slog"
 	"os/exec"
+	"slices"
 )
 
 // An Interpreter interprets scripts.
@@ -16,7 +17,7 @@ func (i *Interpreter) ExecCommand(name string) *exec.Cmd {
 	if i.None() {
 		return exec.Command(name)
 	}
-	return exec.Command(i.Command, append(i.Args, name)...) //nolint:gosec
+	return exec.Command(i.Command, append(slices.Clone(i.Args), name)...) //nolint:gosec
 }
 
 // None returns if i represents no interpreter.
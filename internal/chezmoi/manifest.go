@@ -0,0 +1,205 @@
+package chezmoi
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Manifest entry types.
+const (
+	manifestEntryTypeDir     = "dir"
+	manifestEntryTypeFile    = "file"
+	manifestEntryTypeSymlink = "symlink"
+)
+
+// A ManifestEntry is an entry in a Manifest.
+type ManifestEntry struct {
+	Type string
+	Hash string
+}
+
+// A ManifestKey identifies an entry in a Manifest by the layer that contains
+// it and its path relative to that layer's directory. Layer 0 is the source
+// directory and layers 1 and above are the source layers, in order.
+type ManifestKey struct {
+	Layer   int
+	RelPath RelPath
+}
+
+// A Manifest records the type and hash of every entry in a source directory and
+// its source layers so that later changes to them can be detected.
+type Manifest map[ManifestKey]ManifestEntry
+
+// A ManifestChange is a difference between two Manifests.
+type ManifestChange struct {
+	ManifestKey
+	Change string
+}
+
+// NewManifest returns a new Manifest of the entries in sourceDirAbsPath and
+// layerDirAbsPaths in system. Version control directories and the manifest and
+// its signature are excluded.
+func NewManifest(system System, sourceDirAbsPath AbsPath, layerDirAbsPaths []AbsPath) (Manifest, error) {
+	manifest := make(Manifest)
+	for layer, dirAbsPath := range append([]AbsPath{sourceDirAbsPath}, layerDirAbsPaths...) {
+		if err := manifest.addDir(system, layer, dirAbsPath); err != nil {
+			return nil, err
+		}
+	}
+	return manifest, nil
+}
+
+// addDir adds the entries in dirAbsPath in system to m as layer.
+func (m Manifest) addDir(system System, layer int, dirAbsPath AbsPath) error {
+	walkFunc := func(absPath AbsPath, fileInfo fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if absPath == dirAbsPath {
+			return nil
+		}
+		relPath := absPath.MustTrimDirPrefix(dirAbsPath)
+		switch name := fileInfo.Name(); {
+		case name == ".git" && fileInfo.IsDir():
+			return fs.SkipDir
+		case name == ".git":
+			return nil
+		case layer == 0 && (relPath == NewRelPath(ManifestName) || relPath == NewRelPath(ManifestSignatureName)):
+			return nil
+		case strings.Contains(relPath.String(), "\n"):
+			return fmt.Errorf("%s: file name contains a newline", absPath)
+		}
+		key := ManifestKey{
+			Layer:   layer,
+			RelPath: relPath,
+		}
+		switch fileInfo.Mode().Type() {
+		case fs.ModeDir:
+			m[key] = ManifestEntry{
+				Type: manifestEntryTypeDir,
+				Hash: "-",
+			}
+		case 0:
+			contents, err := system.ReadFile(absPath)
+			if err != nil {
+				return err
+			}
+			m[key] = ManifestEntry{
+				Type: manifestEntryTypeFile,
+				Hash: fmt.Sprintf("%x", sha256.Sum256(contents)),
+			}
+		case fs.ModeSymlink:
+			linkname, err := system.Readlink(absPath)
+			if err != nil {
+				return err
+			}
+			m[key] = ManifestEntry{
+				Type: manifestEntryTypeSymlink,
+				Hash: fmt.Sprintf("%x", sha256.Sum256([]byte(linkname))),
+			}
+		default:
+			return &unsupportedFileTypeError{
+				absPath: absPath,
+				mode:    fileInfo.Mode(),
+			}
+		}
+		return nil
+	}
+	return Walk(system, dirAbsPath, walkFunc)
+}
+
+// ParseManifest parses a Manifest from data.
+func ParseManifest(data []byte) (Manifest, error) {
+	manifest := make(Manifest)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		fields := strings.SplitN(scanner.Text(), " ", 4)
+		if len(fields) != 4 || fields[3] == "" {
+			return nil, fmt.Errorf("line %d: invalid manifest entry", lineNumber)
+		}
+		switch fields[0] {
+		case manifestEntryTypeDir, manifestEntryTypeFile, manifestEntryTypeSymlink:
+		default:
+			return nil, fmt.Errorf("line %d: %s: unknown manifest entry type", lineNumber, fields[0])
+		}
+		layer, err := strconv.Atoi(fields[2])
+		if err != nil || layer < 0 {
+			return nil, fmt.Errorf("line %d: %s: invalid manifest layer", lineNumber, fields[2])
+		}
+		key := ManifestKey{
+			Layer:   layer,
+			RelPath: NewRelPath(fields[3]),
+		}
+		if _, ok := manifest[key]; ok {
+			return nil, fmt.Errorf("line %d: %s: duplicate manifest entry", lineNumber, key.RelPath)
+		}
+		manifest[key] = ManifestEntry{
+			Type: fields[0],
+			Hash: fields[1],
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(manifest) == 0 {
+		return nil, errors.New("empty manifest")
+	}
+	return manifest, nil
+}
+
+// Bytes returns m's canonical representation, with one entry per line sorted
+// by layer and then by path.
+func (m Manifest) Bytes() []byte {
+	keys := make([]ManifestKey, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, compareManifestKeys)
+	var buffer bytes.Buffer
+	for _, key := range keys {
+		entry := m[key]
+		fmt.Fprintf(&buffer, "%s %s %d %s\n", entry.Type, entry.Hash, key.Layer, key.RelPath)
+	}
+	return buffer.Bytes()
+}
+
+// Changes returns the changes from m to actual, sorted by layer and then by
+// path.
+func (m Manifest) Changes(actual Manifest) []ManifestChange {
+	var changes []ManifestChange
+	for key, entry := range m {
+		switch actualEntry, ok := actual[key]; {
+		case !ok:
+			changes = append(changes, ManifestChange{ManifestKey: key, Change: "removed"})
+		case actualEntry != entry:
+			changes = append(changes, ManifestChange{ManifestKey: key, Change: "modified"})
+		}
+	}
+	for key := range actual {
+		if _, ok := m[key]; !ok {
+			changes = append(changes, ManifestChange{ManifestKey: key, Change: "added"})
+		}
+	}
+	slices.SortFunc(changes, func(a, b ManifestChange) int {
+		return compareManifestKeys(a.ManifestKey, b.ManifestKey)
+	})
+	return changes
+}
+
+// compareManifestKeys compares a and b by layer and then by path.
+func compareManifestKeys(a, b ManifestKey) int {
+	if c := cmp.Compare(a.Layer, b.Layer); c != 0 {
+		return c
+	}
+	return strings.Compare(a.RelPath.String(), b.RelPath.String())
+}
@@ -0,0 +1,99 @@
+package chezmoi
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/twpayne/go-vfs/v5"
+	"github.com/twpayne/go-vfs/v5/vfst"
+
+	"github.com/twpayne/chezmoi/v2/internal/chezmoitest"
+)
+
+func TestManifest(t *testing.T) {
+	sourceDirAbsPath := NewAbsPath("/home/user/.local/share/chezmoi")
+	layerDirAbsPath := NewAbsPath("/home/user/layer")
+	root := map[string]any{
+		layerDirAbsPath.String(): map[string]any{
+			".chezmoimanifest": "# contents of .chezmoimanifest\n",
+			"dot_file":         "# contents of .file\n",
+		},
+		sourceDirAbsPath.String(): map[string]any{
+			".chezmoimanifest":     "# contents of .chezmoimanifest\n",
+			".chezmoimanifest.asc": "# contents of .chezmoimanifest.asc\n",
+			".git/HEAD":            "# contents of .git/HEAD\n",
+			"dot_dir/file":         "# contents of .dir/file\n",
+			"dot_file":             "# contents of .file\n",
+			"symlink_dot_symlink":  &vfst.Symlink{Target: "dot_file"},
+		},
+	}
+
+	chezmoitest.WithTestFS(t, root, func(fileSystem vfs.FS) {
+		system := NewRealSystem(fileSystem)
+
+		manifest, err := NewManifest(system, sourceDirAbsPath, []AbsPath{layerDirAbsPath})
+		assert.NoError(t, err)
+		assert.Equal(t, chezmoitest.JoinLines(
+			"dir - 0 dot_dir",
+			"file 91077668b2d3c31a2e568c08d2fa5f17670a390b38a5b50b0298b8b2c8bcc72d 0 dot_dir/file",
+			"file 634a4dd193c7b3b926d2e08026aa81a416fd41cec52854863b974af422495663 0 dot_file",
+			"symlink 1d4c0c29b3d4d53edd96d947b21609f679cbd741dfe34e94fe4c57e7f25c1bed 0 symlink_dot_symlink",
+			"file e5c361af2d183060a89d1844189f35dd426cd361e355cd7d6e01fb5f33a4fc88 1 .chezmoimanifest",
+			"file 634a4dd193c7b3b926d2e08026aa81a416fd41cec52854863b974af422495663 1 dot_file",
+		), string(manifest.Bytes()))
+
+		parsedManifest, err := ParseManifest(manifest.Bytes())
+		assert.NoError(t, err)
+		assert.Equal(t, manifest, parsedManifest)
+
+		assert.NoError(t, system.WriteFile(sourceDirAbsPath.JoinString("dot_file"), []byte("# modified\n"), 0o666))
+		assert.NoError(t, system.RemoveAll(sourceDirAbsPath.JoinString("dot_dir")))
+		assert.NoError(t, system.WriteFile(sourceDirAbsPath.JoinString("run_script.sh"), nil, 0o666))
+		assert.NoError(t, system.WriteFile(layerDirAbsPath.JoinString("dot_file"), []byte("# modified\n"), 0o666))
+		actualManifest, err := NewManifest(system, sourceDirAbsPath, []AbsPath{layerDirAbsPath})
+		assert.NoError(t, err)
+		assert.Equal(t, []ManifestChange{
+			{ManifestKey: ManifestKey{RelPath: NewRelPath("dot_dir")}, Change: "removed"},
+			{ManifestKey: ManifestKey{RelPath: NewRelPath("dot_dir/file")}, Change: "removed"},
+			{ManifestKey: ManifestKey{RelPath: NewRelPath("dot_file")}, Change: "modified"},
+			{ManifestKey: ManifestKey{RelPath: NewRelPath("run_script.sh")}, Change: "added"},
+			{ManifestKey: ManifestKey{Layer: 1, RelPath: NewRelPath("dot_file")}, Change: "modified"},
+		}, manifest.Changes(actualManifest))
+	})
+}
+
+func TestParseManifestErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		data string
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name: "missing_path",
+			data: "file 0123 0\n",
+		},
+		{
+			name: "missing_layer",
+			data: "file 0123 dot_file\n",
+		},
+		{
+			name: "negative_layer",
+			data: "file 0123 -1 dot_file\n",
+		},
+		{
+			name: "unknown_type",
+			data: "fifo - 0 dot_fifo\n",
+		},
+		{
+			name: "duplicate",
+			data: "dir - 0 dot_dir\ndir - 0 dot_dir\n",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseManifest([]byte(tc.data))
+			assert.Error(t, err)
+		})
+	}
+}
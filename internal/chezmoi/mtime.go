@@ -0,0 +1,47 @@
+package chezmoi
+
+// An Mtime determines how the modification times of target files are set when
+// they are applied. It implements the github.com/spf13/flag.Value interface.
+type Mtime string
+
+// Mtimes.
+const (
+	MtimeNone   Mtime = "none"
+	MtimeSource Mtime = "source"
+	MtimeCommit Mtime = "commit"
+)
+
+type invalidMtimeError string
+
+func (e invalidMtimeError) Error() string {
+	return "invalid mtime: " + string(e)
+}
+
+// MtimeFlagCompletionFunc is a function that completes the value of mtime
+// flags.
+var MtimeFlagCompletionFunc = FlagCompletionFunc([]string{
+	string(MtimeCommit),
+	string(MtimeNone),
+	string(MtimeSource),
+})
+
+// Set implements github.com/spf13/flag.Value.Set.
+func (m *Mtime) Set(s string) error {
+	switch Mtime(s) {
+	case MtimeNone, MtimeSource, MtimeCommit:
+		*m = Mtime(s)
+		return nil
+	default:
+		return invalidMtimeError(s)
+	}
+}
+
+// String implements github.com/spf13/flag.Value.String.
+func (m Mtime) String() string {
+	return string(m)
+}
+
+// Type implements github.com/spf13/flag.Value.Type.
+func (m Mtime) Type() string {
+	return "commit|none|source"
+}
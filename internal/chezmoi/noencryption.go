@@ -7,6 +7,9 @@ var errNoEncryption = errors.New("no encryption")
 // NoEncryption returns an error when any method is called.
 type NoEncryption struct{}
 
+// CiphertextRecipients implements Encryption.CiphertextRecipients.
+func (NoEncryption) CiphertextRecipients([]byte) ([]string, error) { return nil, errNoEncryption }
+
 // Decrypt implements Encryption.Decrypt.
 func (NoEncryption) Decrypt([]byte) ([]byte, error) { return nil, errNoEncryption }
 
@@ -34,16 +34,6 @@ func (s *RealSystem) Link(oldname, newname AbsPath) error {
 	return s.fileSystem.Link(oldname.String(), newname.String())
 }
 
-// Lstat implements System.Lstat.
-func (s *RealSystem) Lstat(filename AbsPath) (fs.FileInfo, error) {
-	return s.fileSystem.Lstat(filename.String())
-}
-
-// Mkdir implements System.Mkdir.
-func (s *RealSystem) Mkdir(name AbsPath, perm fs.FileMode) error {
-	return s.fileSystem.Mkdir(name.String(), perm)
-}
-
 // RawPath implements System.RawPath.
 func (s *RealSystem) RawPath(absPath AbsPath) (AbsPath, error) {
 	rawAbsPath, err := s.fileSystem.RawPath(absPath.String())
@@ -134,11 +124,6 @@ func (s *RealSystem) RunScript(scriptname RelPath, dir AbsPath, data []byte, opt
 	return s.RunCmd(cmd)
 }
 
-// Stat implements System.Stat.
-func (s *RealSystem) Stat(name AbsPath) (fs.FileInfo, error) {
-	return s.fileSystem.Stat(name.String())
-}
-
 // UnderlyingFS implements System.UnderlyingFS.
 func (s *RealSystem) UnderlyingFS() vfs.FS {
 	return s.fileSystem
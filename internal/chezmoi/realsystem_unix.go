@@ -32,6 +32,12 @@ func RealSystemWithSafe(safe bool) RealSystemOption {
 	}
 }
 
+// RealSystemWithPrivateACLs does nothing on non-Windows systems, where private
+// permissions are set with chmod.
+func RealSystemWithPrivateACLs(privateACLs bool) RealSystemOption {
+	return func(s *RealSystem) {}
+}
+
 // RealSystemWithScriptTempDir sets the script temporary directory of the RealSystem.
 func RealSystemWithScriptTempDir(scriptTempDir AbsPath) RealSystemOption {
 	return func(s *RealSystem) {
@@ -58,11 +64,26 @@ func (s *RealSystem) Chmod(name AbsPath, mode fs.FileMode) error {
 	return s.fileSystem.Chmod(name.String(), mode)
 }
 
+// Lstat implements System.Lstat.
+func (s *RealSystem) Lstat(filename AbsPath) (fs.FileInfo, error) {
+	return s.fileSystem.Lstat(filename.String())
+}
+
+// Mkdir implements System.Mkdir.
+func (s *RealSystem) Mkdir(name AbsPath, perm fs.FileMode) error {
+	return s.fileSystem.Mkdir(name.String(), perm)
+}
+
 // Readlink implements System.Readlink.
 func (s *RealSystem) Readlink(name AbsPath) (string, error) {
 	return s.fileSystem.Readlink(name.String())
 }
 
+// Stat implements System.Stat.
+func (s *RealSystem) Stat(name AbsPath) (fs.FileInfo, error) {
+	return s.fileSystem.Stat(name.String())
+}
+
 // WriteFile implements System.WriteFile.
 func (s *RealSystem) WriteFile(filename AbsPath, data []byte, perm fs.FileMode) (err error) {
 	// Special case: if writing to the real filesystem in safe mode, use
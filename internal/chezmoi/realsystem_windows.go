@@ -3,25 +3,47 @@ package chezmoi
 import (
 	"errors"
 	"io/fs"
+	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 
 	vfs "github.com/twpayne/go-vfs/v5"
+	"golang.org/x/sys/windows"
+
+	"github.com/twpayne/chezmoi/v2/internal/chezmoierrors"
 )
 
 // An RealSystem is a System that writes to a filesystem and executes scripts.
 type RealSystem struct {
 	fileSystem              vfs.FS
+	privateACLs             bool
+	safe                    bool
 	createScriptTempDirOnce sync.Once
 	scriptTempDir           AbsPath
 }
 
-// RealSystemWithSafe sets the safe flag of the RealSystem. On Windows it does
-// nothing as Windows does not support atomic file or symlink updates. See
+// RealSystemWithPrivateACLs sets whether the RealSystem restricts access to
+// files and directories with private permissions to the current user, and
+// reports files and directories with restricted access as having private
+// permissions. It should only be set for the system that writes targets, so
+// that only targets with the private_ attribute are restricted.
+func RealSystemWithPrivateACLs(privateACLs bool) RealSystemOption {
+	return func(s *RealSystem) {
+		s.privateACLs = privateACLs
+	}
+}
+
+// RealSystemWithSafe sets the safe flag of the RealSystem. On Windows, files
+// are written to a temporary file which is then renamed, so files are never
+// left partially written, but the rename is not guaranteed to be atomic and
+// symlinks are replaced non-atomically. See
 // https://github.com/google/renameio/issues/1 and
 // https://github.com/golang/go/issues/22397#issuecomment-498856679.
 func RealSystemWithSafe(safe bool) RealSystemOption {
-	return func(s *RealSystem) {}
+	return func(s *RealSystem) {
+		s.safe = safe
+	}
 }
 
 // RealSystemWithScriptTempDir sets the script temporary directory of the RealSystem.
@@ -40,9 +62,33 @@ func NewRealSystem(fileSystem vfs.FS, options ...RealSystemOption) *RealSystem {
 	return s
 }
 
-// Chmod implements System.Chmod.
+// Chmod implements System.Chmod. Windows does not have Unix permissions, so
+// files without write permission are given the read-only attribute and, if s
+// sets private ACLs, files without group or other permissions have their
+// access restricted to the current user.
 func (s *RealSystem) Chmod(name AbsPath, mode fs.FileMode) error {
-	return nil
+	if err := s.fileSystem.Chmod(name.String(), mode); err != nil {
+		return err
+	}
+	return s.setACL(name, mode)
+}
+
+// Lstat implements System.Lstat.
+func (s *RealSystem) Lstat(filename AbsPath) (fs.FileInfo, error) {
+	fileInfo, err := s.fileSystem.Lstat(filename.String())
+	if err != nil {
+		return nil, err
+	}
+	return s.privateFileInfo(filename, fileInfo)
+}
+
+// Mkdir implements System.Mkdir. If s sets private ACLs, directories without
+// group or other permissions have their access restricted to the current user.
+func (s *RealSystem) Mkdir(name AbsPath, perm fs.FileMode) error {
+	if err := s.fileSystem.Mkdir(name.String(), perm); err != nil {
+		return err
+	}
+	return s.setACL(name, perm)
 }
 
 // Readlink implements System.Readlink.
@@ -54,9 +100,62 @@ func (s *RealSystem) Readlink(name AbsPath) (string, error) {
 	return normalizeLinkname(linkname), nil
 }
 
+// Stat implements System.Stat.
+func (s *RealSystem) Stat(name AbsPath) (fs.FileInfo, error) {
+	fileInfo, err := s.fileSystem.Stat(name.String())
+	if err != nil {
+		return nil, err
+	}
+	return s.privateFileInfo(name, fileInfo)
+}
+
 // WriteFile implements System.WriteFile.
 func (s *RealSystem) WriteFile(filename AbsPath, data []byte, perm fs.FileMode) error {
-	return s.fileSystem.WriteFile(filename.String(), data, perm)
+	// Clear the read-only attribute of any existing file so that it can be
+	// overwritten.
+	switch fileInfo, err := s.fileSystem.Lstat(filename.String()); {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return err
+	case fileInfo.Mode().IsRegular() && fileInfo.Mode().Perm()&0o222 == 0:
+		if err := s.fileSystem.Chmod(filename.String(), 0o666); err != nil {
+			return err
+		}
+	}
+	if s.safe && s.fileSystem == vfs.OSFS {
+		return s.writeFileViaTempFile(filename, data, perm)
+	}
+	if err := s.fileSystem.WriteFile(filename.String(), data, perm); err != nil {
+		return err
+	}
+	return s.Chmod(filename, perm)
+}
+
+// writeFileViaTempFile writes data to a temporary file in the same directory
+// as filename, sets its permissions, and then renames it to filename.
+func (s *RealSystem) writeFileViaTempFile(filename AbsPath, data []byte, perm fs.FileMode) (err error) {
+	var tempFile *os.File
+	if tempFile, err = os.CreateTemp(filename.Dir().String(), "."+filename.Base()+".*.tmp"); err != nil {
+		return
+	}
+	tempAbsPath := NewAbsPath(tempFile.Name())
+	defer func() {
+		if err != nil {
+			err = chezmoierrors.Combine(err, os.Remove(tempAbsPath.String()))
+		}
+	}()
+	if _, err = tempFile.Write(data); err != nil {
+		err = chezmoierrors.Combine(err, tempFile.Close())
+		return
+	}
+	if err = tempFile.Close(); err != nil {
+		return
+	}
+	if err = s.Chmod(tempAbsPath, perm); err != nil {
+		return
+	}
+	err = os.Rename(tempAbsPath.String(), filename.String())
+	return
 }
 
 // WriteSymlink implements System.WriteSymlink.
@@ -66,3 +165,120 @@ func (s *RealSystem) WriteSymlink(oldname string, newname AbsPath) error {
 	}
 	return s.fileSystem.Symlink(filepath.FromSlash(oldname), newname.String())
 }
+
+// privateFileInfo returns fileInfo, with its group and other permissions
+// cleared if s sets private ACLs and access to the file or directory at name is
+// restricted to the current user. This is the inverse of setACL, so that
+// private_ targets are recognized when they are read back.
+func (s *RealSystem) privateFileInfo(name AbsPath, fileInfo fs.FileInfo) (fs.FileInfo, error) {
+	if !s.privateACLs || !fileInfo.Mode().IsRegular() && !fileInfo.IsDir() {
+		return fileInfo, nil
+	}
+	rawPath, err := s.RawPath(name)
+	if err != nil {
+		return nil, err
+	}
+	switch private, err := hasPrivateACL(rawPath.String()); {
+	case err != nil:
+		return nil, err
+	case private:
+		return privateFileInfo{FileInfo: fileInfo}, nil
+	default:
+		return fileInfo, nil
+	}
+}
+
+// setACL restricts access to the file or directory at name to the current
+// user if s sets private ACLs and perm has no group or other permissions. If
+// access was previously restricted and perm has group or other permissions
+// then the inherited access control list is restored.
+func (s *RealSystem) setACL(name AbsPath, perm fs.FileMode) error {
+	if !s.privateACLs {
+		return nil
+	}
+	rawPath, err := s.RawPath(name)
+	if err != nil {
+		return err
+	}
+	switch private, err := hasPrivateACL(rawPath.String()); {
+	case err != nil:
+		return err
+	case perm.Perm()&0o77 == 0 && !private:
+		return setPrivateACL(rawPath.String())
+	case perm.Perm()&0o77 != 0 && private:
+		return setInheritedACL(rawPath.String())
+	default:
+		return nil
+	}
+}
+
+// A privateFileInfo is an fs.FileInfo with no group or other permissions.
+type privateFileInfo struct {
+	fs.FileInfo
+}
+
+// Mode implements fs.FileInfo.Mode.
+func (i privateFileInfo) Mode() fs.FileMode {
+	return i.FileInfo.Mode() &^ 0o77
+}
+
+// hasPrivateACL returns if the access control list of the file or directory at
+// path is protected from inheritance and grants access only to the current
+// user.
+func hasPrivateACL(path string) (bool, error) {
+	securityDescriptor, err := windows.GetNamedSecurityInfo(
+		windowsLongPath(path),
+		windows.SE_FILE_OBJECT,
+		windows.DACL_SECURITY_INFORMATION,
+	)
+	if err != nil {
+		return false, err
+	}
+	dacl, ok := strings.CutPrefix(securityDescriptor.String(), "D:P")
+	if !ok {
+		return false, nil
+	}
+	tokenUser, err := windows.GetCurrentProcessToken().GetTokenUser()
+	if err != nil {
+		return false, err
+	}
+	return strings.Count(dacl, "(") == 1 && strings.HasSuffix(dacl, ";"+tokenUser.User.Sid.String()+")"), nil
+}
+
+// setInheritedACL replaces the access control list of the file or directory at
+// path with the one inherited from its parent directory.
+func setInheritedACL(path string) error {
+	acl, err := windows.ACLFromEntries(nil, nil)
+	if err != nil {
+		return err
+	}
+	securityInformation := windows.SECURITY_INFORMATION(windows.DACL_SECURITY_INFORMATION | windows.UNPROTECTED_DACL_SECURITY_INFORMATION)
+	return windows.SetNamedSecurityInfo(windowsLongPath(path), windows.SE_FILE_OBJECT, securityInformation, nil, nil, acl, nil)
+}
+
+// setPrivateACL replaces the access control list of the file or directory at
+// path with one that only grants access to the current user. Inherited access
+// control entries are removed.
+func setPrivateACL(path string) error {
+	tokenUser, err := windows.GetCurrentProcessToken().GetTokenUser()
+	if err != nil {
+		return err
+	}
+	acl, err := windows.ACLFromEntries([]windows.EXPLICIT_ACCESS{
+		{
+			AccessPermissions: windows.GENERIC_ALL,
+			AccessMode:        windows.GRANT_ACCESS,
+			Inheritance:       windows.SUB_CONTAINERS_AND_OBJECTS_INHERIT,
+			Trustee: windows.TRUSTEE{
+				TrusteeForm:  windows.TRUSTEE_IS_SID,
+				TrusteeType:  windows.TRUSTEE_IS_USER,
+				TrusteeValue: windows.TrusteeValueFromSID(tokenUser.User.Sid),
+			},
+		},
+	}, nil)
+	if err != nil {
+		return err
+	}
+	securityInformation := windows.SECURITY_INFORMATION(windows.DACL_SECURITY_INFORMATION | windows.PROTECTED_DACL_SECURITY_INFORMATION)
+	return windows.SetNamedSecurityInfo(windowsLongPath(path), windows.SE_FILE_OBJECT, securityInformation, nil, nil, acl, nil)
+}
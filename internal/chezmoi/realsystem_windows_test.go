@@ -0,0 +1,108 @@
+//go:build windows
+
+package chezmoi
+
+import (
+	"io/fs"
+	"os"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	vfs "github.com/twpayne/go-vfs/v5"
+)
+
+func TestRealSystemWriteFileReadOnly(t *testing.T) {
+	system := NewRealSystem(vfs.OSFS)
+	absPath := NewAbsPath(t.TempDir()).JoinString("file")
+
+	assert.NoError(t, system.WriteFile(absPath, []byte("# contents of file\n"), 0o444))
+	fileInfo, err := os.Stat(absPath.String())
+	assert.NoError(t, err)
+	assert.True(t, isReadOnly(fileInfo))
+
+	// Overwriting a read-only file succeeds and preserves the attribute.
+	assert.NoError(t, system.WriteFile(absPath, []byte("# new contents of file\n"), 0o444))
+	actualContents, err := os.ReadFile(absPath.String())
+	assert.NoError(t, err)
+	assert.Equal(t, "# new contents of file\n", string(actualContents))
+	fileInfo, err = os.Stat(absPath.String())
+	assert.NoError(t, err)
+	assert.True(t, isReadOnly(fileInfo))
+
+	assert.NoError(t, system.Chmod(absPath, 0o666))
+	fileInfo, err = os.Stat(absPath.String())
+	assert.NoError(t, err)
+	assert.False(t, isReadOnly(fileInfo))
+}
+
+func TestRealSystemPrivate(t *testing.T) {
+	system := NewRealSystem(vfs.OSFS, RealSystemWithPrivateACLs(true))
+	tempDirAbsPath := NewAbsPath(t.TempDir())
+
+	for _, tc := range []struct {
+		name            string
+		perm            fs.FileMode
+		create          func(AbsPath, fs.FileMode) error
+		expectedPrivate bool
+	}{
+		{
+			name: "dir",
+			perm: fs.ModePerm,
+			create: func(absPath AbsPath, perm fs.FileMode) error {
+				return system.Mkdir(absPath, perm)
+			},
+		},
+		{
+			name: "private_dir",
+			perm: 0o700,
+			create: func(absPath AbsPath, perm fs.FileMode) error {
+				return system.Mkdir(absPath, perm)
+			},
+			expectedPrivate: true,
+		},
+		{
+			name: "file",
+			perm: 0o666,
+			create: func(absPath AbsPath, perm fs.FileMode) error {
+				return system.WriteFile(absPath, nil, perm)
+			},
+		},
+		{
+			name: "private_file",
+			perm: 0o600,
+			create: func(absPath AbsPath, perm fs.FileMode) error {
+				return system.WriteFile(absPath, nil, perm)
+			},
+			expectedPrivate: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			absPath := tempDirAbsPath.JoinString(tc.name)
+			assert.NoError(t, tc.create(absPath, tc.perm))
+			actualPrivate, err := hasPrivateACL(absPath.String())
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedPrivate, actualPrivate)
+
+			// Private files and directories are reported as private.
+			fileInfo, err := system.Lstat(absPath)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedPrivate, isPrivate(fileInfo))
+
+			// Removing the private permissions restores inherited access.
+			assert.NoError(t, system.Chmod(absPath, tc.perm|0o66))
+			actualPrivate, err = hasPrivateACL(absPath.String())
+			assert.NoError(t, err)
+			assert.False(t, actualPrivate)
+		})
+	}
+}
+
+func TestRealSystemPrivateWithoutPrivateACLs(t *testing.T) {
+	system := NewRealSystem(vfs.OSFS)
+	absPath := NewAbsPath(t.TempDir()).JoinString("file")
+
+	assert.NoError(t, system.WriteFile(absPath, nil, 0o600))
+	actualPrivate, err := hasPrivateACL(absPath.String())
+	assert.NoError(t, err)
+	assert.False(t, actualPrivate)
+}
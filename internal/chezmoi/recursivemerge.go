@@ -1,5 +1,7 @@
 package chezmoi
 
+import "math/rand"
+
 // recursiveCopy returns a recursive copy of v.
 func recursiveCopy(v any) any {
 	m, ok := v.(map[string]any)
@@ -17,6 +19,35 @@ func recursiveCopy(v any) any {
 	return c
 }
 
+// shuffleMaps returns a recursive copy of v in which the keys of every map are
+// inserted in a random order, so that iterating over the maps is likely to
+// return the keys in a different order.
+func shuffleMaps(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		rand.Shuffle(len(keys), func(i, j int) {
+			keys[i], keys[j] = keys[j], keys[i]
+		})
+		c := make(map[string]any, len(v))
+		for _, key := range keys {
+			c[key] = shuffleMaps(v[key])
+		}
+		return c
+	case []any:
+		c := make([]any, 0, len(v))
+		for _, element := range v {
+			c = append(c, shuffleMaps(element))
+		}
+		return c
+	default:
+		return v
+	}
+}
+
 // RecursiveMerge recursively merges maps in source into dest.
 func RecursiveMerge(dest, source map[string]any) {
 	for key, sourceValue := range source {
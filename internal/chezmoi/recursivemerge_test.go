@@ -66,3 +66,21 @@ func TestRecursiveMergeCopies(t *testing.T) {
 	assert.Equal(t, "mergedValue", dest["key"])
 	assert.Equal(t, "initialValue", original["key"])
 }
+
+func TestShuffleMaps(t *testing.T) {
+	original := map[string]any{
+		"a": 1,
+		"b": map[string]any{
+			"c": 2,
+			"d": []any{
+				map[string]any{
+					"e": 3,
+				},
+			},
+		},
+	}
+	shuffled := shuffleMaps(original).(map[string]any) //nolint:forcetypeassert
+	assert.Equal(t, original, shuffled)
+	shuffled["b"].(map[string]any)["c"] = 20 //nolint:forcetypeassert
+	assert.Equal(t, 2, original["b"].(map[string]any)["c"])
+}
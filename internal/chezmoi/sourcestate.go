@@ -6,12 +6,14 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"log/slog"
+	"maps"
 	"net/http"
 	"net/url"
 	"os"
@@ -22,14 +24,17 @@ import (
 	"runtime"
 	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
 	"text/template"
 	"time"
 
+	"github.com/bmatcuk/doublestar/v4"
 	"github.com/coreos/go-semver/semver"
 	"github.com/mitchellh/copystructure"
+	"golang.org/x/sync/errgroup"
 
 	"github.com/twpayne/chezmoi/v2/internal/chezmoierrors"
 	"github.com/twpayne/chezmoi/v2/internal/chezmoilog"
@@ -51,6 +56,8 @@ const (
 var (
 	lineEndingRx                    = regexp.MustCompile(`(?m)(?:\r\n|\r|\n)`)
 	modifyTemplateRx                = regexp.MustCompile(`(?m)^.*chezmoi:modify-template.*$(?:\r?\n)?`)
+	permissionsDirectiveRx          = regexp.MustCompile(`(?m)^.*?chezmoi:permissions:(.*)$(?:\r?\n)?`)
+	scriptWorkDirDirectiveRx        = regexp.MustCompile(`(?m)^.*?chezmoi:workdir:(.*)$(?:\r?\n)?`)
 	templateDirectiveRx             = regexp.MustCompile(`(?m)^.*?chezmoi:template:(.*)$(?:\r?\n)?`)
 	templateDirectiveKeyValuePairRx = regexp.MustCompile(`\s*(\S+)=("(?:[^"]|\\")*"|\S+)`)
 
@@ -107,6 +114,38 @@ type External struct {
 	sourceAbsPath   AbsPath
 }
 
+// A DirMetadata contains metadata about a target directory, read from a
+// .chezmoimetadata.<format> file in the corresponding source directory.
+type DirMetadata struct {
+	Description string `json:"description" toml:"description" yaml:"description"`
+}
+
+// A GPGKey is a public key that should be in the user's GPG keyring, read from
+// a .chezmoigpgkeys.<format> file.
+type GPGKey struct {
+	File          string `json:"file"      toml:"file"      yaml:"file"`
+	Keyserver     string `json:"keyserver" toml:"keyserver" yaml:"keyserver"`
+	Trust         string `json:"trust"     toml:"trust"     yaml:"trust"`
+	sourceAbsPath AbsPath
+}
+
+// GPGOwnerTrustLevels maps the names of GPG owner trust levels to their values
+// in gpg --export-ownertrust.
+var GPGOwnerTrustLevels = map[string]int{
+	"undefined": 2,
+	"never":     3,
+	"marginal":  4,
+	"full":      5,
+	"ultimate":  6,
+}
+
+var gpgFingerprintRx = regexp.MustCompile(`\A(?:[0-9A-F]{40}|[0-9A-F]{64})\z`)
+
+// FileAbsPath returns the absolute path of k's file.
+func (k *GPGKey) FileAbsPath() AbsPath {
+	return k.sourceAbsPath.Dir().Join(NewRelPath(k.File))
+}
+
 // A SourceState is a source state.
 type SourceState struct {
 	sync.Mutex
@@ -115,32 +154,53 @@ type SourceState struct {
 	baseSystem              System
 	system                  System
 	sourceDirAbsPath        AbsPath
+	layerDirAbsPaths        []AbsPath
+	layerConflicts          map[RelPath][]string
 	destDirAbsPath          AbsPath
 	cacheDirAbsPath         AbsPath
 	umask                   fs.FileMode
 	encryption              Encryption
 	ignore                  *patternSet
+	ignorePatterns          []string
+	ignoreFilePatterns      []IgnorePattern
+	addIgnore               *patternSet
 	remove                  *patternSet
 	interpreters            map[string]Interpreter
 	httpClient              *http.Client
 	logger                  *slog.Logger
+	verboseFunc             VerboseFunc
 	version                 semver.Version
 	mode                    Mode
+	symlinkCopyDirAbsPath   AbsPath
 	defaultTemplateDataFunc func() map[string]any
 	templateDataOnly        bool
 	readTemplateData        bool
 	readTemplates           bool
+	shuffleTemplateData     bool
 	defaultTemplateData     map[string]any
 	userTemplateData        map[string]any
 	priorityTemplateData    map[string]any
 	templateData            map[string]any
 	templateFuncs           template.FuncMap
+	executeTemplateFuncs    func(string, chezmoiset.Set[string]) template.FuncMap
 	templateOptions         []string
 	templates               map[string]*Template
 	externals               map[RelPath][]*External
+	externalDataRaw         map[string][]byte
+	bandwidthLimiter        *bandwidthLimiter
+	dirMetadata             map[RelPath]*DirMetadata
+	gpgKeys                 map[string]*GPGKey
 	ignoredRelPaths         chezmoiset.Set[RelPath]
 }
 
+// An IgnorePattern is a pattern read from a .chezmoiignore file.
+type IgnorePattern struct {
+	Pattern       string
+	Exclude       bool
+	SourceRelPath RelPath
+	LineNumber    int
+}
+
 // A SourceStateOption sets an option on a source state.
 type SourceStateOption func(*SourceState)
 
@@ -179,6 +239,17 @@ func WithEncryption(encryption Encryption) SourceStateOption {
 	}
 }
 
+// WithExecuteTemplateFuncs sets a function that returns extra template
+// functions for each execution of a template, given the template's target path
+// or, if it does not have one, its name, and the names that the template
+// references. The extra template functions replace template functions with the
+// same name.
+func WithExecuteTemplateFuncs(executeTemplateFuncs func(string, chezmoiset.Set[string]) template.FuncMap) SourceStateOption {
+	return func(s *SourceState) {
+		s.executeTemplateFuncs = executeTemplateFuncs
+	}
+}
+
 // WithHTTPClient sets the HTTP client.
 func WithHTTPClient(httpClient *http.Client) SourceStateOption {
 	return func(s *SourceState) {
@@ -186,6 +257,14 @@ func WithHTTPClient(httpClient *http.Client) SourceStateOption {
 	}
 }
 
+// WithIgnorePatterns sets extra patterns of targets to ignore, in the same
+// format as lines in .chezmoiignore.
+func WithIgnorePatterns(ignorePatterns []string) SourceStateOption {
+	return func(s *SourceState) {
+		s.ignorePatterns = ignorePatterns
+	}
+}
+
 // WithInterpreters sets the interpreters.
 func WithInterpreters(interpreters map[string]Interpreter) SourceStateOption {
 	return func(s *SourceState) {
@@ -193,6 +272,14 @@ func WithInterpreters(interpreters map[string]Interpreter) SourceStateOption {
 	}
 }
 
+// WithLayerDirs sets the source layer directories, which are read in order
+// before the source directory.
+func WithLayerDirs(layerDirAbsPaths []AbsPath) SourceStateOption {
+	return func(s *SourceState) {
+		s.layerDirAbsPaths = layerDirAbsPaths
+	}
+}
+
 // WithLogger sets the logger.
 func WithLogger(logger *slog.Logger) SourceStateOption {
 	return func(s *SourceState) {
@@ -228,6 +315,15 @@ func WithReadTemplates(readTemplates bool) SourceStateOption {
 	}
 }
 
+// WithShuffleTemplateData sets whether the keys of the maps in the template
+// data are inserted in a random order each time the template data are used, to
+// help detect templates whose output depends on map iteration order.
+func WithShuffleTemplateData(shuffleTemplateData bool) SourceStateOption {
+	return func(s *SourceState) {
+		s.shuffleTemplateData = shuffleTemplateData
+	}
+}
+
 // WithSourceDir sets the source directory.
 func WithSourceDir(sourceDirAbsPath AbsPath) SourceStateOption {
 	return func(s *SourceState) {
@@ -235,6 +331,15 @@ func WithSourceDir(sourceDirAbsPath AbsPath) SourceStateOption {
 	}
 }
 
+// WithSymlinkCopyDir sets the directory containing the read-only copies of
+// source files that targets are symlinked to in symlink mode. If it is empty
+// then targets are symlinked to the source files themselves.
+func WithSymlinkCopyDir(symlinkCopyDirAbsPath AbsPath) SourceStateOption {
+	return func(s *SourceState) {
+		s.symlinkCopyDirAbsPath = symlinkCopyDirAbsPath
+	}
+}
+
 // WithSystem sets the system.
 func WithSystem(system System) SourceStateOption {
 	return func(s *SourceState) {
@@ -270,6 +375,13 @@ func WithUmask(umask fs.FileMode) SourceStateOption {
 	}
 }
 
+// WithVerboseFunc sets the function used to report verbose output.
+func WithVerboseFunc(verboseFunc VerboseFunc) SourceStateOption {
+	return func(s *SourceState) {
+		s.verboseFunc = verboseFunc
+	}
+}
+
 // WithVersion sets the version.
 func WithVersion(version semver.Version) SourceStateOption {
 	return func(s *SourceState) {
@@ -288,9 +400,11 @@ func NewSourceState(options ...SourceStateOption) *SourceState {
 		umask:                Umask,
 		encryption:           NoEncryption{},
 		ignore:               newPatternSet(),
+		addIgnore:            newPatternSet(),
 		remove:               newPatternSet(),
 		httpClient:           http.DefaultClient,
 		logger:               slog.Default(),
+		verboseFunc:          func(int, string, string, ...any) {},
 		readTemplateData:     true,
 		readTemplates:        true,
 		priorityTemplateData: make(map[string]any),
@@ -298,7 +412,11 @@ func NewSourceState(options ...SourceStateOption) *SourceState {
 		templateOptions:      DefaultTemplateOptions,
 		templates:            make(map[string]*Template),
 		externals:            make(map[RelPath][]*External),
+		externalDataRaw:      make(map[string][]byte),
+		dirMetadata:          make(map[RelPath]*DirMetadata),
+		gpgKeys:              make(map[string]*GPGKey),
 		ignoredRelPaths:      chezmoiset.New[RelPath](),
+		layerConflicts:       make(map[RelPath][]string),
 	}
 	for _, option := range options {
 		option(s)
@@ -382,13 +500,18 @@ func (s *SourceState) Add(
 	}
 
 	type sourceUpdate struct {
-		destAbsPath    AbsPath
-		entryState     *EntryState
-		sourceRelPaths []SourceRelPath
+		destAbsPath      AbsPath
+		entryState       *EntryState
+		sourceDirAbsPath AbsPath
+		sourceRelPaths   []SourceRelPath
 	}
 
 	sourceUpdates := make([]sourceUpdate, 0, len(destAbsPaths))
 	newSourceStateEntries := make(map[SourceRelPath]SourceStateEntry)
+	// newSourceDirAbsPaths contains the source directory or source layer
+	// directory of each new source state entry, which is the same as that of
+	// the entry that it replaces or of its parent.
+	newSourceDirAbsPaths := make(map[SourceRelPath]AbsPath)
 	newSourceStateEntriesByTargetRelPath := make(map[RelPath]SourceStateEntry)
 	nonEmptyDirs := chezmoiset.New[SourceRelPath]()
 	externalDirRelPaths := chezmoiset.New[RelPath]()
@@ -406,10 +529,12 @@ DEST_ABS_PATH:
 
 		// Find the target's parent directory in the source state.
 		var parentSourceRelPath SourceRelPath
+		sourceDirAbsPath := s.sourceDirAbsPath
 		if targetParentRelPath := targetRelPath.Dir(); targetParentRelPath == DotRelPath {
 			parentSourceRelPath = SourceRelPath{}
 		} else if parentEntry, ok := newSourceStateEntriesByTargetRelPath[targetParentRelPath]; ok {
 			parentSourceRelPath = parentEntry.SourceRelPath()
+			sourceDirAbsPath = newSourceDirAbsPaths[parentSourceRelPath]
 		} else if nodes := s.root.getNodes(targetParentRelPath); nodes != nil {
 			for i, node := range nodes {
 				if i == 0 {
@@ -438,6 +563,7 @@ DEST_ABS_PATH:
 				}
 			}
 			parentSourceRelPath = nodes[len(nodes)-1].sourceStateEntry.SourceRelPath()
+			sourceDirAbsPath = s.SourceDirAbsPathOf(nodes[len(nodes)-1].sourceStateEntry)
 		} else {
 			return fmt.Errorf("%s: parent directory not in source state", destAbsPath)
 		}
@@ -471,13 +597,18 @@ DEST_ABS_PATH:
 		if err != nil {
 			return err
 		}
+		oldSourceStateEntry := s.root.get(targetRelPath)
+		if oldSourceStateEntry != nil {
+			sourceDirAbsPath = s.SourceDirAbsPathOf(oldSourceStateEntry)
+		}
 		update := sourceUpdate{
-			destAbsPath:    destAbsPath,
-			entryState:     entryState,
-			sourceRelPaths: []SourceRelPath{sourceEntryRelPath},
+			destAbsPath:      destAbsPath,
+			entryState:       entryState,
+			sourceDirAbsPath: sourceDirAbsPath,
+			sourceRelPaths:   []SourceRelPath{sourceEntryRelPath},
 		}
 
-		if oldSourceStateEntry := s.root.get(targetRelPath); oldSourceStateEntry != nil {
+		if oldSourceStateEntry != nil {
 			oldSourceEntryRelPath := oldSourceStateEntry.SourceRelPath()
 			if !oldSourceEntryRelPath.Empty() && oldSourceEntryRelPath != sourceEntryRelPath {
 				if options.ReplaceFunc != nil {
@@ -495,8 +626,8 @@ DEST_ABS_PATH:
 				_, newIsDir := newSourceStateEntry.(*SourceStateDir)
 				_, oldIsDir := oldSourceStateEntry.(*SourceStateDir)
 				if newIsDir && oldIsDir {
-					oldSourceAbsPath := s.sourceDirAbsPath.Join(oldSourceEntryRelPath.RelPath())
-					newSourceAbsPath := s.sourceDirAbsPath.Join(sourceEntryRelPath.RelPath())
+					oldSourceAbsPath := sourceDirAbsPath.Join(oldSourceEntryRelPath.RelPath())
+					newSourceAbsPath := sourceDirAbsPath.Join(sourceEntryRelPath.RelPath())
 					dirRenames[oldSourceAbsPath] = newSourceAbsPath
 					continue DEST_ABS_PATH
 				}
@@ -511,6 +642,7 @@ DEST_ABS_PATH:
 
 		newSourceStateEntries[sourceEntryRelPath] = newSourceStateEntry
 		newSourceStateEntriesByTargetRelPath[targetRelPath] = newSourceStateEntry
+		newSourceDirAbsPaths[sourceEntryRelPath] = sourceDirAbsPath
 
 		sourceUpdates = append(sourceUpdates, update)
 	}
@@ -531,7 +663,8 @@ DEST_ABS_PATH:
 				Type: EntryStateTypeFile,
 				Mode: 0o666 &^ s.umask,
 			},
-			sourceRelPaths: []SourceRelPath{dotKeepFileRelPath},
+			sourceDirAbsPath: newSourceDirAbsPaths[sourceEntryRelPath],
+			sourceRelPaths:   []SourceRelPath{dotKeepFileRelPath},
 		}
 		sourceUpdates = append(sourceUpdates, dotKeepFileSourceUpdate)
 
@@ -569,7 +702,8 @@ DEST_ABS_PATH:
 				entryState: &EntryState{
 					Type: EntryStateTypeRemove,
 				},
-				sourceRelPaths: []SourceRelPath{sourceRelPath},
+				sourceDirAbsPath: s.SourceDirAbsPathOf(sourceStateEntry),
+				sourceRelPaths:   []SourceRelPath{sourceRelPath},
 			}
 			sourceUpdates = append(sourceUpdates, update)
 			return nil
@@ -586,7 +720,7 @@ DEST_ABS_PATH:
 				sourceSystem,
 				sourceSystem,
 				NullPersistentState{},
-				s.sourceDirAbsPath,
+				sourceUpdate.sourceDirAbsPath,
 				sourceRelPath.RelPath(),
 				ApplyOptions{
 					Filter: options.Filter,
@@ -667,11 +801,29 @@ func (s *SourceState) AddDestAbsPathInfos(
 // A PreApplyFunc is called before a target is applied.
 type PreApplyFunc func(targetRelPath RelPath, targetEntryState, lastWrittenEntryState, actualEntryState *EntryState) error
 
+// An ApplyTimingsFunc is called after a target is applied with the time taken
+// to compute its target state and the time taken to update it, excluding any
+// time spent in the PreApplyFunc.
+type ApplyTimingsFunc func(targetRelPath RelPath, renderDuration, ioDuration time.Duration)
+
+// An ApplyMtimeFunc is called after a target file is written and returns the
+// modification time to set on it. If it returns the zero time then the
+// modification time is left unchanged.
+type ApplyMtimeFunc func(targetRelPath RelPath, sourceStateEntry SourceStateEntry) (time.Time, error)
+
+// An ApplyChangeFunc is called with the entry states of a target before and
+// after it is changed.
+type ApplyChangeFunc func(targetRelPath RelPath, oldEntryState, newEntryState *EntryState) error
+
 // ApplyOptions are options to SourceState.ApplyAll and SourceState.ApplyOne.
 type ApplyOptions struct {
-	Filter       *EntryTypeFilter
-	PreApplyFunc PreApplyFunc
-	Umask        fs.FileMode
+	Filter         *EntryTypeFilter
+	MtimeFunc      ApplyMtimeFunc
+	PostChangeFunc ApplyChangeFunc
+	PreApplyFunc   PreApplyFunc
+	PreChangeFunc  ApplyChangeFunc
+	TimingsFunc    ApplyTimingsFunc
+	Umask          fs.FileMode
 }
 
 // Apply updates targetRelPath in targetDirAbsPath in destSystem to match s.
@@ -688,6 +840,9 @@ func (s *SourceState) Apply(
 		return nil
 	}
 
+	startTime := time.Now()
+	var renderDuration, preApplyDuration time.Duration
+
 	destAbsPath := s.destDirAbsPath.Join(targetRelPath)
 	targetStateEntry, err := sourceStateEntry.TargetStateEntry(destSystem, destAbsPath)
 	if err != nil {
@@ -705,6 +860,14 @@ func (s *SourceState) Apply(
 		return err
 	}
 
+	if options.TimingsFunc != nil {
+		renderDuration = time.Since(startTime)
+		defer func() {
+			ioDuration := time.Since(startTime) - renderDuration - preApplyDuration
+			options.TimingsFunc(targetRelPath, renderDuration, ioDuration)
+		}()
+	}
+
 	switch skip, err := targetStateEntry.SkipApply(persistentState, targetAbsPath); {
 	case err != nil:
 		return err
@@ -717,6 +880,14 @@ func (s *SourceState) Apply(
 		return err
 	}
 
+	var actualEntryState *EntryState
+	if options.PreApplyFunc != nil || options.PreChangeFunc != nil || options.PostChangeFunc != nil {
+		actualEntryState, err = actualStateEntry.EntryState()
+		if err != nil {
+			return err
+		}
+	}
+
 	if options.PreApplyFunc != nil {
 		var lastWrittenEntryState *EntryState
 		var entryState EntryState
@@ -728,11 +899,6 @@ func (s *SourceState) Apply(
 			lastWrittenEntryState = &entryState
 		}
 
-		actualEntryState, err := actualStateEntry.EntryState()
-		if err != nil {
-			return err
-		}
-
 		// If the target entry state matches the actual entry state, but not the
 		// last written entry state then silently update the last written entry
 		// state. This handles the case where the user makes identical edits to
@@ -747,19 +913,49 @@ func (s *SourceState) Apply(
 			lastWrittenEntryState = targetEntryState
 		}
 
+		preApplyStartTime := time.Now()
 		err = options.PreApplyFunc(targetRelPath, targetEntryState, lastWrittenEntryState, actualEntryState)
+		preApplyDuration = time.Since(preApplyStartTime)
 		if err != nil {
 			return err
 		}
 	}
 
+	if options.PreChangeFunc != nil && !targetEntryState.Equivalent(actualEntryState) {
+		if err := options.PreChangeFunc(targetRelPath, actualEntryState, targetEntryState); err != nil {
+			return err
+		}
+	}
+
 	if changed, err := targetStateEntry.Apply(targetSystem, persistentState, actualStateEntry); err != nil {
 		return err
 	} else if !changed {
 		return nil
 	}
 
-	return PersistentStateSet(persistentState, EntryStateBucket, targetAbsPath.Bytes(), targetEntryState)
+	// Only the modification times of files are set, as the modification times
+	// of directories change whenever their entries change and setting the
+	// modification time of a symlink would set the modification time of its
+	// target.
+	if _, ok := targetStateEntry.(*TargetStateFile); ok && options.MtimeFunc != nil {
+		switch mtime, err := options.MtimeFunc(targetRelPath, sourceStateEntry); {
+		case err != nil:
+			return err
+		case !mtime.IsZero():
+			if err := targetSystem.Chtimes(targetAbsPath, mtime, mtime); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := PersistentStateSet(persistentState, EntryStateBucket, targetAbsPath.Bytes(), targetEntryState); err != nil {
+		return err
+	}
+
+	if options.PostChangeFunc != nil {
+		return options.PostChangeFunc(targetRelPath, actualEntryState, targetEntryState)
+	}
+	return nil
 }
 
 // Encryption returns s's encryption.
@@ -773,6 +969,8 @@ type ExecuteTemplateDataOptions struct {
 	Destination     string
 	Data            []byte
 	TemplateOptions TemplateOptions
+	AccessRecorder  *TemplateAccessRecorder
+	lineRecorder    *templateLineRecorder
 }
 
 // ExecuteTemplateData returns the result of executing template data.
@@ -780,7 +978,10 @@ func (s *SourceState) ExecuteTemplateData(options ExecuteTemplateDataOptions) ([
 	templateOptions := options.TemplateOptions
 	templateOptions.Options = slices.Clone(s.templateOptions)
 
-	tmpl, err := ParseTemplate(options.Name, options.Data, s.templateFuncs, templateOptions)
+	s.verboseFunc(VerbosityDetails, "templates", "executing %s\n", options.Name)
+
+	templateFuncs := s.templateFuncs
+	tmpl, err := ParseTemplate(options.Name, options.Data, templateFuncs, templateOptions)
 	if err != nil {
 		return nil, err
 	}
@@ -792,6 +993,23 @@ func (s *SourceState) ExecuteTemplateData(options ExecuteTemplateDataOptions) ([
 		}
 	}
 
+	if s.executeTemplateFuncs != nil {
+		name := options.Destination
+		if name == "" {
+			name = options.Name
+		}
+		templateFuncs = maps.Clone(templateFuncs)
+		maps.Copy(templateFuncs, s.executeTemplateFuncs(name, tmpl.referencedNames()))
+		tmpl.template.Funcs(templateFuncs)
+	}
+
+	if options.AccessRecorder != nil {
+		options.AccessRecorder.instrument(tmpl, templateFuncs)
+	}
+	if options.lineRecorder != nil {
+		options.lineRecorder.instrument(tmpl, options.Data)
+	}
+
 	// Set .chezmoi.sourceFile to the name of the template.
 	templateData := s.TemplateData()
 	if chezmoiTemplateData, ok := templateData["chezmoi"].(map[string]any); ok {
@@ -799,7 +1017,16 @@ func (s *SourceState) ExecuteTemplateData(options ExecuteTemplateDataOptions) ([
 		chezmoiTemplateData["targetFile"] = options.Destination
 	}
 
-	return tmpl.Execute(templateData)
+	start := time.Now()
+	result, err := tmpl.Execute(templateData)
+	chezmoilog.InfoOrError(s.logger, "ExecuteTemplateData", err,
+		slog.String("name", options.Name),
+		slog.Duration("duration", time.Since(start)),
+	)
+	if err == nil && options.lineRecorder != nil {
+		result = options.lineRecorder.strip(result)
+	}
+	return result, err
 }
 
 // ForEach calls f for each source state entry.
@@ -825,6 +1052,120 @@ func (s *SourceState) Ignore(targetRelPath RelPath) bool {
 	return ignore
 }
 
+// UnusedIgnorePatterns returns the patterns read from .chezmoiignore files that
+// do not match any target, including ignored targets, or any entry in the
+// destination directory.
+func (s *SourceState) UnusedIgnorePatterns() ([]IgnorePattern, error) {
+	targetRelPaths := s.TargetRelPaths()
+	s.Lock()
+	targetRelPaths = append(targetRelPaths, s.ignoredRelPaths.Elements()...)
+	s.Unlock()
+
+	var unusedIgnorePatterns []IgnorePattern
+IGNORE_PATTERN:
+	for _, ignorePattern := range s.ignoreFilePatterns {
+		for _, targetRelPath := range targetRelPaths {
+			if ok, _ := doublestar.Match(ignorePattern.Pattern, targetRelPath.String()); ok {
+				continue IGNORE_PATTERN
+			}
+		}
+		switch ok, err := s.destEntryMatches(ignorePattern.Pattern); {
+		case err != nil:
+			return nil, err
+		case !ok:
+			unusedIgnorePatterns = append(unusedIgnorePatterns, ignorePattern)
+		}
+	}
+
+	// .chezmoiignore files are read concurrently, so sort the patterns by
+	// their origin.
+	sort.SliceStable(unusedIgnorePatterns, func(i, j int) bool {
+		a, b := unusedIgnorePatterns[i], unusedIgnorePatterns[j]
+		if a.SourceRelPath != b.SourceRelPath {
+			return a.SourceRelPath.Less(b.SourceRelPath)
+		}
+		return a.LineNumber < b.LineNumber
+	})
+	return unusedIgnorePatterns, nil
+}
+
+// destEntryMatches returns if pattern matches an entry in the destination
+// directory. To avoid walking the whole destination directory, only the
+// directory containing the last component of pattern is read, and if the
+// directory itself contains a glob then only its static prefix is checked.
+func (s *SourceState) destEntryMatches(pattern string) (bool, error) {
+	exists := func(absPath AbsPath) (bool, error) {
+		switch _, err := s.system.Lstat(absPath); {
+		case errors.Is(err, fs.ErrNotExist):
+			return false, nil
+		case err != nil:
+			return false, err
+		default:
+			return true, nil
+		}
+	}
+
+	dir, base := path.Split(pattern)
+	dir = strings.TrimSuffix(dir, "/")
+	switch {
+	case strings.ContainsAny(dir, globMetaChars):
+		var prefixComponents []string
+		for _, component := range strings.Split(dir, "/") {
+			if strings.ContainsAny(component, globMetaChars) {
+				break
+			}
+			prefixComponents = append(prefixComponents, component)
+		}
+		if len(prefixComponents) == 0 {
+			return false, nil
+		}
+		return exists(s.destDirAbsPath.JoinString(prefixComponents...))
+	case !strings.ContainsAny(base, globMetaChars):
+		return exists(s.destDirAbsPath.JoinString(pattern))
+	default:
+		dirEntries, err := s.system.ReadDir(s.destDirAbsPath.JoinString(dir))
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			return false, nil
+		case err != nil:
+			return false, err
+		}
+		for _, dirEntry := range dirEntries {
+			if ok, _ := doublestar.Match(base, dirEntry.Name()); ok {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+}
+
+// AddIgnored returns if targetRelPath should be ignored when adding targets or
+// finding unmanaged targets.
+func (s *SourceState) AddIgnored(targetRelPath RelPath) bool {
+	s.Lock()
+	defer s.Unlock()
+	return s.addIgnore.match(targetRelPath.String()) == patternSetMatchInclude
+}
+
+// IsRemoveDir returns if targetRelPath is a directory with the remove_
+// attribute.
+func (s *SourceState) IsRemoveDir(targetRelPath RelPath) bool {
+	s.Lock()
+	defer s.Unlock()
+	return s.removeDirs.Contains(targetRelPath)
+}
+
+// GPGKeys returns the GPG keys in s, indexed by fingerprint.
+func (s *SourceState) GPGKeys() map[string]*GPGKey {
+	return s.gpgKeys
+}
+
+// DirMetadata returns the metadata of all target directories that have
+// metadata.
+func (s *SourceState) DirMetadata() map[RelPath]*DirMetadata {
+	return s.dirMetadata
+}
+
 // Ignored returns all ignored RelPaths.
 func (s *SourceState) Ignored() RelPaths {
 	relPaths := RelPaths(s.ignoredRelPaths.Elements())
@@ -832,6 +1173,41 @@ func (s *SourceState) Ignored() RelPaths {
 	return relPaths
 }
 
+// LayerConflicts returns the targets that have entries in more than one of the
+// source layer directories and the source directory, and the origins of those
+// entries in the order that they were read. The last origin is the one that is
+// used.
+func (s *SourceState) LayerConflicts() map[RelPath][]string {
+	return s.layerConflicts
+}
+
+// SourceAbsPath returns the absolute path of sourceStateEntry in the source
+// directory or source layer directory that it was read from.
+func (s *SourceState) SourceAbsPath(sourceStateEntry SourceStateEntry) AbsPath {
+	if origin, ok := sourceStateEntry.Origin().(SourceStateOriginAbsPath); ok {
+		return AbsPath(origin)
+	}
+	return s.sourceDirAbsPath.Join(sourceStateEntry.SourceRelPath().RelPath())
+}
+
+// SourceDirAbsPathOf returns the source directory or source layer directory
+// that sourceStateEntry was read from. If the directories are nested then the
+// innermost one is returned.
+func (s *SourceState) SourceDirAbsPathOf(sourceStateEntry SourceStateEntry) AbsPath {
+	origin, ok := sourceStateEntry.Origin().(SourceStateOriginAbsPath)
+	if !ok {
+		return s.sourceDirAbsPath
+	}
+	result := s.sourceDirAbsPath
+	resultLen := 0
+	for _, dirAbsPath := range append(slices.Clone(s.layerDirAbsPaths), s.sourceDirAbsPath) {
+		if _, err := AbsPath(origin).TrimDirPrefix(dirAbsPath); err == nil && dirAbsPath.Len() > resultLen {
+			result, resultLen = dirAbsPath, dirAbsPath.Len()
+		}
+	}
+	return result
+}
+
 // MustEntry returns the source state entry associated with targetRelPath, and
 // panics if it does not exist.
 func (s *SourceState) MustEntry(targetRelPath RelPath) SourceStateEntry {
@@ -895,6 +1271,8 @@ TARGET:
 
 // ReadOptions are options to SourceState.Read.
 type ReadOptions struct {
+	BandwidthLimit   int64
+	MaxConnections   int
 	ReadHTTPResponse func(*http.Response) ([]byte, error)
 	RefreshExternals RefreshExternals
 	TimeNow          func() time.Time
@@ -902,28 +1280,34 @@ type ReadOptions struct {
 
 // Read reads the source state from the source directory.
 func (s *SourceState) Read(ctx context.Context, options *ReadOptions) error {
-	switch fileInfo, err := s.system.Stat(s.sourceDirAbsPath); {
-	case errors.Is(err, fs.ErrNotExist):
-		return nil
-	case err != nil:
-		return err
-	case !fileInfo.IsDir():
-		return fmt.Errorf("%s: not a directory", s.sourceDirAbsPath)
+	for _, ignorePattern := range s.ignorePatterns {
+		include := patternSetInclude
+		if pattern, ok := strings.CutPrefix(ignorePattern, "!"); ok {
+			ignorePattern = pattern
+			include = patternSetExclude
+		}
+		if err := s.ignore.add(ignorePattern, include); err != nil {
+			return err
+		}
 	}
 
-	// Read all source entries.
-	var allSourceStateEntriesMu sync.Mutex
+	// Read all source entries. The source layer directories are read first, in
+	// order, followed by the source directory. Entries in later directories
+	// override entries for the same target in earlier directories.
 	allSourceStateEntries := make(map[RelPath][]SourceStateEntry)
+	var sourceDirAbsPath AbsPath
+	var layerSourceStateEntriesMu sync.Mutex
+	var layerSourceStateEntries map[RelPath][]SourceStateEntry
 	addSourceStateEntries := func(relPath RelPath, sourceStateEntries ...SourceStateEntry) {
-		allSourceStateEntriesMu.Lock()
-		defer allSourceStateEntriesMu.Unlock()
-		allSourceStateEntries[relPath] = append(allSourceStateEntries[relPath], sourceStateEntries...)
+		layerSourceStateEntriesMu.Lock()
+		defer layerSourceStateEntriesMu.Unlock()
+		layerSourceStateEntries[relPath] = append(layerSourceStateEntries[relPath], sourceStateEntries...)
 	}
 	walkFunc := func(sourceAbsPath AbsPath, fileInfo fs.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
-		if sourceAbsPath == s.sourceDirAbsPath {
+		if sourceAbsPath == sourceDirAbsPath {
 			return nil
 		}
 
@@ -942,7 +1326,7 @@ func (s *SourceState) Read(ctx context.Context, options *ReadOptions) error {
 		}
 
 		sourceRelPath := SourceRelPath{
-			relPath: sourceAbsPath.MustTrimDirPrefix(s.sourceDirAbsPath),
+			relPath: sourceAbsPath.MustTrimDirPrefix(sourceDirAbsPath),
 			isDir:   fileInfo.IsDir(),
 		}
 		parentSourceRelPath, sourceName := sourceRelPath.Split()
@@ -972,18 +1356,24 @@ func (s *SourceState) Read(ctx context.Context, options *ReadOptions) error {
 			return nil
 		case isPrefixDotFormat(fileInfo.Name(), externalName) || isPrefixDotFormatDotTmpl(fileInfo.Name(), externalName):
 			parentAbsPath, _ := sourceAbsPath.Split()
-			return s.addExternal(sourceAbsPath, parentAbsPath)
+			return s.addExternal(sourceDirAbsPath, sourceAbsPath, parentAbsPath)
 		case fileInfo.Name() == externalsDirName:
-			if err := s.addExternalDir(ctx, sourceAbsPath); err != nil {
+			if err := s.addExternalDir(ctx, sourceDirAbsPath, sourceAbsPath); err != nil {
 				return err
 			}
 			return fs.SkipDir
+		case fileInfo.Name() == addIgnoreName || fileInfo.Name() == addIgnoreName+TemplateSuffix:
+			return s.addPatterns(s.addIgnore, sourceAbsPath, parentSourceRelPath)
+		case isPrefixDotFormat(fileInfo.Name(), gpgKeysName):
+			return s.addGPGKeys(sourceAbsPath)
 		case fileInfo.Name() == ignoreName || fileInfo.Name() == ignoreName+TemplateSuffix:
 			return s.addPatterns(s.ignore, sourceAbsPath, parentSourceRelPath)
+		case isPrefixDotFormat(fileInfo.Name(), metadataName):
+			return s.addDirMetadata(sourceAbsPath, parentSourceRelPath)
 		case fileInfo.Name() == removeName || fileInfo.Name() == removeName+TemplateSuffix:
 			return s.addPatterns(s.remove, sourceAbsPath, parentSourceRelPath)
 		case fileInfo.Name() == scriptsDirName:
-			scriptsDirSourceStateEntries, err := s.readScriptsDir(ctx, sourceAbsPath)
+			scriptsDirSourceStateEntries, err := s.readScriptsDir(ctx, sourceDirAbsPath, sourceAbsPath)
 			if err != nil {
 				return err
 			}
@@ -1013,11 +1403,11 @@ func (s *SourceState) Read(ctx context.Context, options *ReadOptions) error {
 				if err != nil {
 					return err
 				}
-				allSourceStateEntriesMu.Lock()
+				layerSourceStateEntriesMu.Lock()
 				for relPath, entries := range sourceStateEntries {
-					allSourceStateEntries[relPath] = append(allSourceStateEntries[relPath], entries...)
+					layerSourceStateEntries[relPath] = append(layerSourceStateEntries[relPath], entries...)
 				}
-				allSourceStateEntriesMu.Unlock()
+				layerSourceStateEntriesMu.Unlock()
 				return fs.SkipDir
 			}
 			if sourceStateDir.Attr.Remove {
@@ -1043,8 +1433,30 @@ func (s *SourceState) Read(ctx context.Context, options *ReadOptions) error {
 			}
 		}
 	}
-	if err := WalkSourceDir(s.system, s.sourceDirAbsPath, walkFunc); err != nil {
-		return err
+	read := false
+	for _, sourceDirAbsPath = range append(slices.Clone(s.layerDirAbsPaths), s.sourceDirAbsPath) {
+		switch fileInfo, err := s.system.Stat(sourceDirAbsPath); {
+		case errors.Is(err, fs.ErrNotExist):
+			continue
+		case err != nil:
+			return err
+		case !fileInfo.IsDir():
+			return fmt.Errorf("%s: not a directory", sourceDirAbsPath)
+		}
+		read = true
+		layerSourceStateEntries = make(map[RelPath][]SourceStateEntry)
+		if err := WalkSourceDir(s.system, sourceDirAbsPath, walkFunc); err != nil {
+			return err
+		}
+		for targetRelPath, sourceStateEntries := range layerSourceStateEntries {
+			if prevSourceStateEntries, ok := allSourceStateEntries[targetRelPath]; ok {
+				s.addLayerConflict(targetRelPath, prevSourceStateEntries, sourceStateEntries)
+			}
+			allSourceStateEntries[targetRelPath] = sourceStateEntries
+		}
+	}
+	if !read {
+		return nil
 	}
 
 	if s.templateDataOnly {
@@ -1057,6 +1469,16 @@ func (s *SourceState) Read(ctx context.Context, options *ReadOptions) error {
 		externalRelPaths = append(externalRelPaths, externalRelPath)
 	}
 	sort.Sort(externalRelPaths)
+	if options != nil && options.BandwidthLimit > 0 {
+		s.bandwidthLimiter = newBandwidthLimiter(options.BandwidthLimit)
+	}
+	// Externals are downloaded one at a time unless more than one connection
+	// is allowed. A MaxConnections of zero is the same as one.
+	if options != nil && options.MaxConnections > 1 {
+		if err := s.prefetchExternals(ctx, externalRelPaths, options); err != nil {
+			return err
+		}
+	}
 	for _, externalRelPath := range externalRelPaths {
 		if s.Ignore(externalRelPath) {
 			continue
@@ -1225,8 +1647,8 @@ func (s *SourceState) Read(ctx context.Context, options *ReadOptions) error {
 		}
 	}
 
-	// Check for inconsistent source entries. Iterate over the target names in
-	// order so that any error is deterministic.
+	// Check for invalid target names and inconsistent source entries. Iterate
+	// over the target names in order so that any error is deterministic.
 	targetRelPaths := make(RelPaths, 0, len(allSourceStateEntries))
 	for targetRelPath := range allSourceStateEntries {
 		targetRelPaths = append(targetRelPaths, targetRelPath)
@@ -1234,6 +1656,11 @@ func (s *SourceState) Read(ctx context.Context, options *ReadOptions) error {
 	sort.Sort(targetRelPaths)
 	errs := make([]error, 0, len(targetRelPaths))
 	for _, targetRelPath := range targetRelPaths {
+		if err := validateTargetRelPath(targetRelPath); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
 		sourceStateEntries := allSourceStateEntries[targetRelPath]
 		if len(sourceStateEntries) == 1 {
 			continue
@@ -1268,9 +1695,27 @@ func (s *SourceState) TargetRelPaths() []RelPath {
 	for targetRelPath := range entries {
 		targetRelPaths = append(targetRelPaths, targetRelPath)
 	}
+	sortTargetRelPaths(targetRelPaths, entries)
+	return targetRelPaths
+}
+
+// SortTargetRelPaths sorts targetRelPaths in the order in which they are
+// applied: scripts with the before_ attribute first, then all other entries,
+// then scripts with the after_ attribute, each in lexical order.
+func (s *SourceState) SortTargetRelPaths(targetRelPaths []RelPath) {
+	sortTargetRelPaths(targetRelPaths, s.root.getMap())
+}
+
+func sortTargetRelPaths(targetRelPaths []RelPath, entries map[RelPath]SourceStateEntry) {
+	order := func(targetRelPath RelPath) ScriptOrder {
+		if entry, ok := entries[targetRelPath]; ok {
+			return entry.Order()
+		}
+		return ScriptOrderDuring
+	}
 	sort.Slice(targetRelPaths, func(i, j int) bool {
-		orderI := entries[targetRelPaths[i]].Order()
-		orderJ := entries[targetRelPaths[j]].Order()
+		orderI := order(targetRelPaths[i])
+		orderJ := order(targetRelPaths[j])
 		switch {
 		case orderI < orderJ:
 			return true
@@ -1280,7 +1725,30 @@ func (s *SourceState) TargetRelPaths() []RelPath {
 			return false
 		}
 	})
-	return targetRelPaths
+}
+
+// autoTemplateData returns the template data whose values are replaced when
+// generating templates automatically. This is the user's template data and the
+// values of autoTemplateChezmoiKeys, as other values in .chezmoi, like
+// .chezmoi.os and .chezmoi.config, are too common to replace.
+func (s *SourceState) autoTemplateData() map[string]any {
+	templateData := s.TemplateData()
+	autoTemplateData := make(map[string]any, len(templateData))
+	for key, value := range templateData {
+		if key != "chezmoi" {
+			autoTemplateData[key] = value
+		}
+	}
+	if chezmoiData, ok := templateData["chezmoi"].(map[string]any); ok {
+		autoTemplateChezmoiData := make(map[string]any, len(autoTemplateChezmoiKeys))
+		for _, key := range autoTemplateChezmoiKeys {
+			if value, ok := chezmoiData[key]; ok {
+				autoTemplateChezmoiData[key] = value
+			}
+		}
+		autoTemplateData["chezmoi"] = autoTemplateChezmoiData
+	}
+	return autoTemplateData
 }
 
 // TemplateData returns a copy of s's template data.
@@ -1298,6 +1766,9 @@ func (s *SourceState) TemplateData() map[string]any {
 		RecursiveMerge(s.templateData, s.userTemplateData)
 		RecursiveMerge(s.templateData, s.priorityTemplateData)
 	}
+	if s.shuffleTemplateData {
+		return shuffleMaps(s.templateData).(map[string]any) //nolint:forcetypeassert
+	}
 	templateData, err := copystructure.Copy(s.templateData)
 	if err != nil {
 		panic(err)
@@ -1306,8 +1777,8 @@ func (s *SourceState) TemplateData() map[string]any {
 }
 
 // addExternal adds external source entries to s.
-func (s *SourceState) addExternal(sourceAbsPath, parentAbsPath AbsPath) error {
-	parentRelPath, err := parentAbsPath.TrimDirPrefix(s.sourceDirAbsPath)
+func (s *SourceState) addExternal(sourceDirAbsPath, sourceAbsPath, parentAbsPath AbsPath) error {
+	parentRelPath, err := parentAbsPath.TrimDirPrefix(sourceDirAbsPath)
 	if err != nil {
 		return err
 	}
@@ -1341,7 +1812,7 @@ func (s *SourceState) addExternal(sourceAbsPath, parentAbsPath AbsPath) error {
 }
 
 // addExternalDir adds all externals in externalsDirAbsPath to s.
-func (s *SourceState) addExternalDir(ctx context.Context, externalsDirAbsPath AbsPath) error {
+func (s *SourceState) addExternalDir(ctx context.Context, sourceDirAbsPath, externalsDirAbsPath AbsPath) error {
 	walkFunc := func(ctx context.Context, externalAbsPath AbsPath, fileInfo fs.FileInfo, err error) error {
 		if externalAbsPath == externalsDirAbsPath {
 			return nil
@@ -1361,7 +1832,7 @@ func (s *SourceState) addExternalDir(ctx context.Context, externalsDirAbsPath Ab
 			return nil
 		case fileInfo.Mode().IsRegular():
 			parentAbsPath, _ := externalAbsPath.Split()
-			return s.addExternal(externalAbsPath, parentAbsPath.TrimSuffix("/").Dir())
+			return s.addExternal(sourceDirAbsPath, externalAbsPath, parentAbsPath.TrimSuffix("/").Dir())
 		case fileInfo.IsDir():
 			return nil
 		default:
@@ -1374,10 +1845,84 @@ func (s *SourceState) addExternalDir(ctx context.Context, externalsDirAbsPath Ab
 	return concurrentWalkSourceDir(ctx, s.system, externalsDirAbsPath, walkFunc)
 }
 
+// addGPGKeys adds the GPG keys in sourceAbsPath to s.
+func (s *SourceState) addGPGKeys(sourceAbsPath AbsPath) error {
+	format, err := FormatFromAbsPath(sourceAbsPath)
+	if err != nil {
+		return err
+	}
+	data, err := s.system.ReadFile(sourceAbsPath)
+	if err != nil {
+		return fmt.Errorf("%s: %w", sourceAbsPath, err)
+	}
+	var gpgKeys map[string]*GPGKey
+	if err := format.Unmarshal(data, &gpgKeys); err != nil {
+		return fmt.Errorf("%s: %w", sourceAbsPath, err)
+	}
+	s.Lock()
+	defer s.Unlock()
+	for fingerprint, gpgKey := range gpgKeys {
+		if gpgKey == nil {
+			gpgKey = &GPGKey{}
+		}
+		normalizedFingerprint := strings.ToUpper(strings.ReplaceAll(fingerprint, " ", ""))
+		switch _, ok := GPGOwnerTrustLevels[gpgKey.Trust]; {
+		case !gpgFingerprintRx.MatchString(normalizedFingerprint):
+			return fmt.Errorf("%s: %s: invalid fingerprint", sourceAbsPath, fingerprint)
+		case gpgKey.File != "" && gpgKey.Keyserver != "":
+			return fmt.Errorf("%s: %s: file and keyserver are mutually exclusive", sourceAbsPath, fingerprint)
+		case gpgKey.Trust != "" && !ok:
+			return fmt.Errorf("%s: %s: %s: invalid trust", sourceAbsPath, fingerprint, gpgKey.Trust)
+		}
+		if _, ok := s.gpgKeys[normalizedFingerprint]; ok {
+			return fmt.Errorf("%s: %s: duplicate GPG key", sourceAbsPath, fingerprint)
+		}
+		gpgKey.sourceAbsPath = sourceAbsPath
+		s.gpgKeys[normalizedFingerprint] = gpgKey
+	}
+	return nil
+}
+
+// addLayerConflict records that the source state entries for targetRelPath in
+// a source layer directory override prevSourceStateEntries from an earlier
+// directory. Directories with the same attributes in multiple directories are
+// merged and so are not conflicts.
+func (s *SourceState) addLayerConflict(targetRelPath RelPath, prevSourceStateEntries, sourceStateEntries []SourceStateEntry) {
+	if len(prevSourceStateEntries) == 1 && len(sourceStateEntries) == 1 {
+		prevSourceStateDir, prevOK := prevSourceStateEntries[0].(*SourceStateDir)
+		sourceStateDir, ok := sourceStateEntries[0].(*SourceStateDir)
+		if prevOK && ok && prevSourceStateDir.Attr == sourceStateDir.Attr {
+			return
+		}
+	}
+	origins := s.layerConflicts[targetRelPath]
+	if len(origins) == 0 {
+		for _, sourceStateEntry := range prevSourceStateEntries {
+			origins = append(origins, sourceStateEntry.Origin().OriginString())
+		}
+	}
+	for _, sourceStateEntry := range sourceStateEntries {
+		origins = append(origins, sourceStateEntry.Origin().OriginString())
+	}
+	s.layerConflicts[targetRelPath] = origins
+}
+
 // addPatterns executes the template at sourceAbsPath, interprets the result as
 // a list of patterns, and adds all patterns found to patternSet.
 func (s *SourceState) addPatterns(patternSet *patternSet, sourceAbsPath AbsPath, sourceRelPath SourceRelPath) error {
-	data, err := s.executeTemplate(sourceAbsPath)
+	templateData, err := s.system.ReadFile(sourceAbsPath)
+	if err != nil {
+		return err
+	}
+	// Record the line of the template that produced each pattern, so that
+	// errors and unused patterns are reported with their line in the source
+	// file.
+	var lineRecorder templateLineRecorder
+	data, err := s.ExecuteTemplateData(ExecuteTemplateDataOptions{
+		Name:         sourceAbsPath.String(),
+		Data:         templateData,
+		lineRecorder: &lineRecorder,
+	})
 	if err != nil {
 		return err
 	}
@@ -1403,7 +1948,15 @@ func (s *SourceState) addPatterns(patternSet *patternSet, sourceAbsPath AbsPath,
 		}
 		pattern := dir.JoinString(text).String()
 		if err := patternSet.add(pattern, include); err != nil {
-			return fmt.Errorf("%s:%d: %w", sourceAbsPath, lineNumber, err)
+			return fmt.Errorf("%s:%d: %w", sourceAbsPath, lineRecorder.lineNumber(lineNumber), err)
+		}
+		if patternSet == s.ignore {
+			s.ignoreFilePatterns = append(s.ignoreFilePatterns, IgnorePattern{
+				Pattern:       pattern,
+				Exclude:       include == patternSetExclude,
+				SourceRelPath: sourceRelPath.RelPath().JoinString(sourceAbsPath.Base()),
+				LineNumber:    lineRecorder.lineNumber(lineNumber),
+			})
 		}
 	}
 	if err := scanner.Err(); err != nil {
@@ -1412,6 +1965,27 @@ func (s *SourceState) addPatterns(patternSet *patternSet, sourceAbsPath AbsPath,
 	return nil
 }
 
+// addDirMetadata adds the directory metadata in sourceAbsPath to s.
+func (s *SourceState) addDirMetadata(sourceAbsPath AbsPath, parentSourceRelPath SourceRelPath) error {
+	format, err := FormatFromAbsPath(sourceAbsPath)
+	if err != nil {
+		return err
+	}
+	data, err := s.system.ReadFile(sourceAbsPath)
+	if err != nil {
+		return fmt.Errorf("%s: %w", sourceAbsPath, err)
+	}
+	var dirMetadata DirMetadata
+	if err := format.Unmarshal(data, &dirMetadata); err != nil {
+		return fmt.Errorf("%s: %w", sourceAbsPath, err)
+	}
+	targetRelPath := parentSourceRelPath.Dir().TargetRelPath(s.encryption.EncryptedSuffix())
+	s.Lock()
+	s.dirMetadata[targetRelPath] = &dirMetadata
+	s.Unlock()
+	return nil
+}
+
 // addTemplateData adds all template data in sourceAbsPath to s.
 func (s *SourceState) addTemplateData(sourceAbsPath AbsPath) error {
 	format, err := FormatFromAbsPath(sourceAbsPath)
@@ -1537,6 +2111,14 @@ func (s *SourceState) getExternalDataRaw(
 	external *External,
 	options *ReadOptions,
 ) ([]byte, error) {
+	s.Lock()
+	data, ok := s.externalDataRaw[external.URL]
+	s.Unlock()
+	if ok {
+		s.verboseFunc(VerbosityDetails, "externals", "%s: using cached %s\n", externalRelPath, external.URL)
+		return data, nil
+	}
+
 	var now time.Time
 	if options != nil && options.TimeNow != nil {
 		now = options.TimeNow()
@@ -1559,6 +2141,7 @@ func (s *SourceState) getExternalDataRaw(
 		if fileInfo, err := s.baseSystem.Stat(cachedDataAbsPath); err == nil {
 			if external.RefreshPeriod == 0 || fileInfo.ModTime().Add(time.Duration(external.RefreshPeriod)).After(now) {
 				if data, err := s.baseSystem.ReadFile(cachedDataAbsPath); err == nil {
+					s.verboseFunc(VerbosityDetails, "externals", "%s: using cached %s\n", externalRelPath, external.URL)
 					return data, nil
 				}
 			}
@@ -1567,26 +2150,81 @@ func (s *SourceState) getExternalDataRaw(
 		// Always use the cache, if available, irrespective of the refresh
 		// period.
 		if data, err := s.baseSystem.ReadFile(cachedDataAbsPath); err == nil {
+			s.verboseFunc(VerbosityDetails, "externals", "%s: using cached %s\n", externalRelPath, external.URL)
 			return data, nil
 		}
 	}
 
+	// If a previous download was interrupted, then request only the remaining
+	// data. The remaining data is only used if the resource has not changed
+	// since, otherwise the server sends all of it.
+	partialDataAbsPath := s.cacheDirAbsPath.JoinString("external", cacheKey+".partial")
+	partialValidatorAbsPath := s.cacheDirAbsPath.JoinString("external", cacheKey+".partial-validator")
+	partialData, err := s.baseSystem.ReadFile(partialDataAbsPath)
+	if err != nil {
+		partialData = nil
+	}
+	var partialValidator []byte
+	if len(partialData) > 0 {
+		partialValidator, err = s.baseSystem.ReadFile(partialValidatorAbsPath)
+		if err != nil || len(partialValidator) == 0 {
+			partialData = nil
+		}
+	}
+
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, external.URL, http.NoBody)
 	if err != nil {
 		return nil, err
 	}
+	if len(partialData) > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", len(partialData)))
+		req.Header.Set("If-Range", string(partialValidator))
+	}
+	s.verboseFunc(VerbosityActions, "externals", "%s: downloading %s\n", externalRelPath, external.URL)
 	resp, err := chezmoilog.LogHTTPRequest(ctx, s.logger, s.httpClient, req)
 	if err != nil {
 		return nil, err
 	}
-	var data []byte
+	if s.bandwidthLimiter != nil {
+		resp.Body = s.bandwidthLimiter.limitReadCloser(resp.Body)
+	}
 	if options == nil || options.ReadHTTPResponse == nil {
 		data, err = io.ReadAll(resp.Body)
 	} else {
 		data, err = options.ReadHTTPResponse(resp)
 	}
 	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && len(partialData) > 0:
+		if start, ok := contentRangeStart(resp.Header.Get("Content-Range")); !ok || start != len(partialData) {
+			_ = s.baseSystem.Remove(partialDataAbsPath)
+			return nil, fmt.Errorf("%s: %s: invalid Content-Range", externalRelPath, external.URL)
+		}
+		data = append(partialData, data...)
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && len(partialData) > 0:
+		// The partial data is no longer valid, so download everything again.
+		if err := s.baseSystem.Remove(partialDataAbsPath); err != nil {
+			return nil, err
+		}
+		return s.getExternalDataRaw(ctx, externalRelPath, external, options)
+	}
+
 	if err != nil {
+		// Keep the data downloaded so far so that the download can be resumed,
+		// if the server supports it and identifies the version of the resource.
+		validator := ifRangeValidator(resp.Header)
+		if resp.StatusCode == http.StatusPartialContent {
+			validator = string(partialValidator)
+		}
+		if len(data) > 0 && validator != "" &&
+			(resp.Header.Get("Accept-Ranges") == "bytes" || resp.StatusCode == http.StatusPartialContent) {
+			if err := MkdirAll(s.baseSystem, partialDataAbsPath.Dir(), 0o700); err == nil {
+				if s.baseSystem.WriteFile(partialValidatorAbsPath, []byte(validator), 0o600) == nil {
+					_ = s.baseSystem.WriteFile(partialDataAbsPath, data, 0o600)
+				}
+			}
+		}
 		return nil, err
 	}
 	if resp.StatusCode < http.StatusOK || http.StatusMultipleChoices <= resp.StatusCode {
@@ -1602,10 +2240,85 @@ func (s *SourceState) getExternalDataRaw(
 	if err := s.baseSystem.Chtimes(cachedDataAbsPath, now, now); err != nil {
 		return nil, err
 	}
+	for _, absPath := range []AbsPath{partialDataAbsPath, partialValidatorAbsPath} {
+		if err := s.baseSystem.Remove(absPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
 
 	return data, nil
 }
 
+// ifRangeValidator returns the value to use in an HTTP If-Range header to
+// resume the download whose response has header, or the empty string if the
+// response does not have a strong validator.
+func ifRangeValidator(header http.Header) string {
+	if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return header.Get("Last-Modified")
+}
+
+// contentRangeStart returns the first byte position of the HTTP Content-Range
+// header value contentRange.
+func contentRangeStart(contentRange string) (int, bool) {
+	byteRange, ok := strings.CutPrefix(contentRange, "bytes ")
+	if !ok {
+		return 0, false
+	}
+	startStr, _, ok := strings.Cut(byteRange, "-")
+	if !ok {
+		return 0, false
+	}
+	start, err := strconv.Atoi(startStr)
+	if err != nil {
+		return 0, false
+	}
+	return start, true
+}
+
+// prefetchExternals concurrently downloads the data of all the archive
+// externals at externalRelPaths, with at most options.MaxConnections concurrent
+// downloads. File externals are not prefetched as they are only downloaded when
+// their contents are needed.
+func (s *SourceState) prefetchExternals(ctx context.Context, externalRelPaths RelPaths, options *ReadOptions) error {
+	// Concurrent downloads cannot share the terminal to report their progress.
+	prefetchOptions := *options
+	prefetchOptions.ReadHTTPResponse = nil
+
+	group, ctx := errgroup.WithContext(ctx)
+	group.SetLimit(options.MaxConnections)
+	urls := chezmoiset.New[string]()
+	for _, externalRelPath := range externalRelPaths {
+		if s.Ignore(externalRelPath) {
+			continue
+		}
+		for _, external := range s.externals[externalRelPath] {
+			switch external.Type {
+			case ExternalTypeArchive, ExternalTypeArchiveFile:
+			default:
+				continue
+			}
+			if urls.Contains(external.URL) {
+				continue
+			}
+			urls.Add(external.URL)
+			externalRelPath, external := externalRelPath, external
+			group.Go(func() error {
+				data, err := s.getExternalDataRaw(ctx, externalRelPath, external, &prefetchOptions)
+				if err != nil {
+					return err
+				}
+				s.Lock()
+				s.externalDataRaw[external.URL] = data
+				s.Unlock()
+				return nil
+			})
+		}
+	}
+	return group.Wait()
+}
+
 // getExternalData reads the external data for externalRelPath from
 // external.URL.
 func (s *SourceState) getExternalData(
@@ -1719,9 +2432,13 @@ func (s *SourceState) newCreateTargetStateEntryFunc(
 ) targetStateEntryFunc {
 	return func(destSystem System, destAbsPath AbsPath) (TargetStateEntry, error) {
 		var lazyContents *lazyContents
+		empty := fileAttr.Empty
 		switch contents, err := destSystem.ReadFile(destAbsPath); {
 		case err == nil:
+			// The existing file is never modified, so it must not be removed
+			// even if it is empty.
 			lazyContents = newLazyContents(contents)
+			empty = true
 		case errors.Is(err, fs.ErrNotExist):
 			lazyContents = newLazyContentsFunc(func() ([]byte, error) {
 				contents, err = sourceLazyContents.Contents()
@@ -1745,7 +2462,7 @@ func (s *SourceState) newCreateTargetStateEntryFunc(
 		}
 		return &TargetStateFile{
 			lazyContents: lazyContents,
-			empty:        fileAttr.Empty,
+			empty:        empty,
 			perm:         fileAttr.perm() &^ s.umask,
 			sourceAttr: SourceAttr{
 				Encrypted: fileAttr.Encrypted,
@@ -1758,27 +2475,51 @@ func (s *SourceState) newCreateTargetStateEntryFunc(
 // newFileTargetStateEntryFunc returns a targetStateEntryFunc that returns a
 // file with sourceLazyContents.
 func (s *SourceState) newFileTargetStateEntryFunc(
+	sourceAbsPath AbsPath,
 	sourceRelPath SourceRelPath,
 	fileAttr FileAttr,
 	sourceLazyContents *lazyContents,
 ) targetStateEntryFunc {
 	return func(destSystem System, destAbsPath AbsPath) (TargetStateEntry, error) {
-		if s.mode == ModeSymlink && !fileAttr.Encrypted && !fileAttr.Executable && !fileAttr.Private && !fileAttr.Template {
+		if s.mode == ModeSymlink && !fileAttr.Encrypted && !fileAttr.Executable && !fileAttr.Private && !fileAttr.ReadOnly &&
+			!fileAttr.Template {
 			switch contents, err := sourceLazyContents.Contents(); {
 			case err != nil:
 				return nil, err
 			case isEmpty(contents) && !fileAttr.Empty:
 				return &TargetStateRemove{}, nil
-			default:
-				linkname := normalizeLinkname(s.sourceDirAbsPath.Join(sourceRelPath.RelPath()).String())
+			case s.symlinkCopyDirAbsPath.Empty():
+				linkname := normalizeLinkname(sourceAbsPath.String())
 				return &TargetStateSymlink{
 					lazyLinkname: newLazyLinkname(linkname),
 					sourceAttr: SourceAttr{
 						Template: fileAttr.Template,
 					},
 				}, nil
+			default:
+				// Copies are named by the SHA256 of their contents, so they
+				// never change once written and a change in contents is a
+				// change in the symlink's target.
+				contentsSHA256 := sha256.Sum256(contents)
+				copyAbsPath := s.symlinkCopyDirAbsPath.JoinString(hex.EncodeToString(contentsSHA256[:]), destAbsPath.Base())
+				return &TargetStateSymlink{
+					lazyLinkname: newLazyLinkname(normalizeLinkname(copyAbsPath.String())),
+					sourceAttr: SourceAttr{
+						Template: fileAttr.Template,
+					},
+					copyAbsPath:  copyAbsPath,
+					copyContents: contents,
+				}, nil
 			}
 		}
+		targetStateFile := &TargetStateFile{
+			empty: fileAttr.Empty,
+			perm:  fileAttr.perm() &^ s.umask,
+			sourceAttr: SourceAttr{
+				Encrypted: fileAttr.Encrypted,
+				Template:  fileAttr.Template,
+			},
+		}
 		contentsFunc := func() ([]byte, error) {
 			contents, err := sourceLazyContents.Contents()
 			if err != nil {
@@ -1793,19 +2534,38 @@ func (s *SourceState) newFileTargetStateEntryFunc(
 				if err != nil {
 					return nil, err
 				}
+				// The permissions directive is parsed after executing the
+				// template so that the permissions can depend on the template
+				// data.
+				if matches := permissionsDirectiveRx.FindAllSubmatchIndex(contents, -1); matches != nil {
+					perm, err := parsePermissionsDirective(sourceRelPath, contents, matches)
+					if err != nil {
+						return nil, err
+					}
+					targetStateFile.perm = perm &^ s.umask
+					contents = removeMatches(contents, matches)
+				}
 			}
 			return contents, nil
 		}
-		return &TargetStateFile{
-			lazyContents: newLazyContentsFunc(contentsFunc),
-			empty:        fileAttr.Empty,
-			perm:         fileAttr.perm() &^ s.umask,
-			sourceAttr: SourceAttr{
-				Encrypted: fileAttr.Encrypted,
-				Template:  fileAttr.Template,
-			},
-		}, nil
+		targetStateFile.lazyContents = newLazyContentsFunc(contentsFunc)
+		return targetStateFile, nil
+	}
+}
+
+// parsePermissionsDirective returns the permissions set by the last
+// chezmoi:permissions: directive in contents.
+func parsePermissionsDirective(sourceRelPath SourceRelPath, contents []byte, matches [][]int) (fs.FileMode, error) {
+	lastMatch := matches[len(matches)-1]
+	fields := strings.Fields(string(contents[lastMatch[2]:lastMatch[3]]))
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("%s: empty chezmoi:permissions: directive", sourceRelPath)
+	}
+	perm, err := strconv.ParseUint(fields[0], 8, 32)
+	if err != nil || perm > uint64(fs.ModePerm) {
+		return 0, fmt.Errorf("%s: %s: invalid chezmoi:permissions: directive", sourceRelPath, fields[0])
 	}
+	return fs.FileMode(perm), nil
 }
 
 // newModifyTargetStateEntryFunc returns a targetStateEntryFunc that returns a
@@ -2003,7 +2763,7 @@ func (s *SourceState) newSourceStateFile(
 	targetRelPath RelPath,
 ) (RelPath, *SourceStateFile) {
 	sourceLazyContents := newLazyContentsFunc(func() ([]byte, error) {
-		contents, err := s.system.ReadFile(s.sourceDirAbsPath.Join(sourceRelPath.RelPath()))
+		contents, err := s.system.ReadFile(absPath)
 		if err != nil {
 			return nil, err
 		}
@@ -2021,7 +2781,7 @@ func (s *SourceState) newSourceStateFile(
 	case SourceFileTypeCreate:
 		targetStateEntryFunc = s.newCreateTargetStateEntryFunc(sourceRelPath, fileAttr, sourceLazyContents)
 	case SourceFileTypeFile:
-		targetStateEntryFunc = s.newFileTargetStateEntryFunc(sourceRelPath, fileAttr, sourceLazyContents)
+		targetStateEntryFunc = s.newFileTargetStateEntryFunc(absPath, sourceRelPath, fileAttr, sourceLazyContents)
 	case SourceFileTypeModify:
 		// If the target has an extension, determine if it indicates an
 		// interpreter to use.
@@ -2117,7 +2877,7 @@ func (s *SourceState) newSourceStateFileEntryFromFile(
 	}
 	if options.AutoTemplate {
 		var replacements bool
-		contents, replacements = autoTemplate(contents, s.TemplateData())
+		contents, replacements = autoTemplate(contents, s.autoTemplateData())
 		if replacements {
 			fileAttr.Template = true
 		}
@@ -2162,7 +2922,7 @@ func (s *SourceState) newSourceStateFileEntryFromSymlink(
 	template := false
 	switch {
 	case options.AutoTemplate:
-		contents, template = autoTemplate(contents, s.TemplateData())
+		contents, template = autoTemplate(contents, s.autoTemplateData())
 	case options.Template:
 		template = true
 	case !options.Template && options.TemplateSymlinks:
@@ -2674,7 +3434,10 @@ func (s *SourceState) readExternalFile(
 }
 
 // readScriptsDir reads all scripts in scriptsDirAbsPath.
-func (s *SourceState) readScriptsDir(ctx context.Context, scriptsDirAbsPath AbsPath) (map[RelPath][]SourceStateEntry, error) {
+func (s *SourceState) readScriptsDir(
+	ctx context.Context,
+	sourceDirAbsPath, scriptsDirAbsPath AbsPath,
+) (map[RelPath][]SourceStateEntry, error) {
 	var allSourceStateEntriesMu sync.Mutex
 	allSourceStateEntries := make(map[RelPath][]SourceStateEntry)
 	addSourceStateEntry := func(relPath RelPath, sourceStateEntry SourceStateEntry) {
@@ -2705,7 +3468,7 @@ func (s *SourceState) readScriptsDir(ctx context.Context, scriptsDirAbsPath AbsP
 		}
 
 		sourceRelPath := SourceRelPath{
-			relPath: sourceAbsPath.MustTrimDirPrefix(s.sourceDirAbsPath),
+			relPath: sourceAbsPath.MustTrimDirPrefix(sourceDirAbsPath),
 			isDir:   fileInfo.IsDir(),
 		}
 		parentSourceRelPath, sourceName := sourceRelPath.Split()
@@ -2854,3 +3617,38 @@ func canonicalSourceStateEntry(sourceStateEntries []SourceStateEntry) (SourceSta
 func isAppleDoubleFile(name string, contents []byte) bool {
 	return strings.HasPrefix(path.Base(name), appleDoubleNamePrefix) && bytes.HasPrefix(contents, appleDoubleContentsPrefix)
 }
+
+// EncryptedSourceAbsPaths returns the absolute paths of all encrypted files in
+// the source directory sourceDirAbsPath, including those that are ignored on
+// the current machine.
+func EncryptedSourceAbsPaths(system System, sourceDirAbsPath AbsPath, encryptedSuffix string) (AbsPaths, error) {
+	var encryptedSourceAbsPaths AbsPaths
+	walkFunc := func(sourceAbsPath AbsPath, fileInfo fs.FileInfo, err error) error {
+		switch {
+		case err != nil:
+			return err
+		case sourceAbsPath == sourceDirAbsPath:
+			return nil
+		}
+		name := fileInfo.Name()
+		switch {
+		case fileInfo.IsDir() && name == scriptsDirName:
+			return nil
+		case fileInfo.IsDir() && strings.HasPrefix(name, ignorePrefix):
+			return fs.SkipDir
+		case fileInfo.IsDir() && parseDirAttr(name).External:
+			return fs.SkipDir
+		case fileInfo.IsDir():
+			return nil
+		case strings.HasPrefix(name, ignorePrefix):
+			return nil
+		case fileInfo.Mode().IsRegular() && parseFileAttr(name, encryptedSuffix).Encrypted:
+			encryptedSourceAbsPaths = append(encryptedSourceAbsPaths, sourceAbsPath)
+		}
+		return nil
+	}
+	if err := WalkSourceDir(system, sourceDirAbsPath, walkFunc); err != nil {
+		return nil, err
+	}
+	return encryptedSourceAbsPaths, nil
+}
@@ -4,12 +4,15 @@ import (
 	"archive/tar"
 	"bytes"
 	"context"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io/fs"
 	"net/http"
 	"net/http/httptest"
 	"path/filepath"
+	"strconv"
+	"sync"
 	"testing"
 	"text/template"
 	"time"
@@ -1438,7 +1441,8 @@ func TestSourceStateRead(t *testing.T) {
 					},
 				},
 			},
-			expectedError: "source state requires chezmoi version 2.3.4 or later, chezmoi is version 1.2.3",
+			expectedError: "source state requires chezmoi version 2.3.4 or later, chezmoi is version 1.2.3: " +
+				"upgrade chezmoi, see https://www.chezmoi.io/install/",
 		},
 		{
 			name: "ignore_dir",
@@ -1489,6 +1493,7 @@ func TestSourceStateRead(t *testing.T) {
 					"/home/user/.local/share/chezmoi",
 				)
 				requireEvaluateAll(t, tc.expectedSourceState, system)
+				s.ignoreFilePatterns = nil
 				s.templateData = nil
 				s.version = semver.Version{}
 				assert.Equal(t, tc.expectedSourceState, s, assert.Exclude[System]())
@@ -1704,6 +1709,198 @@ func TestSourceStateReadExternalCache(t *testing.T) {
 	})
 }
 
+func TestSourceStateReadExternalResume(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789abcdef"), 1024)
+	newData := bytes.Repeat([]byte("fedcba9876543210"), 1024)
+	for _, tc := range []struct {
+		name             string
+		etag             string
+		newETag          string
+		expectedPartial  bool
+		expectedIfRanges []string
+		expectedRanges   []string
+		expectedData     []byte
+	}{
+		{
+			name:             "unchanged",
+			etag:             `"v1"`,
+			newETag:          `"v1"`,
+			expectedPartial:  true,
+			expectedIfRanges: []string{"", `"v1"`},
+			expectedRanges:   []string{"", fmt.Sprintf("bytes=%d-", len(data)/2)},
+			expectedData:     data,
+		},
+		{
+			name:             "changed",
+			etag:             `"v1"`,
+			newETag:          `"v2"`,
+			expectedPartial:  true,
+			expectedIfRanges: []string{"", `"v1"`},
+			expectedRanges:   []string{"", fmt.Sprintf("bytes=%d-", len(data)/2)},
+			expectedData:     newData,
+		},
+		{
+			name:             "no_validator",
+			expectedIfRanges: []string{"", ""},
+			expectedRanges:   []string{"", ""},
+			expectedData:     data,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var ifRangeHeaders []string
+			var rangeHeaders []string
+			httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ifRangeHeaders = append(ifRangeHeaders, r.Header.Get("If-Range"))
+				rangeHeaders = append(rangeHeaders, r.Header.Get("Range"))
+				if len(rangeHeaders) == 1 {
+					// Interrupt the first download halfway through.
+					w.Header().Set("Accept-Ranges", "bytes")
+					w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+					if tc.etag != "" {
+						w.Header().Set("ETag", tc.etag)
+					}
+					_, err := w.Write(data[:len(data)/2])
+					assert.NoError(t, err)
+					return
+				}
+				content := data
+				if tc.newETag != "" {
+					w.Header().Set("ETag", tc.newETag)
+					if tc.newETag != tc.etag {
+						content = newData
+					}
+				}
+				http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(content))
+			}))
+			defer httpServer.Close()
+
+			chezmoitest.WithTestFS(t, map[string]any{
+				"/home/user/.local/share/chezmoi": map[string]any{
+					".chezmoiexternal.yaml": chezmoitest.JoinLines(
+						`.file:`,
+						`    type: "file"`,
+						`    url: "`+httpServer.URL+`/file"`,
+					),
+				},
+			}, func(fileSystem vfs.FS) {
+				ctx := context.Background()
+				system := NewRealSystem(fileSystem)
+				cacheKey := hex.EncodeToString(SHA256Sum([]byte(httpServer.URL + "/file")))
+
+				readExternalFile := func() error {
+					s := NewSourceState(
+						WithBaseSystem(system),
+						WithCacheDir(NewAbsPath("/home/user/.cache/chezmoi")),
+						WithDestDir(NewAbsPath("/home/user")),
+						WithSourceDir(NewAbsPath("/home/user/.local/share/chezmoi")),
+						WithSystem(system),
+					)
+					assert.NoError(t, s.Read(ctx, nil))
+					sourceStateFile, ok := s.Get(NewRelPath(".file")).(*SourceStateFile)
+					assert.True(t, ok)
+					_, err := sourceStateFile.targetStateEntry.(*TargetStateFile).Contents()
+					return err
+				}
+
+				assert.Error(t, readExternalFile())
+				if tc.expectedPartial {
+					vfst.RunTests(t, fileSystem, "",
+						vfst.TestPath("/home/user/.cache/chezmoi/external/"+cacheKey+".partial",
+							vfst.TestContents(data[:len(data)/2]),
+						),
+						vfst.TestPath("/home/user/.cache/chezmoi/external/"+cacheKey+".partial-validator",
+							vfst.TestContentsString(tc.etag),
+						),
+					)
+				} else {
+					vfst.RunTests(t, fileSystem, "",
+						vfst.TestPath("/home/user/.cache/chezmoi/external/"+cacheKey+".partial",
+							vfst.TestDoesNotExist(),
+						),
+					)
+				}
+
+				assert.NoError(t, readExternalFile())
+				assert.Equal(t, tc.expectedIfRanges, ifRangeHeaders)
+				assert.Equal(t, tc.expectedRanges, rangeHeaders)
+				vfst.RunTests(t, fileSystem, "",
+					vfst.TestPath("/home/user/.cache/chezmoi/external/"+cacheKey,
+						vfst.TestContents(tc.expectedData),
+					),
+					vfst.TestPath("/home/user/.cache/chezmoi/external/"+cacheKey+".partial",
+						vfst.TestDoesNotExist(),
+					),
+					vfst.TestPath("/home/user/.cache/chezmoi/external/"+cacheKey+".partial-validator",
+						vfst.TestDoesNotExist(),
+					),
+				)
+			})
+		})
+	}
+}
+
+func TestSourceStateReadExternalMaxConnections(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	tarWriterSystem := NewTarWriterSystem(buffer, tar.Header{})
+	assert.NoError(t, tarWriterSystem.WriteFile(NewAbsPath("file"), []byte("# contents of file\n"), 0o666))
+	assert.NoError(t, tarWriterSystem.Close())
+	archiveData := buffer.Bytes()
+
+	var (
+		mutex           sync.Mutex
+		connections     int
+		maxConnections  int
+		requestsPerPath = make(map[string]int)
+	)
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		connections++
+		maxConnections = max(maxConnections, connections)
+		requestsPerPath[r.URL.Path]++
+		mutex.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		_, err := w.Write(archiveData)
+		assert.NoError(t, err)
+		mutex.Lock()
+		connections--
+		mutex.Unlock()
+	}))
+	defer httpServer.Close()
+
+	externalLines := make([]string, 0, 18)
+	for i := 0; i < 6; i++ {
+		externalLines = append(externalLines,
+			fmt.Sprintf(`.dir%d:`, i),
+			`    type: "archive"`,
+			fmt.Sprintf(`    url: "%s/archive%d.tar"`, httpServer.URL, i),
+		)
+	}
+
+	chezmoitest.WithTestFS(t, map[string]any{
+		"/home/user/.local/share/chezmoi": map[string]any{
+			".chezmoiexternal.yaml": chezmoitest.JoinLines(externalLines...),
+		},
+	}, func(fileSystem vfs.FS) {
+		ctx := context.Background()
+		system := NewRealSystem(fileSystem)
+		s := NewSourceState(
+			WithBaseSystem(system),
+			WithCacheDir(NewAbsPath("/home/user/.cache/chezmoi")),
+			WithDestDir(NewAbsPath("/home/user")),
+			WithSourceDir(NewAbsPath("/home/user/.local/share/chezmoi")),
+			WithSystem(system),
+		)
+		assert.NoError(t, s.Read(ctx, &ReadOptions{
+			MaxConnections: 2,
+		}))
+		assert.True(t, maxConnections <= 2)
+		for i := 0; i < 6; i++ {
+			assert.Equal(t, 1, requestsPerPath[fmt.Sprintf("/archive%d.tar", i)])
+			assert.NotZero(t, s.Get(NewRelPath(fmt.Sprintf(".dir%d/file", i))))
+		}
+	})
+}
+
 func TestSourceStateTargetRelPaths(t *testing.T) {
 	for _, tc := range []struct {
 		name                   string
@@ -1759,6 +1956,66 @@ func TestSourceStateTargetRelPaths(t *testing.T) {
 	}
 }
 
+func TestSourceStateUnusedIgnorePatterns(t *testing.T) {
+	chezmoitest.WithTestFS(t, map[string]any{
+		"/home/user": map[string]any{
+			".destdir/file.conf": "",
+			".destonly":          "",
+			".local/share/chezmoi": map[string]any{
+				".chezmoiignore": chezmoitest.JoinLines(
+					"# chezmoi:template:missing-key=zero",
+					".ignored",
+					"{{ if true }}",
+					".unused",
+					"{{ end }}",
+					".destonly",
+					".destdir/*.conf",
+					".destdir/*.unused",
+					"!.file",
+					"**/.deep",
+				),
+				"dot_dir/.chezmoiignore": "*.unused\n",
+				"dot_file":               "",
+				"dot_ignored":            "",
+			},
+		},
+	}, func(fileSystem vfs.FS) {
+		ctx := context.Background()
+		system := NewRealSystem(fileSystem)
+		s := NewSourceState(
+			WithBaseSystem(system),
+			WithDestDir(NewAbsPath("/home/user")),
+			WithSourceDir(NewAbsPath("/home/user/.local/share/chezmoi")),
+			WithSystem(system),
+		)
+		assert.NoError(t, s.Read(ctx, nil))
+		actualUnusedIgnorePatterns, err := s.UnusedIgnorePatterns()
+		assert.NoError(t, err)
+		assert.Equal(t, []IgnorePattern{
+			{
+				Pattern:       ".unused",
+				SourceRelPath: NewRelPath(".chezmoiignore"),
+				LineNumber:    4,
+			},
+			{
+				Pattern:       ".destdir/*.unused",
+				SourceRelPath: NewRelPath(".chezmoiignore"),
+				LineNumber:    8,
+			},
+			{
+				Pattern:       "**/.deep",
+				SourceRelPath: NewRelPath(".chezmoiignore"),
+				LineNumber:    10,
+			},
+			{
+				Pattern:       ".dir/*.unused",
+				SourceRelPath: NewRelPath("dot_dir/.chezmoiignore"),
+				LineNumber:    1,
+			},
+		}, actualUnusedIgnorePatterns)
+	})
+}
+
 func TestTemplateOptionsParseDirectives(t *testing.T) {
 	for _, tc := range []struct {
 		name            string
@@ -0,0 +1,182 @@
+package chezmoi
+
+import (
+	"fmt"
+	"io/fs"
+	"regexp"
+	"strings"
+)
+
+// stowDotfilesPrefix is the prefix that GNU Stow's --dotfiles option replaces
+// with a dot.
+const stowDotfilesPrefix = "dot-"
+
+var (
+	// stowIgnoredNames are the names that GNU Stow ignores by default
+	// anywhere in a package.
+	stowIgnoredNames = map[string]struct{}{
+		".cvsignore":         {},
+		".git":               {},
+		".gitignore":         {},
+		".gitmodules":        {},
+		".hg":                {},
+		".stow-local-ignore": {},
+		".svn":               {},
+		"CVS":                {},
+		"RCS":                {},
+		"_darcs":             {},
+	}
+
+	// stowIgnoredTopLevelRx matches the names that GNU Stow ignores by default
+	// at the top level of a package.
+	stowIgnoredTopLevelRx = regexp.MustCompile(`\A(?:COPYING|LICENSE.*|README.*)\z`)
+)
+
+// A StowReaderSystem is a system constructed from reading the packages in a
+// GNU Stow directory, as if they had been stowed into a target directory.
+type StowReaderSystem struct {
+	emptySystemMixin
+	noUpdateSystemMixin
+	system    System
+	fileInfos map[AbsPath]fs.FileInfo
+	stowPaths map[AbsPath]AbsPath
+}
+
+// A stowFileInfo is an fs.FileInfo with the name that it has when stowed.
+type stowFileInfo struct {
+	fs.FileInfo
+	name string
+}
+
+// StowReaderSystemOptions are options to NewStowReaderSystem.
+type StowReaderSystemOptions struct {
+	RootAbsPath AbsPath
+}
+
+// NewStowReaderSystem returns a new StowReaderSystem reading the packages in
+// stowDirAbsPath from system. Every subdirectory of stowDirAbsPath is a package.
+func NewStowReaderSystem(
+	system System,
+	stowDirAbsPath AbsPath,
+	options StowReaderSystemOptions,
+) (*StowReaderSystem, error) {
+	s := &StowReaderSystem{
+		system:    system,
+		fileInfos: make(map[AbsPath]fs.FileInfo),
+		stowPaths: make(map[AbsPath]AbsPath),
+	}
+
+	dirEntries, err := system.ReadDir(stowDirAbsPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, dirEntry := range dirEntries {
+		if !dirEntry.IsDir() || strings.HasPrefix(dirEntry.Name(), ".") {
+			continue
+		}
+		if err := s.addPackage(stowDirAbsPath.JoinString(dirEntry.Name()), options.RootAbsPath); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// FileInfos returns s's fs.FileInfos.
+func (s *StowReaderSystem) FileInfos() map[AbsPath]fs.FileInfo {
+	return s.fileInfos
+}
+
+// Lstat implements System.Lstat.
+func (s *StowReaderSystem) Lstat(filename AbsPath) (fs.FileInfo, error) {
+	fileInfo, ok := s.fileInfos[filename]
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+	return fileInfo, nil
+}
+
+// ReadFile implements System.ReadFile.
+func (s *StowReaderSystem) ReadFile(name AbsPath) ([]byte, error) {
+	stowPath, ok := s.stowPaths[name]
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+	return s.system.ReadFile(stowPath)
+}
+
+// Readlink implements System.Readlink.
+func (s *StowReaderSystem) Readlink(name AbsPath) (string, error) {
+	stowPath, ok := s.stowPaths[name]
+	if !ok {
+		return "", fs.ErrNotExist
+	}
+	return s.system.Readlink(stowPath)
+}
+
+// Name implements fs.FileInfo.Name.
+func (i stowFileInfo) Name() string {
+	return i.name
+}
+
+// addPackage adds the contents of the package in packageDirAbsPath, as if it
+// were stowed in rootAbsPath.
+func (s *StowReaderSystem) addPackage(packageDirAbsPath, rootAbsPath AbsPath) error {
+	return Walk(s.system, packageDirAbsPath, func(absPath AbsPath, fileInfo fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if absPath == packageDirAbsPath {
+			return nil
+		}
+		relPath, err := absPath.TrimDirPrefix(packageDirAbsPath)
+		if err != nil {
+			return err
+		}
+
+		name := fileInfo.Name()
+		_, ignored := stowIgnoredNames[name]
+		if !ignored && !strings.Contains(relPath.String(), "/") {
+			ignored = stowIgnoredTopLevelRx.MatchString(name)
+		}
+		if ignored {
+			if fileInfo.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		components := strings.Split(relPath.String(), "/")
+		for i, component := range components {
+			if strings.HasPrefix(component, stowDotfilesPrefix) {
+				components[i] = "." + strings.TrimPrefix(component, stowDotfilesPrefix)
+			}
+		}
+		targetAbsPath := rootAbsPath.JoinString(components...)
+
+		switch fileInfo.Mode().Type() {
+		case fs.ModeDir:
+			// Packages can share directories.
+			if existingFileInfo, ok := s.fileInfos[targetAbsPath]; ok && existingFileInfo.IsDir() {
+				return nil
+			}
+		case 0, fs.ModeSymlink:
+		default:
+			return fmt.Errorf("%s: unsupported mode %o", absPath, fileInfo.Mode().Type())
+		}
+		if stowPath, ok := s.stowPaths[targetAbsPath]; ok {
+			return fmt.Errorf("%s: conflicts with %s", absPath, stowPath)
+		} else if _, ok := s.fileInfos[targetAbsPath]; ok {
+			return fmt.Errorf("%s: conflicts with a directory in another package", absPath)
+		}
+
+		if targetName := components[len(components)-1]; targetName != name {
+			fileInfo = stowFileInfo{FileInfo: fileInfo, name: targetName}
+		}
+		s.fileInfos[targetAbsPath] = fileInfo
+		if !fileInfo.IsDir() {
+			s.stowPaths[targetAbsPath] = absPath
+		}
+		return nil
+	})
+}
@@ -0,0 +1,52 @@
+package chezmoi
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	vfs "github.com/twpayne/go-vfs/v5"
+
+	"github.com/twpayne/chezmoi/v2/internal/chezmoimaps"
+	"github.com/twpayne/chezmoi/v2/internal/chezmoitest"
+)
+
+func TestStowReaderSystem(t *testing.T) {
+	chezmoitest.WithTestFS(t, map[string]any{
+		"/home/user/dotfiles": map[string]any{
+			".stowrc": "--target=~\n",
+			"bash": map[string]any{
+				".bashrc":   "# contents of .bashrc\n",
+				"README.md": "# bash\n",
+			},
+			"git": map[string]any{
+				".git/HEAD":             "ref: refs/heads/main\n",
+				"dot-config/git/config": "# contents of .config/git/config\n",
+			},
+			"vim": map[string]any{
+				".config/nvim/init.vim": "\" contents of .config/nvim/init.vim\n",
+			},
+		},
+	}, func(fileSystem vfs.FS) {
+		stowReaderSystem, err := NewStowReaderSystem(NewRealSystem(fileSystem), NewAbsPath("/home/user/dotfiles"), StowReaderSystemOptions{
+			RootAbsPath: NewAbsPath("/home/user"),
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, []AbsPath{
+			NewAbsPath("/home/user/.bashrc"),
+			NewAbsPath("/home/user/.config"),
+			NewAbsPath("/home/user/.config/git"),
+			NewAbsPath("/home/user/.config/git/config"),
+			NewAbsPath("/home/user/.config/nvim"),
+			NewAbsPath("/home/user/.config/nvim/init.vim"),
+		}, chezmoimaps.SortedKeys(stowReaderSystem.FileInfos()))
+
+		fileInfo, err := stowReaderSystem.Lstat(NewAbsPath("/home/user/.config"))
+		assert.NoError(t, err)
+		assert.Equal(t, ".config", fileInfo.Name())
+
+		data, err := stowReaderSystem.ReadFile(NewAbsPath("/home/user/.config/git/config"))
+		assert.NoError(t, err)
+		assert.Equal(t, "# contents of .config/git/config\n", string(data))
+	})
+}
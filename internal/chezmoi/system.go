@@ -159,6 +159,81 @@ func Walk(system System, rootAbsPath AbsPath, walkFunc WalkFunc) error {
 	return vfs.Walk(system.UnderlyingFS(), rootAbsPath.String(), outerWalkFunc)
 }
 
+// ConcurrentWalk walks rootAbsPath in system like Walk, except that the entries
+// of each directory are walked concurrently, so walkFunc may be called
+// concurrently and in any order. Entries are only visited after their parent
+// directory, and returning fs.SkipDir from walkFunc for a directory skips its
+// entries. As entries are not visited in order, returning fs.SkipDir for a
+// file does not skip the remaining entries in its directory.
+//
+// ConcurrentWalk does not follow symlinks.
+func ConcurrentWalk(ctx context.Context, system System, rootAbsPath AbsPath, walkFunc WalkFunc) error {
+	fileInfo, err := system.Lstat(rootAbsPath)
+	if err != nil {
+		return walkFunc(rootAbsPath, nil, err)
+	}
+	// Limit the number of directories that are read at once, so that walking
+	// very large directories does not run out of file descriptors.
+	readDirSemaphore := make(chan struct{}, concurrentWalkReadDirLimit)
+	return concurrentWalk(ctx, system, rootAbsPath, fileInfo, walkFunc, readDirSemaphore)
+}
+
+// concurrentWalkReadDirLimit is the maximum number of directories that
+// ConcurrentWalk reads at once.
+const concurrentWalkReadDirLimit = 64
+
+// concurrentWalk is a helper function for ConcurrentWalk.
+func concurrentWalk(
+	ctx context.Context,
+	system System,
+	absPath AbsPath,
+	fileInfo fs.FileInfo,
+	walkFunc WalkFunc,
+	readDirSemaphore chan struct{},
+) error {
+	switch err := walkFunc(absPath, fileInfo, nil); {
+	case errors.Is(err, fs.SkipDir):
+		return nil
+	case err != nil:
+		return err
+	case !fileInfo.IsDir():
+		return nil
+	}
+
+	select {
+	case readDirSemaphore <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	dirEntries, err := system.ReadDir(absPath)
+	<-readDirSemaphore
+	if err != nil {
+		if err := walkFunc(absPath, fileInfo, err); err != nil && !errors.Is(err, fs.SkipDir) {
+			return err
+		}
+		return nil
+	}
+
+	visitDirEntryFunc := func(dirEntry fs.DirEntry) func() error {
+		return func() error {
+			absPath := absPath.JoinString(dirEntry.Name())
+			fileInfo, err := dirEntry.Info()
+			if err != nil {
+				if err := walkFunc(absPath, nil, err); err != nil && !errors.Is(err, fs.SkipDir) {
+					return err
+				}
+				return nil
+			}
+			return concurrentWalk(ctx, system, absPath, fileInfo, walkFunc, readDirSemaphore)
+		}
+	}
+	group, ctx := errgroup.WithContext(ctx)
+	for _, dirEntry := range dirEntries {
+		group.Go(visitDirEntryFunc(dirEntry))
+	}
+	return group.Wait()
+}
+
 // A concurrentWalkSourceDirFunc is a function called concurrently for every
 // entry in a source directory.
 type concurrentWalkSourceDirFunc func(ctx context.Context, absPath AbsPath, fileInfo fs.FileInfo, err error) error
@@ -190,11 +265,13 @@ func WalkSourceDir(system System, sourceDirAbsPath AbsPath, walkFunc WalkFunc) e
 // source directory. More negative values are visited first. Entries with the
 // same order are visited alphabetically. The default order is zero.
 var sourceDirEntryOrder = map[string]int{
-	VersionName:        -3,
-	dataName + ".json": -2,
-	dataName + ".toml": -2,
-	dataName + ".yaml": -2,
-	TemplatesDirName:   -1,
+	VersionName:         -3,
+	dataName + ".json":  -2,
+	dataName + ".jsonc": -2,
+	dataName + ".toml":  -2,
+	dataName + ".yaml":  -2,
+	dataName + ".yml":   -2,
+	TemplatesDirName:    -1,
 }
 
 // walkSourceDir is a helper function for WalkSourceDir.
@@ -14,6 +14,54 @@ import (
 	"github.com/twpayne/chezmoi/v2/internal/chezmoitest"
 )
 
+func TestConcurrentWalk(t *testing.T) {
+	rootAbsPath := NewAbsPath("/home/user")
+	root := map[string]any{
+		rootAbsPath.String(): map[string]any{
+			".dir/file":          "",
+			".dir/subdir/.file2": "",
+			".dir/subdir/file":   "",
+			".file":              "",
+			".skipped/dir/file":  "",
+			".skipped/file":      "",
+			".symlink":           &vfst.Symlink{Target: ".dir"},
+		},
+	}
+	expectedAbsPaths := AbsPaths{
+		rootAbsPath,
+		rootAbsPath.JoinString(".dir"),
+		rootAbsPath.JoinString(".dir/file"),
+		rootAbsPath.JoinString(".dir/subdir"),
+		rootAbsPath.JoinString(".dir/subdir/.file2"),
+		rootAbsPath.JoinString(".dir/subdir/file"),
+		rootAbsPath.JoinString(".file"),
+		rootAbsPath.JoinString(".skipped"),
+		rootAbsPath.JoinString(".symlink"),
+	}
+
+	var actualAbsPaths AbsPaths
+	chezmoitest.WithTestFS(t, root, func(fileSystem vfs.FS) {
+		ctx := context.Background()
+		system := NewRealSystem(fileSystem)
+		var mutex sync.Mutex
+		walkFunc := func(absPath AbsPath, fileInfo fs.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			mutex.Lock()
+			actualAbsPaths = append(actualAbsPaths, absPath)
+			mutex.Unlock()
+			if absPath.Base() == ".skipped" {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		assert.NoError(t, ConcurrentWalk(ctx, system, rootAbsPath, walkFunc))
+	})
+	sort.Sort(actualAbsPaths)
+	assert.Equal(t, expectedAbsPaths, actualAbsPaths)
+}
+
 func TestConcurrentWalkSourceDir(t *testing.T) {
 	sourceDirAbsPath := NewAbsPath("/home/user/.local/share/chezmoi")
 	root := map[string]any{
@@ -51,8 +99,10 @@ func TestWalkSourceDir(t *testing.T) {
 		sourceDirAbsPath.String(): map[string]any{
 			".chezmoi.toml.tmpl":    "",
 			".chezmoidata.json":     "",
+			".chezmoidata.jsonc":    "",
 			".chezmoidata.toml":     "",
 			".chezmoidata.yaml":     "",
+			".chezmoidata.yml":      "",
 			".chezmoiexternal.yaml": "",
 			".chezmoiignore":        "",
 			".chezmoiremove":        "",
@@ -65,8 +115,10 @@ func TestWalkSourceDir(t *testing.T) {
 		sourceDirAbsPath,
 		sourceDirAbsPath.JoinString(".chezmoiversion"),
 		sourceDirAbsPath.JoinString(".chezmoidata.json"),
+		sourceDirAbsPath.JoinString(".chezmoidata.jsonc"),
 		sourceDirAbsPath.JoinString(".chezmoidata.toml"),
 		sourceDirAbsPath.JoinString(".chezmoidata.yaml"),
+		sourceDirAbsPath.JoinString(".chezmoidata.yml"),
 		sourceDirAbsPath.JoinString(".chezmoitemplates"),
 		sourceDirAbsPath.JoinString(".chezmoi.toml.tmpl"),
 		sourceDirAbsPath.JoinString(".chezmoiexternal.yaml"),
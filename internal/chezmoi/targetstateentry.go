@@ -3,9 +3,11 @@ package chezmoi
 import (
 	"bytes"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io/fs"
-	"runtime"
+	"path/filepath"
+	"strings"
 	"time"
 )
 
@@ -63,6 +65,10 @@ type TargetStateScript struct {
 type TargetStateSymlink struct {
 	*lazyLinkname
 	sourceAttr SourceAttr
+	// copyAbsPath, if not empty, is the path of a read-only copy of a source
+	// file with copyContents that the symlink points to.
+	copyAbsPath  AbsPath
+	copyContents []byte
 }
 
 // A modifyDirWithCmdState records the state of a directory modified by a
@@ -155,7 +161,7 @@ func (t *TargetStateDir) Apply(
 	actualStateEntry ActualStateEntry,
 ) (bool, error) {
 	if actualStateDir, ok := actualStateEntry.(*ActualStateDir); ok {
-		if runtime.GOOS == "windows" || actualStateDir.perm == t.perm {
+		if permEqual(actualStateDir.perm, t.perm) {
 			return false, nil
 		}
 		return true, system.Chmod(actualStateDir.Path(), t.perm)
@@ -218,7 +224,7 @@ func (t *TargetStateFile) Apply(
 			return false, err
 		}
 		if bytes.Equal(actualContentsSHA256, contentsSHA256) {
-			if runtime.GOOS == "windows" || actualStateFile.perm == t.perm {
+			if permEqual(actualStateFile.perm, t.perm) {
 				return false, nil
 			}
 			return true, system.Chmod(actualStateFile.Path(), t.perm)
@@ -259,9 +265,13 @@ func (t *TargetStateFile) Evaluate() error {
 	return err
 }
 
-// Perm returns t's perm.
-func (t *TargetStateFile) Perm(umask fs.FileMode) fs.FileMode {
-	return t.perm &^ umask
+// Perm returns t's perm. t's contents are computed first, as templates can set
+// t's perm with a chezmoi:permissions: directive.
+func (t *TargetStateFile) Perm(umask fs.FileMode) (fs.FileMode, error) {
+	if _, err := t.Contents(); err != nil {
+		return 0, err
+	}
+	return t.perm &^ umask, nil
 }
 
 // SkipApply implements TargetStateEntry.SkipApply.
@@ -333,7 +343,15 @@ func (t *TargetStateScript) Apply(
 	}
 	runAt := time.Now().UTC()
 	if !isEmpty(contents) {
-		if err := system.RunScript(t.name, actualStateEntry.Path().Dir(), contents, RunScriptOptions{
+		dir := actualStateEntry.Path().Dir()
+		if matches := scriptWorkDirDirectiveRx.FindAllSubmatchIndex(contents, -1); matches != nil {
+			dir, err = t.workDir(system, dir, contents, matches)
+			if err != nil {
+				return false, err
+			}
+			contents = removeMatches(contents, matches)
+		}
+		if err := system.RunScript(t.name, dir, contents, RunScriptOptions{
 			Condition:     t.condition,
 			Interpreter:   t.interpreter,
 			SourceRelPath: t.sourceRelPath,
@@ -379,6 +397,29 @@ func (t *TargetStateScript) Evaluate() error {
 	return err
 }
 
+// workDir returns the working directory requested by the last chezmoi:workdir:
+// directive in contents. Relative directories are relative to dir.
+func (t *TargetStateScript) workDir(system System, dir AbsPath, contents []byte, matches [][]int) (AbsPath, error) {
+	lastMatch := matches[len(matches)-1]
+	workDir := strings.TrimSpace(string(contents[lastMatch[2]:lastMatch[3]]))
+	if workDir == "" {
+		return EmptyAbsPath, fmt.Errorf("%s: empty chezmoi:workdir: directive", t.sourceRelPath)
+	}
+	var workDirAbsPath AbsPath
+	if filepath.IsAbs(workDir) {
+		workDirAbsPath = NewAbsPath(filepath.Clean(workDir))
+	} else {
+		workDirAbsPath = dir.JoinString(filepath.ToSlash(workDir))
+	}
+	switch fileInfo, err := system.Stat(workDirAbsPath); {
+	case err != nil:
+		return EmptyAbsPath, err
+	case !fileInfo.IsDir():
+		return EmptyAbsPath, fmt.Errorf("%s: not a directory", workDirAbsPath)
+	}
+	return workDirAbsPath, nil
+}
+
 // SkipApply implements TargetStateEntry.SkipApply.
 func (t *TargetStateScript) SkipApply(persistentState PersistentState, targetAbsPath AbsPath) (bool, error) {
 	switch contents, err := t.Contents(); {
@@ -445,6 +486,9 @@ func (t *TargetStateSymlink) Apply(
 		}
 		return true, system.RemoveAll(actualStateEntry.Path())
 	}
+	if err := t.writeCopy(system); err != nil {
+		return false, err
+	}
 	if actualStateSymlink, ok := actualStateEntry.(*ActualStateSymlink); ok {
 		actualLinkname, err := actualStateSymlink.Linkname()
 		if err != nil {
@@ -501,3 +545,21 @@ func (t *TargetStateSymlink) SkipApply(persistentState PersistentState, targetAb
 func (t *TargetStateSymlink) SourceAttr() SourceAttr {
 	return t.sourceAttr
 }
+
+// writeCopy writes the read-only copy that t points to, if any and if it does
+// not already exist.
+func (t *TargetStateSymlink) writeCopy(system System) error {
+	if t.copyAbsPath.Empty() {
+		return nil
+	}
+	switch _, err := system.Lstat(t.copyAbsPath); {
+	case err == nil:
+		return nil
+	case !errors.Is(err, fs.ErrNotExist):
+		return err
+	}
+	if err := MkdirAll(system, t.copyAbsPath.Dir(), fs.ModePerm); err != nil {
+		return err
+	}
+	return system.WriteFile(t.copyAbsPath, t.copyContents, 0o444)
+}
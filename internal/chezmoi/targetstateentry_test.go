@@ -139,3 +139,16 @@ func targetStateTest(t *testing.T, ts TargetStateEntry) []vfst.PathTest {
 		return nil
 	}
 }
+
+func TestTargetStateFilePerm(t *testing.T) {
+	targetStateFile := &TargetStateFile{
+		perm: 0o666,
+	}
+	targetStateFile.lazyContents = newLazyContentsFunc(func() ([]byte, error) {
+		targetStateFile.perm = 0o600
+		return []byte("# contents of file\n"), nil
+	})
+	perm, err := targetStateFile.Perm(0o022)
+	assert.NoError(t, err)
+	assert.Equal(t, fs.FileMode(0o600), perm)
+}
@@ -4,10 +4,17 @@ import (
 	"bytes"
 	"strings"
 	"text/template"
+	"text/template/parse"
 
 	"github.com/mitchellh/copystructure"
+
+	"github.com/twpayne/chezmoi/v2/internal/chezmoiset"
 )
 
+// A LazyValue is a value in template data that is expensive to compute. It is
+// only computed when a template refers to it by name, otherwise it is nil.
+type LazyValue func() any
+
 // A Template extends text/template.Template with support for directives.
 type Template struct {
 	name     string
@@ -58,6 +65,9 @@ func (t *Template) Execute(data any) ([]byte, error) {
 		if err != nil {
 			return nil, err
 		}
+		if dataMap, ok := data.(map[string]any); ok {
+			resolveLazyValues(dataMap, t.referencedNames())
+		}
 	}
 
 	var builder strings.Builder
@@ -67,6 +77,53 @@ func (t *Template) Execute(data any) ([]byte, error) {
 	return []byte(replaceLineEndings(builder.String(), t.options.LineEnding)), nil
 }
 
+// referencedNames returns the names of all fields, variables, and string
+// constants in t and its associated templates.
+func (t *Template) referencedNames() chezmoiset.Set[string] {
+	names := chezmoiset.New[string]()
+	for _, associatedTemplate := range t.template.Templates() {
+		if associatedTemplate.Tree == nil {
+			continue
+		}
+		walkTemplateNode(associatedTemplate.Tree.Root, func(node parse.Node) {
+			switch node := node.(type) {
+			case *parse.ChainNode:
+				names.Add(node.Field...)
+			case *parse.FieldNode:
+				names.Add(node.Ident...)
+			case *parse.StringNode:
+				names.Add(node.Text)
+			case *parse.VariableNode:
+				names.Add(node.Ident[1:]...)
+			}
+		})
+	}
+	return names
+}
+
+// ResolveLazyValues replaces every LazyValue in data with its value.
+func ResolveLazyValues(data map[string]any) {
+	resolveLazyValues(data, nil)
+}
+
+// resolveLazyValues replaces every LazyValue in data with its value if its key
+// is in names, or with nil otherwise. If names is nil then every LazyValue is
+// replaced with its value.
+func resolveLazyValues(data map[string]any, names chezmoiset.Set[string]) {
+	for key, value := range data {
+		switch value := value.(type) {
+		case LazyValue:
+			if names == nil || names.Contains(key) {
+				data[key] = value()
+			} else {
+				data[key] = nil
+			}
+		case map[string]any:
+			resolveLazyValues(value, names)
+		}
+	}
+}
+
 // parseAndRemoveDirectives updates o by parsing all template directives in data
 // and returns data with the lines containing directives removed. The lines are
 // removed so that any delimiters do not break template parsing.
@@ -73,3 +73,58 @@ func TestTemplateParseAndExecute(t *testing.T) {
 		})
 	}
 }
+
+func TestTemplateExecuteLazyValue(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		dataStr       string
+		expectedStr   string
+		expectedCalls int
+	}{
+		{
+			name:          "unreferenced",
+			dataStr:       "{{ .a.other }}",
+			expectedStr:   "other",
+			expectedCalls: 0,
+		},
+		{
+			name:          "field",
+			dataStr:       "{{ .a.lazy.key }}",
+			expectedStr:   "value",
+			expectedCalls: 1,
+		},
+		{
+			name:          "variable",
+			dataStr:       "{{ with .a }}{{ $.a.lazy.key }}{{ end }}",
+			expectedStr:   "value",
+			expectedCalls: 1,
+		},
+		{
+			name:          "index",
+			dataStr:       `{{ index .a "lazy" "key" }}`,
+			expectedStr:   "value",
+			expectedCalls: 1,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			calls := 0
+			data := map[string]any{
+				"a": map[string]any{
+					"lazy": LazyValue(func() any {
+						calls++
+						return map[string]any{
+							"key": "value",
+						}
+					}),
+					"other": "other",
+				},
+			}
+			tmpl, err := ParseTemplate(tc.name, []byte(tc.dataStr), nil, TemplateOptions{})
+			assert.NoError(t, err)
+			actual, err := tmpl.Execute(data)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedStr, string(actual))
+			assert.Equal(t, tc.expectedCalls, calls)
+		})
+	}
+}
@@ -0,0 +1,350 @@
+package chezmoi
+
+import (
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/twpayne/chezmoi/v2/internal/chezmoiset"
+)
+
+// templateAccessRecorderFuncName is the name of the template function that
+// instrumented templates call to record data accesses.
+const templateAccessRecorderFuncName = "chezmoiTemplateAccessRecorderRecordData"
+
+// A TemplateAccessRecorder records the template data and template functions
+// that templates access while they are executed.
+type TemplateAccessRecorder struct {
+	mutex sync.Mutex
+	data  chezmoiset.Set[string]
+	funcs chezmoiset.Set[string]
+}
+
+// NewTemplateAccessRecorder returns a new TemplateAccessRecorder.
+func NewTemplateAccessRecorder() *TemplateAccessRecorder {
+	return &TemplateAccessRecorder{
+		data:  chezmoiset.New[string](),
+		funcs: chezmoiset.New[string](),
+	}
+}
+
+// Data returns the sorted paths of all template data accessed, for example
+// .chezmoi.os.
+func (r *TemplateAccessRecorder) Data() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	data := r.data.Elements()
+	slices.Sort(data)
+	return data
+}
+
+// Funcs returns the sorted names of all template functions called.
+func (r *TemplateAccessRecorder) Funcs() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	funcs := r.funcs.Elements()
+	slices.Sort(funcs)
+	return funcs
+}
+
+// A templateDataPath is the template data path of a value, for example
+// .chezmoi.os, if it can be determined from the template's source.
+type templateDataPath struct {
+	path  string
+	known bool
+}
+
+// rootTemplateDataPath is the template data path of the template data itself.
+var rootTemplateDataPath = templateDataPath{known: true}
+
+// child returns the template data path of the field of p at idents.
+func (p templateDataPath) child(idents []string) templateDataPath {
+	if !p.known {
+		return p
+	}
+	return templateDataPath{
+		path:  p.path + "." + strings.Join(idents, "."),
+		known: true,
+	}
+}
+
+// A templateInstrumenter instruments the parse trees of a template and its
+// associated templates.
+type templateInstrumenter struct {
+	recorder *TemplateAccessRecorder
+	// invocations is the template data path of dot in each template. A
+	// template that is invoked with different or unknown template data paths
+	// has an unknown template data path.
+	invocations map[string]templateDataPath
+	changed     bool
+	modify      bool
+}
+
+// instrument modifies t to record accesses in r. Every function in funcs that
+// is referenced by t or its associated templates is wrapped to record calls.
+// Every field of the template data referenced by t or by an associated
+// template is wrapped in a call to a function that records the access.
+// Associated templates are instrumented in copies of their parse trees, as
+// their parse trees are shared with other templates.
+//
+// Builtin functions like and and or are not recorded, as overriding them would
+// change their short-circuit evaluation, and fields are only recorded when the
+// template data path to them can be determined from the template's source.
+func (r *TemplateAccessRecorder) instrument(t *Template, funcs template.FuncMap) {
+	wrappedFuncs := template.FuncMap{
+		templateAccessRecorderFuncName: r.recordData,
+	}
+	var trees []*parse.Tree
+	for _, associatedTemplate := range t.template.Templates() {
+		if associatedTemplate.Tree == nil {
+			continue
+		}
+		tree := associatedTemplate.Tree
+		if associatedTemplate.Name() != t.name {
+			tree = tree.Copy()
+			if _, err := t.template.AddParseTree(associatedTemplate.Name(), tree); err != nil {
+				continue
+			}
+		}
+		trees = append(trees, tree)
+		walkTemplateNode(tree.Root, func(node parse.Node) {
+			identifierNode, ok := node.(*parse.IdentifierNode)
+			if !ok {
+				return
+			}
+			name := identifierNode.Ident
+			if _, ok := wrappedFuncs[name]; ok {
+				return
+			}
+			if fn, ok := funcs[name]; ok {
+				wrappedFuncs[name] = r.wrapFunc(name, fn)
+			}
+		})
+	}
+	t.template.Funcs(wrappedFuncs)
+
+	// Find the template data path of dot in each template that is invoked,
+	// repeating until no more invocations are found, and then instrument all
+	// templates.
+	instrumenter := &templateInstrumenter{
+		recorder: r,
+		invocations: map[string]templateDataPath{
+			t.name: rootTemplateDataPath,
+		},
+	}
+	for instrumenter.changed = true; instrumenter.changed; {
+		instrumenter.changed = false
+		for _, tree := range trees {
+			if dot, ok := instrumenter.invocations[tree.Name]; ok {
+				instrumenter.instrumentList(tree.Root, dot, dot)
+			}
+		}
+	}
+	instrumenter.modify = true
+	for _, tree := range trees {
+		dot := instrumenter.invocations[tree.Name]
+		instrumenter.instrumentList(tree.Root, dot, dot)
+	}
+}
+
+// invoke records that the template called name is invoked with dot.
+func (i *templateInstrumenter) invoke(name string, dot templateDataPath) {
+	switch existingDot, ok := i.invocations[name]; {
+	case !ok:
+		i.invocations[name] = dot
+		i.changed = true
+	case existingDot.known && existingDot != dot:
+		i.invocations[name] = templateDataPath{}
+		i.changed = true
+	}
+}
+
+// instrumentList instruments all nodes in list, where dot and root are the
+// template data paths of dot and $.
+func (i *templateInstrumenter) instrumentList(list *parse.ListNode, dot, root templateDataPath) {
+	if list == nil {
+		return
+	}
+	for _, node := range list.Nodes {
+		switch node := node.(type) {
+		case *parse.ActionNode:
+			i.instrumentPipe(node.Pipe, dot, root)
+		case *parse.IfNode:
+			i.instrumentPipe(node.Pipe, dot, root)
+			i.instrumentList(node.List, dot, root)
+			i.instrumentList(node.ElseList, dot, root)
+		case *parse.RangeNode:
+			// The path of the elements being ranged over cannot be determined
+			// from the template's source.
+			i.instrumentPipe(node.Pipe, dot, root)
+			i.instrumentList(node.List, templateDataPath{}, root)
+			i.instrumentList(node.ElseList, dot, root)
+		case *parse.WithNode:
+			withDot := pipePath(node.Pipe, dot, root)
+			i.instrumentPipe(node.Pipe, dot, root)
+			i.instrumentList(node.List, withDot, root)
+			i.instrumentList(node.ElseList, dot, root)
+		case *parse.TemplateNode:
+			if node.Pipe == nil {
+				i.invoke(node.Name, templateDataPath{})
+				continue
+			}
+			if !i.modify {
+				i.invoke(node.Name, pipePath(node.Pipe, dot, root))
+			}
+			i.instrumentPipe(node.Pipe, dot, root)
+		}
+	}
+}
+
+// instrumentPipe instruments all the arguments of all commands in pipe.
+func (i *templateInstrumenter) instrumentPipe(pipe *parse.PipeNode, dot, root templateDataPath) {
+	if !i.modify {
+		return
+	}
+	for _, cmd := range pipe.Cmds {
+		for j, arg := range cmd.Args {
+			// A field that is the first word of a command with arguments is a
+			// method call, which cannot be wrapped.
+			methodCall := j == 0 && len(cmd.Args) > 1
+			switch arg := arg.(type) {
+			case *parse.FieldNode:
+				if path := dot.child(arg.Ident); path.known && !methodCall {
+					cmd.Args[j] = newRecordDataPipe(arg, path.path)
+				}
+			case *parse.VariableNode:
+				if arg.Ident[0] == "$" && len(arg.Ident) > 1 && !methodCall {
+					if path := root.child(arg.Ident[1:]); path.known {
+						cmd.Args[j] = newRecordDataPipe(arg, path.path)
+					}
+				}
+			case *parse.PipeNode:
+				i.instrumentPipe(arg, dot, root)
+			case *parse.ChainNode:
+				if pipeNode, ok := arg.Node.(*parse.PipeNode); ok {
+					i.instrumentPipe(pipeNode, dot, root)
+				}
+			}
+		}
+	}
+}
+
+// recordData records that the template data at path was accessed and returns
+// value unchanged.
+func (r *TemplateAccessRecorder) recordData(path string, value any) any {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.data.Add(path)
+	return value
+}
+
+// wrapFunc returns fn wrapped to record that it was called.
+func (r *TemplateAccessRecorder) wrapFunc(name string, fn any) any {
+	fnValue := reflect.ValueOf(fn)
+	if fnValue.Kind() != reflect.Func {
+		return fn
+	}
+	return reflect.MakeFunc(fnValue.Type(), func(args []reflect.Value) []reflect.Value {
+		r.mutex.Lock()
+		r.funcs.Add(name)
+		r.mutex.Unlock()
+		if fnValue.Type().IsVariadic() {
+			return fnValue.CallSlice(args)
+		}
+		return fnValue.Call(args)
+	}).Interface()
+}
+
+// newRecordDataPipe returns a pipe that records that the template data at path
+// was accessed and evaluates to the value of node.
+func newRecordDataPipe(node parse.Node, path string) *parse.PipeNode {
+	pos := node.Position()
+	return &parse.PipeNode{
+		NodeType: parse.NodePipe,
+		Pos:      pos,
+		Cmds: []*parse.CommandNode{
+			{
+				NodeType: parse.NodeCommand,
+				Pos:      pos,
+				Args: []parse.Node{
+					parse.NewIdentifier(templateAccessRecorderFuncName).SetPos(pos),
+					&parse.StringNode{
+						NodeType: parse.NodeString,
+						Pos:      pos,
+						Quoted:   strconv.Quote(path),
+						Text:     path,
+					},
+					node,
+				},
+			},
+		},
+	}
+}
+
+// pipePath returns the template data path that pipe evaluates to, where dot
+// and root are the template data paths of dot and $.
+func pipePath(pipe *parse.PipeNode, dot, root templateDataPath) templateDataPath {
+	if len(pipe.Cmds) != 1 || len(pipe.Cmds[0].Args) != 1 {
+		return templateDataPath{}
+	}
+	switch arg := pipe.Cmds[0].Args[0].(type) {
+	case *parse.DotNode:
+		return dot
+	case *parse.FieldNode:
+		return dot.child(arg.Ident)
+	case *parse.VariableNode:
+		switch {
+		case arg.Ident[0] != "$":
+			return templateDataPath{}
+		case len(arg.Ident) == 1:
+			return root
+		default:
+			return root.child(arg.Ident[1:])
+		}
+	}
+	return templateDataPath{}
+}
+
+// walkTemplateNode calls f for node and all of its descendants.
+func walkTemplateNode(node parse.Node, f func(parse.Node)) {
+	if node == nil || reflect.ValueOf(node).IsNil() {
+		return
+	}
+	f(node)
+	switch node := node.(type) {
+	case *parse.ListNode:
+		for _, child := range node.Nodes {
+			walkTemplateNode(child, f)
+		}
+	case *parse.ActionNode:
+		walkTemplateNode(node.Pipe, f)
+	case *parse.IfNode:
+		walkTemplateNode(node.Pipe, f)
+		walkTemplateNode(node.List, f)
+		walkTemplateNode(node.ElseList, f)
+	case *parse.RangeNode:
+		walkTemplateNode(node.Pipe, f)
+		walkTemplateNode(node.List, f)
+		walkTemplateNode(node.ElseList, f)
+	case *parse.WithNode:
+		walkTemplateNode(node.Pipe, f)
+		walkTemplateNode(node.List, f)
+		walkTemplateNode(node.ElseList, f)
+	case *parse.TemplateNode:
+		walkTemplateNode(node.Pipe, f)
+	case *parse.PipeNode:
+		for _, cmd := range node.Cmds {
+			walkTemplateNode(cmd, f)
+		}
+	case *parse.CommandNode:
+		for _, arg := range node.Args {
+			walkTemplateNode(arg, f)
+		}
+	case *parse.ChainNode:
+		walkTemplateNode(node.Node, f)
+	}
+}
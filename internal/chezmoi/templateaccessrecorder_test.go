@@ -0,0 +1,150 @@
+package chezmoi
+
+import (
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestTemplateAccessRecorder(t *testing.T) {
+	funcs := template.FuncMap{
+		"join": func(sep string, elems ...string) string {
+			return strings.Join(elems, sep)
+		},
+		"quote": func(s string) string {
+			return `"` + s + `"`
+		},
+		"unused": func() string {
+			return "unused"
+		},
+		"upper": strings.ToUpper,
+	}
+	data := map[string]any{
+		"chezmoi": map[string]any{
+			"hostname": "host",
+			"os":       "linux",
+		},
+		"email": "me@example.com",
+		"list":  []any{"a", "b"},
+		"work":  false,
+	}
+
+	for _, tc := range []struct {
+		name          string
+		templateStr   string
+		expectedStr   string
+		expectedData  []string
+		expectedFuncs []string
+	}{
+		{
+			name:          "field",
+			templateStr:   "{{ .email }}",
+			expectedStr:   "me@example.com",
+			expectedData:  []string{".email"},
+			expectedFuncs: []string{},
+		},
+		{
+			name:          "nested_field_and_func",
+			templateStr:   "{{ .chezmoi.os | upper }}",
+			expectedStr:   "LINUX",
+			expectedData:  []string{".chezmoi.os"},
+			expectedFuncs: []string{"upper"},
+		},
+		{
+			name:          "branch_not_taken",
+			templateStr:   `{{ if .work }}{{ .chezmoi.hostname | quote }}{{ else }}{{ .email }}{{ end }}`,
+			expectedStr:   "me@example.com",
+			expectedData:  []string{".email", ".work"},
+			expectedFuncs: []string{},
+		},
+		{
+			name:          "with",
+			templateStr:   "{{ with .chezmoi }}{{ .os }}{{ end }}",
+			expectedStr:   "linux",
+			expectedData:  []string{".chezmoi", ".chezmoi.os"},
+			expectedFuncs: []string{},
+		},
+		{
+			name:          "range",
+			templateStr:   "{{ range .list }}{{ . }}{{ $.email }}{{ end }}",
+			expectedStr:   "ame@example.combme@example.com",
+			expectedData:  []string{".email", ".list"},
+			expectedFuncs: []string{},
+		},
+		{
+			name:          "variadic_func",
+			templateStr:   `{{ join "-" .chezmoi.os .chezmoi.hostname }}`,
+			expectedStr:   "linux-host",
+			expectedData:  []string{".chezmoi.hostname", ".chezmoi.os"},
+			expectedFuncs: []string{"join"},
+		},
+		{
+			name:          "partial_template",
+			templateStr:   `{{ define "p" }}{{ .os | upper }} {{ $.hostname }}{{ end }}{{ template "p" .chezmoi }}`,
+			expectedStr:   "LINUX host",
+			expectedData:  []string{".chezmoi", ".chezmoi.hostname", ".chezmoi.os"},
+			expectedFuncs: []string{"upper"},
+		},
+		{
+			name:          "partial_template_unknown_dot",
+			templateStr:   `{{ define "p" }}{{ . }}{{ end }}{{ range .list }}{{ template "p" . }}{{ end }}`,
+			expectedStr:   "ab",
+			expectedData:  []string{".list"},
+			expectedFuncs: []string{},
+		},
+		{
+			name:          "missing_key",
+			templateStr:   "{{ .missing }}",
+			expectedStr:   "<no value>",
+			expectedData:  []string{".missing"},
+			expectedFuncs: []string{},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tmpl, err := ParseTemplate(tc.name, []byte(tc.templateStr), funcs, TemplateOptions{})
+			assert.NoError(t, err)
+			recorder := NewTemplateAccessRecorder()
+			recorder.instrument(tmpl, funcs)
+			actual, err := tmpl.Execute(data)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedStr, string(actual))
+			assert.Equal(t, tc.expectedData, recorder.Data())
+			assert.Equal(t, tc.expectedFuncs, recorder.Funcs())
+		})
+	}
+}
+
+func TestTemplateAccessRecorderMissingKeyError(t *testing.T) {
+	tmpl, err := ParseTemplate("missing_key_error", []byte("{{ .missing }}"), nil, TemplateOptions{
+		Options: []string{"missingkey=error"},
+	})
+	assert.NoError(t, err)
+	NewTemplateAccessRecorder().instrument(tmpl, nil)
+	_, err = tmpl.Execute(map[string]any{})
+	assert.Error(t, err)
+}
+
+func TestTemplateAccessRecorderAssociatedTemplate(t *testing.T) {
+	partial, err := ParseTemplate("partial", []byte("{{ .email }}"), nil, TemplateOptions{})
+	assert.NoError(t, err)
+	partialStr := partial.template.Tree.Root.String()
+
+	tmpl, err := ParseTemplate("main", []byte(`{{ template "partial" . }}`), nil, TemplateOptions{})
+	assert.NoError(t, err)
+	tmpl, err = tmpl.AddParseTree(partial)
+	assert.NoError(t, err)
+	recorder := NewTemplateAccessRecorder()
+	recorder.instrument(tmpl, nil)
+	actual, err := tmpl.Execute(map[string]any{
+		"email": "me@example.com",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "me@example.com", string(actual))
+	assert.Equal(t, []string{".email"}, recorder.Data())
+
+	// The associated template's parse tree is shared, so it must not be
+	// modified.
+	assert.Equal(t, partialStr, partial.template.Tree.Root.String())
+}
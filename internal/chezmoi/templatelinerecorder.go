@@ -0,0 +1,156 @@
+package chezmoi
+
+import (
+	"bytes"
+	"strconv"
+	"text/template/parse"
+)
+
+// templateLineMarker delimits the markers that instrumented templates write to
+// their output to record the line of the template's source that produced the
+// output that follows.
+const templateLineMarker = '\x00'
+
+// A templateLineRecorder records the line of a template's source that produced
+// each line of the template's output.
+type templateLineRecorder struct {
+	sourceLineNumbers []int // sourceLineNumbers maps parsed line numbers to source line numbers.
+	lineNumbers       []int
+}
+
+// instrument modifies t, which was parsed from data, so that its output contains
+// markers recording the line of data that produced each part of the output.
+// Only t's own parse tree is instrumented, so the output of any associated
+// templates is attributed to the line that invokes them.
+func (r *templateLineRecorder) instrument(t *Template, data []byte) {
+	// Lines containing template directives are removed before the template is
+	// parsed, so record the source line number of each parsed line.
+	removedLines := make(map[int]bool)
+	for _, match := range templateDirectiveRx.FindAllIndex(data, -1) {
+		removedLines[bytes.Count(data[:match[0]], []byte{'\n'})+1] = true
+	}
+	r.sourceLineNumbers = []int{0}
+	for lineNumber := 1; lineNumber <= bytes.Count(data, []byte{'\n'})+1; lineNumber++ {
+		if !removedLines[lineNumber] {
+			r.sourceLineNumbers = append(r.sourceLineNumbers, lineNumber)
+		}
+	}
+
+	tmpl := t.template.Lookup(t.name)
+	if tmpl == nil || tmpl.Tree == nil {
+		return
+	}
+	var options TemplateOptions
+	contents := options.parseAndRemoveDirectives(data)
+	r.instrumentList(tmpl.Tree.Root, contents)
+}
+
+// instrumentList inserts markers into list, which was parsed from contents.
+// A marker is inserted before every node and after every newline in text, so
+// that output produced by repeated nodes, for example in a range, is attributed
+// to the correct line.
+func (r *templateLineRecorder) instrumentList(list *parse.ListNode, contents []byte) {
+	if list == nil {
+		return
+	}
+	nodes := make([]parse.Node, 0, 2*len(list.Nodes))
+	for _, node := range list.Nodes {
+		pos := int(node.Position())
+		textNode, ok := node.(*parse.TextNode)
+		if !ok {
+			nodes = append(nodes, r.newMarkerNode(node.Position(), contents), node)
+		}
+		switch node := node.(type) {
+		case *parse.IfNode:
+			r.instrumentList(node.List, contents)
+			r.instrumentList(node.ElseList, contents)
+		case *parse.RangeNode:
+			r.instrumentList(node.List, contents)
+			r.instrumentList(node.ElseList, contents)
+		case *parse.WithNode:
+			r.instrumentList(node.List, contents)
+			r.instrumentList(node.ElseList, contents)
+		}
+		if !ok {
+			continue
+		}
+		text := make([]byte, 0, len(textNode.Text))
+		text = r.appendMarker(text, pos, contents)
+		for i, b := range textNode.Text {
+			text = append(text, b)
+			if b == '\n' {
+				text = r.appendMarker(text, pos+i+1, contents)
+			}
+		}
+		textNode.Text = text
+		nodes = append(nodes, textNode)
+	}
+	list.Nodes = nodes
+}
+
+// appendMarker appends a marker for the line of contents containing pos to
+// text.
+func (r *templateLineRecorder) appendMarker(text []byte, pos int, contents []byte) []byte {
+	pos = min(pos, len(contents))
+	lineNumber := r.sourceLineNumbers[min(bytes.Count(contents[:pos], []byte{'\n'})+1, len(r.sourceLineNumbers)-1)]
+	text = append(text, templateLineMarker)
+	text = strconv.AppendInt(text, int64(lineNumber), 10)
+	return append(text, templateLineMarker)
+}
+
+// newMarkerNode returns a new text node containing a marker for the line of
+// contents containing pos.
+func (r *templateLineRecorder) newMarkerNode(pos parse.Pos, contents []byte) *parse.TextNode {
+	return &parse.TextNode{
+		NodeType: parse.NodeText,
+		Pos:      pos,
+		Text:     r.appendMarker(nil, int(pos), contents),
+	}
+}
+
+// strip returns output with the markers removed, and records the source line
+// number of each line of output.
+func (r *templateLineRecorder) strip(output []byte) []byte {
+	r.lineNumbers = nil
+	result := make([]byte, 0, len(output))
+	sourceLineNumber := 1
+	for i, line := range bytes.Split(output, []byte{'\n'}) {
+		if i > 0 {
+			result = append(result, '\n')
+		}
+		lineNumber := 0
+		for len(line) > 0 {
+			if line[0] != templateLineMarker {
+				if lineNumber == 0 {
+					lineNumber = sourceLineNumber
+				}
+				end := bytes.IndexByte(line, templateLineMarker)
+				if end == -1 {
+					end = len(line)
+				}
+				result = append(result, line[:end]...)
+				line = line[end:]
+				continue
+			}
+			end := bytes.IndexByte(line[1:], templateLineMarker) + 1
+			if end == 0 {
+				break
+			}
+			sourceLineNumber, _ = strconv.Atoi(string(line[1:end]))
+			line = line[end+1:]
+		}
+		if lineNumber == 0 {
+			lineNumber = sourceLineNumber
+		}
+		r.lineNumbers = append(r.lineNumbers, lineNumber)
+	}
+	return result
+}
+
+// lineNumber returns the source line number of line lineNumber of the output.
+func (r *templateLineRecorder) lineNumber(lineNumber int) int {
+	if lineNumber < 1 || lineNumber > len(r.lineNumbers) {
+		return lineNumber
+	}
+	return r.lineNumbers[lineNumber-1]
+}
@@ -0,0 +1,62 @@
+package chezmoi
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestTemplateLineRecorder(t *testing.T) {
+	data := map[string]any{
+		"list": []any{"a", "b"},
+	}
+
+	for _, tc := range []struct {
+		name                string
+		templateStr         string
+		expectedStr         string
+		expectedLineNumbers []int
+	}{
+		{
+			name:                "text",
+			templateStr:         "a\nb\n",
+			expectedStr:         "a\nb\n",
+			expectedLineNumbers: []int{1, 2, 3},
+		},
+		{
+			name:                "if",
+			templateStr:         "a\n{{ if false }}\nb\n{{ end }}\nc\n",
+			expectedStr:         "a\n\nc\n",
+			expectedLineNumbers: []int{1, 4, 5, 6},
+		},
+		{
+			name:                "trimmed_if",
+			templateStr:         "a\n{{- if false }}\nb\n{{- end }}\nc\n",
+			expectedStr:         "a\nc\n",
+			expectedLineNumbers: []int{1, 5, 6},
+		},
+		{
+			name:                "range",
+			templateStr:         "a\n{{ range .list -}}\n{{ . }}\n{{ end -}}\nb\n",
+			expectedStr:         "a\na\nb\nb\n",
+			expectedLineNumbers: []int{1, 3, 3, 5, 6},
+		},
+		{
+			name:                "directive",
+			templateStr:         "# chezmoi:template:missing-key=zero\na\n{{ .missing }}\n",
+			expectedStr:         "a\n<no value>\n",
+			expectedLineNumbers: []int{2, 3, 4},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tmpl, err := ParseTemplate(tc.name, []byte(tc.templateStr), nil, TemplateOptions{})
+			assert.NoError(t, err)
+			var recorder templateLineRecorder
+			recorder.instrument(tmpl, []byte(tc.templateStr))
+			actual, err := tmpl.Execute(data)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedStr, string(recorder.strip(actual)))
+			assert.Equal(t, tc.expectedLineNumbers, recorder.lineNumbers)
+		})
+	}
+}
@@ -0,0 +1,17 @@
+package chezmoi
+
+// Verbosity levels, set by repeating the --verbose flag.
+const (
+	// VerbosityChanges shows the changes that chezmoi makes.
+	VerbosityChanges = 1
+	// VerbosityActions additionally shows the commands that chezmoi runs and
+	// the externals that it downloads.
+	VerbosityActions = 2
+	// VerbosityDetails additionally shows the templates that chezmoi executes
+	// and the cached data that it uses.
+	VerbosityDetails = 3
+)
+
+// A VerboseFunc reports a message from subsystem if the verbosity is at least
+// level.
+type VerboseFunc func(level int, subsystem, format string, args ...any)
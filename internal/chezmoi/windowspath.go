@@ -0,0 +1,71 @@
+package chezmoi
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// windowsMaxPath is the maximum length of a path on Windows that does not
+// need the extended-length prefix.
+const windowsMaxPath = 260
+
+// windowsReservedNameRx matches the device names that cannot be used as file
+// names on Windows, with or without an extension.
+var windowsReservedNameRx = regexp.MustCompile(`(?i)^(?:AUX|CON|CONIN\$|CONOUT\$|NUL|PRN|COM[0-9¹²³]|LPT[0-9¹²³])(?:\..*)?$`)
+
+// An invalidWindowsTargetError is returned when a target cannot be created on
+// Windows.
+type invalidWindowsTargetError struct {
+	targetRelPath RelPath
+	reason        string
+}
+
+func (e *invalidWindowsTargetError) Error() string {
+	format := "%s: invalid target name on Windows: %s, add it to .chezmoiignore on Windows"
+	return fmt.Sprintf(format, e.targetRelPath, e.reason)
+}
+
+// validateWindowsTargetRelPath returns an error if targetRelPath cannot be
+// created on Windows.
+func validateWindowsTargetRelPath(targetRelPath RelPath) error {
+	for _, component := range strings.Split(targetRelPath.String(), "/") {
+		var reason string
+		switch {
+		case windowsReservedNameRx.MatchString(strings.TrimRight(component, ". ")):
+			reason = fmt.Sprintf("%s is a reserved device name", component)
+		case strings.HasSuffix(component, ".") || strings.HasSuffix(component, " "):
+			reason = fmt.Sprintf("%q ends with a dot or a space", component)
+		case strings.ContainsAny(component, `<>:"\|?*`):
+			reason = fmt.Sprintf(`%s contains one of <>:"\|?*`, component)
+		case strings.ContainsFunc(component, func(r rune) bool { return r < ' ' }):
+			reason = fmt.Sprintf("%q contains a control character", component)
+		default:
+			continue
+		}
+		return &invalidWindowsTargetError{
+			targetRelPath: targetRelPath,
+			reason:        reason,
+		}
+	}
+	return nil
+}
+
+// windowsLongPath returns path with the extended-length prefix if it is an
+// absolute path that is too long to be passed to Windows APIs without it.
+// Functions in package os add the prefix themselves, so this is only needed
+// for paths passed directly to Windows APIs.
+func windowsLongPath(path string) string {
+	if len(path) < windowsMaxPath || strings.HasPrefix(path, `\\?\`) {
+		return path
+	}
+	path = strings.ReplaceAll(path, "/", `\`)
+	switch {
+	case len(path) >= 3 && path[1] == ':' && path[2] == '\\':
+		return `\\?\` + path
+	case strings.HasPrefix(path, `\\`) && !strings.HasPrefix(path, `\\.\`):
+		return `\\?\UNC\` + path[2:]
+	default:
+		return path
+	}
+}
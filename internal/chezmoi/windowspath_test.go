@@ -0,0 +1,101 @@
+package chezmoi
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestValidateWindowsTargetRelPath(t *testing.T) {
+	for _, tc := range []struct {
+		targetRelPath string
+		expectedErr   string
+	}{
+		{
+			targetRelPath: ".bashrc",
+		},
+		{
+			targetRelPath: ".config/console/conf",
+		},
+		{
+			targetRelPath: "dir/CONFIG",
+		},
+		{
+			targetRelPath: "con",
+			expectedErr:   "con: invalid target name on Windows: con is a reserved device name",
+		},
+		{
+			targetRelPath: "dir/NUL.txt",
+			expectedErr:   "dir/NUL.txt: invalid target name on Windows: NUL.txt is a reserved device name",
+		},
+		{
+			targetRelPath: "COM1/file",
+			expectedErr:   "COM1/file: invalid target name on Windows: COM1 is a reserved device name",
+		},
+		{
+			targetRelPath: "dir./file",
+			expectedErr:   `dir./file: invalid target name on Windows: "dir." ends with a dot or a space`,
+		},
+		{
+			targetRelPath: "file ",
+			expectedErr:   `file : invalid target name on Windows: "file " ends with a dot or a space`,
+		},
+		{
+			targetRelPath: "a:b",
+			expectedErr:   `a:b: invalid target name on Windows: a:b contains one of <>:"\|?*`,
+		},
+		{
+			targetRelPath: "a\tb",
+			expectedErr:   `a	b: invalid target name on Windows: "a\tb" contains a control character`,
+		},
+	} {
+		t.Run(tc.targetRelPath, func(t *testing.T) {
+			err := validateWindowsTargetRelPath(NewRelPath(tc.targetRelPath))
+			if tc.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.expectedErr+", add it to .chezmoiignore on Windows")
+			}
+		})
+	}
+}
+
+func TestWindowsLongPath(t *testing.T) {
+	long := strings.Repeat("a", windowsMaxPath)
+	for _, tc := range []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{
+			name:     "short",
+			path:     `C:\Users\user\.bashrc`,
+			expected: `C:\Users\user\.bashrc`,
+		},
+		{
+			name:     "long_drive",
+			path:     `C:/Users/user/` + long,
+			expected: `\\?\C:\Users\user\` + long,
+		},
+		{
+			name:     "long_unc",
+			path:     `\\server\share\` + long,
+			expected: `\\?\UNC\server\share\` + long,
+		},
+		{
+			name:     "long_prefixed",
+			path:     `\\?\C:\` + long,
+			expected: `\\?\C:\` + long,
+		},
+		{
+			name:     "long_relative",
+			path:     long,
+			expected: long,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, windowsLongPath(tc.path))
+		})
+	}
+}
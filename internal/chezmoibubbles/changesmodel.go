@@ -0,0 +1,165 @@
+package chezmoibubbles
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// changesHelp is the help shown at the bottom of a ChangesModel.
+const changesHelp = "↑/↓ move • space toggle • a all • n none • pgup/pgdn scroll diff • enter apply • q quit"
+
+// A Change is a pending change to a target.
+type Change struct {
+	Name   string
+	Status string
+	Diff   string
+}
+
+// A ChangesModel lists changes, shows the diff of the current change, and lets
+// the user choose which changes to apply.
+type ChangesModel struct {
+	changes    []Change
+	selected   []bool
+	cursor     int
+	diffOffset int
+	width      int
+	height     int
+	canceled   bool
+}
+
+// NewChangesModel returns a new ChangesModel for changes, with all changes
+// selected.
+func NewChangesModel(changes []Change) ChangesModel {
+	selected := make([]bool, len(changes))
+	for i := range selected {
+		selected[i] = true
+	}
+	return ChangesModel{
+		changes:  changes,
+		selected: selected,
+	}
+}
+
+func (m ChangesModel) Canceled() bool {
+	return m.canceled
+}
+
+func (m ChangesModel) Init() tea.Cmd {
+	return nil
+}
+
+// Selected returns the changes that the user chose to apply.
+func (m ChangesModel) Selected() []Change {
+	var selected []Change
+	for i, change := range m.changes {
+		if m.selected[i] {
+			selected = append(selected, change)
+		}
+	}
+	return selected
+}
+
+func (m ChangesModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c", "esc", "q":
+			m.canceled = true
+			return m, tea.Quit
+		case "enter":
+			return m, tea.Quit
+		case "up", "k":
+			if m.cursor > 0 {
+				m.cursor--
+				m.diffOffset = 0
+			}
+		case "down", "j":
+			if m.cursor < len(m.changes)-1 {
+				m.cursor++
+				m.diffOffset = 0
+			}
+		case " ":
+			if len(m.changes) > 0 {
+				m.selected[m.cursor] = !m.selected[m.cursor]
+			}
+		case "a":
+			for i := range m.selected {
+				m.selected[i] = true
+			}
+		case "n":
+			for i := range m.selected {
+				m.selected[i] = false
+			}
+		case "pgdown", "ctrl+d":
+			if maxDiffOffset := len(m.diffLines()) - 1; m.diffOffset+m.diffHeight() <= maxDiffOffset {
+				m.diffOffset += m.diffHeight()
+			}
+		case "pgup", "ctrl+u":
+			m.diffOffset = max(m.diffOffset-m.diffHeight(), 0)
+		}
+	}
+	return m, nil
+}
+
+func (m ChangesModel) View() string {
+	var builder strings.Builder
+
+	// Show a window of the list of changes that contains the cursor.
+	listHeight := len(m.changes)
+	if m.height > 0 {
+		listHeight = min(listHeight, max(m.height/3, 1))
+	}
+	listOffset := min(max(m.cursor-listHeight/2, 0), len(m.changes)-listHeight)
+	for i := listOffset; i < listOffset+listHeight; i++ {
+		cursor := ' '
+		if i == m.cursor {
+			cursor = '>'
+		}
+		checkbox := ' '
+		if m.selected[i] {
+			checkbox = 'x'
+		}
+		fmt.Fprintf(&builder, "%c [%c] %s %s\n", cursor, checkbox, m.changes[i].Status, m.changes[i].Name)
+	}
+
+	separator := strings.Repeat("─", max(m.width, 1))
+	builder.WriteString(separator + "\n")
+	diffLines := m.diffLines()
+	if m.height > 0 {
+		diffLines = diffLines[min(m.diffOffset, len(diffLines)):min(m.diffOffset+m.diffHeight(), len(diffLines))]
+	}
+	for _, line := range diffLines {
+		builder.WriteString(line + "\n")
+	}
+	builder.WriteString(separator + "\n")
+	builder.WriteString(changesHelp + "\n")
+
+	return builder.String()
+}
+
+// diffHeight returns the number of lines of the diff to show.
+func (m ChangesModel) diffHeight() int {
+	if m.height == 0 {
+		return len(m.diffLines())
+	}
+	listHeight := min(len(m.changes), max(m.height/3, 1))
+	// Leave space for the two separators and the help.
+	return max(m.height-listHeight-3, 1)
+}
+
+// diffLines returns the lines of the diff of the current change.
+func (m ChangesModel) diffLines() []string {
+	if len(m.changes) == 0 {
+		return nil
+	}
+	diff := m.changes[m.cursor].Diff
+	if diff == "" {
+		return []string{"(no diff)"}
+	}
+	return strings.Split(strings.TrimSuffix(diff, "\n"), "\n")
+}
@@ -0,0 +1,92 @@
+package chezmoibubbles
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestChangesModel(t *testing.T) {
+	changes := []Change{
+		{Name: ".bashrc", Status: " M", Diff: "diff --git a/.bashrc b/.bashrc\n"},
+		{Name: ".gitconfig", Status: " A"},
+		{Name: ".vimrc", Status: "MM"},
+	}
+	for _, tc := range []struct {
+		name             string
+		input            string
+		expectedCanceled bool
+		expectedSelected []string
+	}{
+		{
+			name:             "all",
+			input:            "\r",
+			expectedSelected: []string{".bashrc", ".gitconfig", ".vimrc"},
+		},
+		{
+			name:             "skip_first",
+			input:            " \r",
+			expectedSelected: []string{".gitconfig", ".vimrc"},
+		},
+		{
+			name:             "skip_second",
+			input:            "j \r",
+			expectedSelected: []string{".bashrc", ".vimrc"},
+		},
+		{
+			name:             "none_then_third",
+			input:            "njjj \r",
+			expectedSelected: []string{".vimrc"},
+		},
+		{
+			name:             "toggle_twice",
+			input:            "jk  \r",
+			expectedSelected: []string{".bashrc", ".gitconfig", ".vimrc"},
+		},
+		{
+			name:             "none_then_all",
+			input:            "na\r",
+			expectedSelected: []string{".bashrc", ".gitconfig", ".vimrc"},
+		},
+		{
+			name:             "cancel_ctrlc",
+			input:            "\x03",
+			expectedCanceled: true,
+			expectedSelected: []string{".bashrc", ".gitconfig", ".vimrc"},
+		},
+		{
+			name:             "cancel_q",
+			input:            "q",
+			expectedCanceled: true,
+			expectedSelected: []string{".bashrc", ".gitconfig", ".vimrc"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			actualModel := testRunModelWithInput(t, NewChangesModel(changes), tc.input)
+			assert.Equal(t, tc.expectedCanceled, actualModel.Canceled())
+			var actualSelected []string
+			for _, change := range actualModel.Selected() {
+				actualSelected = append(actualSelected, change.Name)
+			}
+			assert.Equal(t, tc.expectedSelected, actualSelected)
+		})
+	}
+}
+
+func TestChangesModelView(t *testing.T) {
+	model := NewChangesModel([]Change{
+		{Name: ".bashrc", Status: " M", Diff: "diff --git a/.bashrc b/.bashrc\n"},
+		{Name: ".gitconfig", Status: " A"},
+	})
+	model = testRunModelWithInput(t, model, " ")
+	assert.Equal(t, "> [ ]  M .bashrc\n"+
+		"  [x]  A .gitconfig\n"+
+		"─\n"+
+		"diff --git a/.bashrc b/.bashrc\n"+
+		"─\n"+
+		changesHelp+"\n", model.View())
+
+	model = testRunModelWithInput(t, model, "j")
+	assert.Contains(t, model.View(), "> [x]  A .gitconfig\n")
+	assert.Contains(t, model.View(), "(no diff)\n")
+}
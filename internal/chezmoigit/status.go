@@ -4,7 +4,10 @@ import (
 	"bufio"
 	"bytes"
 	"regexp"
+	"slices"
 	"strconv"
+
+	"github.com/go-git/go-git/v5"
 )
 
 // A ParseError is a parse error.
@@ -71,9 +74,17 @@ type Status struct {
 	Unmerged        []UnmergedStatus
 	Untracked       []UntrackedStatus
 	Ignored         []IgnoredStatus
+	Ahead           int64
+	Behind          int64
 }
 
 var (
+	statusPorcelainV2ZBranchABRx = regexp.MustCompile(`` +
+		`^# branch\.ab ` +
+		`\+([0-9]+) ` +
+		`-([0-9]+)` +
+		`$`,
+	)
 	statusPorcelainV2ZOrdinaryRx = regexp.MustCompile(`` +
 		`^1 ` +
 		`([!\.\?ACDMRU])([!\.\?ACDMRU]) ` +
@@ -133,6 +144,9 @@ func (e ParseError) Error() string {
 //
 //	git status --ignored --porcelain=v2
 //
+// If the output includes branch headers, as generated by --branch, then the
+// number of commits ahead of and behind the upstream branch are also parsed.
+//
 // See https://git-scm.com/docs/git-status.
 func ParseStatusPorcelainV2(output []byte) (*Status, error) {
 	var status Status
@@ -259,7 +273,20 @@ func ParseStatusPorcelainV2(output []byte) (*Status, error) {
 			}
 			status.Ignored = append(status.Ignored, us)
 		case '#':
-			continue
+			m := statusPorcelainV2ZBranchABRx.FindStringSubmatch(text)
+			if m == nil {
+				continue
+			}
+			ahead, err := strconv.ParseInt(m[1], 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			behind, err := strconv.ParseInt(m[2], 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			status.Ahead = ahead
+			status.Behind = behind
 		default:
 			return nil, ParseError(text)
 		}
@@ -270,6 +297,61 @@ func ParseStatusPorcelainV2(output []byte) (*Status, error) {
 	return &status, nil
 }
 
+// NewStatusFromGoGit returns the Status equivalent to gitStatus, as returned
+// by the builtin git.
+func NewStatusFromGoGit(gitStatus git.Status) *Status {
+	paths := make([]string, 0, len(gitStatus))
+	for path := range gitStatus {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+
+	var status Status
+	for _, path := range paths {
+		fileStatus := gitStatus[path]
+		x := porcelainV2StatusCode(fileStatus.Staging)
+		y := porcelainV2StatusCode(fileStatus.Worktree)
+		switch {
+		case fileStatus.Staging == git.Unmodified && fileStatus.Worktree == git.Unmodified:
+			continue
+		case fileStatus.Staging == git.Untracked || fileStatus.Worktree == git.Untracked:
+			status.Untracked = append(status.Untracked, UntrackedStatus{
+				Path: path,
+			})
+		case fileStatus.Staging == git.UpdatedButUnmerged || fileStatus.Worktree == git.UpdatedButUnmerged:
+			status.Unmerged = append(status.Unmerged, UnmergedStatus{
+				X:    x,
+				Y:    y,
+				Path: path,
+			})
+		case fileStatus.Staging == git.Renamed || fileStatus.Staging == git.Copied:
+			status.RenamedOrCopied = append(status.RenamedOrCopied, RenamedOrCopiedStatus{
+				X:        x,
+				Y:        y,
+				RC:       x,
+				Path:     path,
+				OrigPath: fileStatus.Extra,
+			})
+		default:
+			status.Ordinary = append(status.Ordinary, OrdinaryStatus{
+				X:    x,
+				Y:    y,
+				Path: path,
+			})
+		}
+	}
+	return &status
+}
+
+// porcelainV2StatusCode returns the git status --porcelain=v2 status code
+// equivalent to statusCode.
+func porcelainV2StatusCode(statusCode git.StatusCode) byte {
+	if statusCode == git.Unmodified {
+		return '.'
+	}
+	return byte(statusCode)
+}
+
 // Empty returns true if s is empty.
 func (s *Status) Empty() bool {
 	switch {
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/alecthomas/assert/v2"
+	"github.com/go-git/go-git/v5"
 
 	"github.com/twpayne/chezmoi/v2/internal/chezmoitest"
 )
@@ -222,6 +223,18 @@ func TestParseStatusPorcelainV2(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "branch",
+			outputStr: "" +
+				"# branch.oid 9fd4c04b4c2d5eb4e1c6c9bad8a6f3c2cf3c3e1b\n" +
+				"# branch.head main\n" +
+				"# branch.upstream origin/main\n" +
+				"# branch.ab +1 -2\n",
+			expectedStatus: &Status{
+				Ahead:  1,
+				Behind: 2,
+			},
+		},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
 			actualStatus, err := ParseStatusPorcelainV2([]byte(tc.outputStr))
@@ -230,3 +243,94 @@ func TestParseStatusPorcelainV2(t *testing.T) {
 		})
 	}
 }
+
+func TestNewStatusFromGoGit(t *testing.T) {
+	for _, tc := range []struct {
+		name           string
+		gitStatus      git.Status
+		expectedStatus *Status
+	}{
+		{
+			name:           "empty",
+			gitStatus:      git.Status{},
+			expectedStatus: &Status{},
+		},
+		{
+			name: "unmodified",
+			gitStatus: git.Status{
+				"main.go": &git.FileStatus{Staging: git.Unmodified, Worktree: git.Unmodified},
+			},
+			expectedStatus: &Status{},
+		},
+		{
+			name: "ordinary",
+			gitStatus: git.Status{
+				"main.go":    &git.FileStatus{Staging: git.Added, Worktree: git.Unmodified},
+				"chezmoi.go": &git.FileStatus{Staging: git.Unmodified, Worktree: git.Modified},
+			},
+			expectedStatus: &Status{
+				Ordinary: []OrdinaryStatus{
+					{
+						X:    '.',
+						Y:    'M',
+						Path: "chezmoi.go",
+					},
+					{
+						X:    'A',
+						Y:    '.',
+						Path: "main.go",
+					},
+				},
+			},
+		},
+		{
+			name: "renamed",
+			gitStatus: git.Status{
+				"chezmoi_test.go": &git.FileStatus{Staging: git.Renamed, Worktree: git.Unmodified, Extra: "chezmoi.go"},
+			},
+			expectedStatus: &Status{
+				RenamedOrCopied: []RenamedOrCopiedStatus{
+					{
+						X:        'R',
+						Y:        '.',
+						RC:       'R',
+						Path:     "chezmoi_test.go",
+						OrigPath: "chezmoi.go",
+					},
+				},
+			},
+		},
+		{
+			name: "unmerged",
+			gitStatus: git.Status{
+				"main.go": &git.FileStatus{Staging: git.UpdatedButUnmerged, Worktree: git.UpdatedButUnmerged},
+			},
+			expectedStatus: &Status{
+				Unmerged: []UnmergedStatus{
+					{
+						X:    'U',
+						Y:    'U',
+						Path: "main.go",
+					},
+				},
+			},
+		},
+		{
+			name: "untracked",
+			gitStatus: git.Status{
+				"chezmoi.go": &git.FileStatus{Staging: git.Untracked, Worktree: git.Untracked},
+			},
+			expectedStatus: &Status{
+				Untracked: []UntrackedStatus{
+					{
+						Path: "chezmoi.go",
+					},
+				},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedStatus, NewStatusFromGoGit(tc.gitStatus))
+		})
+	}
+}
@@ -57,6 +57,28 @@ func GPGGenerateKey(command, homeDir string) (key, passphrase string, err error)
 	return
 }
 
+// GPGKeyFingerprint returns the fingerprint of key in homeDir.
+func GPGKeyFingerprint(command, homeDir, key string) (string, error) {
+	cmd := exec.Command(
+		command,
+		"--batch",
+		"--homedir", homeDir,
+		"--list-keys",
+		"--with-colons",
+		key,
+	)
+	output, err := chezmoilog.LogCmdOutput(slog.Default(), cmd)
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(string(output), "\n") {
+		if fields := strings.Split(line, ":"); len(fields) > 9 && fields[0] == "fpr" {
+			return fields[9], nil
+		}
+	}
+	return "", fmt.Errorf("%s: no fingerprint", key)
+}
+
 // HomeDir returns the home directory.
 func HomeDir() string {
 	switch runtime.GOOS {
@@ -1,38 +1,58 @@
 package cmd
 
 import (
+	"archive/tar"
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"io/fs"
+	"slices"
 
 	"github.com/spf13/cobra"
 
 	"github.com/twpayne/chezmoi/v2/internal/chezmoi"
 )
 
+// defaultAddPrune is the default value of add.prune, used when add.prune is not
+// set.
+var defaultAddPrune = []string{
+	".cache",
+	"node_modules",
+}
+
 type addCmdConfig struct {
-	Encrypt          bool     `json:"encrypt"          mapstructure:"encrypt"          yaml:"encrypt"`
-	Secrets          severity `json:"secrets"          mapstructure:"secrets"          yaml:"secrets"`
-	TemplateSymlinks bool     `json:"templateSymlinks" mapstructure:"templateSymlinks" yaml:"templateSymlinks"`
-	autoTemplate     bool
-	create           bool
-	exact            bool
-	filter           *chezmoi.EntryTypeFilter
-	follow           bool
-	prompt           bool
-	quiet            bool
-	recursive        bool
-	template         bool
+	Encrypt            bool     `json:"encrypt"          mapstructure:"encrypt"          yaml:"encrypt"`
+	Prune              []string `json:"prune"            mapstructure:"prune"            yaml:"prune"`
+	Secrets            severity `json:"secrets"          mapstructure:"secrets"          yaml:"secrets"`
+	TemplateSymlinks   bool     `json:"templateSymlinks" mapstructure:"templateSymlinks" yaml:"templateSymlinks"`
+	autoTemplate       bool
+	create             bool
+	createFromTemplate bool
+	exact              bool
+	filter             *chezmoi.EntryTypeFilter
+	follow             bool
+	prompt             bool
+	recursive          bool
+	stdin              bool
+	target             string
+	template           bool
 }
 
 func (c *Config) newAddCmd() *cobra.Command {
 	addCmd := &cobra.Command{
 		Use:     "add targets...",
 		Aliases: []string{"manage"},
-		Short:   "Add an existing file, directory, or symlink to the source state",
+		Short:   mustShortHelp("add"),
 		Long:    mustLongHelp("add"),
 		Example: example("add"),
-		Args:    cobra.MinimumNArgs(1),
-		RunE:    c.makeRunEWithSourceState(c.runAddCmd),
+		Args: func(cmd *cobra.Command, args []string) error {
+			if c.Add.stdin || c.Add.createFromTemplate {
+				return cobra.NoArgs(cmd, args)
+			}
+			return cobra.MinimumNArgs(1)(cmd, args)
+		},
+		RunE: c.makeRunEWithSourceState(c.runAddCmd),
 		Annotations: newAnnotations(
 			createSourceDirectoryIfNeeded,
 			modifiesSourceDirectory,
@@ -42,35 +62,42 @@ func (c *Config) newAddCmd() *cobra.Command {
 	}
 
 	addCmd.Flags().
-		BoolVarP(&c.Add.autoTemplate, "autotemplate", "a", c.Add.autoTemplate, "Generate the template when adding files as templates")
+		BoolVarP(&c.Add.autoTemplate, "autotemplate", "a", c.Add.autoTemplate, "")
 	addCmd.Flags().BoolVar(&c.Add.create, "create", c.Add.create, "Add files that should exist, irrespective of their contents")
-	addCmd.Flags().BoolVar(&c.Add.Encrypt, "encrypt", c.Add.Encrypt, "Encrypt files")
-	addCmd.Flags().BoolVar(&c.Add.exact, "exact", c.Add.exact, "Add directories exactly")
-	addCmd.Flags().VarP(c.Add.filter.Exclude, "exclude", "x", "Exclude entry types")
-	addCmd.Flags().BoolVarP(&c.Add.follow, "follow", "f", c.Add.follow, "Add symlink targets instead of symlinks")
-	addCmd.Flags().VarP(c.Add.filter.Include, "include", "i", "Include entry types")
-	addCmd.Flags().BoolVarP(&c.Add.prompt, "prompt", "p", c.Add.prompt, "Prompt before adding each entry")
-	addCmd.Flags().BoolVarP(&c.Add.quiet, "quiet", "q", c.Add.quiet, "Suppress warnings")
-	addCmd.Flags().BoolVarP(&c.Add.recursive, "recursive", "r", c.Add.recursive, "Recurse into subdirectories")
-	addCmd.Flags().Var(&c.Add.Secrets, "secrets", "Scan for secrets when adding unencrypted files")
-	addCmd.Flags().BoolVarP(&c.Add.template, "template", "T", c.Add.template, "Add files as templates")
 	addCmd.Flags().
-		BoolVar(&c.Add.TemplateSymlinks, "template-symlinks", c.Add.TemplateSymlinks, "Add symlinks with target in source or home dirs as templates")
+		BoolVar(&c.Add.createFromTemplate, "create-from-template", c.Add.createFromTemplate, "")
+	addCmd.Flags().BoolVar(&c.Add.Encrypt, "encrypt", c.Add.Encrypt, "")
+	addCmd.Flags().BoolVar(&c.Add.exact, "exact", c.Add.exact, "")
+	addCmd.Flags().VarP(c.Add.filter.Exclude, "exclude", "x", "")
+	addCmd.Flags().BoolVarP(&c.Add.follow, "follow", "f", c.Add.follow, "")
+	addCmd.Flags().VarP(c.Add.filter.Include, "include", "i", "")
+	addCmd.Flags().BoolVarP(&c.Add.prompt, "prompt", "p", c.Add.prompt, "")
+	addCmd.Flags().BoolVarP(&c.Add.recursive, "recursive", "r", c.Add.recursive, "")
+	addCmd.Flags().Var(&c.Add.Secrets, "secrets", "")
+	addCmd.Flags().BoolVar(&c.Add.stdin, "stdin", c.Add.stdin, "")
+	addCmd.Flags().StringVar(&c.Add.target, "target", c.Add.target, "")
+	addCmd.Flags().BoolVarP(&c.Add.template, "template", "T", c.Add.template, "")
+	addCmd.Flags().
+		BoolVar(&c.Add.TemplateSymlinks, "template-symlinks", c.Add.TemplateSymlinks, "")
 
 	return addCmd
 }
 
 func (c *Config) defaultOnIgnoreFunc(targetRelPath chezmoi.RelPath) {
-	if !c.Add.quiet {
-		c.errorf("warning: ignoring %s\n", targetRelPath)
-	}
+	c.warnf("ignoring %s\n", targetRelPath)
 }
 
 func (c *Config) defaultPreAddFunc(targetRelPath chezmoi.RelPath, fileInfo fs.FileInfo) error {
+	return c.preAdd(c.destSystem, targetRelPath, fileInfo)
+}
+
+// preAdd scans the target for secrets, reading its contents from
+// destSystem, and prompts the user before adding it, if configured.
+func (c *Config) preAdd(destSystem chezmoi.System, targetRelPath chezmoi.RelPath, fileInfo fs.FileInfo) error {
 	// Scan unencrypted files for secrets, if configured.
 	if c.Add.Secrets != severityIgnore && fileInfo.Mode().Type() == 0 && !c.Add.Encrypt {
 		absPath := c.DestDirAbsPath.Join(targetRelPath)
-		content, err := c.destSystem.ReadFile(absPath)
+		content, err := destSystem.ReadFile(absPath)
 		if err != nil {
 			return err
 		}
@@ -162,47 +189,156 @@ func (c *Config) defaultReplaceFunc(
 	}
 }
 
+// addPrune returns the names of subdirectories to skip when walking the
+// destination directory. An empty add.prune disables pruning.
+func (c *Config) addPrune() []string {
+	if c.Add.Prune == nil {
+		return defaultAddPrune
+	}
+	return c.Add.Prune
+}
+
 func (c *Config) runAddCmd(cmd *cobra.Command, args []string, sourceState *chezmoi.SourceState) error {
-	destAbsPathInfos, err := c.destAbsPathInfos(sourceState, args, destAbsPathInfosOptions{
+	if c.Add.stdin || c.Add.createFromTemplate {
+		return c.runAddStdinCmd(sourceState)
+	}
+
+	destAbsPathInfos, err := c.destAbsPathInfos(cmd.Context(), sourceState, args, destAbsPathInfosOptions{
 		follow:       c.Mode == chezmoi.ModeSymlink || c.Add.follow,
 		onIgnoreFunc: c.defaultOnIgnoreFunc,
+		prune:        c.addPrune(),
 		recursive:    c.Add.recursive,
 	})
 	if err != nil {
 		return err
 	}
 
-	persistentStateFileAbsPath, err := c.persistentStateFile()
+	addOptions, err := c.addOptions()
+	if err != nil {
+		return err
+	}
+
+	return sourceState.Add(c.sourceSystem, c.persistentState, c.destSystem, destAbsPathInfos, addOptions)
+}
+
+// runAddStdinCmd adds c.Add.target with contents read from stdin, without
+// creating it in the destination directory.
+func (c *Config) runAddStdinCmd(sourceState *chezmoi.SourceState) error {
+	if c.Add.target == "" {
+		return errors.New("--target is required when reading from stdin")
+	}
+	destAbsPath, err := chezmoi.NewAbsPathFromExtPath(c.Add.target, c.homeDirAbsPath)
+	if err != nil {
+		return err
+	}
+	targetRelPath, err := c.targetRelPath(destAbsPath)
+	if err != nil {
+		return err
+	}
+	if sourceState.Ignore(targetRelPath) {
+		return fmt.Errorf("%s: ignored", targetRelPath)
+	}
+
+	contents, err := io.ReadAll(c.stdin)
 	if err != nil {
 		return err
 	}
 
+	if c.Add.createFromTemplate {
+		// Check that the template parses before adding it. The template is
+		// not executed, so that adding it does not call any secret or
+		// network template functions.
+		if _, err := chezmoi.ParseTemplate(targetRelPath.String(), contents, c.templateFuncs, chezmoi.TemplateOptions{
+			Options: slices.Clone(c.Template.Options),
+		}); err != nil {
+			return err
+		}
+	}
+
+	// Build a system containing only the target and its parent directories,
+	// using the permissions of any parent directories that already exist.
+	archive := &bytes.Buffer{}
+	tarWriterSystem := chezmoi.NewTarWriterSystem(archive, tar.Header{})
+	components := targetRelPath.SplitAll()
+	for i := 1; i < len(components); i++ {
+		dirRelPath := chezmoi.EmptyRelPath.Join(components[:i]...)
+		perm := fs.ModePerm &^ c.Umask
+		if fileInfo, err := c.destSystem.Stat(c.DestDirAbsPath.Join(dirRelPath)); err == nil && fileInfo.IsDir() {
+			perm = fileInfo.Mode().Perm()
+		}
+		if err := tarWriterSystem.Mkdir(chezmoi.NewAbsPath(dirRelPath.String()), perm); err != nil {
+			return err
+		}
+	}
+	if err := tarWriterSystem.WriteFile(chezmoi.NewAbsPath(targetRelPath.String()), contents, 0o666&^c.Umask); err != nil {
+		return err
+	}
+	if err := tarWriterSystem.Close(); err != nil {
+		return err
+	}
+	archiveReaderSystem, err := chezmoi.NewArchiveReaderSystem(
+		"stdin.tar", archive.Bytes(), chezmoi.ArchiveFormatTar, chezmoi.ArchiveReaderSystemOptions{
+			RootAbsPath: c.DestDirAbsPath,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	destAbsPathInfos := make(map[chezmoi.AbsPath]fs.FileInfo)
+	if err := sourceState.AddDestAbsPathInfos(destAbsPathInfos, archiveReaderSystem, destAbsPath, nil); err != nil {
+		return err
+	}
+
+	addOptions, err := c.addOptions()
+	if err != nil {
+		return err
+	}
+	addOptions.PreAddFunc = func(targetRelPath chezmoi.RelPath, fileInfo fs.FileInfo) error {
+		return c.preAdd(archiveReaderSystem, targetRelPath, fileInfo)
+	}
+	if c.Add.createFromTemplate {
+		addOptions.Template = true
+	}
+
+	// The target does not exist in the destination directory, so do not
+	// record its state as if chezmoi had written it.
 	return sourceState.Add(
 		c.sourceSystem,
-		c.persistentState,
-		c.destSystem,
+		chezmoi.NullPersistentState{},
+		archiveReaderSystem,
 		destAbsPathInfos,
-		&chezmoi.AddOptions{
-			AutoTemplate:      c.Add.autoTemplate,
-			Create:            c.Add.create,
-			Encrypt:           c.Add.Encrypt,
-			EncryptedSuffix:   c.encryption.EncryptedSuffix(),
-			Exact:             c.Add.exact,
-			Errorf:            c.errorf,
-			Filter:            c.Add.filter,
-			OnIgnoreFunc:      c.defaultOnIgnoreFunc,
-			PreAddFunc:        c.defaultPreAddFunc,
-			ConfigFileAbsPath: c.getConfigFileAbsPath(),
-			ProtectedAbsPaths: []chezmoi.AbsPath{
-				c.CacheDirAbsPath,
-				c.WorkingTreeAbsPath,
-				c.getConfigFileAbsPath().Dir(),
-				persistentStateFileAbsPath,
-				c.sourceDirAbsPath,
-			},
-			ReplaceFunc:      c.defaultReplaceFunc,
-			Template:         c.Add.template,
-			TemplateSymlinks: c.Add.TemplateSymlinks,
-		},
+		addOptions,
 	)
 }
+
+// addOptions returns the options for adding targets to the source state.
+func (c *Config) addOptions() (*chezmoi.AddOptions, error) {
+	persistentStateFileAbsPath, err := c.persistentStateFile()
+	if err != nil {
+		return nil, err
+	}
+
+	return &chezmoi.AddOptions{
+		AutoTemplate:      c.Add.autoTemplate,
+		Create:            c.Add.create,
+		Encrypt:           c.Add.Encrypt,
+		EncryptedSuffix:   c.encryption.EncryptedSuffix(),
+		Exact:             c.Add.exact,
+		Errorf:            c.errorf,
+		Filter:            c.Add.filter,
+		OnIgnoreFunc:      c.defaultOnIgnoreFunc,
+		PreAddFunc:        c.defaultPreAddFunc,
+		ConfigFileAbsPath: c.getConfigFileAbsPath(),
+		ProtectedAbsPaths: []chezmoi.AbsPath{
+			c.CacheDirAbsPath,
+			c.WorkingTreeAbsPath,
+			c.getConfigFileAbsPath().Dir(),
+			persistentStateFileAbsPath,
+			c.sourceDirAbsPath,
+		},
+		ReplaceFunc:      c.defaultReplaceFunc,
+		Template:         c.Add.template,
+		TemplateSymlinks: c.Add.TemplateSymlinks,
+	}, nil
+}
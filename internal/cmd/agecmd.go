@@ -27,7 +27,7 @@ func (c *Config) newAgeCmd() *cobra.Command {
 	ageCmd := &cobra.Command{
 		Use:   "age",
 		Args:  cobra.NoArgs,
-		Short: "Interact with age",
+		Short: mustShortHelp("age"),
 	}
 
 	ageDecryptCmd := &cobra.Command{
@@ -2,11 +2,17 @@ package cmd
 
 import (
 	"fmt"
+	"maps"
 	"slices"
+	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
+// annotationKeyPrefix is the prefix of the keys of all annotations.
+const annotationKeyPrefix = "chezmoi_"
+
 // Annotations.
 var (
 	createSourceDirectoryIfNeeded = tagAnnotation("chezmoi_create_source_directory_if_needed")
@@ -45,7 +51,33 @@ func getAnnotations(cmd *cobra.Command) annotationsSet {
 	if cmd.Annotations == nil && !slices.Contains(thirdPartyCommandNames, cmd.Name()) {
 		panic(fmt.Sprintf("%q: no annotations", cmd.Name()))
 	}
-	return annotationsSet(cmd.Annotations)
+	// Flags that change what a command does can add to or override its
+	// annotations when they are set.
+	annotations := annotationsSet(maps.Clone(cmd.Annotations))
+	cmd.Flags().Visit(func(flag *pflag.Flag) {
+		if flag.Value.Type() == "bool" && flag.Value.String() != "true" {
+			return
+		}
+		for key, values := range flag.Annotations {
+			if strings.HasPrefix(key, annotationKeyPrefix) && len(values) == 1 {
+				if annotations == nil {
+					annotations = make(annotationsSet)
+				}
+				annotations[key] = values[0]
+			}
+		}
+	})
+	return annotations
+}
+
+// annotateFlag sets annotations on the flag name in flags that are added to
+// the annotations of its command when the flag is set.
+func annotateFlag(flags *pflag.FlagSet, name string, annotations annotationsSet) {
+	for key, value := range annotations {
+		if err := flags.SetAnnotation(name, key, []string{value}); err != nil {
+			panic(err)
+		}
+	}
 }
 
 func newAnnotations(annotations ...annotation) annotationsSet {
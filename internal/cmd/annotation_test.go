@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/spf13/cobra"
+)
+
+func TestGetAnnotationsFlag(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		args     []string
+		expected annotationsSet
+	}{
+		{
+			name: "unset",
+			expected: newAnnotations(
+				persistentStateModeReadMockWrite,
+				requiresSourceDirectory,
+			),
+		},
+		{
+			name: "false",
+			args: []string{"--flag=false"},
+			expected: newAnnotations(
+				persistentStateModeReadMockWrite,
+				requiresSourceDirectory,
+			),
+		},
+		{
+			name: "true",
+			args: []string{"--flag"},
+			expected: newAnnotations(
+				modifiesDestinationDirectory,
+				persistentStateModeReadWrite,
+				requiresSourceDirectory,
+			),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cmd := &cobra.Command{
+				Use: "test",
+				Annotations: newAnnotations(
+					persistentStateModeReadMockWrite,
+					requiresSourceDirectory,
+				),
+			}
+			cmd.Flags().Bool("flag", false, "")
+			annotateFlag(cmd.Flags(), "flag", newAnnotations(
+				modifiesDestinationDirectory,
+				persistentStateModeReadWrite,
+			))
+			assert.NoError(t, cmd.Flags().Parse(tc.args))
+			assert.Equal(t, tc.expected, getAnnotations(cmd))
+			assert.Equal(t, newAnnotations(
+				persistentStateModeReadMockWrite,
+				requiresSourceDirectory,
+			), annotationsSet(cmd.Annotations))
+		})
+	}
+}
@@ -1,21 +1,38 @@
 package cmd
 
 import (
+	"io/fs"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
 	"github.com/spf13/cobra"
 
 	"github.com/twpayne/chezmoi/v2/internal/chezmoi"
+	"github.com/twpayne/chezmoi/v2/internal/chezmoilog"
 )
 
+// applyWatchDelay is how long chezmoi apply --watch waits after the last change
+// to the source directory before applying, so that a burst of changes, for
+// example from an editor saving a file, is applied once.
+const applyWatchDelay = 100 * time.Millisecond
+
 type applyCmdConfig struct {
-	filter    *chezmoi.EntryTypeFilter
-	init      bool
-	recursive bool
+	allowDirty bool
+	filter     *chezmoi.EntryTypeFilter
+	init       bool
+	recursive  bool
+	timings    bool
+	watch      bool
 }
 
 func (c *Config) newApplyCmd() *cobra.Command {
 	applyCmd := &cobra.Command{
 		Use:               "apply [target]...",
-		Short:             "Update the destination directory to match the target state",
+		Short:             mustShortHelp("apply"),
 		Long:              mustLongHelp("apply"),
 		Example:           example("apply"),
 		ValidArgsFunction: c.targetValidArgs,
@@ -27,21 +44,229 @@ func (c *Config) newApplyCmd() *cobra.Command {
 		),
 	}
 
-	applyCmd.Flags().VarP(c.apply.filter.Exclude, "exclude", "x", "Exclude entry types")
-	applyCmd.Flags().VarP(c.apply.filter.Include, "include", "i", "Include entry types")
+	applyCmd.Flags().BoolVar(&c.apply.allowDirty, "allow-dirty", c.apply.allowDirty, "")
+	applyCmd.Flags().VarP(c.apply.filter.Exclude, "exclude", "x", "")
+	applyCmd.Flags().VarP(c.apply.filter.Include, "include", "i", "")
 	applyCmd.Flags().BoolVar(&c.apply.init, "init", c.apply.init, "Recreate config file from template")
+	applyCmd.Flags().Var(&c.Mtime, "mtime", "")
 	applyCmd.Flags().BoolVarP(&c.apply.recursive, "recursive", "r", c.apply.recursive, "Recurse into subdirectories")
+	applyCmd.Flags().BoolVar(&c.apply.timings, "timings", c.apply.timings, "")
+	applyCmd.Flags().BoolVar(&c.apply.watch, "watch", c.apply.watch, "Apply on changes to the source directory")
 
 	return applyCmd
 }
 
 func (c *Config) runApplyCmd(cmd *cobra.Command, args []string) error {
-	return c.applyArgs(cmd.Context(), c.destSystem, c.DestDirAbsPath, args, applyArgsOptions{
-		cmd:          cmd,
-		filter:       c.apply.filter,
-		init:         c.apply.init,
-		recursive:    c.apply.recursive,
-		umask:        c.Umask,
-		preApplyFunc: c.defaultPreApplyFunc,
+	if c.apply.watch {
+		return c.watchApply(cmd, args)
+	}
+	return c.applyTargets(cmd, args)
+}
+
+// applyTargets applies args, printing the timings if requested.
+func (c *Config) applyTargets(cmd *cobra.Command, args []string) error {
+	var timings *timings
+	var timingsFunc chezmoi.ApplyTimingsFunc
+	if c.apply.timings {
+		timings = newTimings()
+		timingsFunc = timings.applyTimingsFunc
+		c.timeSecretTemplateFuncs(timings)
+	}
+
+	err := c.applyArgs(cmd.Context(), c.destSystem, c.DestDirAbsPath, args, applyArgsOptions{
+		cmd:           cmd,
+		filter:        c.apply.filter,
+		importGPGKeys: true,
+		init:          c.apply.init,
+		recursive:     c.apply.recursive,
+		umask:         c.Umask,
+		mtimeFunc:     c.applyMtimeFunc(),
+		preApplyFunc:  c.defaultPreApplyFunc,
+		requireClean:  !c.apply.allowDirty,
+		targetHooks:   true,
+		timingsFunc:   timingsFunc,
+	})
+
+	// Print the timings even if applying failed, as a slow or failing target
+	// is often the reason for looking at them.
+	if timings != nil {
+		if err := timings.write(c.stdout); err != nil {
+			return err
+		}
+	}
+
+	return err
+}
+
+// watchApply applies args and then watches the source directory and source
+// layers, applying the targets affected by each change, until cmd's context is
+// done. Errors when
+// applying or watching are printed, rather than returned, so that they can be
+// fixed without restarting. The persistent state is closed between applies so
+// that other chezmoi commands can use it.
+func (c *Config) watchApply(cmd *cobra.Command, args []string) error {
+	sourceDirAbsPath, err := c.getSourceDirAbsPath(nil)
+	if err != nil {
+		return err
+	}
+	sourceLayerDirAbsPaths, err := c.getSourceLayerDirAbsPaths()
+	if err != nil {
+		return err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	// Watch the source layers before the source directory, in the same order
+	// as they are read, so that changes in a source layer inside the source
+	// directory are attributed to the source layer.
+	dirAbsPaths := append(slices.Clone(sourceLayerDirAbsPaths), sourceDirAbsPath)
+	rawDirAbsPaths := make([]chezmoi.AbsPath, 0, len(dirAbsPaths))
+	for _, dirAbsPath := range dirAbsPaths {
+		rawDirAbsPath, err := c.baseSystem.RawPath(dirAbsPath)
+		if err != nil {
+			return err
+		}
+		if err := c.watchDirs(watcher, rawDirAbsPath); err != nil {
+			return err
+		}
+		rawDirAbsPaths = append(rawDirAbsPaths, rawDirAbsPath)
+	}
+
+	applyTargets := func(args []string) error {
+		if err := c.applyTargets(cmd, args); err != nil {
+			c.errorf("%v\n", err)
+		}
+		return c.persistentState.Close()
+	}
+
+	if err := applyTargets(args); err != nil {
+		return err
+	}
+
+	changedAbsPaths := make(map[chezmoi.AbsPath]struct{})
+	timer := time.NewTimer(applyWatchDelay)
+	timer.Stop()
+	for {
+		select {
+		case <-cmd.Context().Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			c.logger.Debug("watcher.Events", slog.String("Name", event.Name), chezmoilog.Stringer("Op", event.Op))
+			absPath := chezmoi.NewAbsPath(event.Name)
+			changedAbsPath, ok := watchChangedAbsPath(dirAbsPaths, rawDirAbsPaths, absPath)
+			if !ok {
+				continue
+			}
+			// Watch new directories, as fsnotify does not watch directories
+			// recursively.
+			if event.Has(fsnotify.Create) {
+				if fileInfo, err := c.baseSystem.Lstat(absPath); err == nil && fileInfo.IsDir() {
+					if err := c.watchDirs(watcher, absPath); err != nil {
+						return err
+					}
+				}
+			}
+			changedAbsPaths[changedAbsPath] = struct{}{}
+			timer.Reset(applyWatchDelay)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			c.errorf("%v\n", err)
+		case <-timer.C:
+			c.resetSourceState()
+			c.templateFuncResults.reset()
+			targetArgs := args
+			if len(args) == 0 {
+				sourceState, err := c.getSourceState(cmd.Context(), cmd)
+				if err != nil {
+					c.errorf("%v\n", err)
+					if err := c.persistentState.Close(); err != nil {
+						return err
+					}
+					continue
+				}
+				targetRelPaths, all := watchTargetRelPaths(sourceState, changedAbsPaths)
+				if !all {
+					targetArgs = make([]string, 0, len(targetRelPaths))
+					for _, targetRelPath := range targetRelPaths {
+						targetArgs = append(targetArgs, c.DestDirAbsPath.Join(targetRelPath).String())
+					}
+				}
+			}
+			clear(changedAbsPaths)
+			if err := applyTargets(targetArgs); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// watchDirs adds dirAbsPath and all its subdirectories, except .git
+// directories, to watcher.
+func (c *Config) watchDirs(watcher *fsnotify.Watcher, dirAbsPath chezmoi.AbsPath) error {
+	return chezmoi.Walk(c.baseSystem, dirAbsPath, func(absPath chezmoi.AbsPath, fileInfo fs.FileInfo, err error) error {
+		switch {
+		case err != nil:
+			return err
+		case !fileInfo.IsDir():
+			return nil
+		case fileInfo.Name() == ".git":
+			return fs.SkipDir
+		default:
+			return watcher.Add(absPath.String())
+		}
+	})
+}
+
+// watchChangedAbsPath returns the path of rawAbsPath, reported by the watcher,
+// in the first of dirAbsPaths that contains it, given the raw paths of
+// dirAbsPaths in rawDirAbsPaths. It returns false if rawAbsPath is not in any of
+// them or is in a .git directory.
+func watchChangedAbsPath(dirAbsPaths, rawDirAbsPaths []chezmoi.AbsPath, rawAbsPath chezmoi.AbsPath) (chezmoi.AbsPath, bool) {
+	for i, rawDirAbsPath := range rawDirAbsPaths {
+		relPath, err := rawAbsPath.TrimDirPrefix(rawDirAbsPath)
+		switch {
+		case err != nil:
+			continue
+		case slices.Contains(strings.Split(relPath.String(), "/"), ".git"):
+			return chezmoi.EmptyAbsPath, false
+		default:
+			return dirAbsPaths[i].Join(relPath), true
+		}
+	}
+	return chezmoi.EmptyAbsPath, false
+}
+
+// watchTargetRelPaths returns the targets whose source files are
+// changedSourceAbsPaths, or all if a change might affect any target, for
+// example a change to a template, to the template data, or the removal of a
+// source file. Changes are matched by the origin of each source state entry, so
+// a change to a file in one source layer does not match a file with the same
+// name in another.
+func watchTargetRelPaths(
+	sourceState *chezmoi.SourceState,
+	changedSourceAbsPaths map[chezmoi.AbsPath]struct{},
+) (targetRelPaths chezmoi.RelPaths, all bool) {
+	targetRelPathsByOrigin := make(map[chezmoi.AbsPath]chezmoi.RelPath)
+	_ = sourceState.ForEach(func(targetRelPath chezmoi.RelPath, sourceStateEntry chezmoi.SourceStateEntry) error {
+		targetRelPathsByOrigin[sourceStateEntry.Origin().Path()] = targetRelPath
+		return nil
 	})
+	for changedSourceAbsPath := range changedSourceAbsPaths {
+		targetRelPath, ok := targetRelPathsByOrigin[changedSourceAbsPath]
+		if !ok {
+			return nil, true
+		}
+		targetRelPaths = append(targetRelPaths, targetRelPath)
+	}
+	sort.Sort(targetRelPaths)
+	return targetRelPaths, false
 }
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"io/fs"
 	"net/http"
 	"net/http/httptest"
@@ -11,6 +12,7 @@ import (
 	"github.com/twpayne/go-vfs/v5"
 	"github.com/twpayne/go-vfs/v5/vfst"
 
+	"github.com/twpayne/chezmoi/v2/internal/chezmoi"
 	"github.com/twpayne/chezmoi/v2/internal/chezmoitest"
 )
 
@@ -323,3 +325,84 @@ func TestIssue3703(t *testing.T) {
 		)
 	})
 }
+
+func TestWatchTargetRelPaths(t *testing.T) {
+	for _, tc := range []struct {
+		name                   string
+		changedSourceAbsPaths  []string
+		expectedTargetRelPaths chezmoi.RelPaths
+		expectedAll            bool
+	}{
+		{
+			name:                   "file",
+			changedSourceAbsPaths:  []string{"/home/user/.local/share/chezmoi/dot_file"},
+			expectedTargetRelPaths: chezmoi.RelPaths{chezmoi.NewRelPath(".file")},
+		},
+		{
+			name: "files",
+			changedSourceAbsPaths: []string{
+				"/home/user/.local/share/chezmoi/dot_dir/file.tmpl",
+				"/home/user/.local/share/chezmoi/dot_file",
+			},
+			expectedTargetRelPaths: chezmoi.RelPaths{
+				chezmoi.NewRelPath(".dir/file"),
+				chezmoi.NewRelPath(".file"),
+			},
+		},
+		{
+			name: "template",
+			changedSourceAbsPaths: []string{
+				"/home/user/.local/share/chezmoi/.chezmoitemplates/template",
+				"/home/user/.local/share/chezmoi/dot_file",
+			},
+			expectedAll: true,
+		},
+		{
+			name:                  "removed",
+			changedSourceAbsPaths: []string{"/home/user/.local/share/chezmoi/dot_removed"},
+			expectedAll:           true,
+		},
+		{
+			name:                   "layer",
+			changedSourceAbsPaths:  []string{"/home/user/layer/dot_layer"},
+			expectedTargetRelPaths: chezmoi.RelPaths{chezmoi.NewRelPath(".layer")},
+		},
+		{
+			name:                  "overridden_layer",
+			changedSourceAbsPaths: []string{"/home/user/layer/dot_file"},
+			expectedAll:           true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			chezmoitest.WithTestFS(t, map[string]any{
+				"/home/user/.local/share/chezmoi": map[string]any{
+					".chezmoitemplates/template": "",
+					"dot_dir/file.tmpl":          "",
+					"dot_file":                   "",
+				},
+				"/home/user/layer": map[string]any{
+					"dot_file":  "",
+					"dot_layer": "",
+				},
+			}, func(fileSystem vfs.FS) {
+				system := chezmoi.NewRealSystem(fileSystem)
+				sourceState := chezmoi.NewSourceState(
+					chezmoi.WithBaseSystem(system),
+					chezmoi.WithDestDir(chezmoi.NewAbsPath("/home/user")),
+					chezmoi.WithLayerDirs([]chezmoi.AbsPath{chezmoi.NewAbsPath("/home/user/layer")}),
+					chezmoi.WithSourceDir(chezmoi.NewAbsPath("/home/user/.local/share/chezmoi")),
+					chezmoi.WithSystem(system),
+				)
+				assert.NoError(t, sourceState.Read(context.Background(), nil))
+
+				changedSourceAbsPaths := make(map[chezmoi.AbsPath]struct{})
+				for _, changedSourceAbsPath := range tc.changedSourceAbsPaths {
+					changedSourceAbsPaths[chezmoi.NewAbsPath(changedSourceAbsPath)] = struct{}{}
+				}
+				actualTargetRelPaths, actualAll := watchTargetRelPaths(sourceState, changedSourceAbsPaths)
+				assert.Equal(t, tc.expectedTargetRelPaths, actualTargetRelPaths)
+				assert.Equal(t, tc.expectedAll, actualAll)
+			})
+		})
+	}
+}
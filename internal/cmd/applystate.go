@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/twpayne/chezmoi/v2/internal/chezmoi"
+	"github.com/twpayne/chezmoi/v2/internal/chezmoiset"
+)
+
+// applyStateBucket is the bucket for recording targets that applies have
+// started to change but not yet finished applying.
+var applyStateBucket = []byte("applyState")
+
+// An applyState records a target that an apply has started to change, keyed by
+// the target's absolute path, so that an apply that was interrupted, for
+// example by a crash or a power failure, can be detected and completed the
+// next time that chezmoi applies to the same directory.
+type applyState struct {
+	StartTime time.Time `json:"startTime" yaml:"startTime"`
+}
+
+// An applyJournal records the targets of an apply to a directory that are
+// pending in the persistent state. A target is recorded as pending just before
+// it is first changed and its record is removed once it has been applied, so
+// applies that do not change anything do not modify the persistent state and
+// the records of targets that are skipped or fail to apply are kept.
+type applyJournal struct {
+	c                          *Config
+	targetDirAbsPath           chezmoi.AbsPath
+	persistentStateFileAbsPath chezmoi.AbsPath
+	state                      applyState
+	interrupted                chezmoi.RelPaths
+	pending                    chezmoiset.Set[chezmoi.RelPath]
+}
+
+// beginApplyJournal starts a journal for an apply to targetDirAbsPath. If a
+// previous apply to targetDirAbsPath was interrupted then it warns the user, as
+// the new apply will complete the interrupted targets.
+func (c *Config) beginApplyJournal(targetDirAbsPath chezmoi.AbsPath) (*applyJournal, error) {
+	persistentStateFileAbsPath, err := c.persistentStateFile()
+	if err != nil {
+		return nil, err
+	}
+	j := &applyJournal{
+		c:                          c,
+		targetDirAbsPath:           targetDirAbsPath,
+		persistentStateFileAbsPath: persistentStateFileAbsPath,
+		state: applyState{
+			StartTime: time.Now().UTC(),
+		},
+		pending: chezmoiset.New[chezmoi.RelPath](),
+	}
+
+	var startTime time.Time
+	if err := c.persistentState.ForEach(applyStateBucket, func(k, v []byte) error {
+		// Records of applies to other directories are ignored.
+		targetRelPath, trimErr := chezmoi.NewAbsPath(string(k)).TrimDirPrefix(targetDirAbsPath)
+		if trimErr != nil || targetRelPath.Empty() {
+			return nil
+		}
+		var state applyState
+		if err := json.Unmarshal(v, &state); err != nil {
+			return err
+		}
+		if startTime.IsZero() || state.StartTime.Before(startTime) {
+			startTime = state.StartTime
+		}
+		j.interrupted = append(j.interrupted, targetRelPath)
+		j.pending.Add(targetRelPath)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	if len(j.interrupted) != 0 {
+		sort.Sort(j.interrupted)
+		targetRelPathStrs := make([]string, 0, len(j.interrupted))
+		for _, targetRelPath := range j.interrupted {
+			targetRelPathStrs = append(targetRelPathStrs, targetRelPath.String())
+		}
+		c.warnf(
+			"%s: apply started at %s was interrupted before it finished applying %s, completing it now\n",
+			targetDirAbsPath, startTime.Format(time.RFC3339), strings.Join(targetRelPathStrs, ", "),
+		)
+	}
+
+	return j, nil
+}
+
+// preChangeFunc records that the apply is about to change targetRelPath. It is
+// a chezmoi.ApplyChangeFunc.
+func (j *applyJournal) preChangeFunc(targetRelPath chezmoi.RelPath, oldEntryState, newEntryState *chezmoi.EntryState) error {
+	if j.pending.Contains(targetRelPath) {
+		return nil
+	}
+	// Writing to the persistent state creates the directories that contain
+	// it, so do not record changes to them, as chezmoi is about to create
+	// them itself.
+	targetAbsPath := j.targetDirAbsPath.Join(targetRelPath)
+	if _, err := j.persistentStateFileAbsPath.TrimDirPrefix(targetAbsPath); err == nil {
+		return nil
+	}
+	if err := chezmoi.PersistentStateSet(j.c.persistentState, applyStateBucket, targetAbsPath.Bytes(), &j.state); err != nil {
+		return err
+	}
+	j.pending.Add(targetRelPath)
+	return nil
+}
+
+// done records that targetRelPath has been applied.
+func (j *applyJournal) done(targetRelPath chezmoi.RelPath) error {
+	if !j.pending.Contains(targetRelPath) {
+		return nil
+	}
+	if err := j.c.persistentState.Delete(applyStateBucket, j.targetDirAbsPath.Join(targetRelPath).Bytes()); err != nil {
+		return err
+	}
+	j.pending.Remove(targetRelPath)
+	return nil
+}
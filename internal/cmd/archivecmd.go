@@ -2,6 +2,8 @@ package cmd
 
 import (
 	"archive/tar"
+	"os"
+	"os/exec"
 	"os/user"
 	"strconv"
 	"strings"
@@ -11,6 +13,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/twpayne/chezmoi/v2/internal/chezmoi"
+	"github.com/twpayne/chezmoi/v2/internal/chezmoilog"
 )
 
 type archiveCmdConfig struct {
@@ -24,7 +27,7 @@ type archiveCmdConfig struct {
 func (c *Config) newArchiveCmd() *cobra.Command {
 	archiveCmd := &cobra.Command{
 		Use:               "archive [target]...",
-		Short:             "Generate a tar archive of the target state",
+		Short:             mustShortHelp("archive"),
 		Long:              mustLongHelp("archive"),
 		Example:           example("archive"),
 		ValidArgsFunction: c.targetValidArgs,
@@ -35,10 +38,10 @@ func (c *Config) newArchiveCmd() *cobra.Command {
 		),
 	}
 
-	archiveCmd.Flags().VarP(c.archive.filter.Exclude, "exclude", "x", "Exclude entry types")
-	archiveCmd.Flags().VarP(&c.archive.format, "format", "f", "Set archive format")
-	archiveCmd.Flags().BoolVarP(&c.archive.gzip, "gzip", "z", c.archive.gzip, "Compress output with gzip")
-	archiveCmd.Flags().VarP(c.archive.filter.Exclude, "include", "i", "Include entry types")
+	archiveCmd.Flags().VarP(c.archive.filter.Exclude, "exclude", "x", "")
+	archiveCmd.Flags().VarP(&c.archive.format, "format", "f", "")
+	archiveCmd.Flags().BoolVarP(&c.archive.gzip, "gzip", "z", c.archive.gzip, "")
+	archiveCmd.Flags().VarP(c.archive.filter.Include, "include", "i", "")
 	archiveCmd.Flags().BoolVar(&c.archive.init, "init", c.archive.init, "Recreate config file from template")
 	archiveCmd.Flags().BoolVarP(&c.archive.recursive, "recursive", "r", c.archive.recursive, "Recurse into subdirectories")
 
@@ -65,7 +68,7 @@ func (c *Config) runArchiveCmd(cmd *cobra.Command, args []string) error {
 		Close() error
 	}
 	switch format {
-	case chezmoi.ArchiveFormatTar, chezmoi.ArchiveFormatTarGz, chezmoi.ArchiveFormatTgz:
+	case chezmoi.ArchiveFormatTar, chezmoi.ArchiveFormatTarBz2, chezmoi.ArchiveFormatTarGz, chezmoi.ArchiveFormatTbz2, chezmoi.ArchiveFormatTgz:
 		archiveSystem = chezmoi.NewTarWriterSystem(&output, tarHeaderTemplate())
 	case chezmoi.ArchiveFormatZip:
 		archiveSystem = chezmoi.NewZIPWriterSystem(&output, time.Now().UTC())
@@ -84,7 +87,19 @@ func (c *Config) runArchiveCmd(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	if format == chezmoi.ArchiveFormatZip || !gzipOutput {
+	switch {
+	case format == chezmoi.ArchiveFormatTarBz2 || format == chezmoi.ArchiveFormatTbz2:
+		// The standard library does not include a bzip2 compressor, so use
+		// the bzip2 command.
+		bzip2Cmd := exec.Command("bzip2", "--compress", "--stdout")
+		bzip2Cmd.Stdin = strings.NewReader(output.String())
+		bzip2Cmd.Stderr = os.Stderr
+		bzip2edArchive, err := chezmoilog.LogCmdOutput(c.logger, bzip2Cmd)
+		if err != nil {
+			return newCmdOutputError(bzip2Cmd, bzip2edArchive, err)
+		}
+		return c.writeOutput(bzip2edArchive)
+	case format == chezmoi.ArchiveFormatZip || !gzipOutput:
 		return c.writeOutputString(output.String())
 	}
 
@@ -6,8 +6,6 @@ import (
 	"os"
 	"os/exec"
 	"strings"
-
-	"github.com/twpayne/chezmoi/v2/internal/chezmoilog"
 )
 
 type bitwardenSecretsConfig struct {
@@ -38,7 +36,7 @@ func (c *Config) bitwardenSecretsTemplateFunc(secretID string, additionalArgs ..
 
 func (c *Config) bitwardenSecretsOutput(args []string) ([]byte, error) {
 	key := strings.Join(args, "\x00")
-	if data, ok := c.Bitwarden.outputCache[key]; ok {
+	if data, ok := c.BitwardenSecrets.outputCache[key]; ok {
 		return data, nil
 	}
 
@@ -46,7 +44,7 @@ func (c *Config) bitwardenSecretsOutput(args []string) ([]byte, error) {
 	cmd := exec.Command(name, args...)
 	cmd.Stdin = os.Stdin
 	cmd.Stderr = os.Stderr
-	output, err := chezmoilog.LogCmdOutput(c.logger, cmd)
+	output, err := c.secretCmdOutput(cmd)
 	if err != nil {
 		return nil, newCmdOutputError(cmd, output, err)
 	}
@@ -5,8 +5,6 @@ import (
 	"os"
 	"os/exec"
 	"strings"
-
-	"github.com/twpayne/chezmoi/v2/internal/chezmoilog"
 )
 
 type bitwardenConfig struct {
@@ -78,7 +76,7 @@ func (c *Config) bitwardenOutput(args []string) ([]byte, error) {
 	cmd := exec.Command(name, args...)
 	cmd.Stdin = os.Stdin
 	cmd.Stderr = os.Stderr
-	output, err := chezmoilog.LogCmdOutput(c.logger, cmd)
+	output, err := c.secretCmdOutput(cmd)
 	if err != nil {
 		return nil, newCmdOutputError(cmd, output, err)
 	}
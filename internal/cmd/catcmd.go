@@ -9,10 +9,14 @@ import (
 	"github.com/twpayne/chezmoi/v2/internal/chezmoi"
 )
 
+type catCmdConfig struct {
+	noDecrypt bool
+}
+
 func (c *Config) newCatCmd() *cobra.Command {
 	catCmd := &cobra.Command{
 		Use:               "cat target...",
-		Short:             "Print the target contents of a file, script, or symlink",
+		Short:             mustShortHelp("cat"),
 		Long:              mustLongHelp("cat"),
 		Example:           example("cat"),
 		ValidArgsFunction: c.targetValidArgs,
@@ -23,18 +27,34 @@ func (c *Config) newCatCmd() *cobra.Command {
 		),
 	}
 
+	catCmd.Flags().BoolVar(&c.cat.noDecrypt, "no-decrypt", c.cat.noDecrypt, "")
+
 	return catCmd
 }
 
 func (c *Config) runCatCmd(cmd *cobra.Command, args []string, sourceState *chezmoi.SourceState) error {
-	targetRelPaths, err := c.targetRelPaths(sourceState, args, nil)
-	if err != nil {
-		return err
+	// Print the targets in the order in which they are given, like cat(1).
+	targetRelPaths := make([]chezmoi.RelPath, 0, len(args))
+	for _, arg := range args {
+		argTargetRelPaths, err := c.targetRelPaths(sourceState, []string{arg}, nil)
+		if err != nil {
+			return err
+		}
+		targetRelPaths = append(targetRelPaths, argTargetRelPaths...)
 	}
 
 	builder := strings.Builder{}
 	for _, targetRelPath := range targetRelPaths {
 		sourceStateEntry := sourceState.MustEntry(targetRelPath)
+		if sourceStateFile, ok := sourceStateEntry.(*chezmoi.SourceStateFile); ok && c.cat.noDecrypt &&
+			sourceStateFile.Attr.Encrypted {
+			ciphertext, err := c.sourceSystem.ReadFile(sourceStateFile.Origin().Path())
+			if err != nil {
+				return fmt.Errorf("%s: %w", targetRelPath, err)
+			}
+			builder.Write(ciphertext)
+			continue
+		}
 		targetStateEntry, err := sourceStateEntry.TargetStateEntry(c.destSystem, c.DestDirAbsPath.Join(targetRelPath))
 		if err != nil {
 			return fmt.Errorf("%s: %w", targetRelPath, err)
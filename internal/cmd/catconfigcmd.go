@@ -5,7 +5,7 @@ import "github.com/spf13/cobra"
 func (c *Config) newCatConfigCmd() *cobra.Command {
 	catConfigCmd := &cobra.Command{
 		Use:     "cat-config",
-		Short:   "Print the configuration file",
+		Short:   mustShortHelp("cat-config"),
 		Long:    mustLongHelp("cat-config"),
 		Example: example("cat-config"),
 		Args:    cobra.NoArgs,
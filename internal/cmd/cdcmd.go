@@ -18,7 +18,7 @@ type cdCmdConfig struct {
 func (c *Config) newCDCmd() *cobra.Command {
 	cdCmd := &cobra.Command{
 		Use:     "cd [path]",
-		Short:   "Launch a shell in the source directory",
+		Short:   mustShortHelp("cd"),
 		Long:    mustLongHelp("cd"),
 		Example: example("cd"),
 		RunE:    c.runCDCmd,
@@ -35,6 +35,11 @@ func (c *Config) newCDCmd() *cobra.Command {
 }
 
 func (c *Config) runCDCmd(cmd *cobra.Command, args []string) error {
+	// Nested subshells are easy to create by accident, as the prompt usually
+	// looks the same, so warn when the user is already in one.
+	if os.Getenv("CHEZMOI_SUBSHELL") != "" {
+		c.warnf("already in a chezmoi cd subshell, exit it to return to the previous shell\n")
+	}
 	os.Setenv("CHEZMOI_SUBSHELL", "1")
 
 	cdCommand, cdArgs, err := c.cdCommand()
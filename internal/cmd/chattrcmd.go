@@ -1,13 +1,16 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"sort"
 	"strings"
 
 	"github.com/spf13/cobra"
 
 	"github.com/twpayne/chezmoi/v2/internal/chezmoi"
+	"github.com/twpayne/chezmoi/v2/internal/chezmoierrors"
 )
 
 type chattrCmdConfig struct {
@@ -76,7 +79,7 @@ type modifier struct {
 func (c *Config) newChattrCmd() *cobra.Command {
 	chattrCmd := &cobra.Command{
 		Use:               "chattr attributes target...",
-		Short:             "Change the attributes of a target in the source state",
+		Short:             mustShortHelp("chattr"),
 		Long:              mustLongHelp("chattr"),
 		Example:           example("chattr"),
 		Args:              cobra.MinimumNArgs(2),
@@ -88,7 +91,7 @@ func (c *Config) newChattrCmd() *cobra.Command {
 		),
 	}
 
-	chattrCmd.Flags().BoolVarP(&c.chattr.recursive, "recursive", "r", c.chattr.recursive, "Recurse into subdirectories")
+	chattrCmd.Flags().BoolVarP(&c.chattr.recursive, "recursive", "r", c.chattr.recursive, "")
 
 	return chattrCmd
 }
@@ -151,6 +154,7 @@ func (c *Config) runChattrCmd(cmd *cobra.Command, args []string, sourceState *ch
 	}
 
 	targetRelPaths, err := c.targetRelPaths(sourceState, args[1:], &targetRelPathsOptions{
+		glob:      true,
 		recursive: c.chattr.recursive,
 	})
 	if err != nil {
@@ -161,6 +165,10 @@ func (c *Config) runChattrCmd(cmd *cobra.Command, args []string, sourceState *ch
 	// directories.
 	sort.Sort(sort.Reverse(targetRelPaths))
 
+	// Plan all changes before making any of them, so that an error, for
+	// example a failure to decrypt a file, leaves the source directory
+	// unchanged.
+	var changes []*chattrChange
 	encryptedSuffix := sourceState.Encryption().EncryptedSuffix()
 	for _, targetRelPath := range targetRelPaths {
 		sourceStateEntry := sourceState.MustEntry(targetRelPath)
@@ -168,36 +176,31 @@ func (c *Config) runChattrCmd(cmd *cobra.Command, args []string, sourceState *ch
 		parentSourceRelPath, fileSourceRelPath := sourceRelPath.Split()
 		parentRelPath := parentSourceRelPath.RelPath()
 		fileRelPath := fileSourceRelPath.RelPath()
+		change := &chattrChange{
+			verb:             "rename",
+			sourceDirAbsPath: sourceState.SourceDirAbsPathOf(sourceStateEntry),
+			oldSourceRelPath: parentRelPath.Join(fileRelPath),
+		}
 		switch sourceStateEntry := sourceStateEntry.(type) {
 		case *chezmoi.SourceStateDir:
-			relPath := m.modifyDirAttr(sourceStateEntry.Attr).SourceName()
-			if newBaseNameRelPath := chezmoi.NewRelPath(relPath); newBaseNameRelPath != fileRelPath {
-				oldSourceAbsPath := c.SourceDirAbsPath.Join(parentRelPath, fileRelPath)
-				newSourceAbsPath := c.SourceDirAbsPath.Join(parentRelPath, newBaseNameRelPath)
-				if err := c.sourceSystem.Rename(oldSourceAbsPath, newSourceAbsPath); err != nil {
-					return err
-				}
+			newBaseNameRelPath := chezmoi.NewRelPath(m.modifyDirAttr(sourceStateEntry.Attr).SourceName())
+			if newBaseNameRelPath == fileRelPath {
+				continue
 			}
+			change.newSourceRelPath = parentRelPath.Join(newBaseNameRelPath)
 		case *chezmoi.SourceStateFile:
 			newAttr := m.modifyFileAttr(sourceStateEntry.Attr)
 			newBaseNameRelPath := chezmoi.NewRelPath(newAttr.SourceName(encryptedSuffix))
-			oldSourceAbsPath := c.SourceDirAbsPath.Join(parentRelPath, fileRelPath)
-			newSourceAbsPath := c.SourceDirAbsPath.Join(parentRelPath, newBaseNameRelPath)
+			change.newSourceRelPath = parentRelPath.Join(newBaseNameRelPath)
 			switch encryptedBefore, encryptedAfter := sourceStateEntry.Attr.Encrypted, newAttr.Encrypted; {
 			case encryptedBefore && !encryptedAfter:
-				// Write the plaintext and then remove the ciphertext.
 				plaintext, err := sourceStateEntry.Contents()
 				if err != nil {
 					return err
 				}
-				if err := c.sourceSystem.WriteFile(newSourceAbsPath, plaintext, 0o666&^c.Umask); err != nil {
-					return err
-				}
-				if err := c.sourceSystem.Remove(oldSourceAbsPath); err != nil {
-					return err
-				}
+				change.verb = "decrypt"
+				change.newContents = plaintext
 			case !encryptedBefore && encryptedAfter:
-				// Write the ciphertext and then remove the plaintext.
 				plaintext, err := sourceStateEntry.Contents()
 				if err != nil {
 					return err
@@ -206,24 +209,95 @@ func (c *Config) runChattrCmd(cmd *cobra.Command, args []string, sourceState *ch
 				if err != nil {
 					return err
 				}
-				if err := c.sourceSystem.WriteFile(newSourceAbsPath, ciphertext, 0o666&^c.Umask); err != nil {
-					return err
-				}
-				if err := c.sourceSystem.Remove(oldSourceAbsPath); err != nil {
-					return err
-				}
-			case newBaseNameRelPath != fileRelPath:
-				// Contents have not changed so a rename is sufficient.
-				if err := c.sourceSystem.Rename(oldSourceAbsPath, newSourceAbsPath); err != nil {
+				change.verb = "encrypt"
+				change.newContents = ciphertext
+			case newBaseNameRelPath == fileRelPath:
+				continue
+			}
+			if change.newContents != nil {
+				oldContents, err := c.sourceSystem.ReadFile(change.sourceDirAbsPath.Join(change.oldSourceRelPath))
+				if err != nil {
 					return err
 				}
+				change.oldContents = oldContents
+			}
+		default:
+			continue
+		}
+		if change.newSourceRelPath != change.oldSourceRelPath {
+			switch _, err := c.sourceSystem.Lstat(change.sourceDirAbsPath.Join(change.newSourceRelPath)); {
+			case err == nil:
+				return fmt.Errorf("%s: %s already exists", targetRelPath, change.newSourceRelPath)
+			case !errors.Is(err, fs.ErrNotExist):
+				return err
 			}
 		}
+		changes = append(changes, change)
+	}
+
+	if c.dryRun && !c.Verbose {
+		var builder strings.Builder
+		for _, change := range changes {
+			fmt.Fprintf(&builder, "%s %s %s\n", change.verb, change.oldSourceRelPath, change.newSourceRelPath)
+		}
+		return c.writeOutputString(builder.String())
+	}
+
+	perm := 0o666 &^ c.Umask
+	for i, change := range changes {
+		if err := change.apply(c.sourceSystem, perm); err != nil {
+			// Undo the changes already made so that the source directory is
+			// left as it was.
+			for j := i - 1; j >= 0; j-- {
+				err = chezmoierrors.Combine(err, changes[j].undo(c.sourceSystem, perm))
+			}
+			return err
+		}
 	}
 
 	return nil
 }
 
+// A chattrChange is a change to an entry in the source directory or a source
+// layer directory. If newContents is nil then the entry is renamed, otherwise
+// its contents are replaced.
+type chattrChange struct {
+	verb             string
+	sourceDirAbsPath chezmoi.AbsPath
+	oldSourceRelPath chezmoi.RelPath
+	newSourceRelPath chezmoi.RelPath
+	oldContents      []byte
+	newContents      []byte
+}
+
+// apply makes c in system.
+func (c *chattrChange) apply(system chezmoi.System, perm fs.FileMode) error {
+	return c.replace(system, c.sourceDirAbsPath.Join(c.oldSourceRelPath), c.sourceDirAbsPath.Join(c.newSourceRelPath), c.newContents, perm)
+}
+
+// undo reverts c in system.
+func (c *chattrChange) undo(system chezmoi.System, perm fs.FileMode) error {
+	return c.replace(system, c.sourceDirAbsPath.Join(c.newSourceRelPath), c.sourceDirAbsPath.Join(c.oldSourceRelPath), c.oldContents, perm)
+}
+
+// replace replaces fromAbsPath with toAbsPath, with contents if contents is
+// non-nil.
+func (c *chattrChange) replace(
+	system chezmoi.System,
+	fromAbsPath, toAbsPath chezmoi.AbsPath,
+	contents []byte,
+	perm fs.FileMode,
+) error {
+	if contents == nil {
+		return system.Rename(fromAbsPath, toAbsPath)
+	}
+	// Write the new file and then remove the old one.
+	if err := system.WriteFile(toAbsPath, contents, perm); err != nil {
+		return err
+	}
+	return system.Remove(fromAbsPath)
+}
+
 // modify returns the modified value of b.
 func (m boolModifier) modify(b bool) bool {
 	switch m {
@@ -6,6 +6,11 @@ import (
 
 	"github.com/alecthomas/assert/v2"
 	"github.com/spf13/cobra"
+	"github.com/twpayne/go-vfs/v5"
+	"github.com/twpayne/go-vfs/v5/vfst"
+
+	"github.com/twpayne/chezmoi/v2/internal/chezmoi"
+	"github.com/twpayne/chezmoi/v2/internal/chezmoitest"
 )
 
 func TestChattrCmdValidArgs(t *testing.T) {
@@ -234,3 +239,67 @@ func TestParseAttrModifier(t *testing.T) {
 		})
 	}
 }
+
+func TestChattrChangeApplyUndo(t *testing.T) {
+	chezmoitest.WithTestFS(t, map[string]any{
+		"/home/user/.local/share/chezmoi": map[string]any{
+			"dot_file":     "# contents of .file\n",
+			"dot_password": "plaintext\n",
+		},
+	}, func(fileSystem vfs.FS) {
+		system := chezmoi.NewRealSystem(fileSystem)
+		sourceDirAbsPath := chezmoi.NewAbsPath("/home/user/.local/share/chezmoi")
+		changes := []*chattrChange{
+			{
+				verb:             "rename",
+				sourceDirAbsPath: sourceDirAbsPath,
+				oldSourceRelPath: chezmoi.NewRelPath("dot_file"),
+				newSourceRelPath: chezmoi.NewRelPath("private_dot_file"),
+			},
+			{
+				verb:             "encrypt",
+				sourceDirAbsPath: sourceDirAbsPath,
+				oldSourceRelPath: chezmoi.NewRelPath("dot_password"),
+				newSourceRelPath: chezmoi.NewRelPath("encrypted_dot_password.age"),
+				oldContents:      []byte("plaintext\n"),
+				newContents:      []byte("ciphertext\n"),
+			},
+		}
+
+		for _, change := range changes {
+			assert.NoError(t, change.apply(system, 0o666))
+		}
+		vfst.RunTests(t, fileSystem, "",
+			vfst.TestPath("/home/user/.local/share/chezmoi/dot_file",
+				vfst.TestDoesNotExist(),
+			),
+			vfst.TestPath("/home/user/.local/share/chezmoi/private_dot_file",
+				vfst.TestContentsString("# contents of .file\n"),
+			),
+			vfst.TestPath("/home/user/.local/share/chezmoi/dot_password",
+				vfst.TestDoesNotExist(),
+			),
+			vfst.TestPath("/home/user/.local/share/chezmoi/encrypted_dot_password.age",
+				vfst.TestContentsString("ciphertext\n"),
+			),
+		)
+
+		for i := len(changes) - 1; i >= 0; i-- {
+			assert.NoError(t, changes[i].undo(system, 0o666))
+		}
+		vfst.RunTests(t, fileSystem, "",
+			vfst.TestPath("/home/user/.local/share/chezmoi/dot_file",
+				vfst.TestContentsString("# contents of .file\n"),
+			),
+			vfst.TestPath("/home/user/.local/share/chezmoi/private_dot_file",
+				vfst.TestDoesNotExist(),
+			),
+			vfst.TestPath("/home/user/.local/share/chezmoi/dot_password",
+				vfst.TestContentsString("plaintext\n"),
+			),
+			vfst.TestPath("/home/user/.local/share/chezmoi/encrypted_dot_password.age",
+				vfst.TestDoesNotExist(),
+			),
+		)
+	})
+}
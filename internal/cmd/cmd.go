@@ -14,6 +14,7 @@ import (
 
 	"github.com/charmbracelet/glamour"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"go.etcd.io/bbolt"
 
 	"github.com/twpayne/chezmoi/v2/assets/chezmoi.io/docs/reference/commands"
@@ -28,6 +29,12 @@ var (
 	noArgs = []string(nil)
 
 	deDuplicateErrorRx = regexp.MustCompile(`:\s+`)
+	helpBoldRx         = regexp.MustCompile(`\*\*(.+?)\*\*`)
+	helpCodeSpanRx     = regexp.MustCompile("`([^`]+)`")
+	helpFlagRx         = regexp.MustCompile("`--([0-9a-z-]+)`")
+	helpItalicRx       = regexp.MustCompile(`\*([^*\s][^*]*?)\*`)
+	helpLinkRx         = regexp.MustCompile(`\[([^\]]+)\]\([^)]+\)`)
+	helpSentenceEndRx  = regexp.MustCompile(`\.\s`)
 	trailingSpaceRx    = regexp.MustCompile(` +\n`)
 
 	helps = make(map[string]*help)
@@ -42,8 +49,10 @@ type VersionInfo struct {
 }
 
 type help struct {
+	short    string
 	longHelp string
 	example  string
+	flags    map[string]string
 }
 
 func init() {
@@ -52,28 +61,7 @@ func init() {
 		panic(err)
 	}
 
-	longHelpStyleConfig := glamour.ASCIIStyleConfig
-	longHelpStyleConfig.Code.StylePrimitive.BlockPrefix = ""
-	longHelpStyleConfig.Code.StylePrimitive.BlockSuffix = ""
-	longHelpStyleConfig.Emph.BlockPrefix = ""
-	longHelpStyleConfig.Emph.BlockSuffix = ""
-	longHelpStyleConfig.H2.Prefix = ""
-	longHelpTermRenderer, err := glamour.NewTermRenderer(
-		glamour.WithStyles(longHelpStyleConfig),
-		glamour.WithWordWrap(80),
-	)
-	if err != nil {
-		panic(err)
-	}
-
-	exampleStyleConfig := glamour.ASCIIStyleConfig
-	exampleStyleConfig.Code.StylePrimitive.BlockPrefix = ""
-	exampleStyleConfig.Code.StylePrimitive.BlockSuffix = ""
-	exampleStyleConfig.Document.Margin = nil
-	exampleTermRenderer, err := glamour.NewTermRenderer(
-		glamour.WithStyles(exampleStyleConfig),
-		glamour.WithWordWrap(80),
-	)
+	longHelpTermRenderer, exampleTermRenderer, err := newHelpTermRenderers()
 	if err != nil {
 		panic(err)
 	}
@@ -129,6 +117,36 @@ func deDuplicateError(err error) string {
 	return strings.Join(uniqueComponents, ": ")
 }
 
+// newHelpTermRenderers returns the renderers for long help and examples.
+func newHelpTermRenderers() (*glamour.TermRenderer, *glamour.TermRenderer, error) {
+	longHelpStyleConfig := glamour.ASCIIStyleConfig
+	longHelpStyleConfig.Code.StylePrimitive.BlockPrefix = ""
+	longHelpStyleConfig.Code.StylePrimitive.BlockSuffix = ""
+	longHelpStyleConfig.Emph.BlockPrefix = ""
+	longHelpStyleConfig.Emph.BlockSuffix = ""
+	longHelpStyleConfig.H2.Prefix = ""
+	longHelpTermRenderer, err := glamour.NewTermRenderer(
+		glamour.WithStyles(longHelpStyleConfig),
+		glamour.WithWordWrap(80),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	exampleStyleConfig := glamour.ASCIIStyleConfig
+	exampleStyleConfig.Code.StylePrimitive.BlockPrefix = ""
+	exampleStyleConfig.Code.StylePrimitive.BlockSuffix = ""
+	exampleStyleConfig.Document.Margin = nil
+	exampleTermRenderer, err := glamour.NewTermRenderer(
+		glamour.WithStyles(exampleStyleConfig),
+		glamour.WithWordWrap(80),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	return longHelpTermRenderer, exampleTermRenderer, nil
+}
+
 // example returns command's example.
 func example(command string) string {
 	help, ok := helps[command]
@@ -152,6 +170,18 @@ func extractHelp(command string, data []byte, longHelpTermRenderer, exampleTermR
 	state := stateReadTitle
 	var longHelpLines []string
 	var exampleLines []string
+	flags := make(map[string]string)
+	var flagNames []string
+	var flagLines []string
+	flushFlag := func() {
+		if summary := helpSummary(flagLines); summary != "" {
+			for _, flagName := range flagNames {
+				flags[flagName] = summary
+			}
+		}
+		flagNames = nil
+		flagLines = nil
+	}
 	for _, line := range strings.Split(string(data), "\n") {
 		switch state {
 		case stateReadTitle:
@@ -166,6 +196,7 @@ func extractHelp(command string, data []byte, longHelpTermRenderer, exampleTermR
 			switch {
 			case strings.HasPrefix(line, "## "):
 				state = stateInOptions
+				flagNames = helpFlagNames(line)
 			case line == "!!! example":
 				state = stateInExample
 			case strings.HasPrefix(line, "!!!"):
@@ -174,17 +205,35 @@ func extractHelp(command string, data []byte, longHelpTermRenderer, exampleTermR
 				longHelpLines = append(longHelpLines, line)
 			}
 		case stateInOptions:
-			if line == "!!! example" {
+			switch {
+			case line == "!!! example":
+				flushFlag()
 				state = stateInExample
+			case strings.HasPrefix(line, "## "):
+				flushFlag()
+				flagNames = helpFlagNames(line)
+			case strings.TrimSpace(line) == "":
+				if len(flagLines) != 0 {
+					flushFlag()
+				}
+			case strings.HasPrefix(line, ">"):
+				// Skip configuration and default value notes.
+			case len(flagNames) != 0:
+				flagLines = append(flagLines, line)
 			}
 		case stateInExample:
 			exampleLines = append(exampleLines, strings.TrimPrefix(line, "    "))
 		case stateInAdmonition:
-			if line == "!!! example" {
+			switch {
+			case line == "!!! example":
 				state = stateInExample
+			case strings.HasPrefix(line, "## "):
+				state = stateInOptions
+				flagNames = helpFlagNames(line)
 			}
 		}
 	}
+	flushFlag()
 
 	longHelp, err := renderLines(longHelpLines, longHelpTermRenderer)
 	if err != nil {
@@ -195,11 +244,59 @@ func extractHelp(command string, data []byte, longHelpTermRenderer, exampleTermR
 		return nil, err
 	}
 	return &help{
+		short:    helpSummary(longHelpLines),
 		longHelp: "Description:\n" + longHelp,
 		example:  example,
+		flags:    flags,
 	}, nil
 }
 
+// helpFlagNames returns the long flag names documented in the option heading
+// line.
+func helpFlagNames(line string) []string {
+	var flagNames []string
+	for _, match := range helpFlagRx.FindAllStringSubmatch(line, -1) {
+		flagNames = append(flagNames, match[1])
+	}
+	return flagNames
+}
+
+// helpSummary returns the first sentence of the first paragraph in lines as
+// plain text, without a trailing period.
+func helpSummary(lines []string) string {
+	var paragraph []string
+FOR:
+	for _, line := range lines {
+		switch {
+		case strings.TrimSpace(line) == "":
+			if len(paragraph) != 0 {
+				break FOR
+			}
+		case strings.HasPrefix(line, ">"):
+			if len(paragraph) != 0 {
+				break FOR
+			}
+		case strings.HasPrefix(line, "```"), strings.HasPrefix(line, "|"), strings.HasPrefix(line, "    "):
+			break FOR
+		default:
+			paragraph = append(paragraph, strings.TrimSpace(line))
+		}
+	}
+	text := strings.Join(paragraph, " ")
+	for _, loc := range helpSentenceEndRx.FindAllStringIndex(text, -1) {
+		if sentence := text[:loc[0]]; !strings.HasSuffix(sentence, ".") &&
+			!strings.HasSuffix(sentence, "e.g") && !strings.HasSuffix(sentence, "i.e") {
+			text = sentence
+			break
+		}
+	}
+	text = helpLinkRx.ReplaceAllString(text, "$1")
+	text = helpCodeSpanRx.ReplaceAllString(text, "$1")
+	text = helpBoldRx.ReplaceAllString(text, "$1")
+	text = helpItalicRx.ReplaceAllString(text, "$1")
+	return strings.TrimSuffix(text, ".")
+}
+
 // renderLines renders lines, trimming extraneous whitespace.
 func renderLines(lines []string, termRenderer *glamour.TermRenderer) (string, error) {
 	renderedLines, err := termRenderer.Render(strings.Join(lines, "\n"))
@@ -211,6 +308,27 @@ func renderLines(lines []string, termRenderer *glamour.TermRenderer) (string, er
 	return renderedLines, nil
 }
 
+// applyHelp sets the usage of cmd's flags from its reference documentation,
+// where documented. Documented flags are registered with an empty usage so that
+// the reference documentation is the only source of their usage, and it panics
+// if one is not.
+func applyHelp(cmd *cobra.Command) {
+	help, ok := helps[cmd.Name()]
+	if !ok {
+		return
+	}
+	cmd.NonInheritedFlags().VisitAll(func(flag *pflag.Flag) {
+		usage, ok := help.flags[flag.Name]
+		if !ok {
+			return
+		}
+		if flag.Usage != "" {
+			panic(cmd.Name() + ": --" + flag.Name + ": usage is documented in the reference")
+		}
+		flag.Usage = usage
+	})
+}
+
 // markFlagsRequired marks all of flags as required for cmd.
 func markFlagsRequired(cmd *cobra.Command, flags ...string) {
 	for _, flag := range flags {
@@ -220,6 +338,16 @@ func markFlagsRequired(cmd *cobra.Command, flags ...string) {
 	}
 }
 
+// mustShortHelp returns the short help for command or panics if no short help
+// exists.
+func mustShortHelp(command string) string {
+	help, ok := helps[command]
+	if !ok || help.short == "" {
+		panic(command + ": missing short help")
+	}
+	return help.short
+}
+
 // mustLongHelp returns the long help for command or panics if no long help
 // exists.
 func mustLongHelp(command string) string {
@@ -2,15 +2,26 @@ package cmd
 
 import (
 	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/alecthomas/assert/v2"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	vfs "github.com/twpayne/go-vfs/v5"
 
 	"github.com/twpayne/chezmoi/v2/internal/chezmoi"
 	"github.com/twpayne/chezmoi/v2/internal/chezmoitest"
 )
 
+var update = flag.Bool("update", false, "update golden files")
+
 func init() {
 	// github.com/twpayne/chezmoi/v2/internal/chezmoi reads the umask before
 	// github.com/twpayne/chezmoi/v2/internal/chezmoitest sets it, so update it.
@@ -62,8 +73,130 @@ func TestDeDuplicateError(t *testing.T) {
 	}
 }
 
+func TestHelpSummary(t *testing.T) {
+	for i, tc := range []struct {
+		lines    []string
+		expected string
+	}{
+		{
+			lines:    nil,
+			expected: "",
+		},
+		{
+			lines:    []string{"", "Print the help."},
+			expected: "Print the help",
+		},
+		{
+			lines:    []string{"Add *target*s to the source state. If any target", "is already in the source state."},
+			expected: "Add targets to the source state",
+		},
+		{
+			lines:    []string{"Remove *target*s, i.e. stop managing them. Other", "text."},
+			expected: "Remove targets, i.e. stop managing them",
+		},
+		{
+			lines:    []string{"Ensure that *target*... are up to date.", "", "Second paragraph."},
+			expected: "Ensure that target... are up to date",
+		},
+		{
+			lines:    []string{"> Configuration: `add.encrypt`", "", "Encrypt files using [encryption](encryption.md)."},
+			expected: "Encrypt files using encryption",
+		},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			assert.Equal(t, tc.expected, helpSummary(tc.lines))
+		})
+	}
+}
+
+func TestHelpsFromReference(t *testing.T) {
+	help, ok := helps["add"]
+	assert.True(t, ok)
+	assert.Equal(t, "Add targets to the source state", help.short)
+	assert.Equal(t, "Encrypt files using the defined encryption method", help.flags["encrypt"])
+	assert.Equal(t, "Only add entries of type types", help.flags["include"])
+}
+
 func TestMustGetLongHelpPanics(t *testing.T) {
 	assert.Panics(t, func() {
 		mustLongHelp("non-existent-command")
 	})
 }
+
+func TestExtractHelpGolden(t *testing.T) {
+	longHelpTermRenderer, exampleTermRenderer, err := newHelpTermRenderers()
+	assert.NoError(t, err)
+	dirEntries, err := os.ReadDir(filepath.Join("testdata", "help"))
+	assert.NoError(t, err)
+	for _, dirEntry := range dirEntries {
+		name, ok := strings.CutSuffix(dirEntry.Name(), ".md")
+		if !ok {
+			continue
+		}
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", "help", dirEntry.Name()))
+			assert.NoError(t, err)
+			help, err := extractHelp(name, data, longHelpTermRenderer, exampleTermRenderer)
+			assert.NoError(t, err)
+
+			var builder strings.Builder
+			fmt.Fprintf(&builder, "Short:\n%s\n\n", help.short)
+			fmt.Fprintf(&builder, "Long:\n%s\n\n", help.longHelp)
+			fmt.Fprintf(&builder, "Example:\n%s\n\n", help.example)
+			builder.WriteString("Flags:\n")
+			flagNames := make([]string, 0, len(help.flags))
+			for flagName := range help.flags {
+				flagNames = append(flagNames, flagName)
+			}
+			slices.Sort(flagNames)
+			for _, flagName := range flagNames {
+				fmt.Fprintf(&builder, "--%s: %s\n", flagName, help.flags[flagName])
+			}
+			actual := builder.String()
+
+			goldenPath := filepath.Join("testdata", "help", name+".golden")
+			if *update {
+				assert.NoError(t, os.WriteFile(goldenPath, []byte(actual), 0o666))
+			}
+			expected, err := os.ReadFile(goldenPath)
+			assert.NoError(t, err)
+			assert.Equal(t, string(expected), actual)
+		})
+	}
+}
+
+func TestApplyHelpPanicsOnDocumentedUsage(t *testing.T) {
+	cmd := &cobra.Command{
+		Use: "add",
+	}
+	cmd.Flags().Bool("encrypt", false, "Encrypt files")
+	assert.Panics(t, func() {
+		applyHelp(cmd)
+	})
+}
+
+func TestMustShortHelpPanics(t *testing.T) {
+	assert.Panics(t, func() {
+		mustShortHelp("non-existent-command")
+	})
+}
+
+func TestCommandsHaveHelp(t *testing.T) {
+	chezmoitest.WithTestFS(t, nil, func(fileSystem vfs.FS) {
+		rootCmd, err := newTestConfig(t, fileSystem).newRootCmd()
+		assert.NoError(t, err)
+		var walk func(*cobra.Command)
+		walk = func(cmd *cobra.Command) {
+			if !cmd.Hidden && cmd.Deprecated == "" {
+				assert.NotEqual(t, "", cmd.Short, cmd.CommandPath())
+			}
+			cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+				assert.NotEqual(t, "", flag.Usage, cmd.CommandPath()+" --"+flag.Name)
+			})
+			for _, subCmd := range cmd.Commands() {
+				walk(subCmd)
+			}
+		}
+		walk(rootCmd)
+	})
+}
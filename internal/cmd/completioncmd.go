@@ -14,7 +14,7 @@ type completionCmdConfig struct {
 func (c *Config) newCompletionCmd() *cobra.Command {
 	completionCmd := &cobra.Command{
 		Use:       "completion shell",
-		Short:     "Generate shell completion code",
+		Short:     mustShortHelp("completion"),
 		Args:      cobra.ExactArgs(1),
 		ValidArgs: []string{"bash", "fish", "powershell", "zsh"},
 		Long:      mustLongHelp("completion"),
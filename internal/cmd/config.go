@@ -24,14 +24,21 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"text/template"
 	"time"
 	"unicode"
 
 	"github.com/Masterminds/sprig/v3"
+	"github.com/bmatcuk/doublestar/v4"
 	"github.com/coreos/go-semver/semver"
 	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/format/diff"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
 	"github.com/gregjones/httpcache"
 	"github.com/gregjones/httpcache/diskcache"
 	"github.com/mitchellh/mapstructure"
@@ -42,6 +49,7 @@ import (
 	"github.com/twpayne/go-vfs/v5"
 	"github.com/twpayne/go-xdg/v6"
 	"github.com/zricethezav/gitleaks/v8/detect"
+	"golang.org/x/sync/errgroup"
 	"golang.org/x/term"
 	"mvdan.cc/sh/v3/expand"
 	"mvdan.cc/sh/v3/syntax"
@@ -51,6 +59,7 @@ import (
 	"github.com/twpayne/chezmoi/v2/internal/chezmoierrors"
 	"github.com/twpayne/chezmoi/v2/internal/chezmoigit"
 	"github.com/twpayne/chezmoi/v2/internal/chezmoilog"
+	"github.com/twpayne/chezmoi/v2/internal/chezmoimaps"
 	"github.com/twpayne/chezmoi/v2/internal/chezmoiset"
 )
 
@@ -81,13 +90,41 @@ type commandConfig struct {
 	Args    []string `json:"args"    mapstructure:"args"    yaml:"args"`
 }
 
+type externalsConfig struct {
+	BandwidthLimit int64 `json:"bandwidthLimit" mapstructure:"bandwidthLimit" yaml:"bandwidthLimit"`
+	MaxConnections int   `json:"maxConnections" mapstructure:"maxConnections" yaml:"maxConnections"`
+}
+
+type loggingConfig struct {
+	File   chezmoi.AbsPath `json:"file"   mapstructure:"file"   yaml:"file"`
+	Format string          `json:"format" mapstructure:"format" yaml:"format"`
+}
+
+type profileConfig struct {
+	Data   map[string]any `json:"data"   mapstructure:"data"   yaml:"data"`
+	Ignore []string       `json:"ignore" mapstructure:"ignore" yaml:"ignore"`
+}
+
+type scriptsConfig struct {
+	Confirm       bool `json:"confirm"       mapstructure:"confirm"       yaml:"confirm"`
+	HaltOnFailure bool `json:"haltOnFailure" mapstructure:"haltOnFailure" yaml:"haltOnFailure"`
+}
+
+type hookCommandConfig struct {
+	Command string   `json:"command" mapstructure:"command" yaml:"command"`
+	Args    []string `json:"args"    mapstructure:"args"    yaml:"args"`
+	Script  string   `json:"script"  mapstructure:"script"  yaml:"script"`
+}
+
 type hookConfig struct {
-	Pre  commandConfig `json:"pre"  mapstructure:"pre"  yaml:"pre"`
-	Post commandConfig `json:"post" mapstructure:"post" yaml:"post"`
+	Pre  hookCommandConfig `json:"pre"  mapstructure:"pre"  yaml:"pre"`
+	Post hookCommandConfig `json:"post" mapstructure:"post" yaml:"post"`
 }
 
 type templateConfig struct {
-	Options []string `json:"options" mapstructure:"options" yaml:"options"`
+	Cache   templateCacheConfig `json:"cache"   mapstructure:"cache"   yaml:"cache"`
+	Options []string            `json:"options" mapstructure:"options" yaml:"options"`
+	Sprig   bool                `json:"sprig"   mapstructure:"sprig"   yaml:"sprig"`
 }
 
 type warningsConfig struct {
@@ -101,25 +138,39 @@ type ConfigFile struct {
 	Color                  autoBool                       `json:"color"           mapstructure:"color"           yaml:"color"`
 	Data                   map[string]any                 `json:"data"            mapstructure:"data"            yaml:"data"`
 	Env                    map[string]string              `json:"env"             mapstructure:"env"             yaml:"env"`
+	Externals              externalsConfig                `json:"externals"       mapstructure:"externals"       yaml:"externals"`
 	Format                 writeDataFormat                `json:"format"          mapstructure:"format"          yaml:"format"`
 	DestDirAbsPath         chezmoi.AbsPath                `json:"destDir"         mapstructure:"destDir"         yaml:"destDir"`
+	Elevate                commandConfig                  `json:"elevate"         mapstructure:"elevate"         yaml:"elevate"`
 	GitHub                 gitHubConfig                   `json:"gitHub"          mapstructure:"gitHub"          yaml:"gitHub"`
 	Hooks                  map[string]hookConfig          `json:"hooks"           mapstructure:"hooks"           yaml:"hooks"`
 	Interpreters           map[string]chezmoi.Interpreter `json:"interpreters"    mapstructure:"interpreters"    yaml:"interpreters"`
+	KnownHosts             knownHostsConfig               `json:"knownHosts"      mapstructure:"knownHosts"      yaml:"knownHosts"`
+	Logging                loggingConfig                  `json:"logging"         mapstructure:"logging"         yaml:"logging"`
 	Mode                   chezmoi.Mode                   `json:"mode"            mapstructure:"mode"            yaml:"mode"`
+	Mtime                  chezmoi.Mtime                  `json:"mtime"           mapstructure:"mtime"           yaml:"mtime"`
 	Pager                  string                         `json:"pager"           mapstructure:"pager"           yaml:"pager"`
 	PersistentStateAbsPath chezmoi.AbsPath                `json:"persistentState" mapstructure:"persistentState" yaml:"persistentState"`
 	PINEntry               pinEntryConfig                 `json:"pinentry"        mapstructure:"pinentry"        yaml:"pinentry"`
+	Profile                string                         `json:"profile"         mapstructure:"profile"         yaml:"profile"`
+	Profiles               map[string]profileConfig       `json:"profiles"        mapstructure:"profiles"        yaml:"profiles"`
 	Progress               autoBool                       `json:"progress"        mapstructure:"progress"        yaml:"progress"`
+	Roles                  []string                       `json:"roles"           mapstructure:"roles"           yaml:"roles"`
 	Safe                   bool                           `json:"safe"            mapstructure:"safe"            yaml:"safe"`
 	ScriptEnv              map[string]string              `json:"scriptEnv"       mapstructure:"scriptEnv"       yaml:"scriptEnv"`
 	ScriptTempDir          chezmoi.AbsPath                `json:"scriptTempDir"   mapstructure:"scriptTempDir"   yaml:"scriptTempDir"`
+	Scripts                scriptsConfig                  `json:"scripts"         mapstructure:"scripts"         yaml:"scripts"`
 	SourceDirAbsPath       chezmoi.AbsPath                `json:"sourceDir"       mapstructure:"sourceDir"       yaml:"sourceDir"`
+	SourceLayerAbsPaths    []chezmoi.AbsPath              `json:"sourceLayers"    mapstructure:"sourceLayers"    yaml:"sourceLayers"`
+	SymlinkCopyDirAbsPath  chezmoi.AbsPath                `json:"symlinkCopyDir"  mapstructure:"symlinkCopyDir"  yaml:"symlinkCopyDir"`
+	TargetHooks            []targetHookConfig             `json:"targetHooks"     mapstructure:"targetHooks"     yaml:"targetHooks"`
 	Template               templateConfig                 `json:"template"        mapstructure:"template"        yaml:"template"`
 	TextConv               textConv                       `json:"textConv"        mapstructure:"textConv"        yaml:"textConv"`
 	Umask                  fs.FileMode                    `json:"umask"           mapstructure:"umask"           yaml:"umask"`
 	UseBuiltinAge          autoBool                       `json:"useBuiltinAge"   mapstructure:"useBuiltinAge"   yaml:"useBuiltinAge"`
 	UseBuiltinGit          autoBool                       `json:"useBuiltinGit"   mapstructure:"useBuiltinGit"   yaml:"useBuiltinGit"`
+	Validate               validate                       `json:"validate"        mapstructure:"validate"        yaml:"validate"`
+	VCS                    vcsConfig                      `json:"vcs"             mapstructure:"vcs"             yaml:"vcs"`
 	Verbose                bool                           `json:"verbose"         mapstructure:"verbose"         yaml:"verbose"`
 	Warnings               warningsConfig                 `json:"warnings"        mapstructure:"warnings"        yaml:"warnings"`
 	WorkingTreeAbsPath     chezmoi.AbsPath                `json:"workingTree"     mapstructure:"workingTree"     yaml:"workingTree"`
@@ -156,7 +207,10 @@ type ConfigFile struct {
 	Diff       diffCmdConfig       `json:"diff"       mapstructure:"diff"       yaml:"diff"`
 	Edit       editCmdConfig       `json:"edit"       mapstructure:"edit"       yaml:"edit"`
 	Git        gitCmdConfig        `json:"git"        mapstructure:"git"        yaml:"git"`
+	Hg         hgCmdConfig         `json:"hg"         mapstructure:"hg"         yaml:"hg"`
 	Merge      mergeCmdConfig      `json:"merge"      mapstructure:"merge"      yaml:"merge"`
+	Packages   packagesCmdConfig   `json:"packages"   mapstructure:"packages"   yaml:"packages"`
+	Sign       signCmdConfig       `json:"sign"       mapstructure:"sign"       yaml:"sign"`
 	Status     statusCmdConfig     `json:"status"     mapstructure:"status"     yaml:"status"`
 	Update     updateCmdConfig     `json:"update"     mapstructure:"update"     yaml:"update"`
 	Verify     verifyCmdConfig     `json:"verify"     mapstructure:"verify"     yaml:"verify"`
@@ -167,21 +221,27 @@ type Config struct {
 	ConfigFile
 
 	// Global configuration.
-	configFormat     readDataFormat
-	cpuProfile       chezmoi.AbsPath
-	debug            bool
-	dryRun           bool
-	force            bool
-	homeDir          string
-	interactive      bool
-	keepGoing        bool
-	noPager          bool
-	noTTY            bool
-	outputAbsPath    chezmoi.AbsPath
-	refreshExternals chezmoi.RefreshExternals
-	sourcePath       bool
-	templateFuncs    template.FuncMap
-	useBuiltinDiff   bool
+	configFormat            readDataFormat
+	cpuProfile              chezmoi.AbsPath
+	debug                   bool
+	dryRun                  bool
+	force                   bool
+	homeDir                 string
+	interactive             bool
+	keepGoing               bool
+	mockSecrets             bool
+	mockedSecrets           chezmoiset.Set[string]
+	noPager                 bool
+	noTTY                   bool
+	outputAbsPath           chezmoi.AbsPath
+	quiet                   bool
+	refreshConfig           bool
+	refreshExternals        chezmoi.RefreshExternals
+	secretTemplateFuncNames chezmoiset.Set[string]
+	sourcePath              bool
+	templateFuncs           template.FuncMap
+	useBuiltinDiff          bool
+	verbosity               int
 
 	// Password manager data.
 	gitHub  gitHubData
@@ -191,19 +251,25 @@ type Config struct {
 	age             ageCmdConfig
 	apply           applyCmdConfig
 	archive         archiveCmdConfig
+	cat             catCmdConfig
 	chattr          chattrCmdConfig
 	destroy         destroyCmdConfig
+	doctor          doctorCmdConfig
 	dump            dumpCmdConfig
+	encrypted       encryptedCmdConfig
 	executeTemplate executeTemplateCmdConfig
 	ignored         ignoredCmdConfig
 	_import         importCmdConfig
 	init            initCmdConfig
+	lint            lintCmdConfig
 	managed         managedCmdConfig
 	mergeAll        mergeAllCmdConfig
 	purge           purgeCmdConfig
 	reAdd           reAddCmdConfig
+	rotateKey       rotateKeyCmdConfig
 	secret          secretCmdConfig
 	state           stateCmdConfig
+	ui              uiCmdConfig
 	unmanaged       unmanagedCmdConfig
 	upgrade         upgradeCmdConfig
 
@@ -227,6 +293,7 @@ type Config struct {
 	persistentState             chezmoi.PersistentState
 	httpClient                  *http.Client
 	logger                      *slog.Logger
+	logFile                     *os.File
 
 	// Computed configuration.
 	commandDirAbsPath   chezmoi.AbsPath
@@ -246,10 +313,16 @@ type Config struct {
 	bufioReader       *bufio.Reader
 	diffPagerCmdStdin io.WriteCloser
 	diffPagerCmd      *exec.Cmd
+	diffFormatOutput  strings.Builder
 
 	tempDirs map[string]chezmoi.AbsPath
 
-	ioregData ioregData
+	ioregData               ioregData
+	machineSecret           []byte
+	machineSecretWritable   bool
+	persistentStateReadOnly bool
+	secretValues            secretValues
+	templateFuncResults     templateFuncResults
 
 	restoreWindowsConsole func() error
 }
@@ -269,11 +342,15 @@ type templateData struct {
 	homeDir           chezmoi.AbsPath
 	hostname          string
 	kernel            map[string]any
+	macOSVersion      map[string]any
 	os                string
 	osRelease         map[string]any
 	pathListSeparator string
 	pathSeparator     string
+	profile           string
+	roles             []string
 	sourceDir         chezmoi.AbsPath
+	sourceVersion     chezmoi.LazyValue
 	uid               string
 	username          string
 	version           map[string]any
@@ -285,7 +362,8 @@ type templateData struct {
 type configOption func(*Config) error
 
 type configState struct {
-	ConfigTemplateContentsSHA256 chezmoi.HexBytes `json:"configTemplateContentsSHA256" yaml:"configTemplateContentsSHA256"` //nolint:tagliatelle
+	ConfigFileContentsSHA256     chezmoi.HexBytes `json:"configFileContentsSHA256,omitempty" yaml:"configFileContentsSHA256,omitempty"` //nolint:tagliatelle
+	ConfigTemplateContentsSHA256 chezmoi.HexBytes `json:"configTemplateContentsSHA256"       yaml:"configTemplateContentsSHA256"`       //nolint:tagliatelle
 }
 
 var (
@@ -295,6 +373,17 @@ var (
 
 	configStateKey = []byte("configState")
 
+	// overriddenSprigTemplateFuncs are the sprig template functions that
+	// chezmoi replaces with its own implementations.
+	overriddenSprigTemplateFuncs = []string{
+		"bcrypt",
+		"fromJson",
+		"htpasswd",
+		"splitList",
+		"toPrettyJson",
+		"uuidv4",
+	}
+
 	defaultAgeEncryptionConfig = chezmoi.AgeEncryption{
 		Command: "age",
 		Suffix:  ".age",
@@ -310,6 +399,7 @@ var (
 		"exclude":    chezmoi.EntryTypeSetFlagCompletionFunc,
 		"format":     writeDataFormatFlagCompletionFunc,
 		"include":    chezmoi.EntryTypeSetFlagCompletionFunc,
+		"mtime":      chezmoi.MtimeFlagCompletionFunc,
 		"path-style": chezmoi.PathStyleFlagCompletionFunc,
 		"secrets":    severityFlagCompletionFunc,
 	}
@@ -337,8 +427,9 @@ func newConfig(options ...configOption) (*Config, error) {
 		ConfigFile: newConfigFile(bds),
 
 		// Global configuration.
-		homeDir:       userHomeDir,
-		templateFuncs: sprig.TxtFuncMap(),
+		homeDir:                 userHomeDir,
+		secretTemplateFuncNames: chezmoiset.New[string](),
+		templateFuncs:           sprig.TxtFuncMap(),
 
 		// Command configurations.
 		apply: applyCmdConfig{
@@ -366,6 +457,12 @@ func newConfig(options ...configOption) (*Config, error) {
 			guessRepoURL:      true,
 			recurseSubmodules: true,
 		},
+		encrypted: encryptedCmdConfig{
+			pathStyle: chezmoi.PathStyleRelative,
+		},
+		lint: lintCmdConfig{
+			renders: 2,
+		},
 		managed: managedCmdConfig{
 			filter:    chezmoi.NewEntryTypeFilter(chezmoi.EntryTypesAll, chezmoi.EntryTypesNone),
 			pathStyle: chezmoi.PathStyleRelative,
@@ -377,6 +474,10 @@ func newConfig(options ...configOption) (*Config, error) {
 			filter:    chezmoi.NewEntryTypeFilter(chezmoi.EntryTypesAll, chezmoi.EntryTypesNone),
 			recursive: true,
 		},
+		ui: uiCmdConfig{
+			filter:    chezmoi.NewEntryTypeFilter(chezmoi.EntryTypesAll, chezmoi.EntryTypesNone),
+			recursive: true,
+		},
 		unmanaged: unmanagedCmdConfig{
 			pathStyle: chezmoi.PathStyleRelative,
 		},
@@ -398,30 +499,18 @@ func newConfig(options ...configOption) (*Config, error) {
 
 	// Override sprig template functions. Delete them from the template function
 	// map first to avoid a duplicate function panic.
-	delete(c.templateFuncs, "fromJson")
-	delete(c.templateFuncs, "splitList")
-	delete(c.templateFuncs, "toPrettyJson")
+	for _, key := range overriddenSprigTemplateFuncs {
+		delete(c.templateFuncs, key)
+	}
 
 	// The completion template function is added in persistentPreRunRootE as
 	// it needs a *cobra.Command, which we don't yet have.
 	for key, value := range map[string]any{
-		"awsSecretsManager":           c.awsSecretsManagerTemplateFunc,
-		"awsSecretsManagerRaw":        c.awsSecretsManagerRawTemplateFunc,
-		"azureKeyVault":               c.azureKeyVaultTemplateFunc,
-		"bitwarden":                   c.bitwardenTemplateFunc,
-		"bitwardenAttachment":         c.bitwardenAttachmentTemplateFunc,
-		"bitwardenAttachmentByRef":    c.bitwardenAttachmentByRefTemplateFunc,
-		"bitwardenFields":             c.bitwardenFieldsTemplateFunc,
-		"bitwardenSecrets":            c.bitwardenSecretsTemplateFunc,
+		"argon2":                      c.argon2TemplateFunc,
+		"bcrypt":                      c.bcryptTemplateFunc,
 		"comment":                     c.commentTemplateFunc,
-		"dashlaneNote":                c.dashlaneNoteTemplateFunc,
-		"dashlanePassword":            c.dashlanePasswordTemplateFunc,
 		"decrypt":                     c.decryptTemplateFunc,
 		"deleteValueAtPath":           c.deleteValueAtPathTemplateFunc,
-		"doppler":                     c.dopplerTemplateFunc,
-		"dopplerProjectJson":          c.dopplerProjectJSONTemplateFunc,
-		"ejsonDecrypt":                c.ejsonDecryptTemplateFunc,
-		"ejsonDecryptWithKey":         c.ejsonDecryptWithKeyTemplateFunc,
 		"encrypt":                     c.encryptTemplateFunc,
 		"eqFold":                      c.eqFoldTemplateFunc,
 		"findExecutable":              c.findExecutableTemplateFunc,
@@ -438,57 +527,88 @@ func newConfig(options ...configOption) (*Config, error) {
 		"gitHubReleases":              c.gitHubReleasesTemplateFunc,
 		"gitHubTags":                  c.gitHubTagsTemplateFunc,
 		"glob":                        c.globTemplateFunc,
-		"gopass":                      c.gopassTemplateFunc,
-		"gopassRaw":                   c.gopassRawTemplateFunc,
-		"hcpVaultSecret":              c.hcpVaultSecretTemplateFunc,
-		"hcpVaultSecretJson":          c.hcpVaultSecretJSONTemplateFunc,
+		"hashKnownHost":               c.hashKnownHostTemplateFunc,
+		"hasRole":                     c.hasRoleTemplateFunc,
 		"hexDecode":                   c.hexDecodeTemplateFunc,
 		"hexEncode":                   c.hexEncodeTemplateFunc,
+		"htpasswd":                    c.htpasswdTemplateFunc,
 		"include":                     c.includeTemplateFunc,
 		"includeTemplate":             c.includeTemplateTemplateFunc,
 		"ioreg":                       c.ioregTemplateFunc,
 		"isExecutable":                c.isExecutableTemplateFunc,
 		"joinPath":                    c.joinPathTemplateFunc,
 		"jq":                          c.jqTemplateFunc,
-		"keepassxc":                   c.keepassxcTemplateFunc,
-		"keepassxcAttachment":         c.keepassxcAttachmentTemplateFunc,
-		"keepassxcAttribute":          c.keepassxcAttributeTemplateFunc,
-		"keeper":                      c.keeperTemplateFunc,
-		"keeperDataFields":            c.keeperDataFieldsTemplateFunc,
-		"keeperFindPassword":          c.keeperFindPasswordTemplateFunc,
-		"keyring":                     c.keyringTemplateFunc,
-		"lastpass":                    c.lastpassTemplateFunc,
-		"lastpassRaw":                 c.lastpassRawTemplateFunc,
+		"knownHosts":                  c.knownHostsTemplateFunc,
 		"lookPath":                    c.lookPathTemplateFunc,
 		"lstat":                       c.lstatTemplateFunc,
+		"machineSecret":               c.machineSecretTemplateFunc,
 		"mozillaInstallHash":          c.mozillaInstallHashTemplateFunc,
-		"onepassword":                 c.onepasswordTemplateFunc,
-		"onepasswordDetailsFields":    c.onepasswordDetailsFieldsTemplateFunc,
-		"onepasswordDocument":         c.onepasswordDocumentTemplateFunc,
-		"onepasswordItemFields":       c.onepasswordItemFieldsTemplateFunc,
-		"onepasswordRead":             c.onepasswordReadTemplateFunc,
 		"output":                      c.outputTemplateFunc,
-		"pass":                        c.passTemplateFunc,
-		"passFields":                  c.passFieldsTemplateFunc,
-		"passhole":                    c.passholeTemplateFunc,
-		"passRaw":                     c.passRawTemplateFunc,
 		"pruneEmptyDicts":             c.pruneEmptyDictsTemplateFunc,
 		"quoteList":                   c.quoteListTemplateFunc,
-		"rbw":                         c.rbwTemplateFunc,
-		"rbwFields":                   c.rbwFieldsTemplateFunc,
 		"replaceAllRegex":             c.replaceAllRegexTemplateFunc,
-		"secret":                      c.secretTemplateFunc,
-		"secretJSON":                  c.secretJSONTemplateFunc,
 		"setValueAtPath":              c.setValueAtPathTemplateFunc,
+		"shellConfig":                 c.shellConfigTemplateFunc,
 		"splitList":                   c.splitListTemplateFunc,
+		"sshKeyscan":                  c.sshKeyscanTemplateFunc,
+		"stableRandom":                c.stableRandomTemplateFunc,
 		"stat":                        c.statTemplateFunc,
 		"toIni":                       c.toIniTemplateFunc,
 		"toPrettyJson":                c.toPrettyJsonTemplateFunc,
 		"toToml":                      c.toTomlTemplateFunc,
 		"toYaml":                      c.toYamlTemplateFunc,
-		"vault":                       c.vaultTemplateFunc,
+		"uuidv4":                      c.uuidv4TemplateFunc,
+	} {
+		c.addTemplateFunc(key, value)
+	}
+
+	// Secret template functions read secrets from password managers. Record
+	// their names so that they can be mocked, memoized, redacted, and timed.
+	for key, value := range map[string]any{
+		"awsSecretsManager":        c.awsSecretsManagerTemplateFunc,
+		"awsSecretsManagerRaw":     c.awsSecretsManagerRawTemplateFunc,
+		"azureKeyVault":            c.azureKeyVaultTemplateFunc,
+		"bitwarden":                c.bitwardenTemplateFunc,
+		"bitwardenAttachment":      c.bitwardenAttachmentTemplateFunc,
+		"bitwardenAttachmentByRef": c.bitwardenAttachmentByRefTemplateFunc,
+		"bitwardenFields":          c.bitwardenFieldsTemplateFunc,
+		"bitwardenSecrets":         c.bitwardenSecretsTemplateFunc,
+		"dashlaneNote":             c.dashlaneNoteTemplateFunc,
+		"dashlanePassword":         c.dashlanePasswordTemplateFunc,
+		"doppler":                  c.dopplerTemplateFunc,
+		"dopplerProjectJson":       c.dopplerProjectJSONTemplateFunc,
+		"ejsonDecrypt":             c.ejsonDecryptTemplateFunc,
+		"ejsonDecryptWithKey":      c.ejsonDecryptWithKeyTemplateFunc,
+		"gopass":                   c.gopassTemplateFunc,
+		"gopassRaw":                c.gopassRawTemplateFunc,
+		"hcpVaultSecret":           c.hcpVaultSecretTemplateFunc,
+		"hcpVaultSecretJson":       c.hcpVaultSecretJSONTemplateFunc,
+		"keepassxc":                c.keepassxcTemplateFunc,
+		"keepassxcAttachment":      c.keepassxcAttachmentTemplateFunc,
+		"keepassxcAttribute":       c.keepassxcAttributeTemplateFunc,
+		"keeper":                   c.keeperTemplateFunc,
+		"keeperDataFields":         c.keeperDataFieldsTemplateFunc,
+		"keeperFindPassword":       c.keeperFindPasswordTemplateFunc,
+		"keyring":                  c.keyringTemplateFunc,
+		"lastpass":                 c.lastpassTemplateFunc,
+		"lastpassRaw":              c.lastpassRawTemplateFunc,
+		"onepassword":              c.onepasswordTemplateFunc,
+		"onepasswordDetailsFields": c.onepasswordDetailsFieldsTemplateFunc,
+		"onepasswordDocument":      c.onepasswordDocumentTemplateFunc,
+		"onepasswordItemFields":    c.onepasswordItemFieldsTemplateFunc,
+		"onepasswordRead":          c.onepasswordReadTemplateFunc,
+		"pass":                     c.passTemplateFunc,
+		"passFields":               c.passFieldsTemplateFunc,
+		"passhole":                 c.passholeTemplateFunc,
+		"passRaw":                  c.passRawTemplateFunc,
+		"rbw":                      c.rbwTemplateFunc,
+		"rbwFields":                c.rbwFieldsTemplateFunc,
+		"secret":                   c.secretTemplateFunc,
+		"secretJSON":               c.secretJSONTemplateFunc,
+		"vault":                    c.vaultTemplateFunc,
 	} {
 		c.addTemplateFunc(key, value)
+		c.secretTemplateFuncNames.Add(key)
 	}
 
 	for _, option := range options {
@@ -551,13 +671,32 @@ func (c *Config) addTemplateFunc(key string, value any) {
 	c.templateFuncs[key] = value
 }
 
+// removeSprigTemplateFuncs removes the sprig template functions from c,
+// leaving chezmoi's own template functions, including those that override
+// sprig template functions.
+func (c *Config) removeSprigTemplateFuncs() {
+	for key := range sprig.TxtFuncMap() {
+		if !slices.Contains(overriddenSprigTemplateFuncs, key) {
+			delete(c.templateFuncs, key)
+		}
+	}
+}
+
 type applyArgsOptions struct {
-	cmd          *cobra.Command
-	filter       *chezmoi.EntryTypeFilter
-	init         bool
-	recursive    bool
-	umask        fs.FileMode
-	preApplyFunc chezmoi.PreApplyFunc
+	cmd           *cobra.Command
+	filter        *chezmoi.EntryTypeFilter
+	importGPGKeys bool
+	init          bool
+	mtimeFunc     chezmoi.ApplyMtimeFunc
+	recursive     bool
+	requireClean  bool
+	targetHooks   bool
+	umask         fs.FileMode
+	preApplyFunc  chezmoi.PreApplyFunc
+	timingsFunc   chezmoi.ApplyTimingsFunc
+	// errorFunc, if not nil, is called with each target that failed when
+	// keeping going after errors.
+	errorFunc func(targetRelPath chezmoi.RelPath, err error) error
 }
 
 // applyArgs is the core of all commands that make changes to a target system.
@@ -570,53 +709,30 @@ func (c *Config) applyArgs(
 	targetDirAbsPath chezmoi.AbsPath,
 	args []string,
 	options applyArgsOptions,
-) error {
+) (err error) {
 	if options.init {
 		if err := c.createAndReloadConfigFile(options.cmd); err != nil {
 			return err
 		}
 	}
 
-	var currentConfigTemplateContentsSHA256 []byte
-	configTemplate, err := c.findConfigTemplate()
-	if err != nil {
-		return err
-	}
-	if configTemplate != nil {
-		currentConfigTemplateContentsSHA256 = chezmoi.SHA256Sum(configTemplate.contents)
-	}
-	var previousConfigTemplateContentsSHA256 []byte
-	if configStateData, err := c.persistentState.Get(chezmoi.ConfigStateBucket, configStateKey); err != nil {
-		return err
-	} else if configStateData != nil {
-		var configState configState
-		if err := json.Unmarshal(configStateData, &configState); err != nil {
+	if options.requireClean && c.Git.RequireClean {
+		if err := c.checkWorkingTreeClean(); err != nil {
 			return err
 		}
-		previousConfigTemplateContentsSHA256 = []byte(configState.ConfigTemplateContentsSHA256)
 	}
-	configTemplatesEmpty := currentConfigTemplateContentsSHA256 == nil && previousConfigTemplateContentsSHA256 == nil
-	configTemplateContentsUnchanged := configTemplatesEmpty ||
-		bytes.Equal(currentConfigTemplateContentsSHA256, previousConfigTemplateContentsSHA256)
-	if !configTemplateContentsUnchanged {
-		if c.force {
-			if configTemplate == nil {
-				if err := c.persistentState.Delete(chezmoi.ConfigStateBucket, configStateKey); err != nil {
-					return err
-				}
-			} else {
-				configStateValue, err := chezmoi.FormatJSON.Marshal(configState{
-					ConfigTemplateContentsSHA256: chezmoi.HexBytes(currentConfigTemplateContentsSHA256),
-				})
-				if err != nil {
-					return err
-				}
-				if err := c.persistentState.Set(chezmoi.ConfigStateBucket, configStateKey, configStateValue); err != nil {
-					return err
-				}
+
+	// The config file template has already been checked in
+	// persistentPreRunRootE. If --force is set then record that the user has
+	// accepted any changes to it.
+	if c.force {
+		switch configTemplateChanged, err := c.configTemplateChanged(); {
+		case err != nil:
+			return err
+		case configTemplateChanged:
+			if err := c.recordConfigTemplateState(nil); err != nil {
+				return err
 			}
-		} else if c.Warnings.ConfigFileTemplateHasChanged {
-			c.errorf("warning: config file template has changed, run chezmoi init to regenerate config file\n")
 		}
 	}
 
@@ -625,6 +741,18 @@ func (c *Config) applyArgs(
 		return err
 	}
 
+	if err := c.setScriptEnvironmentVariables(sourceState); err != nil {
+		return err
+	}
+
+	// GPG keys are not target entries, so they are only imported when all
+	// target entries are applied.
+	if options.importGPGKeys && len(args) == 0 {
+		if err := c.importGPGKeys(sourceState); err != nil {
+			return err
+		}
+	}
+
 	var targetRelPaths chezmoi.RelPaths
 	switch {
 	case len(args) == 0:
@@ -645,21 +773,81 @@ func (c *Config) applyArgs(
 
 	applyOptions := chezmoi.ApplyOptions{
 		Filter:       options.filter,
+		MtimeFunc:    options.mtimeFunc,
 		PreApplyFunc: options.preApplyFunc,
+		TimingsFunc:  options.timingsFunc,
 		Umask:        options.umask,
 	}
 
+	// Target hooks are not run on dry runs, as, unlike other hooks, they are
+	// expected to have side effects like reloading configuration.
+	var targetHooksRunner *targetHooksRunner
+	if options.targetHooks && !c.dryRun && len(c.TargetHooks) != 0 {
+		targetHooksRunner = newTargetHooksRunner(c)
+		applyOptions.PreChangeFunc = targetHooksRunner.preChangeFunc
+		applyOptions.PostChangeFunc = targetHooksRunner.postChangeFunc
+	}
+
+	// Record the progress of applies that change the destination directory so
+	// that interrupted applies can be detected. On dry runs, the persistent
+	// state is a copy, so nothing is recorded.
+	var journal *applyJournal
+	rollForwardTargetRelPaths := chezmoiset.New[chezmoi.RelPath]()
+	if targetSystem == c.destSystem && getAnnotations(options.cmd).hasTag(modifiesDestinationDirectory) {
+		if journal, err = c.beginApplyJournal(targetDirAbsPath); err != nil {
+			return err
+		}
+		// Complete the targets of any interrupted apply, whichever targets
+		// were requested. Targets that are no longer in the source state
+		// cannot be completed.
+		for _, targetRelPath := range journal.interrupted {
+			if sourceState.Get(targetRelPath) == nil {
+				if err := journal.done(targetRelPath); err != nil {
+					return err
+				}
+				continue
+			}
+			rollForwardTargetRelPaths.Add(targetRelPath)
+			if !slices.Contains(targetRelPaths, targetRelPath) {
+				targetRelPaths = append(targetRelPaths, targetRelPath)
+				sort.Sort(targetRelPaths)
+			}
+		}
+		if preChangeFunc := applyOptions.PreChangeFunc; preChangeFunc != nil {
+			applyOptions.PreChangeFunc = func(targetRelPath chezmoi.RelPath, oldEntryState, newEntryState *chezmoi.EntryState) error {
+				if err := journal.preChangeFunc(targetRelPath, oldEntryState, newEntryState); err != nil {
+					return err
+				}
+				return preChangeFunc(targetRelPath, oldEntryState, newEntryState)
+			}
+		} else {
+			applyOptions.PreChangeFunc = journal.preChangeFunc
+		}
+	}
+
 	keptGoingAfterErr := false
 	for _, targetRelPath := range targetRelPaths {
-		switch err := sourceState.Apply(targetSystem, c.destSystem, c.persistentState, targetDirAbsPath, targetRelPath, applyOptions); {
+		targetApplyOptions := applyOptions
+		if rollForwardTargetRelPaths.Contains(targetRelPath) {
+			targetApplyOptions.Filter = chezmoi.NewEntryTypeFilter(chezmoi.EntryTypesAll, chezmoi.EntryTypesNone)
+		}
+		switch err := sourceState.Apply(targetSystem, c.destSystem, c.persistentState, targetDirAbsPath, targetRelPath, targetApplyOptions); {
 		case errors.Is(err, fs.SkipDir):
 			continue
 		case err != nil:
-			err = fmt.Errorf("%s: %w", targetRelPath, err)
-			if c.keepGoing {
-				c.errorf("%v\n", err)
+			if c.keepGoing || !c.Scripts.HaltOnFailure && isScript(sourceState, targetRelPath) {
+				c.errorf("%s: %v\n", targetRelPath, err)
 				keptGoingAfterErr = true
+				if options.errorFunc != nil {
+					if err := options.errorFunc(targetRelPath, err); err != nil {
+						return err
+					}
+				}
 			} else {
+				return fmt.Errorf("%s: %w", targetRelPath, err)
+			}
+		case journal != nil:
+			if err := journal.done(targetRelPath); err != nil {
 				return err
 			}
 		}
@@ -673,6 +861,12 @@ func (c *Config) applyArgs(
 		return err
 	}
 
+	if targetHooksRunner != nil {
+		if err := targetHooksRunner.runPost(); err != nil {
+			return err
+		}
+	}
+
 	if keptGoingAfterErr {
 		return chezmoi.ExitCodeError(1)
 	}
@@ -680,6 +874,225 @@ func (c *Config) applyArgs(
 	return nil
 }
 
+// checkWorkingTreeClean returns an error if the working tree has uncommitted
+// changes or is behind its upstream branch. The upstream branch is not fetched,
+// so it is compared as of the last fetch.
+func (c *Config) checkWorkingTreeClean() error {
+	var dirty, behind bool
+	if c.UseBuiltinGit.Value(c.useBuiltinGitAutoFunc) {
+		repo, worktree, err := c.builtinGitWorktree()
+		if err != nil {
+			return err
+		}
+		gitStatus, err := worktree.Status()
+		if err != nil {
+			return err
+		}
+		dirty = !gitStatus.IsClean()
+		if behind, err = builtinGitBehindUpstream(repo); err != nil {
+			return err
+		}
+	} else {
+		status, err := c.gitStatusBranch()
+		if err != nil {
+			return err
+		}
+		dirty = !status.Empty()
+		behind = status.Behind > 0
+	}
+	switch {
+	case dirty:
+		return fmt.Errorf("%s: working tree has uncommitted changes, commit them or run with --allow-dirty", c.WorkingTreeAbsPath)
+	case behind:
+		return fmt.Errorf("%s: working tree is behind its upstream branch, pull or run with --allow-dirty", c.WorkingTreeAbsPath)
+	default:
+		return nil
+	}
+}
+
+// gitStatusBranch returns the status of the working tree, including its
+// upstream branch, using git.command.
+func (c *Config) gitStatusBranch() (*chezmoigit.Status, error) {
+	output, err := c.vcsOutput(gitVCSName, c.WorkingTreeAbsPath, c.Git.Command, []string{"status", "--branch", "--porcelain=v2"})
+	if err != nil {
+		return nil, err
+	}
+	return chezmoigit.ParseStatusPorcelainV2(output)
+}
+
+// builtinGitBehindUpstream returns true if HEAD in repo is behind the
+// remote-tracking branch of its upstream branch. If HEAD is not a branch, the
+// branch has no upstream, or the upstream branch has not been fetched then it
+// returns false.
+func builtinGitBehindUpstream(repo *git.Repository) (bool, error) {
+	head, err := repo.Head()
+	if err != nil {
+		return false, err
+	}
+	if !head.Name().IsBranch() {
+		return false, nil
+	}
+	repoConfig, err := repo.Config()
+	if err != nil {
+		return false, err
+	}
+	branch, ok := repoConfig.Branches[head.Name().Short()]
+	if !ok || branch.Remote == "" || branch.Merge == "" {
+		return false, nil
+	}
+	remoteConfig, ok := repoConfig.Remotes[branch.Remote]
+	if !ok {
+		return false, nil
+	}
+	var upstreamRefName plumbing.ReferenceName
+	for _, refSpec := range remoteConfig.Fetch {
+		if refSpec.Match(branch.Merge) {
+			upstreamRefName = refSpec.Dst(branch.Merge)
+			break
+		}
+	}
+	if upstreamRefName == "" {
+		return false, nil
+	}
+	upstreamRef, err := repo.Reference(upstreamRefName, true)
+	switch {
+	case errors.Is(err, plumbing.ErrReferenceNotFound):
+		return false, nil
+	case err != nil:
+		return false, err
+	case upstreamRef.Hash() == head.Hash():
+		return false, nil
+	}
+	headCommit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return false, err
+	}
+	upstreamCommit, err := repo.CommitObject(upstreamRef.Hash())
+	if err != nil {
+		return false, err
+	}
+	upstreamIsAncestor, err := upstreamCommit.IsAncestor(headCommit)
+	if err != nil {
+		return false, err
+	}
+	return !upstreamIsAncestor, nil
+}
+
+// applyMtimeFunc returns the function that returns the modification times to
+// set on applied files, or nil if modification times are not set.
+func (c *Config) applyMtimeFunc() chezmoi.ApplyMtimeFunc {
+	switch c.Mtime {
+	case chezmoi.MtimeNone:
+		return nil
+	case chezmoi.MtimeSource:
+		return func(targetRelPath chezmoi.RelPath, sourceStateEntry chezmoi.SourceStateEntry) (time.Time, error) {
+			// Files from externals do not have a source file.
+			sourceStateOriginAbsPath, ok := sourceStateEntry.Origin().(chezmoi.SourceStateOriginAbsPath)
+			if !ok {
+				return time.Time{}, nil
+			}
+			fileInfo, err := c.sourceSystem.Stat(sourceStateOriginAbsPath.Path())
+			if err != nil {
+				return time.Time{}, err
+			}
+			return fileInfo.ModTime(), nil
+		}
+	case chezmoi.MtimeCommit:
+		var commitTime time.Time
+		return func(targetRelPath chezmoi.RelPath, sourceStateEntry chezmoi.SourceStateEntry) (time.Time, error) {
+			if commitTime.IsZero() {
+				var err error
+				if commitTime, err = c.headCommitTime(); err != nil {
+					return time.Time{}, err
+				}
+			}
+			return commitTime, nil
+		}
+	default:
+		return func(targetRelPath chezmoi.RelPath, sourceStateEntry chezmoi.SourceStateEntry) (time.Time, error) {
+			return time.Time{}, fmt.Errorf("%s: invalid mtime", c.Mtime)
+		}
+	}
+}
+
+// headCommitTime returns the commit time of HEAD in the working tree.
+func (c *Config) headCommitTime() (time.Time, error) {
+	if c.UseBuiltinGit.Value(c.useBuiltinGitAutoFunc) {
+		repo, _, err := c.builtinGitWorktree()
+		if err != nil {
+			return time.Time{}, err
+		}
+		head, err := repo.Head()
+		if err != nil {
+			return time.Time{}, err
+		}
+		commit, err := repo.CommitObject(head.Hash())
+		if err != nil {
+			return time.Time{}, err
+		}
+		return commit.Committer.When, nil
+	}
+	output, err := c.vcsOutput(gitVCSName, c.WorkingTreeAbsPath, c.Git.Command, []string{"log", "-1", "--format=%ct", "HEAD"})
+	if err != nil {
+		return time.Time{}, err
+	}
+	unixTime, err := strconv.ParseInt(strings.TrimSpace(string(output)), 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(unixTime, 0), nil
+}
+
+// isScript returns true if targetRelPath is a script in sourceState.
+func isScript(sourceState *chezmoi.SourceState, targetRelPath chezmoi.RelPath) bool {
+	sourceStateFile, ok := sourceState.MustEntry(targetRelPath).(*chezmoi.SourceStateFile)
+	return ok && sourceStateFile.Attr.Type == chezmoi.SourceFileTypeScript
+}
+
+// confirmScript shows the contents of the script at targetRelPath, which has
+// not been run before or has changed since it was last run, and prompts the
+// user to confirm that it should be run.
+func (c *Config) confirmScript(targetRelPath chezmoi.RelPath, lastWrittenEntryState *chezmoi.EntryState) error {
+	sourceStateEntry := c.sourceState.MustEntry(targetRelPath)
+	targetStateEntry, err := sourceStateEntry.TargetStateEntry(c.destSystem, c.DestDirAbsPath.Join(targetRelPath))
+	if err != nil {
+		return err
+	}
+	contents, err := targetStateEntry.(*chezmoi.TargetStateScript).Contents()
+	if err != nil {
+		return err
+	}
+
+	prompt := fmt.Sprintf("Run new script %s", targetRelPath)
+	if lastWrittenEntryState != nil {
+		prompt = fmt.Sprintf("Run changed script %s", targetRelPath)
+	}
+	if _, err := fmt.Fprintf(c.stdout, "Contents of %s:\n%s", sourceStateEntry.SourceRelPath(), contents); err != nil {
+		return err
+	}
+	if len(contents) > 0 && contents[len(contents)-1] != '\n' {
+		if _, err := c.stdout.Write([]byte{'\n'}); err != nil {
+			return err
+		}
+	}
+
+	switch choice, err := c.promptChoice(prompt, choicesYesNoAllQuit); {
+	case err != nil:
+		return err
+	case choice == "yes":
+		return nil
+	case choice == "no":
+		return fs.SkipDir
+	case choice == "all":
+		c.Scripts.Confirm = false
+		return nil
+	case choice == "quit":
+		return chezmoi.ExitCodeError(0)
+	default:
+		panic(choice + ": unexpected choice")
+	}
+}
+
 // checkVersion checks that chezmoi is at least the required version for the
 // source state.
 func (c *Config) checkVersion() error {
@@ -704,8 +1117,9 @@ func (c *Config) checkVersion() error {
 	return nil
 }
 
-// cmdOutput returns the of running the command name with args in dirAbsPath.
-func (c *Config) cmdOutput(dirAbsPath chezmoi.AbsPath, name string, args []string) ([]byte, error) {
+// vcsOutput returns the output of running the VCS command name with args in
+// dirAbsPath, reporting it as from subsystem.
+func (c *Config) vcsOutput(subsystem string, dirAbsPath chezmoi.AbsPath, name string, args []string) ([]byte, error) {
 	cmd := exec.Command(name, args...)
 	cmd.Stderr = os.Stderr
 	if !dirAbsPath.Empty() {
@@ -715,9 +1129,16 @@ func (c *Config) cmdOutput(dirAbsPath chezmoi.AbsPath, name string, args []strin
 		}
 		cmd.Dir = dirRawAbsPath.String()
 	}
+	c.verboseCmdf(subsystem, name, args)
 	return chezmoilog.LogCmdOutput(slog.Default(), cmd)
 }
 
+// secretCmdOutput returns the output of running the password manager command cmd.
+func (c *Config) secretCmdOutput(cmd *exec.Cmd) ([]byte, error) {
+	c.verbosef(chezmoi.VerbosityActions, "secrets", "running %s\n", shellQuoteCommand(cmd.Args[0], cmd.Args[1:]))
+	return chezmoilog.LogCmdOutput(c.logger, cmd)
+}
+
 // colorAutoFunc detects whether color should be used.
 func (c *Config) colorAutoFunc() bool {
 	if _, ok := os.LookupEnv("NO_COLOR"); ok {
@@ -773,20 +1194,21 @@ func (c *Config) createAndReloadConfigFile(cmd *cobra.Command) error {
 			configPath = c.customConfigFileAbsPath
 		}
 	}
-	if err := chezmoi.MkdirAll(c.baseSystem, configPath.Dir(), fs.ModePerm); err != nil {
-		return err
-	}
-	if err := c.baseSystem.WriteFile(configPath, configFileContents, 0o600); err != nil {
-		return err
+	if c.dryRun {
+		// In dry run mode, show the config file instead of writing it.
+		if err := c.writeOutput(configFileContents); err != nil {
+			return err
+		}
+	} else {
+		if err := chezmoi.MkdirAll(c.baseSystem, configPath.Dir(), fs.ModePerm); err != nil {
+			return err
+		}
+		if err := c.baseSystem.WriteFile(configPath, configFileContents, 0o600); err != nil {
+			return err
+		}
 	}
 
-	configStateValue, err := chezmoi.FormatJSON.Marshal(configState{
-		ConfigTemplateContentsSHA256: chezmoi.HexBytes(chezmoi.SHA256Sum(configTemplate.contents)),
-	})
-	if err != nil {
-		return err
-	}
-	if err := c.persistentState.Set(chezmoi.ConfigStateBucket, configStateKey, configStateValue); err != nil {
+	if err := c.recordConfigTemplateState(configFileContents); err != nil {
 		return err
 	}
 
@@ -806,6 +1228,148 @@ func (c *Config) createAndReloadConfigFile(cmd *cobra.Command) error {
 	return nil
 }
 
+// checkConfigTemplate checks whether the config file template has changed
+// since the config file was last generated. If it has, then the config file is
+// regenerated if --refresh-config is set, otherwise a warning is printed.
+func (c *Config) checkConfigTemplate(cmd *cobra.Command, annotations annotationsSet) error {
+	switch {
+	case annotations.hasTag(doesNotRequireValidConfig):
+		return nil
+	case annotations.hasTag(modifiesConfigFile):
+		return nil
+	case annotations.persistentStateMode() == persistentStateModeEmpty:
+		return nil
+	}
+
+	switch configTemplateChanged, err := c.configTemplateChanged(); {
+	case err != nil:
+		return err
+	case !configTemplateChanged:
+		return nil
+	case c.refreshConfig:
+		return c.refreshConfigFile(cmd)
+	case !c.force && c.Warnings.ConfigFileTemplateHasChanged:
+		c.warnf("config file template has changed, run chezmoi init to regenerate config file\n")
+	}
+	return nil
+}
+
+// configTemplateChanged returns whether the config file template has changed
+// since the config file was last generated.
+func (c *Config) configTemplateChanged() (bool, error) {
+	configTemplate, err := c.findConfigTemplate()
+	if err != nil {
+		return false, err
+	}
+	var currentConfigTemplateContentsSHA256 []byte
+	if configTemplate != nil {
+		currentConfigTemplateContentsSHA256 = chezmoi.SHA256Sum(configTemplate.contents)
+	}
+
+	var configState configState
+	if _, err := c.readPersistentState(chezmoi.ConfigStateBucket, configStateKey, &configState); err != nil {
+		return false, err
+	}
+	previousConfigTemplateContentsSHA256 := []byte(configState.ConfigTemplateContentsSHA256)
+
+	if currentConfigTemplateContentsSHA256 == nil && previousConfigTemplateContentsSHA256 == nil {
+		return false, nil
+	}
+	return !bytes.Equal(currentConfigTemplateContentsSHA256, previousConfigTemplateContentsSHA256), nil
+}
+
+// recordConfigTemplateState records the current config file template and
+// configFileContents, the config file generated from it, in the persistent
+// state. If there is no config file template then the state is deleted.
+func (c *Config) recordConfigTemplateState(configFileContents []byte) error {
+	configTemplate, err := c.findConfigTemplate()
+	if err != nil {
+		return err
+	}
+	if configTemplate == nil {
+		return c.persistentState.Delete(chezmoi.ConfigStateBucket, configStateKey)
+	}
+	state := configState{
+		ConfigTemplateContentsSHA256: chezmoi.HexBytes(chezmoi.SHA256Sum(configTemplate.contents)),
+	}
+	if configFileContents != nil {
+		state.ConfigFileContentsSHA256 = chezmoi.HexBytes(chezmoi.SHA256Sum(configFileContents))
+	} else {
+		// Keep the hash of the config file, as it was not regenerated.
+		var previousConfigState configState
+		if _, err := c.readPersistentState(chezmoi.ConfigStateBucket, configStateKey, &previousConfigState); err != nil {
+			return err
+		}
+		state.ConfigFileContentsSHA256 = previousConfigState.ConfigFileContentsSHA256
+	}
+	return chezmoi.PersistentStateSet(c.persistentState, chezmoi.ConfigStateBucket, configStateKey, &state)
+}
+
+// refreshConfigFile regenerates the config file from its template. The config
+// file is only overwritten if it has not been modified since it was last
+// generated, unless --force is set.
+func (c *Config) refreshConfigFile(cmd *cobra.Command) error {
+	var configState configState
+	if _, err := c.readPersistentState(chezmoi.ConfigStateBucket, configStateKey, &configState); err != nil {
+		return err
+	}
+	if len(configState.ConfigFileContentsSHA256) != 0 && !c.force {
+		switch configFileContents, err := c.baseSystem.ReadFile(c.getConfigFileAbsPath()); {
+		case errors.Is(err, fs.ErrNotExist):
+			// Do nothing.
+		case err != nil:
+			return err
+		case !bytes.Equal(chezmoi.SHA256Sum(configFileContents), configState.ConfigFileContentsSHA256):
+			return fmt.Errorf("%s: config file has been modified since it was generated, run chezmoi init to regenerate it", c.getConfigFileAbsPath())
+		}
+	}
+
+	// The command's persistent state might be read-only or a mock, so record
+	// the new config state in a read-write persistent state, unless this is a
+	// dry run.
+	persistentState := c.persistentState
+	defer func() {
+		c.persistentState = persistentState
+	}()
+	if c.dryRun {
+		c.persistentState = chezmoi.NewMockPersistentState()
+		return c.createAndReloadConfigFile(cmd)
+	}
+	if err := persistentState.Close(); err != nil {
+		return err
+	}
+	persistentStateFileAbsPath, err := c.persistentStateFile()
+	if err != nil {
+		return err
+	}
+	readWritePersistentState, err := chezmoi.NewBoltPersistentState(
+		c.baseSystem,
+		persistentStateFileAbsPath,
+		chezmoi.BoltPersistentStateReadWrite,
+	)
+	if err != nil {
+		return err
+	}
+	defer readWritePersistentState.Close()
+	c.persistentState = readWritePersistentState
+	if err := c.createAndReloadConfigFile(cmd); err != nil {
+		return err
+	}
+
+	// Update the command's persistent state if it is a copy of the real
+	// persistent state.
+	if _, ok := persistentState.(*chezmoi.MockPersistentState); ok {
+		configStateValue, err := readWritePersistentState.Get(chezmoi.ConfigStateBucket, configStateKey)
+		if err != nil {
+			return err
+		}
+		if err := persistentState.Set(chezmoi.ConfigStateBucket, configStateKey, configStateValue); err != nil {
+			return err
+		}
+	}
+	return readWritePersistentState.Close()
+}
+
 // createConfigFile creates a config file using a template and returns its
 // contents.
 func (c *Config) createConfigFile(filename chezmoi.RelPath, data []byte, cmd *cobra.Command) ([]byte, error) {
@@ -955,6 +1519,41 @@ func (c *Config) decodeConfigMap(configMap map[string]any, configFile *ConfigFil
 	return decoder.Decode(configMap)
 }
 
+// isEntryTypeConflict returns if applying targetEntryState would replace a
+// directory with a non-directory, or vice versa, and chezmoi has either never
+// written the entry or its type has changed since chezmoi last wrote it.
+func isEntryTypeConflict(targetEntryState, lastWrittenEntryState, actualEntryState *chezmoi.EntryState) bool {
+	switch targetEntryState.Type {
+	case chezmoi.EntryStateTypeDir, chezmoi.EntryStateTypeFile, chezmoi.EntryStateTypeSymlink:
+	default:
+		return false
+	}
+	switch actualEntryState.Type {
+	case chezmoi.EntryStateTypeDir, chezmoi.EntryStateTypeFile, chezmoi.EntryStateTypeSymlink:
+	default:
+		return false
+	}
+	if (targetEntryState.Type == chezmoi.EntryStateTypeDir) == (actualEntryState.Type == chezmoi.EntryStateTypeDir) {
+		return false
+	}
+	return lastWrittenEntryState == nil || lastWrittenEntryState.Type != actualEntryState.Type
+}
+
+// backupTarget moves the destination entry of targetRelPath out of the way so
+// that it can be replaced. On dry runs it does nothing.
+func (c *Config) backupTarget(targetRelPath chezmoi.RelPath) error {
+	if c.dryRun {
+		return nil
+	}
+	destAbsPath := c.DestDirAbsPath.Join(targetRelPath)
+	backupAbsPath := destAbsPath.Append(".chezmoi-backup-" + time.Now().Format("20060102150405"))
+	if err := c.destSystem.Rename(destAbsPath, backupAbsPath); err != nil {
+		return err
+	}
+	c.warnf("%s: moved to %s\n", destAbsPath, backupAbsPath)
+	return nil
+}
+
 // defaultPreApplyFunc is the default pre-apply function. If the target entry
 // has changed since chezmoi last wrote it then it prompts the user for the
 // action to take.
@@ -969,11 +1568,37 @@ func (c *Config) defaultPreApplyFunc(
 		slog.Any("actualEntryState", actualEntryState),
 	)
 
+	if err := c.confirmSSHKeyscanKeys(); err != nil {
+		return err
+	}
+
+	if targetEntryState.Type == chezmoi.EntryStateTypeFile && !targetEntryState.Equivalent(actualEntryState) {
+		if err := c.validateTarget(targetRelPath, targetEntryState.Contents()); err != nil {
+			return err
+		}
+	}
+
+	if isEntryTypeConflict(targetEntryState, lastWrittenEntryState, actualEntryState) &&
+		(c.sourceState == nil || !c.sourceState.IsRemoveDir(targetRelPath)) {
+		if !c.force {
+			return fmt.Errorf(
+				"%s: %s in destination but %s in target state, move it or run with --force to replace it and keep a backup",
+				targetRelPath,
+				actualEntryState.Type,
+				targetEntryState.Type,
+			)
+		}
+		return c.backupTarget(targetRelPath)
+	}
+
 	switch {
 	case c.force:
 		return nil
 	case targetEntryState.Equivalent(actualEntryState):
 		return nil
+	case c.Scripts.Confirm && targetEntryState.Type == chezmoi.EntryStateTypeScript &&
+		!targetEntryState.Equivalent(lastWrittenEntryState):
+		return c.confirmScript(targetRelPath, lastWrittenEntryState)
 	}
 
 	if c.interactive {
@@ -1030,6 +1655,10 @@ func (c *Config) defaultPreApplyFunc(
 	choices = append(choices, "overwrite", "all-overwrite", "skip", "quit")
 	for {
 		switch choice, err := c.promptChoice(prompt, choices); {
+		case errors.Is(err, io.EOF):
+			// The user cannot be prompted, so refuse to overwrite the changes.
+			return errors.New("has changed since chezmoi last wrote it, " +
+				"run chezmoi add to keep the changes or run with --force to overwrite them")
 		case err != nil:
 			return err
 		case choice == "diff":
@@ -1077,18 +1706,34 @@ type destAbsPathInfosOptions struct {
 	follow         bool
 	ignoreNotExist bool
 	onIgnoreFunc   func(chezmoi.RelPath)
+	prune          []string
 	recursive      bool
 }
 
 // destAbsPathInfos returns the os/fs.FileInfos for each destination entry in
 // args, recursing into subdirectories and following symlinks if configured in
-// options.
+// options. Subdirectories are walked concurrently and subdirectories whose
+// names match any of options.prune are skipped.
 func (c *Config) destAbsPathInfos(
+	ctx context.Context,
 	sourceState *chezmoi.SourceState,
 	args []string,
 	options destAbsPathInfosOptions,
 ) (map[chezmoi.AbsPath]fs.FileInfo, error) {
 	destAbsPathInfos := make(map[chezmoi.AbsPath]fs.FileInfo)
+	// mutex protects destAbsPathInfos and calls to options.onIgnoreFunc, as
+	// subdirectories are walked concurrently.
+	var mutex sync.Mutex
+	onIgnore := func(targetRelPath chezmoi.RelPath) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		options.onIgnoreFunc(targetRelPath)
+	}
+	addDestAbsPathInfo := func(destAbsPath chezmoi.AbsPath, fileInfo fs.FileInfo) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return sourceState.AddDestAbsPathInfos(destAbsPathInfos, c.destSystem, destAbsPath, fileInfo)
+	}
 	for _, arg := range args {
 		arg = filepath.Clean(arg)
 		destAbsPath, err := chezmoi.NewAbsPathFromExtPath(arg, c.homeDirAbsPath)
@@ -1099,12 +1744,14 @@ func (c *Config) destAbsPathInfos(
 		if err != nil {
 			return nil, err
 		}
-		if sourceState.Ignore(targetRelPath) {
+		if sourceState.Ignore(targetRelPath) || sourceState.AddIgnored(targetRelPath) {
 			options.onIgnoreFunc(targetRelPath)
 			continue
 		}
 		if options.recursive {
-			walkFunc := func(destAbsPath chezmoi.AbsPath, fileInfo fs.FileInfo, err error) error {
+			rootAbsPath := destAbsPath
+			var walkFunc chezmoi.WalkFunc
+			walkFunc = func(destAbsPath chezmoi.AbsPath, fileInfo fs.FileInfo, err error) error {
 				switch {
 				case options.ignoreNotExist && errors.Is(err, fs.ErrNotExist):
 					return nil
@@ -1116,24 +1763,63 @@ func (c *Config) destAbsPathInfos(
 				if err != nil {
 					return err
 				}
-				if sourceState.Ignore(targetRelPath) {
-					options.onIgnoreFunc(targetRelPath)
+				if sourceState.Ignore(targetRelPath) || sourceState.AddIgnored(targetRelPath) {
+					onIgnore(targetRelPath)
 					if fileInfo.IsDir() {
 						return fs.SkipDir
 					}
 					return nil
 				}
 
+				if fileInfo.IsDir() && destAbsPath != rootAbsPath {
+					pruned, err := matchesAnyPattern(options.prune, fileInfo.Name())
+					if err != nil {
+						return err
+					}
+					if pruned {
+						onIgnore(targetRelPath)
+						return fs.SkipDir
+					}
+				}
+
 				if options.follow && fileInfo.Mode().Type() == fs.ModeSymlink {
-					fileInfo, err = c.destSystem.Stat(destAbsPath)
+					targetFileInfo, err := c.destSystem.Stat(destAbsPath)
 					if err != nil {
 						return err
 					}
+
+					// chezmoi.ConcurrentWalk does not descend into symlinks, so
+					// walk the entries of symlinks to directories here. Only
+					// the argument itself is followed. Symlinks to directories
+					// found while walking are added as symlinks, so walking
+					// terminates even if a symlink points to one of its
+					// parents.
+					switch {
+					case !targetFileInfo.IsDir():
+						fileInfo = targetFileInfo
+					case destAbsPath == rootAbsPath:
+						fileInfo = targetFileInfo
+						if err := addDestAbsPathInfo(destAbsPath, fileInfo); err != nil {
+							return err
+						}
+						dirEntries, err := c.destSystem.ReadDir(destAbsPath)
+						if err != nil {
+							return err
+						}
+						group, ctx := errgroup.WithContext(ctx)
+						for _, dirEntry := range dirEntries {
+							dirEntryAbsPath := destAbsPath.JoinString(dirEntry.Name())
+							group.Go(func() error {
+								return chezmoi.ConcurrentWalk(ctx, c.destSystem, dirEntryAbsPath, walkFunc)
+							})
+						}
+						return group.Wait()
+					}
 				}
 
-				return sourceState.AddDestAbsPathInfos(destAbsPathInfos, c.destSystem, destAbsPath, fileInfo)
+				return addDestAbsPathInfo(destAbsPath, fileInfo)
 			}
-			if err := chezmoi.Walk(c.destSystem, destAbsPath, walkFunc); err != nil {
+			if err := chezmoi.ConcurrentWalk(ctx, c.destSystem, destAbsPath, walkFunc); err != nil {
 				return nil, err
 			}
 		} else {
@@ -1217,6 +1903,23 @@ func (c *Config) errorf(format string, args ...any) {
 	fmt.Fprintf(c.stderr, "chezmoi: "+format, args...)
 }
 
+// verbosef writes a message from subsystem to stderr if the verbosity is at
+// least level.
+func (c *Config) verbosef(level int, subsystem, format string, args ...any) {
+	if c.verbosity < level {
+		return
+	}
+	fmt.Fprintf(c.stderr, "chezmoi: "+subsystem+": "+format, args...)
+}
+
+// warnf writes a warning to stderr, unless --quiet is set.
+func (c *Config) warnf(format string, args ...any) {
+	if c.quiet {
+		return
+	}
+	fmt.Fprintf(c.stderr, "chezmoi: warning: "+format, args...)
+}
+
 // execute creates a new root command and executes it with args.
 func (c *Config) execute(args []string) error {
 	rootCmd, err := c.newRootCmd()
@@ -1403,16 +2106,53 @@ func (c *Config) getSourceDirAbsPath(options *getSourceDirAbsPathOptions) (chezm
 		}
 	}
 
-	switch data, err := c.sourceSystem.ReadFile(c.SourceDirAbsPath.JoinString(chezmoi.RootName)); {
-	case errors.Is(err, fs.ErrNotExist):
-		c.sourceDirAbsPath = c.SourceDirAbsPath
-	case err != nil:
-		c.sourceDirAbsPathErr = err
-	default:
-		c.sourceDirAbsPath = c.SourceDirAbsPath.JoinString(string(bytes.TrimSpace(data)))
+	c.sourceDirAbsPath, c.sourceDirAbsPathErr = c.sourceRootAbsPath(c.SourceDirAbsPath)
+	return c.sourceDirAbsPath, c.sourceDirAbsPathErr
+}
+
+// getProfile returns the configuration of the selected profile, if any.
+// Profile names are case-insensitive.
+func (c *Config) getProfile() (profileConfig, error) {
+	if c.Profile == "" {
+		return profileConfig{}, nil
+	}
+	profile, ok := c.Profiles[c.Profile]
+	if !ok {
+		for name, p := range c.Profiles {
+			if strings.EqualFold(name, c.Profile) {
+				profile, ok = p, true
+				break
+			}
+		}
+	}
+	if !ok && len(c.Profiles) != 0 {
+		return profileConfig{}, fmt.Errorf("%s: unknown profile", c.Profile)
+	}
+	return profile, nil
+}
+
+// executeTemplateFuncs returns the template functions for an execution of the
+// template for target.
+func (c *Config) executeTemplateFuncs(target string, referencedNames chezmoiset.Set[string]) template.FuncMap {
+	templateFuncs := c.hashTemplateFuncs(target)
+	if c.mockSecrets {
+		maps.Copy(templateFuncs, c.mockSecretTemplateFuncsFor(target, referencedNames))
 	}
+	return templateFuncs
+}
 
-	return c.sourceDirAbsPath, c.sourceDirAbsPathErr
+// getSourceLayerDirAbsPaths returns the source layer directories, taking into
+// account any .chezmoiroot file in each.
+func (c *Config) getSourceLayerDirAbsPaths() ([]chezmoi.AbsPath, error) {
+	sourceLayerDirAbsPaths := make([]chezmoi.AbsPath, 0, len(c.SourceLayerAbsPaths))
+	for _, sourceLayerAbsPath := range c.SourceLayerAbsPaths {
+		sourceLayerDirAbsPath, err := c.sourceRootAbsPath(sourceLayerAbsPath)
+		if err != nil {
+			return nil, err
+		}
+		sourceLayerDirAbsPaths = append(sourceLayerDirAbsPaths, sourceLayerDirAbsPath)
+	}
+	return sourceLayerDirAbsPaths, nil
 }
 
 func (c *Config) getSourceState(ctx context.Context, cmd *cobra.Command) (*chezmoi.SourceState, error) {
@@ -1451,11 +2191,15 @@ func (c *Config) getTemplateDataMap(cmd *cobra.Command) map[string]any {
 			"homeDir":           templateData.homeDir.String(),
 			"hostname":          templateData.hostname,
 			"kernel":            templateData.kernel,
+			"macOSVersion":      templateData.macOSVersion,
 			"os":                templateData.os,
 			"osRelease":         templateData.osRelease,
 			"pathListSeparator": templateData.pathListSeparator,
 			"pathSeparator":     templateData.pathSeparator,
+			"profile":           templateData.profile,
+			"roles":             templateData.roles,
 			"sourceDir":         templateData.sourceDir.String(),
+			"sourceVersion":     templateData.sourceVersion,
 			"uid":               templateData.uid,
 			"username":          templateData.username,
 			"version":           templateData.version,
@@ -1465,37 +2209,258 @@ func (c *Config) getTemplateDataMap(cmd *cobra.Command) map[string]any {
 	}
 }
 
-// gitAutoAdd adds all changes to the git index and returns the new git status.
-func (c *Config) gitAutoAdd() (*chezmoigit.Status, error) {
-	if err := c.run(c.WorkingTreeAbsPath, c.Git.Command, []string{"add", "."}); err != nil {
-		return nil, err
+// gitAutoAdd adds all changes to the git index and returns the new git status.
+func (c *Config) gitAutoAdd() (*chezmoigit.Status, error) {
+	if c.UseBuiltinGit.Value(c.useBuiltinGitAutoFunc) {
+		_, worktree, err := c.builtinGitWorktree()
+		if err != nil {
+			return nil, err
+		}
+		if err := worktree.AddWithOptions(&git.AddOptions{
+			All: true,
+		}); err != nil {
+			return nil, err
+		}
+		gitStatus, err := worktree.Status()
+		if err != nil {
+			return nil, err
+		}
+		return chezmoigit.NewStatusFromGoGit(gitStatus), nil
+	}
+	if err := c.runVCS(gitVCSName, c.WorkingTreeAbsPath, c.Git.Command, []string{"add", "."}); err != nil {
+		return nil, err
+	}
+	output, err := c.vcsOutput(gitVCSName, c.WorkingTreeAbsPath, c.Git.Command, []string{"status", "--porcelain=v2"})
+	if err != nil {
+		return nil, err
+	}
+	return chezmoigit.ParseStatusPorcelainV2(output)
+}
+
+// gitAutoCommit commits all changes in the git index, including generating a
+// commit message from status.
+func (c *Config) gitAutoCommit(cmd *cobra.Command, status *chezmoigit.Status) error {
+	if status.Empty() {
+		return nil
+	}
+	commitMessage, err := c.gitCommitMessage(cmd, status)
+	if err != nil {
+		return err
+	}
+	return c.gitCommit(cmd, commitMessage)
+}
+
+// gitAdd adds the changes to relPaths, which are relative to the working tree,
+// including their removal, to the git index.
+func (c *Config) gitAdd(relPaths []chezmoi.RelPath) error {
+	if c.UseBuiltinGit.Value(c.useBuiltinGitAutoFunc) {
+		_, worktree, err := c.builtinGitWorktree()
+		if err != nil {
+			return err
+		}
+		for _, relPath := range relPaths {
+			if _, err := worktree.Add(relPath.String()); err != nil {
+				return fmt.Errorf("%s: %w", relPath, err)
+			}
+		}
+		return nil
+	}
+	args := make([]string, 0, 2+len(relPaths))
+	args = append(args, "add", "--")
+	for _, relPath := range relPaths {
+		args = append(args, relPath.String())
+	}
+	return c.runVCS(gitVCSName, c.WorkingTreeAbsPath, c.Git.Command, args)
+}
+
+// gitCommit commits all changes in the git index with commitMessage.
+func (c *Config) gitCommit(cmd *cobra.Command, commitMessage []byte) error {
+	if c.UseBuiltinGit.Value(c.useBuiltinGitAutoFunc) {
+		repo, worktree, err := c.builtinGitWorktree()
+		if err != nil {
+			return err
+		}
+		commitOptions := &git.CommitOptions{}
+		// Unlike git, go-git does not fall back to a default author, so use
+		// chezmoi <username@hostname> if git's config does not set one.
+		gitConfig, err := repo.ConfigScoped(gitconfig.SystemScope)
+		if err != nil {
+			return err
+		}
+		if (gitConfig.Author.Name == "" || gitConfig.Author.Email == "") &&
+			(gitConfig.User.Name == "" || gitConfig.User.Email == "") {
+			templateData := c.getTemplateData(cmd)
+			commitOptions.Author = &object.Signature{
+				Name:  "chezmoi",
+				Email: templateData.username + "@" + templateData.hostname,
+				When:  time.Now(),
+			}
+		}
+		_, err = worktree.Commit(string(commitMessage), commitOptions)
+		return err
+	}
+	return c.runVCS(gitVCSName, c.WorkingTreeAbsPath, c.Git.Command, []string{"commit", "--message", string(commitMessage)})
+}
+
+// gitAutoPush pushes all changes to the remote, or to each of
+// c.Git.PushRemotes, if status is not empty.
+func (c *Config) gitAutoPush(status *chezmoigit.Status) error {
+	if status.Empty() {
+		return nil
 	}
-	output, err := c.cmdOutput(c.WorkingTreeAbsPath, c.Git.Command, []string{"status", "--porcelain=v2"})
-	if err != nil {
-		return nil, err
+	return c.gitPushRemotes()
+}
+
+// gitPushRemotes pushes to the remote, or to each of c.Git.PushRemotes. A
+// failure to push to one remote does not prevent pushing to the others.
+func (c *Config) gitPushRemotes() error {
+	if len(c.Git.PushRemotes) == 0 {
+		return c.gitPush("")
 	}
-	return chezmoigit.ParseStatusPorcelainV2(output)
+	errs := make([]error, 0, len(c.Git.PushRemotes))
+	for _, remote := range c.Git.PushRemotes {
+		if err := c.gitPush(remote); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", remote, err))
+		}
+	}
+	return chezmoierrors.Combine(errs...)
 }
 
-// gitAutoCommit commits all changes in the git index, including generating a
-// commit message from status.
-func (c *Config) gitAutoCommit(cmd *cobra.Command, status *chezmoigit.Status) error {
-	if status.Empty() {
+// gitPush pushes to remote, or to the default remote if remote is empty.
+func (c *Config) gitPush(remote string) error {
+	if c.UseBuiltinGit.Value(c.useBuiltinGitAutoFunc) {
+		repo, _, err := c.builtinGitWorktree()
+		if err != nil {
+			return err
+		}
+		if err := repo.Push(&git.PushOptions{
+			RemoteName: remote,
+		}); err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+			return err
+		}
 		return nil
 	}
-	commitMessage, err := c.gitCommitMessage(cmd, status)
+	args := []string{"push"}
+	if remote != "" {
+		args = append(args, remote)
+	}
+	return c.runVCS(gitVCSName, c.WorkingTreeAbsPath, c.Git.Command, args)
+}
+
+// builtinGitWorktree returns the builtin git repository and worktree of the
+// working tree.
+func (c *Config) builtinGitWorktree() (*git.Repository, *git.Worktree, error) {
+	rawWorkingTreeAbsPath, err := c.baseSystem.RawPath(c.WorkingTreeAbsPath)
 	if err != nil {
-		return err
+		return nil, nil, err
+	}
+	repo, err := git.PlainOpen(rawWorkingTreeAbsPath.String())
+	if err != nil {
+		return nil, nil, err
 	}
-	return c.run(c.WorkingTreeAbsPath, c.Git.Command, []string{"commit", "--message", string(commitMessage)})
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return nil, nil, err
+	}
+	return repo, worktree, nil
 }
 
-// gitAutoPush pushes all changes to the remote if status is not empty.
-func (c *Config) gitAutoPush(status *chezmoigit.Status) error {
-	if status.Empty() {
+// getSourceVersion returns the git commit, tag, dirty flag, and commit date of
+// the working tree. If the working tree is not a git repository then it
+// returns nil.
+func (c *Config) getSourceVersion() (map[string]any, error) {
+	switch _, err := c.baseSystem.Stat(c.WorkingTreeAbsPath.JoinString(git.GitDirName)); {
+	case errors.Is(err, fs.ErrNotExist):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+	repo, worktree, err := c.builtinGitWorktree()
+	if err != nil {
+		return nil, err
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return nil, err
+	}
+	commit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return nil, err
+	}
+	globalExcludes, err := c.builtinGitGlobalExcludes()
+	if err != nil {
+		return nil, err
+	}
+	worktree.Excludes = append(worktree.Excludes, globalExcludes...)
+	status, err := worktree.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	var tag string
+	tagRefs, err := repo.Tags()
+	if err != nil {
+		return nil, err
+	}
+	if err := tagRefs.ForEach(func(ref *plumbing.Reference) error {
+		hash := ref.Hash()
+		if tagObject, err := repo.TagObject(hash); err == nil {
+			hash = tagObject.Target
+		}
+		if hash == head.Hash() {
+			tag = ref.Name().Short()
+			return storer.ErrStop
+		}
 		return nil
+	}); err != nil {
+		return nil, err
 	}
-	return c.run(c.WorkingTreeAbsPath, c.Git.Command, []string{"push"})
+
+	return map[string]any{
+		"commit":     head.Hash().String(),
+		"commitDate": commit.Committer.When.Format(time.RFC3339),
+		"dirty":      !status.IsClean(),
+		"tag":        tag,
+	}, nil
+}
+
+// builtinGitGlobalExcludes returns the patterns in the user's global git
+// excludes file, which is core.excludesFile or, if it is not set,
+// $XDG_CONFIG_HOME/git/ignore. The builtin git does not read it itself.
+func (c *Config) builtinGitGlobalExcludes() ([]gitignore.Pattern, error) {
+	globalConfig, err := gitconfig.LoadConfig(gitconfig.GlobalScope)
+	if err != nil {
+		return nil, err
+	}
+	var excludesFileAbsPath chezmoi.AbsPath
+	if excludesFile := globalConfig.Raw.Section("core").Option("excludesFile"); excludesFile != "" {
+		excludesFileAbsPath, err = chezmoi.NewAbsPathFromExtPath(excludesFile, c.homeDirAbsPath)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		excludesFileAbsPath, err = chezmoi.NewAbsPathFromExtPath(c.bds.ConfigHome, c.homeDirAbsPath)
+		if err != nil {
+			return nil, err
+		}
+		excludesFileAbsPath = excludesFileAbsPath.JoinString("git", "ignore")
+	}
+	data, err := c.baseSystem.ReadFile(excludesFileAbsPath)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+	var patterns []gitignore.Pattern
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, gitignore.ParsePattern(line, nil))
+	}
+	return patterns, nil
 }
 
 // gitCommitMessage returns the git commit message for the given status.
@@ -1530,6 +2495,11 @@ func (c *Config) gitCommitMessage(cmd *cobra.Command, status *chezmoigit.Status)
 	default:
 		name = "COMMIT_MESSAGE"
 		commitMessageTemplateData = []byte(templates.CommitMessageTmpl)
+		// The default commit message template uses sprig's fail function, so
+		// make it available even if the sprig template functions are disabled.
+		if _, ok := funcMap["fail"]; !ok {
+			funcMap["fail"] = sprig.TxtFuncMap()["fail"]
+		}
 	}
 	commitMessageTmpl, err := chezmoi.ParseTemplate(name, commitMessageTemplateData, funcMap, chezmoi.TemplateOptions{
 		Options: slices.Clone(c.Template.Options),
@@ -1569,6 +2539,27 @@ func (c *Config) marshal(dataFormat writeDataFormat, data any) error {
 	return c.writeOutput(marshaledData)
 }
 
+// writeFormatTemplate executes formatTemplate with each of items as data,
+// writing each result on its own line.
+func writeFormatTemplate[T any](c *Config, formatTemplate string, items []T) error {
+	tmpl, err := chezmoi.ParseTemplate("format-template", []byte(formatTemplate), c.templateFuncs, chezmoi.TemplateOptions{
+		Options: slices.Clone(c.Template.Options),
+	})
+	if err != nil {
+		return err
+	}
+	builder := strings.Builder{}
+	for _, item := range items {
+		output, err := tmpl.Execute(item)
+		if err != nil {
+			return err
+		}
+		builder.Write(output)
+		builder.WriteByte('\n')
+	}
+	return c.writeOutputString(builder.String())
+}
+
 // newRootCmd returns a new root github.com/spf13/cobra.Command.
 func (c *Config) newRootCmd() (*cobra.Command, error) {
 	rootCmd := &cobra.Command{
@@ -1588,12 +2579,13 @@ func (c *Config) newRootCmd() (*cobra.Command, error) {
 	persistentFlags.VarP(&c.DestDirAbsPath, "destination", "D", "Set destination directory")
 	persistentFlags.Var(&c.Mode, "mode", "Mode")
 	persistentFlags.Var(&c.PersistentStateAbsPath, "persistent-state", "Set persistent state file")
+	persistentFlags.StringVar(&c.Profile, "profile", c.Profile, "Set profile")
 	persistentFlags.Var(&c.Progress, "progress", "Display progress bars")
 	persistentFlags.BoolVar(&c.Safe, "safe", c.Safe, "Safely replace files and symlinks")
 	persistentFlags.VarP(&c.SourceDirAbsPath, "source", "S", "Set source directory")
 	persistentFlags.Var(&c.UseBuiltinAge, "use-builtin-age", "Use builtin age")
 	persistentFlags.Var(&c.UseBuiltinGit, "use-builtin-git", "Use builtin git")
-	persistentFlags.BoolVarP(&c.Verbose, "verbose", "v", c.Verbose, "Make output more verbose")
+	persistentFlags.CountVarP(&c.verbosity, "verbose", "v", "Make output more verbose, repeat for more detail")
 	persistentFlags.VarP(&c.WorkingTreeAbsPath, "working-tree", "W", "Set working tree directory")
 
 	persistentFlags.VarP(&c.customConfigFileAbsPath, "config", "c", "Set config file")
@@ -1606,7 +2598,9 @@ func (c *Config) newRootCmd() (*cobra.Command, error) {
 	persistentFlags.BoolVarP(&c.keepGoing, "keep-going", "k", c.keepGoing, "Keep going as far as possible after an error")
 	persistentFlags.BoolVar(&c.noPager, "no-pager", c.noPager, "Do not use the pager")
 	persistentFlags.BoolVar(&c.noTTY, "no-tty", c.noTTY, "Do not attempt to get a TTY for prompts")
+	persistentFlags.BoolVarP(&c.quiet, "quiet", "q", c.quiet, "Suppress all output except errors")
 	persistentFlags.VarP(&c.outputAbsPath, "output", "o", "Write output to path instead of stdout")
+	persistentFlags.BoolVar(&c.refreshConfig, "refresh-config", c.refreshConfig, "Regenerate the config file if its template has changed")
 	persistentFlags.VarP(&c.refreshExternals, "refresh-externals", "R", "Refresh external cache")
 	persistentFlags.Lookup("refresh-externals").NoOptDefVal = chezmoi.RefreshExternalsAlways.String()
 	persistentFlags.BoolVar(&c.sourcePath, "source-path", c.sourcePath, "Specify targets by source path")
@@ -1652,27 +2646,34 @@ func (c *Config) newRootCmd() (*cobra.Command, error) {
 		c.newEditConfigCmd(),
 		c.newEditConfigTemplateCmd(),
 		c.newEncryptCommand(),
+		c.newEncryptedCmd(),
 		c.newExecuteTemplateCmd(),
 		c.newForgetCmd(),
 		c.newGenerateCmd(),
 		c.newGitCmd(),
+		c.newHgCmd(),
 		c.newIgnoredCmd(),
 		c.newImportCmd(),
 		c.newInitCmd(),
 		c.newInternalTestCmd(),
 		c.newLicenseCmd(),
+		c.newLintCmd(),
 		c.newMackupCmd(),
 		c.newManagedCmd(),
 		c.newMergeCmd(),
 		c.newMergeAllCmd(),
+		c.newPackagesCmd(),
 		c.newPurgeCmd(),
 		c.newReAddCmd(),
 		c.newRemoveCmd(),
+		c.newRotateKeyCmd(),
 		c.newSecretCmd(),
+		c.newSignCmd(),
 		c.newSourcePathCmd(),
 		c.newStateCmd(),
 		c.newStatusCmd(),
 		c.newTargetPathCmd(),
+		c.newUICmd(),
 		c.newUnmanagedCmd(),
 		c.newUpdateCmd(),
 		c.newUpgradeCmd(),
@@ -1680,6 +2681,7 @@ func (c *Config) newRootCmd() (*cobra.Command, error) {
 	} {
 		if cmd != nil {
 			registerCommonFlagCompletionFuncs(cmd)
+			applyHelp(cmd)
 			rootCmd.AddCommand(cmd)
 		}
 	}
@@ -1690,13 +2692,14 @@ func (c *Config) newRootCmd() (*cobra.Command, error) {
 // newDiffSystem returns a system that logs all changes to s to w using
 // diff.command if set or the builtin git diff otherwise.
 func (c *Config) newDiffSystem(s chezmoi.System, w io.Writer, dirAbsPath chezmoi.AbsPath) chezmoi.System {
-	if c.useBuiltinDiff || c.Diff.Command == "" {
+	if c.useBuiltinDiff || c.Diff.Command == "" || c.Diff.captureOutput() {
 		options := &chezmoi.GitDiffSystemOptions{
-			Color:          c.Color.Value(c.colorAutoFunc),
+			Color:          c.Color.Value(c.colorAutoFunc) && !c.Diff.captureOutput(),
 			Filter:         chezmoi.NewEntryTypeFilter(c.Diff.include.Bits(), c.Diff.Exclude.Bits()),
 			Reverse:        c.Diff.Reverse,
 			ScriptContents: c.Diff.ScriptContents,
 			TextConvFunc:   c.TextConv.convert,
+			RedactFunc:     c.redactDiff,
 		}
 		return chezmoi.NewGitDiffSystem(s, w, dirAbsPath, options)
 	}
@@ -1704,6 +2707,7 @@ func (c *Config) newDiffSystem(s chezmoi.System, w io.Writer, dirAbsPath chezmoi
 		Filter:         chezmoi.NewEntryTypeFilter(c.Diff.include.Bits(), c.Diff.Exclude.Bits()),
 		Reverse:        c.Diff.Reverse,
 		ScriptContents: c.Diff.ScriptContents,
+		RedactFunc:     c.redactDiff,
 	}
 	return chezmoi.NewExternalDiffSystem(s, c.Diff.Command, c.Diff.Args, c.DestDirAbsPath, options)
 }
@@ -1730,6 +2734,16 @@ func (c *Config) newSourceState(
 		return nil, err
 	}
 
+	sourceLayerDirAbsPaths, err := c.getSourceLayerDirAbsPaths()
+	if err != nil {
+		return nil, err
+	}
+
+	profile, err := c.getProfile()
+	if err != nil {
+		return nil, err
+	}
+
 	if err := c.runHookPre(readSourceStateHookName); err != nil {
 		return nil, err
 	}
@@ -1742,20 +2756,28 @@ func (c *Config) newSourceState(
 		}),
 		chezmoi.WithDestDir(c.DestDirAbsPath),
 		chezmoi.WithEncryption(c.encryption),
+		chezmoi.WithExecuteTemplateFuncs(c.executeTemplateFuncs),
 		chezmoi.WithHTTPClient(httpClient),
+		chezmoi.WithIgnorePatterns(profile.Ignore),
 		chezmoi.WithInterpreters(c.Interpreters),
+		chezmoi.WithLayerDirs(sourceLayerDirAbsPaths),
 		chezmoi.WithLogger(sourceStateLogger),
 		chezmoi.WithMode(c.Mode),
 		chezmoi.WithPriorityTemplateData(c.Data),
+		chezmoi.WithPriorityTemplateData(profile.Data),
 		chezmoi.WithSourceDir(c.SourceDirAbsPath),
+		chezmoi.WithSymlinkCopyDir(c.SymlinkCopyDirAbsPath),
 		chezmoi.WithSystem(c.sourceSystem),
 		chezmoi.WithTemplateFuncs(c.templateFuncs),
 		chezmoi.WithTemplateOptions(c.Template.Options),
 		chezmoi.WithUmask(c.Umask),
+		chezmoi.WithVerboseFunc(c.verbosef),
 		chezmoi.WithVersion(c.version),
 	}, options...)...)
 
 	if err := sourceState.Read(ctx, &chezmoi.ReadOptions{
+		BandwidthLimit:   c.Externals.BandwidthLimit,
+		MaxConnections:   c.Externals.MaxConnections,
 		RefreshExternals: c.refreshExternals,
 		ReadHTTPResponse: c.readHTTPResponse,
 	}); err != nil {
@@ -1813,7 +2835,7 @@ func (c *Config) persistentPostRunRootE(cmd *cobra.Command, args []string) error
 			}
 		}
 		if err != nil {
-			c.errorf("warning: %s: %v\n", c.getConfigFileAbsPath(), err)
+			c.warnf("%s: %v\n", c.getConfigFileAbsPath(), err)
 		}
 	}
 
@@ -1848,6 +2870,13 @@ func (c *Config) persistentPostRunRootE(cmd *cobra.Command, args []string) error
 		return err
 	}
 
+	if c.logFile != nil {
+		if err := c.logFile.Close(); err != nil {
+			return err
+		}
+		c.logFile = nil
+	}
+
 	return nil
 }
 
@@ -1932,16 +2961,56 @@ func (c *Config) persistentPreRunRootE(cmd *cobra.Command, args []string) error
 		}
 	}
 
+	if !c.Template.Sprig {
+		c.removeSprigTemplateFuncs()
+	}
+
 	if c.force && c.interactive {
 		return errors.New("the --force and --interactive flags are mutually exclusive")
 	}
 
-	// Configure the logger.
-	var handler slog.Handler
-	if c.debug {
-		handler = slog.NewTextHandler(c.stderr, nil)
+	// The verbose config file variable sets the lowest verbosity level.
+	switch {
+	case c.quiet && c.verbosity > 0:
+		return errors.New("the --quiet and --verbose flags are mutually exclusive")
+	case c.Verbose && !c.quiet && c.verbosity == 0:
+		c.verbosity = chezmoi.VerbosityChanges
+	}
+	c.Verbose = c.verbosity >= chezmoi.VerbosityChanges
+
+	if c.mockSecrets {
+		c.mockSecretTemplateFuncs()
 	} else {
+		c.recordSecretTemplateFuncs()
+	}
+	if err := c.memoizeTemplateFuncs(); err != nil {
+		return err
+	}
+
+	// Configure the logger. Setting logging.file enables debug logging to
+	// that file.
+	if !c.Logging.File.Empty() {
+		logFile, err := os.OpenFile(c.Logging.File.String(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			return err
+		}
+		c.logFile = logFile
+		c.debug = true
+	}
+	var logWriter io.Writer = c.stderr
+	if c.logFile != nil {
+		logWriter = c.logFile
+	}
+	var handler slog.Handler
+	switch {
+	case !c.debug:
 		handler = chezmoilog.NullHandler{}
+	case c.Logging.Format == "" || c.Logging.Format == "text":
+		handler = slog.NewTextHandler(logWriter, nil)
+	case c.Logging.Format == "json":
+		handler = slog.NewJSONHandler(logWriter, nil)
+	default:
+		return fmt.Errorf("%s: invalid logging format", c.Logging.Format)
 	}
 	c.logger = slog.New(handler)
 	slog.SetDefault(c.logger)
@@ -1952,14 +3021,20 @@ func (c *Config) persistentPreRunRootE(cmd *cobra.Command, args []string) error
 		slog.Any("args", os.Args),
 		slog.String("goVersion", runtime.Version()),
 	)
-	realSystem := chezmoi.NewRealSystem(c.fileSystem,
+	realSystemOptions := []chezmoi.RealSystemOption{
 		chezmoi.RealSystemWithSafe(c.Safe),
 		chezmoi.RealSystemWithScriptTempDir(c.ScriptTempDir),
+	}
+	c.baseSystem = chezmoi.NewRealSystem(c.fileSystem, realSystemOptions...)
+	// On Windows, only targets have their access restricted by private ACLs,
+	// so the destination system is a separate RealSystem.
+	var destBaseSystem chezmoi.System = chezmoi.NewRealSystem(c.fileSystem,
+		append(realSystemOptions, chezmoi.RealSystemWithPrivateACLs(true))...,
 	)
-	c.baseSystem = realSystem
 	if c.debug {
 		systemLogger := c.logger.With(slog.String(logComponentKey, logComponentValueSystem))
 		c.baseSystem = chezmoi.NewDebugSystem(c.baseSystem, systemLogger)
+		destBaseSystem = chezmoi.NewDebugSystem(destBaseSystem, systemLogger)
 	}
 
 	// Set up the persistent state.
@@ -1979,6 +3054,7 @@ func (c *Config) persistentPreRunRootE(cmd *cobra.Command, args []string) error
 		if err != nil {
 			return err
 		}
+		c.persistentStateReadOnly = true
 	case persistentStateMode == persistentStateModeReadMockWrite:
 		fallthrough
 	case persistentStateMode == persistentStateModeReadWrite && c.dryRun:
@@ -2015,6 +3091,7 @@ func (c *Config) persistentPreRunRootE(cmd *cobra.Command, args []string) error
 		if err != nil {
 			return err
 		}
+		c.machineSecretWritable = true
 	default:
 		c.persistentState = chezmoi.NullPersistentState{}
 	}
@@ -2025,7 +3102,10 @@ func (c *Config) persistentPreRunRootE(cmd *cobra.Command, args []string) error
 
 	// Set up the source and destination systems.
 	c.sourceSystem = c.baseSystem
-	c.destSystem = c.baseSystem
+	c.destSystem = destBaseSystem
+	if c.Elevate.Command != "" {
+		c.destSystem = chezmoi.NewElevateSystem(c.destSystem, c.Elevate.Command, c.Elevate.Args)
+	}
 	if !annotations.hasTag(modifiesDestinationDirectory) {
 		c.destSystem = chezmoi.NewReadOnlySystem(c.destSystem)
 	}
@@ -2042,6 +3122,8 @@ func (c *Config) persistentPreRunRootE(cmd *cobra.Command, args []string) error
 		// Otherwise, write the diff output directly to stdout.
 		var writer io.Writer
 		switch pagerCmd, err := c.getDiffPagerCmd(); {
+		case c.Diff.captureOutput():
+			writer = &c.diffFormatOutput
 		case err != nil:
 			return err
 		case pagerCmd == nil:
@@ -2153,6 +3235,7 @@ func (c *Config) persistentPreRunRootE(cmd *cobra.Command, args []string) error
 		"HOME_DIR":      templateData.homeDir.String(),
 		"HOSTNAME":      templateData.hostname,
 		"OS":            templateData.os,
+		"PROFILE":       templateData.profile,
 		"SOURCE_DIR":    templateData.sourceDir.String(),
 		"UID":           templateData.uid,
 		"USERNAME":      templateData.username,
@@ -2160,11 +3243,12 @@ func (c *Config) persistentPreRunRootE(cmd *cobra.Command, args []string) error
 	} {
 		os.Setenv("CHEZMOI_"+key, value)
 	}
-	if c.Verbose {
-		os.Setenv("CHEZMOI_VERBOSE", "1")
+	if c.verbosity > 0 {
+		os.Setenv("CHEZMOI_VERBOSE", strconv.Itoa(c.verbosity))
 	}
 	for groupKey, group := range map[string]map[string]any{
 		"KERNEL":          templateData.kernel,
+		"MACOS_VERSION":   templateData.macOSVersion,
 		"OS_RELEASE":      templateData.osRelease,
 		"VERSION":         templateData.version,
 		"WINDOWS_VERSION": templateData.windowsVersion,
@@ -2180,6 +3264,10 @@ func (c *Config) persistentPreRunRootE(cmd *cobra.Command, args []string) error
 		return err
 	}
 
+	if err := c.checkConfigTemplate(cmd, annotations); err != nil {
+		return err
+	}
+
 	if err := c.runHookPre(cmd.Name()); err != nil {
 		return err
 	}
@@ -2216,6 +3304,9 @@ func (c *Config) persistentStateFile() (chezmoi.AbsPath, error) {
 
 // progressAutoFunc detects whether progress bars should be displayed.
 func (c *Config) progressAutoFunc() bool {
+	if c.quiet {
+		return false
+	}
 	if stdout, ok := c.stdout.(*os.File); ok {
 		return term.IsTerminal(int(stdout.Fd()))
 	}
@@ -2300,10 +3391,27 @@ func (c *Config) newTemplateData(cmd *cobra.Command) *templateData {
 		}
 	}
 
+	var macOSVersion map[string]any
+	if runtime.GOOS == "darwin" {
+		if macOSVersion, err = chezmoi.MacOSVersion(c.fileSystem); err != nil {
+			c.logger.Info("chezmoi.MacOSVersion", slog.Any("err", err))
+		}
+	}
+
 	executable, _ := os.Executable()
 	windowsVersion, _ := windowsVersion()
 	sourceDirAbsPath, _ := c.getSourceDirAbsPath(nil)
 
+	// Getting the source version requires reading the git repository, so only
+	// do it if a template uses it.
+	sourceVersion := sync.OnceValue(func() any {
+		sourceVersion, err := c.getSourceVersion()
+		if err != nil {
+			c.logger.Info("getSourceVersion", slog.Any("err", err))
+		}
+		return sourceVersion
+	})
+
 	return &templateData{
 		arch:              runtime.GOARCH,
 		args:              os.Args,
@@ -2319,11 +3427,15 @@ func (c *Config) newTemplateData(cmd *cobra.Command) *templateData {
 		homeDir:           c.homeDirAbsPath,
 		hostname:          hostname,
 		kernel:            kernel,
+		macOSVersion:      macOSVersion,
 		os:                runtime.GOOS,
 		osRelease:         osRelease,
 		pathListSeparator: string(os.PathListSeparator),
 		pathSeparator:     string(os.PathSeparator),
+		profile:           c.Profile,
+		roles:             slices.Clone(c.Roles),
 		sourceDir:         sourceDirAbsPath,
+		sourceVersion:     chezmoi.LazyValue(sourceVersion),
 		uid:               uid,
 		username:          username,
 		version: map[string]any{
@@ -2372,6 +3484,29 @@ func (c *Config) run(dir chezmoi.AbsPath, name string, args []string) error {
 	return nil
 }
 
+// runVCS runs the VCS command name with args in dir, reporting it as from
+// subsystem.
+func (c *Config) runVCS(subsystem string, dir chezmoi.AbsPath, name string, args []string) error {
+	c.verboseCmdf(subsystem, name, args)
+	return c.run(dir, name, args)
+}
+
+// runPassthroughCmd runs the VCS command name with args in the working tree,
+// for commands like chezmoi git that pass their arguments through. As any
+// command might modify the working tree, it is not run on dry runs.
+func (c *Config) runPassthroughCmd(subsystem, name string, args []string) error {
+	if c.dryRun {
+		c.verbosef(chezmoi.VerbosityChanges, subsystem, "not running %s on dry run\n", shellQuoteCommand(name, args))
+		return nil
+	}
+	return c.runVCS(subsystem, c.WorkingTreeAbsPath, name, args)
+}
+
+// verboseCmdf reports that subsystem is running the command name with args.
+func (c *Config) verboseCmdf(subsystem, name string, args []string) {
+	c.verbosef(chezmoi.VerbosityActions, subsystem, "running %s\n", shellQuoteCommand(name, args))
+}
+
 // runEditor runs the configured editor with args.
 func (c *Config) runEditor(args []string) error {
 	if err := c.persistentState.Close(); err != nil {
@@ -2385,28 +3520,74 @@ func (c *Config) runEditor(args []string) error {
 	err = c.run(chezmoi.EmptyAbsPath, editor, editorArgs)
 	if runtime.GOOS != "windows" && c.Edit.MinDuration != 0 {
 		if duration := time.Since(start); duration < c.Edit.MinDuration {
-			c.errorf("warning: %s: returned in less than %s\n", shellQuoteCommand(editor, editorArgs), c.Edit.MinDuration)
+			c.warnf("%s: returned in less than %s\n", shellQuoteCommand(editor, editorArgs), c.Edit.MinDuration)
 		}
 	}
 	return err
 }
 
-// runHookPost runs the hook's post command, if it is set.
-func (c *Config) runHookPost(hook string) error {
-	command := c.Hooks[hook].Post
-	if command.Command == "" {
+// runHookCommand runs hookCommand, if it is set.
+func (c *Config) runHookCommand(hook string, hookCommand hookCommandConfig) error {
+	switch {
+	case hookCommand.Command != "" && hookCommand.Script != "":
+		return fmt.Errorf("%s: only one of command or script may be set", hook)
+	case hookCommand.Command != "":
+		return c.run(c.homeDirAbsPath, hookCommand.Command, hookCommand.Args)
+	case hookCommand.Script != "":
+		return c.runHookScript(hookCommand.Script, hookCommand.Args)
+	default:
 		return nil
 	}
-	return c.run(c.homeDirAbsPath, command.Command, command.Args)
+}
+
+// runHookPost runs the hook's post command, if it is set.
+func (c *Config) runHookPost(hook string) error {
+	return c.runHookCommand(hook, c.Hooks[hook].Post)
 }
 
 // runHookPre runs the hook's pre command, if it is set.
 func (c *Config) runHookPre(hook string) error {
-	command := c.Hooks[hook].Pre
-	if command.Command == "" {
-		return nil
+	return c.runHookCommand(hook, c.Hooks[hook].Pre)
+}
+
+// runHookScript runs script with args. Relative paths are relative to the
+// source directory and the script is run with the interpreter for its
+// extension, if any.
+func (c *Config) runHookScript(script string, args []string) error {
+	var scriptAbsPath chezmoi.AbsPath
+	if filepath.IsAbs(script) || strings.HasPrefix(script, "~") {
+		var err error
+		scriptAbsPath, err = chezmoi.NewAbsPathFromExtPath(script, c.homeDirAbsPath)
+		if err != nil {
+			return err
+		}
+	} else {
+		sourceDirAbsPath, err := c.getSourceDirAbsPath(nil)
+		if err != nil {
+			return err
+		}
+		scriptAbsPath = sourceDirAbsPath.JoinString(filepath.ToSlash(script))
+	}
+	scriptRawAbsPath, err := c.baseSystem.RawPath(scriptAbsPath)
+	if err != nil {
+		return err
+	}
+	extension := strings.ToLower(strings.TrimPrefix(scriptAbsPath.Ext(), "."))
+	interpreter := c.Interpreters[extension]
+	cmd := interpreter.ExecCommand(scriptRawAbsPath.String())
+	cmd.Args = append(cmd.Args, args...)
+	homeDirRawAbsPath, err := c.baseSystem.RawPath(c.homeDirAbsPath)
+	if err != nil {
+		return err
+	}
+	cmd.Dir = homeDirRawAbsPath.String()
+	cmd.Stdin = c.stdin
+	cmd.Stdout = c.stdout
+	cmd.Stderr = c.stderr
+	if err := chezmoilog.LogCmdRun(c.logger, cmd); err != nil {
+		return fmt.Errorf("%s: %w", scriptAbsPath, err)
 	}
-	return c.run(c.homeDirAbsPath, command.Command, command.Args)
+	return nil
 }
 
 // setEncryption configures c's encryption.
@@ -2445,20 +3626,32 @@ func (c *Config) setEncryption() error {
 	return nil
 }
 
-// setEnvironmentVariables sets all environment variables defined in c.
-func (c *Config) setEnvironmentVariables() error {
-	var env map[string]string
+// environmentVariables returns the environment variables defined in c.
+func (c *Config) environmentVariables() (map[string]string, error) {
 	switch {
 	case len(c.Env) != 0 && len(c.ScriptEnv) != 0:
-		return errors.New("only one of env or scriptEnv may be set")
+		return nil, errors.New("only one of env or scriptEnv may be set")
 	case len(c.Env) != 0:
-		env = c.Env
-	case len(c.ScriptEnv) != 0:
-		env = c.ScriptEnv
+		return c.Env, nil
+	default:
+		return c.ScriptEnv, nil
+	}
+}
+
+// setEnvironmentVariables sets all environment variables defined in c, except
+// those whose values are templates, which are set by
+// setScriptEnvironmentVariables.
+func (c *Config) setEnvironmentVariables() error {
+	env, err := c.environmentVariables()
+	if err != nil {
+		return err
 	}
 	for key, value := range env {
+		if strings.HasSuffix(key, chezmoi.TemplateSuffix) {
+			continue
+		}
 		if strings.HasPrefix(key, "CHEZMOI_") {
-			c.errorf("warning: %s: overriding reserved environment variable", key)
+			c.warnf("%s: overriding reserved environment variable", key)
 		}
 		if err := os.Setenv(key, value); err != nil {
 			return err
@@ -2467,6 +3660,38 @@ func (c *Config) setEnvironmentVariables() error {
 	return nil
 }
 
+// setScriptEnvironmentVariables sets the environment variables defined in c
+// whose keys have a .tmpl suffix to their values executed as templates with
+// sourceState's template data, so that scripts can be passed values from
+// .chezmoidata files. The suffix is removed from the environment variable's
+// name. Other values are never executed as templates.
+func (c *Config) setScriptEnvironmentVariables(sourceState *chezmoi.SourceState) error {
+	env, err := c.environmentVariables()
+	if err != nil {
+		return err
+	}
+	for _, key := range chezmoimaps.SortedKeys(env) {
+		name, ok := strings.CutSuffix(key, chezmoi.TemplateSuffix)
+		if !ok {
+			continue
+		}
+		if strings.HasPrefix(name, "CHEZMOI_") {
+			c.warnf("%s: overriding reserved environment variable", name)
+		}
+		value, err := sourceState.ExecuteTemplateData(chezmoi.ExecuteTemplateDataOptions{
+			Name: "scriptEnv." + key,
+			Data: []byte(env[key]),
+		})
+		if err != nil {
+			return err
+		}
+		if err := os.Setenv(name, string(value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // sourceAbsPaths returns the source absolute paths for each target path in
 // args.
 func (c *Config) sourceAbsPaths(sourceState *chezmoi.SourceState, args []string) ([]chezmoi.AbsPath, error) {
@@ -2478,12 +3703,24 @@ func (c *Config) sourceAbsPaths(sourceState *chezmoi.SourceState, args []string)
 	}
 	sourceAbsPaths := make([]chezmoi.AbsPath, 0, len(targetRelPaths))
 	for _, targetRelPath := range targetRelPaths {
-		sourceAbsPath := c.SourceDirAbsPath.Join(sourceState.MustEntry(targetRelPath).SourceRelPath().RelPath())
-		sourceAbsPaths = append(sourceAbsPaths, sourceAbsPath)
+		sourceAbsPaths = append(sourceAbsPaths, sourceState.SourceAbsPath(sourceState.MustEntry(targetRelPath)))
 	}
 	return sourceAbsPaths, nil
 }
 
+// sourceRootAbsPath returns the directory containing the source state in
+// dirAbsPath, taking into account any .chezmoiroot file.
+func (c *Config) sourceRootAbsPath(dirAbsPath chezmoi.AbsPath) (chezmoi.AbsPath, error) {
+	switch data, err := c.sourceSystem.ReadFile(dirAbsPath.JoinString(chezmoi.RootName)); {
+	case errors.Is(err, fs.ErrNotExist):
+		return dirAbsPath, nil
+	case err != nil:
+		return chezmoi.EmptyAbsPath, err
+	default:
+		return dirAbsPath.JoinString(string(bytes.TrimSpace(data))), nil
+	}
+}
+
 func (c *Config) targetRelPath(absPath chezmoi.AbsPath) (chezmoi.RelPath, error) {
 	relPath, err := absPath.TrimDirPrefix(c.DestDirAbsPath)
 	if notInAbsDirError := (&chezmoi.NotInAbsDirError{}); errors.As(err, &notInAbsDirError) {
@@ -2493,12 +3730,15 @@ func (c *Config) targetRelPath(absPath chezmoi.AbsPath) (chezmoi.RelPath, error)
 }
 
 type targetRelPathsOptions struct {
+	glob                bool
 	mustBeInSourceState bool
 	recursive           bool
 }
 
 // targetRelPaths returns the target relative paths for each target path in
-// args. The returned paths are sorted and de-duplicated.
+// args. If options.glob is set then args containing glob metacharacters match
+// all managed targets. The returned paths are sorted in the order in which
+// they are applied and de-duplicated.
 func (c *Config) targetRelPaths(
 	sourceState *chezmoi.SourceState,
 	args []string,
@@ -2510,27 +3750,44 @@ func (c *Config) targetRelPaths(
 		if err != nil {
 			return nil, err
 		}
-		targetRelPath, err := c.targetRelPath(argAbsPath)
+		argTargetRelPath, err := c.targetRelPath(argAbsPath)
 		if err != nil {
 			return nil, err
 		}
-		sourceStateEntry := sourceState.Get(targetRelPath)
-		if sourceStateEntry == nil {
-			return nil, fmt.Errorf("%s: not managed", arg)
-		}
-		if options != nil && options.mustBeInSourceState {
-			if _, ok := sourceStateEntry.(*chezmoi.SourceStateRemove); ok {
-				return nil, fmt.Errorf("%s: not in source state", arg)
+		argTargetRelPaths := []chezmoi.RelPath{argTargetRelPath}
+		if options != nil && options.glob && strings.ContainsAny(arg, "*?[{") {
+			argTargetRelPaths = nil
+			for _, targetRelPath := range sourceState.TargetRelPaths() {
+				switch match, err := doublestar.Match(argTargetRelPath.String(), targetRelPath.String()); {
+				case err != nil:
+					return nil, fmt.Errorf("%s: %w", arg, err)
+				case match:
+					argTargetRelPaths = append(argTargetRelPaths, targetRelPath)
+				}
+			}
+			if len(argTargetRelPaths) == 0 {
+				return nil, fmt.Errorf("%s: not managed", arg)
 			}
 		}
-		targetRelPaths = append(targetRelPaths, targetRelPath)
-		if options != nil && options.recursive {
-			parentRelPath := targetRelPath
-			// FIXME we should not call s.TargetRelPaths() here - risk of
-			// accidentally quadratic
-			for _, targetRelPath := range sourceState.TargetRelPaths() {
-				if _, err := targetRelPath.TrimDirPrefix(parentRelPath); err == nil {
-					targetRelPaths = append(targetRelPaths, targetRelPath)
+		for _, targetRelPath := range argTargetRelPaths {
+			sourceStateEntry := sourceState.Get(targetRelPath)
+			if sourceStateEntry == nil {
+				return nil, fmt.Errorf("%s: not managed", arg)
+			}
+			if options != nil && options.mustBeInSourceState {
+				if _, ok := sourceStateEntry.(*chezmoi.SourceStateRemove); ok {
+					return nil, fmt.Errorf("%s: not in source state", arg)
+				}
+			}
+			targetRelPaths = append(targetRelPaths, targetRelPath)
+			if options != nil && options.recursive {
+				parentRelPath := targetRelPath
+				// FIXME we should not call s.TargetRelPaths() here - risk of
+				// accidentally quadratic
+				for _, targetRelPath := range sourceState.TargetRelPaths() {
+					if _, err := targetRelPath.TrimDirPrefix(parentRelPath); err == nil {
+						targetRelPaths = append(targetRelPaths, targetRelPath)
+					}
 				}
 			}
 		}
@@ -2541,7 +3798,7 @@ func (c *Config) targetRelPaths(
 	}
 
 	// Sort and de-duplicate targetRelPaths in place.
-	sort.Sort(targetRelPaths)
+	sourceState.SortTargetRelPaths(targetRelPaths)
 	n := 1
 	for i := 1; i < len(targetRelPaths); i++ {
 		if targetRelPaths[i] != targetRelPaths[i-1] {
@@ -2553,14 +3810,14 @@ func (c *Config) targetRelPaths(
 }
 
 // targetRelPathsBySourcePath returns the target relative paths for each arg in
-// args.
+// args. Each arg may be in the source directory or in a source layer directory.
 func (c *Config) targetRelPathsBySourcePath(sourceState *chezmoi.SourceState, args []string) ([]chezmoi.RelPath, error) {
 	targetRelPaths := make([]chezmoi.RelPath, 0, len(args))
-	targetRelPathsBySourceRelPath := make(map[chezmoi.RelPath]chezmoi.RelPath)
+	targetRelPathsBySourceAbsPath := make(map[chezmoi.AbsPath]chezmoi.RelPath)
 	_ = sourceState.ForEach(
 		func(targetRelPath chezmoi.RelPath, sourceStateEntry chezmoi.SourceStateEntry) error {
-			sourceRelPath := sourceStateEntry.SourceRelPath().RelPath()
-			targetRelPathsBySourceRelPath[sourceRelPath] = targetRelPath
+			sourceAbsPath := sourceState.SourceAbsPath(sourceStateEntry)
+			targetRelPathsBySourceAbsPath[sourceAbsPath] = targetRelPath
 			return nil
 		},
 	)
@@ -2569,12 +3826,11 @@ func (c *Config) targetRelPathsBySourcePath(sourceState *chezmoi.SourceState, ar
 		if err != nil {
 			return nil, err
 		}
-		sourceRelPath, err := argAbsPath.TrimDirPrefix(c.SourceDirAbsPath)
-		if err != nil {
-			return nil, err
-		}
-		targetRelPath, ok := targetRelPathsBySourceRelPath[sourceRelPath]
+		targetRelPath, ok := targetRelPathsBySourceAbsPath[argAbsPath]
 		if !ok {
+			if _, err := argAbsPath.TrimDirPrefix(c.SourceDirAbsPath); err != nil && len(c.SourceLayerAbsPaths) == 0 {
+				return nil, err
+			}
 			return nil, fmt.Errorf("%s: not in source state", arg)
 		}
 		targetRelPaths = append(targetRelPaths, targetRelPath)
@@ -2627,10 +3883,36 @@ func (c *Config) targetValidArgs(cmd *cobra.Command, args []string, toComplete s
 // tempDir returns the temporary directory for the given key, creating it if
 // needed.
 func (c *Config) tempDir(key string) (chezmoi.AbsPath, error) {
+	return c.makeTempDir("", key)
+}
+
+// privateTempDir returns a temporary directory for the given key, like
+// tempDir, but on a memory-backed filesystem where one is available so that
+// sensitive contents, like decrypted files, are not written to disk.
+func (c *Config) privateTempDir(key string) (chezmoi.AbsPath, error) {
+	if tempDirAbsPath, ok := c.tempDirs[key]; ok {
+		return tempDirAbsPath, nil
+	}
+	if runtime.GOOS == "linux" {
+		for _, dir := range []string{c.bds.RuntimeDir, "/dev/shm"} {
+			if dir == "" {
+				continue
+			}
+			if tempDirAbsPath, err := c.makeTempDir(dir, key); err == nil {
+				return tempDirAbsPath, nil
+			}
+		}
+	}
+	return c.makeTempDir("", key)
+}
+
+// makeTempDir returns a temporary directory in dir for the given key, creating
+// it if needed.
+func (c *Config) makeTempDir(dir, key string) (chezmoi.AbsPath, error) {
 	if tempDirAbsPath, ok := c.tempDirs[key]; ok {
 		return tempDirAbsPath, nil
 	}
-	tempDir, err := os.MkdirTemp("", key)
+	tempDir, err := os.MkdirTemp(dir, key)
 	chezmoilog.InfoOrError(c.logger, "MkdirTemp", err, slog.String("tempDir", tempDir))
 	if err != nil {
 		return chezmoi.EmptyAbsPath, err
@@ -2671,13 +3953,22 @@ func (c *Config) writeOutput(data []byte) error {
 }
 
 type writePathsOptions struct {
-	tree bool
+	format      writeDataFormat
+	tree        bool
+	treeOptions pathListTreeOptions
 }
 
 func (c *Config) writePaths(paths []string, options writePathsOptions) error {
+	if options.format != "" {
+		if options.tree {
+			return errors.New("the --format and --tree flags are mutually exclusive")
+		}
+		sort.Strings(paths)
+		return c.marshal(options.format, append(make([]string, 0, len(paths)), paths...))
+	}
 	builder := strings.Builder{}
 	if options.tree {
-		newPathListTreeFromPathsSlice(paths).writeChildren(&builder, "", "  ")
+		newPathListTreeFromPathsSlice(paths).writeChildren(&builder, "", "  ", &options.treeOptions)
 	} else {
 		sort.Strings(paths)
 		for _, path := range paths {
@@ -2699,9 +3990,13 @@ func newConfigFile(bds *xdg.BaseDirectorySpecification) ConfigFile {
 		Color: autoBool{
 			auto: true,
 		},
-		Interpreters: defaultInterpreters,
-		Mode:         chezmoi.ModeFile,
-		Pager:        os.Getenv("PAGER"),
+		Interpreters: maps.Clone(defaultInterpreters),
+		KnownHosts: knownHostsConfig{
+			Command: "ssh-keyscan",
+		},
+		Mode:  chezmoi.ModeFile,
+		Mtime: chezmoi.MtimeNone,
+		Pager: os.Getenv("PAGER"),
 		Progress: autoBool{
 			auto: true,
 		},
@@ -2709,8 +4004,12 @@ func newConfigFile(bds *xdg.BaseDirectorySpecification) ConfigFile {
 			Options: pinEntryDefaultOptions,
 		},
 		Safe: true,
+		Scripts: scriptsConfig{
+			HaltOnFailure: true,
+		},
 		Template: templateConfig{
 			Options: chezmoi.DefaultTemplateOptions,
+			Sprig:   true,
 		},
 		Umask: chezmoi.Umask,
 		UseBuiltinAge: autoBool{
@@ -2796,6 +4095,9 @@ func newConfigFile(bds *xdg.BaseDirectorySpecification) ConfigFile {
 			MinDuration: 1 * time.Second,
 			filter:      chezmoi.NewEntryTypeFilter(chezmoi.EntryTypesAll, chezmoi.EntryTypesNone),
 		},
+		Externals: externalsConfig{
+			MaxConnections: 1,
+		},
 		Format: writeDataFormatJSON,
 		Git: gitCmdConfig{
 			Command: "git",
@@ -2803,6 +4105,9 @@ func newConfigFile(bds *xdg.BaseDirectorySpecification) ConfigFile {
 		GitHub: gitHubConfig{
 			RefreshPeriod: 1 * time.Minute,
 		},
+		Hg: hgCmdConfig{
+			Command: "hg",
+		},
 		Merge: mergeCmdConfig{
 			Command: "vimdiff",
 		},
@@ -75,8 +75,11 @@ func TestConfigFileFormatRoundTrip(t *testing.T) {
 	} {
 		t.Run(format.Name(), func(t *testing.T) {
 			configFile := ConfigFile{
-				Color:        autoBool{auto: true},
-				Data:         map[string]any{},
+				Color: autoBool{auto: true},
+				Data:  map[string]any{},
+				Elevate: commandConfig{
+					Args: []string{},
+				},
 				Env:          map[string]string{},
 				Hooks:        map[string]hookConfig{},
 				Interpreters: map[string]chezmoi.Interpreter{},
@@ -87,11 +90,17 @@ func TestConfigFileFormatRoundTrip(t *testing.T) {
 				},
 				ScriptEnv: map[string]string{},
 				Template: templateConfig{
+					Cache: templateCacheConfig{
+						Functions: []string{},
+					},
 					Options: []string{},
 				},
 				TextConv:      []*textConvElement{},
 				UseBuiltinAge: autoBool{value: false},
 				UseBuiltinGit: autoBool{value: true},
+				VCS: vcsConfig{
+					Backends: map[string]vcsBackendConfig{},
+				},
 				Dashlane: dashlaneConfig{
 					Args: []string{},
 				},
@@ -107,6 +116,9 @@ func TestConfigFileFormatRoundTrip(t *testing.T) {
 				Keeper: keeperConfig{
 					Args: []string{},
 				},
+				KnownHosts: knownHostsConfig{
+					Args: []string{},
+				},
 				Passhole: passholeConfig{
 					Args: []string{},
 				},
@@ -136,9 +148,15 @@ func TestConfigFileFormatRoundTrip(t *testing.T) {
 				Edit: editCmdConfig{
 					Args: []string{},
 				},
+				Git: gitCmdConfig{
+					PushRemotes: []string{},
+				},
 				Merge: mergeCmdConfig{
 					Args: []string{},
 				},
+				Packages: packagesCmdConfig{
+					Managers: map[string]packageManagerConfig{},
+				},
 				Update: updateCmdConfig{
 					Args: []string{},
 				},
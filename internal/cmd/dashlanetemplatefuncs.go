@@ -5,8 +5,6 @@ import (
 	"os"
 	"os/exec"
 	"slices"
-
-	"github.com/twpayne/chezmoi/v2/internal/chezmoilog"
 )
 
 type dashlaneConfig struct {
@@ -64,7 +62,7 @@ func (c *Config) dashlaneOutput(args ...string) ([]byte, error) {
 	args = append(slices.Clone(c.Dashlane.Args), args...)
 	cmd := exec.Command(name, args...)
 	cmd.Stderr = os.Stderr
-	output, err := chezmoilog.LogCmdOutput(c.logger, cmd)
+	output, err := c.secretCmdOutput(cmd)
 	if err != nil {
 		return nil, err
 	}
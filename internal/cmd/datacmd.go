@@ -9,7 +9,7 @@ import (
 func (c *Config) newDataCmd() *cobra.Command {
 	dataCmd := &cobra.Command{
 		Use:         "data",
-		Short:       "Print the template data",
+		Short:       mustShortHelp("data"),
 		Long:        mustLongHelp("data"),
 		Example:     example("data"),
 		Args:        cobra.NoArgs,
@@ -17,7 +17,7 @@ func (c *Config) newDataCmd() *cobra.Command {
 		Annotations: newAnnotations(),
 	}
 
-	dataCmd.Flags().VarP(&c.Format, "format", "f", "Output format")
+	dataCmd.Flags().VarP(&c.Format, "format", "f", "")
 
 	return dataCmd
 }
@@ -29,5 +29,7 @@ func (c *Config) runDataCmd(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
-	return c.marshal(c.Format, sourceState.TemplateData())
+	templateData := sourceState.TemplateData()
+	chezmoi.ResolveLazyValues(templateData)
+	return c.marshal(c.Format, templateData)
 }
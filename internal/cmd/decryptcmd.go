@@ -7,7 +7,7 @@ import (
 func (c *Config) newDecryptCommand() *cobra.Command {
 	decryptCmd := &cobra.Command{
 		Use:         "decrypt [file...]",
-		Short:       "Decrypt file or standard input",
+		Short:       mustShortHelp("decrypt"),
 		Long:        mustLongHelp("decrypt"),
 		Example:     example("decrypt"),
 		RunE:        c.runDecryptCmd,
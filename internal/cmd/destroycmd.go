@@ -30,7 +30,7 @@ func (c *Config) newDestroyCmd() *cobra.Command {
 		),
 	}
 
-	destroyCmd.Flags().BoolVarP(&c.destroy.recursive, "recursive", "r", c.destroy.recursive, "Recurse into subdirectories")
+	destroyCmd.Flags().BoolVarP(&c.destroy.recursive, "recursive", "r", c.destroy.recursive, "")
 
 	return destroyCmd
 }
@@ -62,7 +62,7 @@ func (c *Config) runDestroyCmd(cmd *cobra.Command, args []string, sourceState *c
 		var sourceAbsPath chezmoi.AbsPath
 		sourceStateEntry := sourceState.MustEntry(targetRelPath)
 		if _, ok := sourceStateEntry.(*chezmoi.SourceStateRemove); !ok {
-			sourceAbsPath = c.SourceDirAbsPath.Join(sourceStateEntry.SourceRelPath().RelPath())
+			sourceAbsPath = sourceState.SourceAbsPath(sourceStateEntry)
 		}
 		if !c.force {
 			var prompt string
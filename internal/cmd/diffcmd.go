@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"regexp"
+
 	"github.com/spf13/cobra"
 
 	"github.com/twpayne/chezmoi/v2/internal/chezmoi"
@@ -13,15 +15,26 @@ type diffCmdConfig struct {
 	Pager          string                `json:"pager"          mapstructure:"pager"          yaml:"pager"`
 	Reverse        bool                  `json:"reverse"        mapstructure:"reverse"        yaml:"reverse"`
 	ScriptContents bool                  `json:"scriptContents" mapstructure:"scriptContents" yaml:"scriptContents"`
+	format         writeDataFormat
 	include        *chezmoi.EntryTypeSet
 	init           bool
 	recursive      bool
+	showEncrypted  bool
+	stat           bool
+}
+
+// A diffEntry is the machine-readable diff of a single path.
+type diffEntry struct {
+	Path string `json:"path" yaml:"path"`
+	Diff string `json:"diff" yaml:"diff"`
 }
 
+var gitDiffHeaderRx = regexp.MustCompile(`(?m)^diff --git a/.* b/(.*)$`)
+
 func (c *Config) newDiffCmd() *cobra.Command {
 	diffCmd := &cobra.Command{
 		Use:               "diff [target]...",
-		Short:             "Print the diff between the target state and the destination state",
+		Short:             mustShortHelp("diff"),
 		Long:              mustLongHelp("diff"),
 		Example:           example("diff"),
 		ValidArgsFunction: c.targetValidArgs,
@@ -34,23 +47,65 @@ func (c *Config) newDiffCmd() *cobra.Command {
 		),
 	}
 
-	diffCmd.Flags().VarP(c.Diff.Exclude, "exclude", "x", "Exclude entry types")
-	diffCmd.Flags().VarP(c.Diff.include, "include", "i", "Include entry types")
+	diffCmd.Flags().VarP(c.Diff.Exclude, "exclude", "x", "")
+	diffCmd.Flags().VarP(&c.Diff.format, "format", "f", "")
+	diffCmd.Flags().VarP(c.Diff.include, "include", "i", "")
 	diffCmd.Flags().BoolVar(&c.Diff.init, "init", c.Diff.init, "Recreate config file from template")
-	diffCmd.Flags().StringVar(&c.Diff.Pager, "pager", c.Diff.Pager, "Set pager")
+	diffCmd.Flags().BoolVar(&c.mockSecrets, "mock-secrets", c.mockSecrets, "")
+	diffCmd.Flags().StringVar(&c.Diff.Pager, "pager", c.Diff.Pager, "")
 	diffCmd.Flags().BoolVarP(&c.Diff.recursive, "recursive", "r", c.Diff.recursive, "Recurse into subdirectories")
-	diffCmd.Flags().BoolVar(&c.Diff.Reverse, "reverse", c.Diff.Reverse, "Reverse the direction of the diff")
+	diffCmd.Flags().BoolVar(&c.Diff.Reverse, "reverse", c.Diff.Reverse, "")
 	diffCmd.Flags().BoolVar(&c.Diff.ScriptContents, "script-contents", c.Diff.ScriptContents, "Show script contents")
+	diffCmd.Flags().BoolVar(&c.Diff.stat, "stat", c.Diff.stat, "")
 
 	return diffCmd
 }
 
 func (c *Config) runDiffCmd(cmd *cobra.Command, args []string) (err error) {
-	return c.applyArgs(cmd.Context(), c.destSystem, c.DestDirAbsPath, args, applyArgsOptions{
+	// Only show the contents of encrypted files if the user explicitly asked
+	// for them.
+	c.Diff.showEncrypted = cmd.Flags().Changed("include") && c.Diff.include.ContainsEntryTypeBits(chezmoi.EntryTypeEncrypted)
+
+	if err := c.applyArgs(cmd.Context(), c.destSystem, c.DestDirAbsPath, args, applyArgsOptions{
 		cmd:       cmd,
 		filter:    chezmoi.NewEntryTypeFilter(c.Diff.include.Bits(), c.Diff.Exclude.Bits()),
 		init:      c.Diff.init,
 		recursive: c.Diff.recursive,
 		umask:     c.Umask,
-	})
+	}); err != nil {
+		return err
+	}
+	switch {
+	case c.Diff.stat && c.Diff.format != "":
+		return c.marshal(c.Diff.format, parseGitDiffStats(c.diffFormatOutput.String()))
+	case c.Diff.stat:
+		return writeDiffStats(c.stdout, parseGitDiffStats(c.diffFormatOutput.String()))
+	case c.Diff.format != "":
+		return c.marshal(c.Diff.format, parseGitDiffEntries(c.diffFormatOutput.String()))
+	}
+	return nil
+}
+
+// captureOutput returns whether the diff output is captured to be processed
+// after the diff is complete, rather than written directly.
+func (c *diffCmdConfig) captureOutput() bool {
+	return c.format != "" || c.stat
+}
+
+// parseGitDiffEntries splits the git diff output into a diffEntry for each
+// path.
+func parseGitDiffEntries(output string) []diffEntry {
+	entries := []diffEntry{}
+	matches := gitDiffHeaderRx.FindAllStringSubmatchIndex(output, -1)
+	for i, match := range matches {
+		end := len(output)
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		}
+		entries = append(entries, diffEntry{
+			Path: output[match[2]:match[3]],
+			Diff: output[match[0]:end],
+		})
+	}
+	return entries
 }
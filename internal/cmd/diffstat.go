@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxDiffStatBarWidth is the maximum width of the bars in a diffstat.
+const maxDiffStatBarWidth = 40
+
+// A diffStat is the number of lines changed in a single path.
+type diffStat struct {
+	Path       string `json:"path"       yaml:"path"`
+	Insertions int    `json:"insertions" yaml:"insertions"`
+	Deletions  int    `json:"deletions"  yaml:"deletions"`
+	Binary     bool   `json:"binary"     yaml:"binary"`
+}
+
+// parseGitDiffStats returns the diffStat of each path in the git diff output.
+func parseGitDiffStats(output string) []diffStat {
+	entries := parseGitDiffEntries(output)
+	diffStats := make([]diffStat, 0, len(entries))
+	for _, entry := range entries {
+		diffStat := diffStat{
+			Path: entry.Path,
+		}
+		inHunk := false
+		for _, line := range strings.Split(entry.Diff, "\n") {
+			switch {
+			case strings.HasPrefix(line, "@@"):
+				inHunk = true
+			case !inHunk && strings.HasPrefix(line, "Binary files "):
+				diffStat.Binary = true
+			case !inHunk:
+				// Skip headers, including the --- and +++ lines.
+			case strings.HasPrefix(line, "+"):
+				diffStat.Insertions++
+			case strings.HasPrefix(line, "-"):
+				diffStat.Deletions++
+			}
+		}
+		diffStats = append(diffStats, diffStat)
+	}
+	return diffStats
+}
+
+// writeDiffStats writes diffStats to w in the style of git diff --stat.
+func writeDiffStats(w io.Writer, diffStats []diffStat) error {
+	if len(diffStats) == 0 {
+		return nil
+	}
+
+	var pathWidth, countWidth, maxChanges, totalInsertions, totalDeletions int
+	for _, diffStat := range diffStats {
+		pathWidth = max(pathWidth, utf8.RuneCountInString(diffStat.Path))
+		if diffStat.Binary {
+			countWidth = max(countWidth, len("Bin"))
+		}
+		changes := diffStat.Insertions + diffStat.Deletions
+		countWidth = max(countWidth, len(strconv.Itoa(changes)))
+		maxChanges = max(maxChanges, changes)
+		totalInsertions += diffStat.Insertions
+		totalDeletions += diffStat.Deletions
+	}
+
+	for _, diffStat := range diffStats {
+		padding := strings.Repeat(" ", pathWidth-utf8.RuneCountInString(diffStat.Path))
+		if diffStat.Binary {
+			if _, err := fmt.Fprintf(w, " %s%s | %*s\n", diffStat.Path, padding, countWidth, "Bin"); err != nil {
+				return err
+			}
+			continue
+		}
+		insertions, deletions := diffStat.Insertions, diffStat.Deletions
+		if maxChanges > maxDiffStatBarWidth {
+			insertions = scaleDiffStat(insertions, maxChanges)
+			deletions = scaleDiffStat(deletions, maxChanges)
+		}
+		bar := strings.Repeat("+", insertions) + strings.Repeat("-", deletions)
+		changes := diffStat.Insertions + diffStat.Deletions
+		if _, err := fmt.Fprintf(w, " %s%s | %*d %s\n", diffStat.Path, padding, countWidth, changes, bar); err != nil {
+			return err
+		}
+	}
+
+	summary := []string{
+		countNoun(len(diffStats), "file changed", "files changed"),
+		countNoun(totalInsertions, "insertion(+)", "insertions(+)"),
+		countNoun(totalDeletions, "deletion(-)", "deletions(-)"),
+	}
+	_, err := fmt.Fprintf(w, " %s\n", strings.Join(summary, ", "))
+	return err
+}
+
+// countNoun returns n followed by singular or plural, depending on n.
+func countNoun(n int, singular, plural string) string {
+	if n == 1 {
+		return "1 " + singular
+	}
+	return strconv.Itoa(n) + " " + plural
+}
+
+// scaleDiffStat scales n so that maxChanges fits in maxDiffStatBarWidth, while
+// keeping non-zero values non-zero.
+func scaleDiffStat(n, maxChanges int) int {
+	if n == 0 {
+		return 0
+	}
+	return max(1, n*maxDiffStatBarWidth/maxChanges)
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/twpayne/chezmoi/v2/internal/chezmoitest"
+)
+
+func TestParseGitDiffStats(t *testing.T) {
+	output := chezmoitest.JoinLines(
+		`diff --git a/.file b/.file`,
+		`index 8a52cb9ce9551221716a53786ad74104c5902362..6d64b2ad86ff31dd7e25dc1b8f22fb2d25b60c11 100644`,
+		`--- a/.file`,
+		`+++ b/.file`,
+		`@@ -1,2 +1,3 @@`,
+		`-# contents of .file`,
+		`--- not a header`,
+		`+# new contents of .file`,
+		`++++ not a header`,
+		`+# edited`,
+		` unchanged`,
+		`diff --git a/.binary b/.binary`,
+		`new file mode 100644`,
+		`index 0000000000000000000000000000000000000000..e69de29bb2d1d6434b8b29ae775ad8c2e48c5391`,
+		`Binary files /dev/null and b/.binary differ`,
+	)
+	assert.Equal(t, []diffStat{
+		{
+			Path:       ".file",
+			Insertions: 3,
+			Deletions:  2,
+		},
+		{
+			Path:   ".binary",
+			Binary: true,
+		},
+	}, parseGitDiffStats(output))
+}
+
+func TestWriteDiffStats(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		diffStats []diffStat
+		expected  string
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name: "single",
+			diffStats: []diffStat{
+				{Path: ".file", Insertions: 1},
+			},
+			expected: chezmoitest.JoinLines(
+				` .file | 1 +`,
+				` 1 file changed, 1 insertion(+), 0 deletions(-)`,
+			),
+		},
+		{
+			name: "multiple",
+			diffStats: []diffStat{
+				{Path: ".bashrc", Insertions: 2, Deletions: 1},
+				{Path: ".binary", Binary: true},
+				{Path: ".config/file", Insertions: 8, Deletions: 4},
+			},
+			expected: chezmoitest.JoinLines(
+				` .bashrc      |   3 ++-`,
+				` .binary      | Bin`,
+				` .config/file |  12 ++++++++----`,
+				` 3 files changed, 10 insertions(+), 5 deletions(-)`,
+			),
+		},
+		{
+			name: "scaled",
+			diffStats: []diffStat{
+				{Path: ".large", Insertions: 80},
+				{Path: ".small", Insertions: 1, Deletions: 1},
+			},
+			expected: chezmoitest.JoinLines(
+				` .large | 80 `+strings.Repeat("+", maxDiffStatBarWidth),
+				` .small |  2 +-`,
+				` 2 files changed, 81 insertions(+), 1 deletion(-)`,
+			),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var builder strings.Builder
+			assert.NoError(t, writeDiffStats(&builder, tc.diffStats))
+			assert.Equal(t, tc.expected, builder.String())
+		})
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"time"
 
 	"github.com/coreos/go-semver/semver"
+	"github.com/go-git/go-git/v5"
 	"github.com/google/go-github/v62/github"
 	"github.com/spf13/cobra"
 	"github.com/twpayne/go-shell"
@@ -97,8 +98,9 @@ type configFileCheck struct {
 
 // A dirCheck checks that a directory exists.
 type dirCheck struct {
-	name    string
-	dirname chezmoi.AbsPath
+	name          string
+	dirname       chezmoi.AbsPath
+	useBuiltinGit bool
 }
 
 // An executableCheck checks the executable.
@@ -128,6 +130,13 @@ type osArchCheck struct{}
 // A skippedCheck is a check that is skipped.
 type skippedCheck struct{}
 
+// A sourceLayersCheck checks for targets with entries in more than one source
+// layer.
+type sourceLayersCheck struct {
+	sourceLayerAbsPaths []chezmoi.AbsPath
+	sourceStateFunc     func() (*chezmoi.SourceState, error)
+}
+
 // A suspiciousEntriesCheck checks that a source directory does not contain any
 // suspicious files.
 type suspiciousEntriesCheck struct {
@@ -137,17 +146,34 @@ type suspiciousEntriesCheck struct {
 // A upgradeMethodCheck checks the upgrade method.
 type upgradeMethodCheck struct{}
 
+// A useBuiltinGitCheck reports whether the builtin git or the git command is
+// used.
+type useBuiltinGitCheck struct {
+	useBuiltinGit bool
+}
+
 // A versionCheck checks the version information.
 type versionCheck struct {
 	versionInfo VersionInfo
 	versionStr  string
 }
 
+type doctorCmdConfig struct {
+	format writeDataFormat
+}
+
+// A doctorResult is the machine-readable result of a check.
+type doctorResult struct {
+	Result  string `json:"result"  yaml:"result"`
+	Check   string `json:"check"   yaml:"check"`
+	Message string `json:"message" yaml:"message"`
+}
+
 func (c *Config) newDoctorCmd() *cobra.Command {
 	doctorCmd := &cobra.Command{
 		Args:    cobra.NoArgs,
 		Use:     "doctor",
-		Short:   "Check your system for potential problems",
+		Short:   mustShortHelp("doctor"),
 		Example: example("doctor"),
 		Long:    mustLongHelp("doctor"),
 		RunE:    c.runDoctorCmd,
@@ -157,6 +183,8 @@ func (c *Config) newDoctorCmd() *cobra.Command {
 		),
 	}
 
+	doctorCmd.Flags().VarP(&c.doctor.format, "format", "f", "")
+
 	return doctorCmd
 }
 
@@ -170,6 +198,7 @@ func (c *Config) runDoctorCmd(cmd *cobra.Command, args []string) error {
 	shellCommand, shellArgs, _ := parseCommand(shellCommand, nil)
 	cdCommand, cdArgs, _ := c.cdCommand()
 	editCommand, editArgs, _ := c.editor(nil)
+	useBuiltinGit := c.UseBuiltinGit.Value(c.useBuiltinGitAutoFunc)
 	checks := []check{
 		&versionCheck{
 			versionInfo: c.versionInfo,
@@ -195,12 +224,23 @@ func (c *Config) runDoctorCmd(cmd *cobra.Command, args []string) error {
 			name:    "source-dir",
 			dirname: c.SourceDirAbsPath,
 		},
+		&dirPermCheck{
+			name:    "source-dir-perm",
+			dirname: c.SourceDirAbsPath,
+		},
 		&suspiciousEntriesCheck{
 			dirname: c.SourceDirAbsPath,
 		},
+		&sourceLayersCheck{
+			sourceLayerAbsPaths: c.SourceLayerAbsPaths,
+			sourceStateFunc: func() (*chezmoi.SourceState, error) {
+				return c.getSourceState(cmd.Context(), cmd)
+			},
+		},
 		&dirCheck{
-			name:    "working-tree",
-			dirname: c.WorkingTreeAbsPath,
+			name:          "working-tree",
+			dirname:       c.WorkingTreeAbsPath,
+			useBuiltinGit: useBuiltinGit,
 		},
 		&dirCheck{
 			name:    "dest-dir",
@@ -243,6 +283,9 @@ func (c *Config) runDoctorCmd(cmd *cobra.Command, args []string) error {
 			versionArgs: []string{"--version"},
 			versionRx:   regexp.MustCompile(`^git\s+version\s+(\d+\.\d+\.\d+)`),
 		},
+		&useBuiltinGitCheck{
+			useBuiltinGit: useBuiltinGit,
+		},
 		&binaryCheck{
 			name:       "merge-command",
 			binaryname: c.Merge.Command,
@@ -418,8 +461,7 @@ func (c *Config) runDoctorCmd(cmd *cobra.Command, args []string) error {
 	}
 
 	worstResult := checkResultOK
-	resultWriter := tabwriter.NewWriter(c.stdout, 3, 0, 3, ' ', 0)
-	fmt.Fprint(resultWriter, "RESULT\tCHECK\tMESSAGE\n")
+	results := make([]doctorResult, 0, len(checks))
 	for _, check := range checks {
 		checkResult, message := check.Run(c.baseSystem, homeDirAbsPath)
 		if checkResult == checkResultSkipped {
@@ -429,12 +471,28 @@ func (c *Config) runDoctorCmd(cmd *cobra.Command, args []string) error {
 		// output of chezmoi doctor is often posted publicly and would otherwise
 		// reveal the user's username.
 		message = strings.ReplaceAll(message, homeDirAbsPath.String(), "~")
-		fmt.Fprintf(resultWriter, "%s\t%s\t%s\n", checkResultStr[checkResult], check.Name(), message)
+		results = append(results, doctorResult{
+			Result:  checkResultStr[checkResult],
+			Check:   check.Name(),
+			Message: message,
+		})
 		if checkResult > worstResult {
 			worstResult = checkResult
 		}
 	}
-	resultWriter.Flush()
+
+	if c.doctor.format != "" {
+		if err := c.marshal(c.doctor.format, results); err != nil {
+			return err
+		}
+	} else {
+		resultWriter := tabwriter.NewWriter(c.stdout, 3, 0, 3, ' ', 0)
+		fmt.Fprint(resultWriter, "RESULT\tCHECK\tMESSAGE\n")
+		for _, result := range results {
+			fmt.Fprintf(resultWriter, "%s\t%s\t%s\n", result.Result, result.Check, result.Message)
+		}
+		resultWriter.Flush()
+	}
 
 	if worstResult > checkResultWarning {
 		return chezmoi.ExitCodeError(1)
@@ -568,20 +626,7 @@ func (c *dirCheck) Run(system chezmoi.System, homeDirAbsPath chezmoi.AbsPath) (c
 		if dirEntry.Name() != ".git" {
 			continue
 		}
-		cmd := exec.Command( //nolint:gosec
-			"git",
-			"-C",
-			c.dirname.String(),
-			"status",
-			"--porcelain=v2",
-		)
-		cmd.Stderr = os.Stderr
-		output, err := cmd.Output()
-		if err != nil {
-			gitStatus = gitStatusError
-			break
-		}
-		switch status, err := chezmoigit.ParseStatusPorcelainV2(output); {
+		switch status, err := c.gitStatus(); {
 		case err != nil:
 			gitStatus = gitStatusError
 		case status.Empty():
@@ -605,6 +650,38 @@ func (c *dirCheck) Run(system chezmoi.System, homeDirAbsPath chezmoi.AbsPath) (c
 	}
 }
 
+// gitStatus returns the git status of c.dirname.
+func (c *dirCheck) gitStatus() (*chezmoigit.Status, error) {
+	if c.useBuiltinGit {
+		repo, err := git.PlainOpen(c.dirname.String())
+		if err != nil {
+			return nil, err
+		}
+		worktree, err := repo.Worktree()
+		if err != nil {
+			return nil, err
+		}
+		gitStatus, err := worktree.Status()
+		if err != nil {
+			return nil, err
+		}
+		return chezmoigit.NewStatusFromGoGit(gitStatus), nil
+	}
+	cmd := exec.Command( //nolint:gosec
+		"git",
+		"-C",
+		c.dirname.String(),
+		"status",
+		"--porcelain=v2",
+	)
+	cmd.Stderr = os.Stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	return chezmoigit.ParseStatusPorcelainV2(output)
+}
+
 func (executableCheck) Name() string {
 	return "executable"
 }
@@ -712,6 +789,35 @@ func (skippedCheck) Run(system chezmoi.System, homeDirAbsPath chezmoi.AbsPath) (
 	return checkResultSkipped, ""
 }
 
+func (c *sourceLayersCheck) Name() string {
+	return "source-layers"
+}
+
+func (c *sourceLayersCheck) Run(system chezmoi.System, homeDirAbsPath chezmoi.AbsPath) (checkResult, string) {
+	if len(c.sourceLayerAbsPaths) == 0 {
+		return checkResultSkipped, ""
+	}
+	sourceState, err := c.sourceStateFunc()
+	if err != nil {
+		return checkResultFailed, err.Error()
+	}
+	layerConflicts := sourceState.LayerConflicts()
+	if len(layerConflicts) == 0 {
+		return checkResultOK, countNoun(len(c.sourceLayerAbsPaths), "source layer", "source layers") + ", no conflicts"
+	}
+	targetRelPaths := make(chezmoi.RelPaths, 0, len(layerConflicts))
+	for targetRelPath := range layerConflicts {
+		targetRelPaths = append(targetRelPaths, targetRelPath)
+	}
+	sort.Sort(targetRelPaths)
+	conflicts := make([]string, 0, len(targetRelPaths))
+	for _, targetRelPath := range targetRelPaths {
+		origins := layerConflicts[targetRelPath]
+		conflicts = append(conflicts, fmt.Sprintf("%s from %s", targetRelPath, origins[len(origins)-1]))
+	}
+	return checkResultInfo, "overridden targets: " + englishList(conflicts)
+}
+
 func (c *suspiciousEntriesCheck) Name() string {
 	return "suspicious-entries"
 }
@@ -764,6 +870,17 @@ func (upgradeMethodCheck) Run(system chezmoi.System, homeDirAbsPath chezmoi.AbsP
 	return checkResultOK, method
 }
 
+func (c *useBuiltinGitCheck) Name() string {
+	return "use-builtin-git"
+}
+
+func (c *useBuiltinGitCheck) Run(system chezmoi.System, homeDirAbsPath chezmoi.AbsPath) (checkResult, string) {
+	if c.useBuiltinGit {
+		return checkResultInfo, "using builtin git"
+	}
+	return checkResultOK, "using git command"
+}
+
 func (c *versionCheck) Name() string {
 	return "version"
 }
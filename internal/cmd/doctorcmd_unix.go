@@ -4,7 +4,9 @@ package cmd
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log/slog"
 	"os"
 	"os/exec"
@@ -22,6 +24,35 @@ type (
 	unameCheck      struct{}
 )
 
+// A dirPermCheck checks that a directory is not writable by other users.
+type dirPermCheck struct {
+	name    string
+	dirname chezmoi.AbsPath
+}
+
+func (c *dirPermCheck) Name() string {
+	return c.name
+}
+
+func (c *dirPermCheck) Run(system chezmoi.System, homeDirAbsPath chezmoi.AbsPath) (checkResult, string) {
+	fileInfo, err := system.Stat(c.dirname)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return checkResultSkipped, ""
+	case err != nil:
+		return checkResultFailed, err.Error()
+	}
+	perm := fileInfo.Mode().Perm()
+	switch {
+	case perm&0o002 != 0:
+		return checkResultWarning, fmt.Sprintf("%s has permissions %03o, writable by others", c.dirname, perm)
+	case perm&0o020 != 0:
+		return checkResultInfo, fmt.Sprintf("%s has permissions %03o, writable by group", c.dirname, perm)
+	default:
+		return checkResultOK, fmt.Sprintf("%s has permissions %03o", c.dirname, perm)
+	}
+}
+
 func (umaskCheck) Name() string {
 	return "umask"
 }
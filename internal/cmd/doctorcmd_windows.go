@@ -18,6 +18,14 @@ type (
 	unameCheck      struct{ skippedCheck }
 )
 
+// A dirPermCheck is skipped on Windows, where permissions are controlled by
+// ACLs rather than mode bits.
+type dirPermCheck struct {
+	skippedCheck
+	name    string
+	dirname chezmoi.AbsPath
+}
+
 func (systeminfoCheck) Name() string {
 	return "systeminfo"
 }
@@ -7,8 +7,6 @@ import (
 	"os/exec"
 	"slices"
 	"strings"
-
-	"github.com/twpayne/chezmoi/v2/internal/chezmoilog"
 )
 
 type dopplerConfig struct {
@@ -89,7 +87,7 @@ func (c *Config) dopplerOutput(args []string) ([]byte, error) {
 	cmd.Dir = c.DestDirAbsPath.String()
 	cmd.Stdin = os.Stdin
 	cmd.Stderr = os.Stderr
-	output, err := chezmoilog.LogCmdOutput(c.logger, cmd)
+	output, err := c.secretCmdOutput(cmd)
 	if err != nil {
 		return nil, newCmdOutputError(cmd, output, err)
 	}
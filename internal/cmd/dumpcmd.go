@@ -15,7 +15,7 @@ type dumpCmdConfig struct {
 func (c *Config) newDumpCmd() *cobra.Command {
 	dumpCmd := &cobra.Command{
 		Use:               "dump [target]...",
-		Short:             "Generate a dump of the target state",
+		Short:             mustShortHelp("dump"),
 		Long:              mustLongHelp("dump"),
 		Example:           example("dump"),
 		ValidArgsFunction: c.targetValidArgs,
@@ -26,9 +26,9 @@ func (c *Config) newDumpCmd() *cobra.Command {
 		),
 	}
 
-	dumpCmd.Flags().VarP(c.dump.filter.Exclude, "exclude", "x", "Exclude entry types")
-	dumpCmd.Flags().VarP(&c.Format, "format", "f", "Output format")
-	dumpCmd.Flags().VarP(c.dump.filter.Include, "include", "i", "Include entry types")
+	dumpCmd.Flags().VarP(c.dump.filter.Exclude, "exclude", "x", "")
+	dumpCmd.Flags().VarP(&c.Format, "format", "f", "")
+	dumpCmd.Flags().VarP(c.dump.filter.Include, "include", "i", "")
 	dumpCmd.Flags().BoolVar(&c.dump.init, "init", c.dump.init, "Recreate config file from template")
 	dumpCmd.Flags().BoolVarP(&c.dump.recursive, "recursive", "r", c.dump.recursive, "Recurse into subdirectories")
 
@@ -37,14 +37,22 @@ func (c *Config) newDumpCmd() *cobra.Command {
 
 func (c *Config) runDumpCmd(cmd *cobra.Command, args []string) error {
 	dumpSystem := chezmoi.NewDumpSystem()
-	if err := c.applyArgs(cmd.Context(), dumpSystem, chezmoi.EmptyAbsPath, args, applyArgsOptions{
+	err := c.applyArgs(cmd.Context(), dumpSystem, chezmoi.EmptyAbsPath, args, applyArgsOptions{
 		cmd:       cmd,
 		filter:    c.dump.filter,
 		init:      c.dump.init,
 		recursive: c.dump.recursive,
 		umask:     c.Umask,
-	}); err != nil {
+		errorFunc: func(targetRelPath chezmoi.RelPath, err error) error {
+			dumpSystem.SetError(chezmoi.EmptyAbsPath.Join(targetRelPath), err)
+			return nil
+		},
+	})
+	if err != nil && !c.keepGoing {
 		return err
 	}
-	return c.marshal(c.Format, dumpSystem.Data())
+	if err := c.marshal(c.Format, dumpSystem.Data()); err != nil {
+		return err
+	}
+	return err
 }
@@ -5,7 +5,7 @@ import "github.com/spf13/cobra"
 func (c *Config) newDumpConfigCmd() *cobra.Command {
 	dumpConfigCmd := &cobra.Command{
 		Use:         "dump-config",
-		Short:       "Dump the configuration values",
+		Short:       mustShortHelp("dump-config"),
 		Long:        mustLongHelp("dump-config"),
 		Example:     example("dump-config"),
 		Args:        cobra.NoArgs,
@@ -1,8 +1,11 @@
 package cmd
 
 import (
+	"errors"
+	"io/fs"
 	"log/slog"
 	"os"
+	"path/filepath"
 	"runtime"
 	"time"
 
@@ -10,6 +13,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/twpayne/chezmoi/v2/internal/chezmoi"
+	"github.com/twpayne/chezmoi/v2/internal/chezmoierrors"
 	"github.com/twpayne/chezmoi/v2/internal/chezmoilog"
 )
 
@@ -20,6 +24,7 @@ type editCmdConfig struct {
 	MinDuration time.Duration `json:"minDuration" mapstructure:"minDuration" yaml:"minDuration"`
 	Watch       bool          `json:"watch"       mapstructure:"watch"       yaml:"watch"`
 	Apply       bool          `json:"apply"       mapstructure:"apply"       yaml:"apply"`
+	allowDirty  bool
 	filter      *chezmoi.EntryTypeFilter
 	init        bool
 }
@@ -27,7 +32,7 @@ type editCmdConfig struct {
 func (c *Config) newEditCmd() *cobra.Command {
 	editCmd := &cobra.Command{
 		Use:               "edit targets...",
-		Short:             "Edit the source state of a target",
+		Short:             mustShortHelp("edit"),
 		Long:              mustLongHelp("edit"),
 		Example:           example("edit"),
 		ValidArgsFunction: c.targetValidArgs,
@@ -41,17 +46,26 @@ func (c *Config) newEditCmd() *cobra.Command {
 		),
 	}
 
-	editCmd.Flags().BoolVarP(&c.Edit.Apply, "apply", "a", c.Edit.Apply, "Apply after editing")
+	editCmd.Flags().BoolVar(&c.Edit.allowDirty, "allow-dirty", c.Edit.allowDirty, "")
+	editCmd.Flags().BoolVarP(&c.Edit.Apply, "apply", "a", c.Edit.Apply, "")
 	editCmd.Flags().VarP(c.Edit.filter.Exclude, "exclude", "x", "Exclude entry types")
-	editCmd.Flags().BoolVar(&c.Edit.Hardlink, "hardlink", c.Edit.Hardlink, "Invoke editor with a hardlink to the source file")
+	editCmd.Flags().BoolVar(&c.Edit.Hardlink, "hardlink", c.Edit.Hardlink, "")
 	editCmd.Flags().VarP(c.Edit.filter.Include, "include", "i", "Include entry types")
 	editCmd.Flags().BoolVar(&c.Edit.init, "init", c.Edit.init, "Recreate config file from template")
-	editCmd.Flags().BoolVar(&c.Edit.Watch, "watch", c.Edit.Watch, "Apply on save")
+	editCmd.Flags().BoolVar(&c.Edit.Watch, "watch", c.Edit.Watch, "")
 
 	return editCmd
 }
 
 func (c *Config) runEditCmd(cmd *cobra.Command, args []string) error {
+	// Editing changes the working tree, so check that it is clean before
+	// editing rather than when applying.
+	if (c.Edit.Apply || (c.Edit.Watch && len(args) != 0)) && c.Git.RequireClean && !c.Edit.allowDirty {
+		if err := c.checkWorkingTreeClean(); err != nil {
+			return err
+		}
+	}
+
 	if len(args) == 0 {
 		if err := c.runEditor([]string{c.WorkingTreeAbsPath.String()}); err != nil {
 			return err
@@ -63,7 +77,9 @@ func (c *Config) runEditCmd(cmd *cobra.Command, args []string) error {
 				init:         c.Edit.init,
 				recursive:    true,
 				umask:        c.Umask,
+				mtimeFunc:    c.applyMtimeFunc(),
 				preApplyFunc: c.defaultPreApplyFunc,
+				targetHooks:  true,
 			}); err != nil {
 				return err
 			}
@@ -89,10 +105,12 @@ func (c *Config) runEditCmd(cmd *cobra.Command, args []string) error {
 		decryptedAbsPath chezmoi.AbsPath
 	}
 	var transparentlyDecryptedFiles []transparentlyDecryptedFile
+	var decryptedTempDirAbsPath chezmoi.AbsPath
 TARGET_REL_PATH:
 	for _, targetRelPath := range targetRelPaths {
 		sourceStateEntry := sourceState.MustEntry(targetRelPath)
 		sourceRelPath := sourceStateEntry.SourceRelPath()
+		sourceAbsPath := sourceState.SourceAbsPath(sourceStateEntry)
 		switch sourceStateFile, ok := sourceStateEntry.(*chezmoi.SourceStateFile); {
 		case ok && sourceStateFile.Attr.Encrypted:
 			// FIXME in the case that the file is an encrypted template then we
@@ -100,12 +118,28 @@ TARGET_REL_PATH:
 			// secondly add a hardlink from the edit directory to the temporary
 			// directory.
 
-			tempDirAbsPath, err := c.tempDir("chezmoi-encrypted")
+			tempDirAbsPath, err := c.privateTempDir("chezmoi-encrypted")
 			if err != nil {
 				return err
 			}
+			// Wipe any decrypted files, including any backup files created by
+			// the editor, once the edited files have been re-encrypted, or if
+			// decrypting a later file fails.
+			if decryptedTempDirAbsPath.Empty() {
+				decryptedTempDirAbsPath = tempDirAbsPath
+				defer func() {
+					err := wipeDir(decryptedTempDirAbsPath)
+					chezmoilog.InfoOrError(c.logger, "wipeDir", err, chezmoilog.Stringer("tempDir", decryptedTempDirAbsPath))
+				}()
+			}
 			// FIXME use RawContents and DecryptFile
-			decryptedAbsPath := tempDirAbsPath.Join(sourceRelPath.TargetRelPath(c.encryption.EncryptedSuffix()))
+			// If the file is a template then preserve the .tmpl suffix as a
+			// clue to the editor.
+			decryptedRelPath := sourceRelPath.TargetRelPath(c.encryption.EncryptedSuffix())
+			if sourceStateFile.Attr.Template {
+				decryptedRelPath = decryptedRelPath.AppendString(chezmoi.TemplateSuffix)
+			}
+			decryptedAbsPath := tempDirAbsPath.Join(decryptedRelPath)
 			contents, err := sourceStateFile.Contents()
 			if err != nil {
 				return err
@@ -117,7 +151,7 @@ TARGET_REL_PATH:
 				return err
 			}
 			transparentlyDecryptedFile := transparentlyDecryptedFile{
-				sourceAbsPath:    c.SourceDirAbsPath.Join(sourceRelPath.RelPath()),
+				sourceAbsPath:    sourceAbsPath,
 				decryptedAbsPath: decryptedAbsPath,
 			}
 			transparentlyDecryptedFiles = append(transparentlyDecryptedFiles, transparentlyDecryptedFile)
@@ -151,7 +185,7 @@ TARGET_REL_PATH:
 			if err := os.MkdirAll(hardlinkAbsPath.Dir().String(), 0o700); err != nil {
 				return err
 			}
-			if err := c.baseSystem.Link(c.SourceDirAbsPath.Join(sourceRelPath.RelPath()), hardlinkAbsPath); err == nil {
+			if err := c.baseSystem.Link(sourceAbsPath, hardlinkAbsPath); err == nil {
 				editorArgs = append(editorArgs, hardlinkAbsPath.String())
 				continue TARGET_REL_PATH
 			}
@@ -160,7 +194,6 @@ TARGET_REL_PATH:
 			// source file in the source state.
 			fallthrough
 		default:
-			sourceAbsPath := c.SourceDirAbsPath.Join(sourceRelPath.RelPath())
 			editorArgs = append(editorArgs, sourceAbsPath.String())
 		}
 	}
@@ -190,7 +223,9 @@ TARGET_REL_PATH:
 				init:         c.Edit.init,
 				recursive:    true,
 				umask:        c.Umask,
+				mtimeFunc:    c.applyMtimeFunc(),
 				preApplyFunc: c.defaultPreApplyFunc,
+				targetHooks:  true,
 			}); err != nil {
 				return err
 			}
@@ -239,3 +274,38 @@ TARGET_REL_PATH:
 
 	return postEditFunc()
 }
+
+// wipeDir overwrites the contents of all regular files in dirAbsPath with
+// zeros and then removes them, so that decrypted contents do not remain on
+// disk after editing.
+func wipeDir(dirAbsPath chezmoi.AbsPath) error {
+	return filepath.WalkDir(dirAbsPath.String(), func(path string, dirEntry fs.DirEntry, err error) error {
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			return nil
+		case err != nil:
+			return err
+		case !dirEntry.Type().IsRegular():
+			return nil
+		}
+		return wipeFile(path)
+	})
+}
+
+// wipeFile overwrites the contents of the file at path with zeros and then
+// removes it.
+func wipeFile(path string) error {
+	file, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	fileInfo, err := file.Stat()
+	if err == nil {
+		_, err = file.Write(make([]byte, fileInfo.Size()))
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	err = chezmoierrors.Combine(err, file.Close())
+	return chezmoierrors.Combine(err, os.Remove(path))
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/twpayne/chezmoi/v2/internal/chezmoi"
+)
+
+func TestWipeDir(t *testing.T) {
+	tempDir := t.TempDir()
+	for _, name := range []string{"file", "dir/file", "dir/.file.swp"} {
+		path := filepath.Join(tempDir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o700))
+		assert.NoError(t, os.WriteFile(path, []byte("secret\n"), 0o600))
+	}
+
+	assert.NoError(t, wipeDir(chezmoi.NewAbsPath(tempDir)))
+
+	var paths []string
+	assert.NoError(t, filepath.WalkDir(tempDir, func(path string, dirEntry fs.DirEntry, err error) error {
+		if err == nil && !dirEntry.IsDir() {
+			paths = append(paths, path)
+		}
+		return err
+	}))
+	assert.Zero(t, paths)
+}
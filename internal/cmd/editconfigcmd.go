@@ -7,7 +7,7 @@ import (
 func (c *Config) newEditConfigCmd() *cobra.Command {
 	editConfigCmd := &cobra.Command{
 		Use:     "edit-config",
-		Short:   "Edit the configuration file",
+		Short:   mustShortHelp("edit-config"),
 		Long:    mustLongHelp("edit-config"),
 		Example: example("edit-config"),
 		Args:    cobra.NoArgs,
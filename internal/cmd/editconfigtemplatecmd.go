@@ -12,7 +12,7 @@ import (
 func (c *Config) newEditConfigTemplateCmd() *cobra.Command {
 	editConfigCmd := &cobra.Command{
 		Use:     "edit-config-template",
-		Short:   "Edit the configuration file template",
+		Short:   mustShortHelp("edit-config-template"),
 		Long:    mustLongHelp("edit-config-template"),
 		Example: example("edit-config-template"),
 		Args:    cobra.NoArgs,
@@ -7,7 +7,7 @@ import (
 func (c *Config) newEncryptCommand() *cobra.Command {
 	encryptCmd := &cobra.Command{
 		Use:         "encrypt [file...]",
-		Short:       "Encrypt file or standard input",
+		Short:       mustShortHelp("encrypt"),
 		Long:        mustLongHelp("encrypt"),
 		Example:     example("encrypt"),
 		RunE:        c.runEncryptCmd,
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/twpayne/chezmoi/v2/internal/chezmoi"
+)
+
+type encryptedCmdConfig struct {
+	format    writeDataFormat
+	pathStyle chezmoi.PathStyle
+}
+
+// An encryptedEntry is an encrypted file in the source state, the recipients
+// that it is encrypted to, and whether it can be decrypted with the current
+// encryption configuration.
+type encryptedEntry struct {
+	Path        string   `json:"path"        yaml:"path"`
+	Recipients  []string `json:"recipients"  yaml:"recipients"`
+	Decryptable bool     `json:"decryptable" yaml:"decryptable"`
+}
+
+func (c *Config) newEncryptedCmd() *cobra.Command {
+	encryptedCmd := &cobra.Command{
+		Use:     "encrypted",
+		Short:   mustShortHelp("encrypted"),
+		Long:    mustLongHelp("encrypted"),
+		Example: example("encrypted"),
+		Args:    cobra.NoArgs,
+		RunE:    c.runEncryptedCmd,
+		Annotations: newAnnotations(
+			requiresSourceDirectory,
+		),
+	}
+
+	encryptedCmd.Flags().VarP(&c.encrypted.format, "format", "f", "")
+	encryptedCmd.Flags().VarP(&c.encrypted.pathStyle, "path-style", "p", "")
+
+	return encryptedCmd
+}
+
+func (c *Config) runEncryptedCmd(cmd *cobra.Command, args []string) error {
+	encryptedSuffix := c.encryption.EncryptedSuffix()
+	sourceAbsPaths, err := chezmoi.EncryptedSourceAbsPaths(c.sourceSystem, c.sourceDirAbsPath, encryptedSuffix)
+	if err != nil {
+		return err
+	}
+
+	entries := make([]encryptedEntry, 0, len(sourceAbsPaths))
+	for _, sourceAbsPath := range sourceAbsPaths {
+		ciphertext, err := c.sourceSystem.ReadFile(sourceAbsPath)
+		if err != nil {
+			return err
+		}
+		recipients, err := c.encryption.CiphertextRecipients(ciphertext)
+		if err != nil {
+			return fmt.Errorf("%s: %w", sourceAbsPath, err)
+		}
+		path, err := c.encryptedPath(sourceAbsPath, encryptedSuffix)
+		if err != nil {
+			return err
+		}
+		_, decryptErr := c.encryption.Decrypt(ciphertext)
+		entries = append(entries, encryptedEntry{
+			Path:        path.String(),
+			Recipients:  recipients,
+			Decryptable: decryptErr == nil,
+		})
+	}
+	slices.SortFunc(entries, func(a, b encryptedEntry) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	if c.encrypted.format != "" {
+		return c.marshal(c.encrypted.format, entries)
+	}
+
+	builder := strings.Builder{}
+	tabWriter := tabwriter.NewWriter(&builder, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tabWriter, "PATH\tRECIPIENTS\tDECRYPTABLE")
+	for _, entry := range entries {
+		decryptable := "no"
+		if entry.Decryptable {
+			decryptable = "yes"
+		}
+		fmt.Fprintf(tabWriter, "%s\t%s\t%s\n", entry.Path, strings.Join(entry.Recipients, ","), decryptable)
+	}
+	if err := tabWriter.Flush(); err != nil {
+		return err
+	}
+	return c.writeOutputString(builder.String())
+}
+
+// encryptedPath returns the path of the encrypted source file sourceAbsPath in
+// the encrypted path style.
+func (c *Config) encryptedPath(sourceAbsPath chezmoi.AbsPath, encryptedSuffix string) (fmt.Stringer, error) {
+	sourceRelPath, err := sourceAbsPath.TrimDirPrefix(c.sourceDirAbsPath)
+	if err != nil {
+		return nil, err
+	}
+	targetRelPath := chezmoi.NewSourceRelPath(sourceRelPath.String()).TargetRelPath(encryptedSuffix)
+	switch c.encrypted.pathStyle {
+	case chezmoi.PathStyleAbsolute:
+		return c.DestDirAbsPath.Join(targetRelPath), nil
+	case chezmoi.PathStyleSourceAbsolute:
+		return sourceAbsPath, nil
+	case chezmoi.PathStyleSourceRelative:
+		return sourceRelPath, nil
+	default:
+		return targetRelPath, nil
+	}
+}
@@ -1,11 +1,12 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"io"
+	"path/filepath"
 	"slices"
 	"strconv"
-	"strings"
 
 	"github.com/spf13/cobra"
 
@@ -13,6 +14,8 @@ import (
 )
 
 type executeTemplateCmdConfig struct {
+	dumpDataUsed    bool
+	file            string
 	init            bool
 	promptBool      map[string]string
 	promptChoice    map[string]string
@@ -26,7 +29,7 @@ type executeTemplateCmdConfig struct {
 func (c *Config) newExecuteTemplateCmd() *cobra.Command {
 	executeTemplateCmd := &cobra.Command{
 		Use:     "execute-template [template]...",
-		Short:   "Execute the given template(s)",
+		Short:   mustShortHelp("execute-template"),
 		Long:    mustLongHelp("execute-template"),
 		Example: example("execute-template"),
 		RunE:    c.runExecuteTemplateCmd,
@@ -35,7 +38,11 @@ func (c *Config) newExecuteTemplateCmd() *cobra.Command {
 		),
 	}
 
-	executeTemplateCmd.Flags().BoolVarP(&c.executeTemplate.init, "init", "i", c.executeTemplate.init, "Simulate chezmoi init")
+	executeTemplateCmd.Flags().
+		BoolVar(&c.executeTemplate.dumpDataUsed, "dump-data-used", c.executeTemplate.dumpDataUsed, "")
+	executeTemplateCmd.Flags().StringVar(&c.executeTemplate.file, "file", c.executeTemplate.file, "")
+	executeTemplateCmd.Flags().VarP(&c.Format, "format", "f", "")
+	executeTemplateCmd.Flags().BoolVarP(&c.executeTemplate.init, "init", "i", c.executeTemplate.init, "")
 	executeTemplateCmd.Flags().
 		StringToStringVar(&c.executeTemplate.promptBool, "promptBool", c.executeTemplate.promptBool, "Simulate promptBool")
 	executeTemplateCmd.Flags().
@@ -45,17 +52,26 @@ func (c *Config) newExecuteTemplateCmd() *cobra.Command {
 	executeTemplateCmd.Flags().
 		StringToStringVarP(&c.executeTemplate.promptString, "promptString", "p", c.executeTemplate.promptString, "Simulate promptString")
 	executeTemplateCmd.Flags().
-		BoolVar(&c.executeTemplate.stdinIsATTY, "stdinisatty", c.executeTemplate.stdinIsATTY, "Simulate stdinIsATTY")
+		BoolVar(&c.executeTemplate.stdinIsATTY, "stdinisatty", c.executeTemplate.stdinIsATTY, "")
 	executeTemplateCmd.Flags().
-		StringVar(&c.executeTemplate.templateOptions.LeftDelimiter, "left-delimiter", c.executeTemplate.templateOptions.LeftDelimiter, "Set left template delimiter")
+		StringVar(&c.executeTemplate.templateOptions.LeftDelimiter, "left-delimiter", c.executeTemplate.templateOptions.LeftDelimiter, "")
 	executeTemplateCmd.Flags().
-		StringVar(&c.executeTemplate.templateOptions.RightDelimiter, "right-delimiter", c.executeTemplate.templateOptions.RightDelimiter, "Set right template delimiter")
+		StringVar(&c.executeTemplate.templateOptions.RightDelimiter, "right-delimiter", c.executeTemplate.templateOptions.RightDelimiter, "")
 	executeTemplateCmd.Flags().
-		BoolVar(&c.executeTemplate.withStdin, "with-stdin", c.executeTemplate.withStdin, "Set .chezmoi.stdin to the contents of the standard input")
+		BoolVar(&c.executeTemplate.withStdin, "with-stdin", c.executeTemplate.withStdin, "")
+
+	executeTemplateCmd.MarkFlagsMutuallyExclusive("file", "with-stdin")
 
 	return executeTemplateCmd
 }
 
+// An executeTemplateDataUsed describes the template data and template
+// functions used by templates.
+type executeTemplateDataUsed struct {
+	Data      []string `json:"data"      yaml:"data"`
+	Functions []string `json:"functions" yaml:"functions"`
+}
+
 func (c *Config) runExecuteTemplateCmd(cmd *cobra.Command, args []string) error {
 	options := []chezmoi.SourceStateOption{
 		chezmoi.WithTemplateDataOnly(true),
@@ -238,35 +254,96 @@ func (c *Config) runExecuteTemplateCmd(cmd *cobra.Command, args []string) error
 		chezmoi.RecursiveMerge(c.templateFuncs, initTemplateFuncs)
 	}
 
-	if len(args) == 0 {
+	var accessRecorder *chezmoi.TemplateAccessRecorder
+	if c.executeTemplate.dumpDataUsed {
+		accessRecorder = chezmoi.NewTemplateAccessRecorder()
+	}
+
+	var output []byte
+	switch {
+	case c.executeTemplate.file != "":
+		if len(args) != 0 {
+			return errors.New("--file cannot be used with arguments")
+		}
+		output, err = c.executeTemplateFile(sourceState, c.executeTemplate.file, accessRecorder)
+		if err != nil {
+			return err
+		}
+	case len(args) == 0:
 		data, err := io.ReadAll(c.stdin)
 		if err != nil {
 			return err
 		}
-		output, err := sourceState.ExecuteTemplateData(chezmoi.ExecuteTemplateDataOptions{
+		output, err = sourceState.ExecuteTemplateData(chezmoi.ExecuteTemplateDataOptions{
 			Name:            "stdin",
 			Data:            data,
 			TemplateOptions: c.executeTemplate.templateOptions,
+			AccessRecorder:  accessRecorder,
 		})
 		if err != nil {
 			return err
 		}
-		return c.writeOutput(output)
+	default:
+		for i, arg := range args {
+			result, err := sourceState.ExecuteTemplateData(chezmoi.ExecuteTemplateDataOptions{
+				Name:            "arg" + strconv.Itoa(i+1),
+				Data:            []byte(arg),
+				TemplateOptions: c.executeTemplate.templateOptions,
+				AccessRecorder:  accessRecorder,
+			})
+			if err != nil {
+				return err
+			}
+			output = append(output, result...)
+		}
 	}
 
-	output := strings.Builder{}
-	for i, arg := range args {
-		result, err := sourceState.ExecuteTemplateData(chezmoi.ExecuteTemplateDataOptions{
-			Name:            "arg" + strconv.Itoa(i+1),
-			Data:            []byte(arg),
-			TemplateOptions: c.executeTemplate.templateOptions,
+	if accessRecorder != nil {
+		return c.marshal(c.Format, &executeTemplateDataUsed{
+			Data:      accessRecorder.Data(),
+			Functions: accessRecorder.Funcs(),
 		})
+	}
+	return c.writeOutput(output)
+}
+
+// executeTemplateFile executes the template in the source file at sourcePath,
+// which is either relative to the source directory or an absolute path in the
+// source directory. .chezmoi.sourceFile and .chezmoi.targetFile are set as if
+// the template was being executed by chezmoi apply.
+func (c *Config) executeTemplateFile(
+	sourceState *chezmoi.SourceState,
+	sourcePath string,
+	accessRecorder *chezmoi.TemplateAccessRecorder,
+) ([]byte, error) {
+	sourceDirAbsPath, err := c.getSourceDirAbsPath(nil)
+	if err != nil {
+		return nil, err
+	}
+	fileAbsPath := sourceDirAbsPath.Join(chezmoi.NewRelPath(filepath.ToSlash(sourcePath)))
+	if filepath.IsAbs(sourcePath) {
+		fileAbsPath, err = chezmoi.NewAbsPathFromExtPath(sourcePath, c.homeDirAbsPath)
 		if err != nil {
-			return err
-		}
-		if _, err := output.Write(result); err != nil {
-			return err
+			return nil, err
 		}
 	}
-	return c.writeOutputString(output.String())
+	sourceRelPath, err := fileAbsPath.TrimDirPrefix(sourceDirAbsPath)
+	if err != nil {
+		return nil, fmt.Errorf("%s: not in source directory (%s)", fileAbsPath, sourceDirAbsPath)
+	}
+
+	data, err := c.baseSystem.ReadFile(fileAbsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	name := sourceRelPath.String()
+	targetRelPath := chezmoi.NewSourceRelPath(name).TargetRelPath(c.encryption.EncryptedSuffix())
+	return sourceState.ExecuteTemplateData(chezmoi.ExecuteTemplateDataOptions{
+		Name:            name,
+		Destination:     c.DestDirAbsPath.Join(targetRelPath).String(),
+		Data:            data,
+		TemplateOptions: c.executeTemplate.templateOptions,
+		AccessRecorder:  accessRecorder,
+	})
 }
@@ -12,7 +12,7 @@ func (c *Config) newForgetCmd() *cobra.Command {
 	forgetCmd := &cobra.Command{
 		Use:               "forget target...",
 		Aliases:           []string{"unmanage"},
-		Short:             "Remove a target from the source state",
+		Short:             mustShortHelp("forget"),
 		Long:              mustLongHelp("forget"),
 		Example:           example("forget"),
 		ValidArgsFunction: c.targetValidArgs,
@@ -43,16 +43,16 @@ TARGET_REL_PATH:
 		case chezmoi.SourceStateOriginAbsPath:
 			// OK, keep going.
 		case chezmoi.SourceStateOriginRemove:
-			c.errorf("warning: %s: cannot forget entry from remove\n", targetRelPath)
+			c.warnf("%s: cannot forget entry from remove\n", targetRelPath)
 			continue TARGET_REL_PATH
 		case *chezmoi.External:
-			c.errorf("warning: %s: cannot forget entry from external %s\n", targetRelPath, sourceStateOrigin.OriginString())
+			c.warnf("%s: cannot forget entry from external %s\n", targetRelPath, sourceStateOrigin.OriginString())
 			continue TARGET_REL_PATH
 		default:
 			panic(fmt.Sprintf("%s: %T: unknown source state origin type", targetRelPath, sourceStateOrigin))
 		}
 
-		sourceAbsPath := c.SourceDirAbsPath.Join(sourceStateEntry.SourceRelPath().RelPath())
+		sourceAbsPath := sourceState.SourceAbsPath(sourceStateEntry)
 		if !c.force {
 			choice, err := c.promptChoice(fmt.Sprintf("Remove %s", sourceAbsPath), choicesYesNoAllQuit)
 			if err != nil {
@@ -63,7 +63,7 @@ TARGET_REL_PATH:
 			case "no":
 				continue
 			case "all":
-				c.force = false
+				c.force = true
 			case "quit":
 				return nil
 			}
@@ -1,23 +1,56 @@
 package cmd
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io/fs"
 	"strings"
 
 	"github.com/spf13/cobra"
 
 	"github.com/twpayne/chezmoi/v2/assets/templates"
+	"github.com/twpayne/chezmoi/v2/internal/chezmoi"
 	"github.com/twpayne/chezmoi/v2/internal/chezmoigit"
+	"github.com/twpayne/chezmoi/v2/internal/chezmoimaps"
+)
+
+const (
+	gitMetaBeginMarker = "# BEGIN chezmoi generate git-meta\n"
+	gitMetaEndMarker   = "# END chezmoi generate git-meta\n"
+)
+
+var (
+	gitMetaGitAttributes = []byte("" +
+		"# Store text files with LF line endings, as chezmoi writes them to the\n" +
+		"# destination directory unchanged.\n" +
+		"* text=auto eol=lf\n" +
+		"# Windows batch files require CRLF line endings.\n" +
+		"*.bat text eol=crlf\n" +
+		"*.bat.tmpl text eol=crlf\n" +
+		"*.cmd text eol=crlf\n" +
+		"*.cmd.tmpl text eol=crlf\n" +
+		"# Encrypted files are binary, so never diff or merge them as text.\n" +
+		"encrypted_* binary\n",
+	)
+	gitMetaGitIgnore = []byte("" +
+		"# Backup and temporary files created by editors and operating systems.\n" +
+		"*~\n" +
+		"*.swp\n" +
+		".#*\n" +
+		".DS_Store\n" +
+		"Thumbs.db\n",
+	)
 )
 
 func (c *Config) newGenerateCmd() *cobra.Command {
 	generateCmd := &cobra.Command{
-		Use:       "generate file",
-		Short:     "Generate a file for use with chezmoi",
+		Use:       "generate file [name]",
+		Short:     mustShortHelp("generate"),
 		Long:      mustLongHelp("generate"),
 		Example:   example("generate"),
-		Args:      cobra.ExactArgs(1),
-		ValidArgs: []string{"install.sh"},
+		Args:      cobra.RangeArgs(1, 2),
+		ValidArgs: []string{"git-commit-message", "git-meta", "install-packages.sh.tmpl", "install.sh", "schema"},
 		RunE:      c.runGenerateCmd,
 		Annotations: newAnnotations(
 			doesNotRequireValidConfig,
@@ -28,11 +61,24 @@ func (c *Config) newGenerateCmd() *cobra.Command {
 }
 
 func (c *Config) runGenerateCmd(cmd *cobra.Command, args []string) error {
+	switch args[0] {
+	case "git-meta":
+		if len(args) > 1 {
+			return fmt.Errorf("%s: too many arguments", args[0])
+		}
+		return c.runGenerateGitMeta()
+	case "schema":
+		return c.runGenerateSchema(args[1:])
+	}
+	if len(args) > 1 {
+		return fmt.Errorf("%s: too many arguments", args[0])
+	}
+
 	builder := strings.Builder{}
 	builder.Grow(16384)
 	switch args[0] {
 	case "git-commit-message":
-		output, err := c.cmdOutput(c.WorkingTreeAbsPath, c.Git.Command, []string{"status", "--porcelain=v2"})
+		output, err := c.vcsOutput(gitVCSName, c.WorkingTreeAbsPath, c.Git.Command, []string{"status", "--porcelain=v2"})
 		if err != nil {
 			return err
 		}
@@ -47,6 +93,10 @@ func (c *Config) runGenerateCmd(cmd *cobra.Command, args []string) error {
 		if _, err := builder.Write(data); err != nil {
 			return err
 		}
+	case "install-packages.sh.tmpl":
+		if _, err := builder.Write(templates.InstallPackagesSHTmpl); err != nil {
+			return err
+		}
 	case "install.sh":
 		if _, err := builder.Write(templates.InstallSH); err != nil {
 			return err
@@ -56,3 +106,90 @@ func (c *Config) runGenerateCmd(cmd *cobra.Command, args []string) error {
 	}
 	return c.writeOutputString(builder.String())
 }
+
+// runGenerateGitMeta creates or updates the .gitattributes and .gitignore files
+// in the root of the working tree. Only the block of lines between the
+// gitMetaBeginMarker and gitMetaEndMarker is changed, so users can add their
+// own entries outside it.
+func (c *Config) runGenerateGitMeta() error {
+	switch fileInfo, err := c.baseSystem.Stat(c.WorkingTreeAbsPath); {
+	case errors.Is(err, fs.ErrNotExist):
+		return fmt.Errorf("%s: working tree does not exist, run chezmoi init first", c.WorkingTreeAbsPath)
+	case err != nil:
+		return err
+	case !fileInfo.IsDir():
+		return fmt.Errorf("%s: not a directory", c.WorkingTreeAbsPath)
+	}
+
+	for _, gitMetaFile := range []struct {
+		name  string
+		block []byte
+	}{
+		{name: ".gitattributes", block: gitMetaGitAttributes},
+		{name: ".gitignore", block: gitMetaGitIgnore},
+	} {
+		absPath := c.WorkingTreeAbsPath.JoinString(gitMetaFile.name)
+		contents, err := c.baseSystem.ReadFile(absPath)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		newContents := replaceGitMetaBlock(contents, gitMetaFile.block)
+		if bytes.Equal(newContents, contents) {
+			continue
+		}
+		if c.dryRun {
+			c.verbosef(chezmoi.VerbosityChanges, "generate", "not writing %s on dry run\n", absPath)
+			continue
+		}
+		if err := c.baseSystem.WriteFile(absPath, newContents, 0o666&^c.Umask); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runGenerateSchema writes the JSON Schema named by args to the standard
+// output.
+func (c *Config) runGenerateSchema(args []string) error {
+	schemas := jsonSchemas()
+	if len(args) == 0 {
+		return fmt.Errorf("schema: missing name, expected one of %s", englishList(chezmoimaps.SortedKeys(schemas)))
+	}
+	schema, ok := schemas[args[0]]
+	if !ok {
+		return fmt.Errorf("%s: unsupported schema", args[0])
+	}
+	return c.marshal(writeDataFormatJSON, schema)
+}
+
+// replaceGitMetaBlock returns contents with the block between
+// gitMetaBeginMarker and gitMetaEndMarker replaced by block. If contents does
+// not contain the markers then the markers and block are appended.
+func replaceGitMetaBlock(contents, block []byte) []byte {
+	newBlock := make([]byte, 0, len(gitMetaBeginMarker)+len(block)+len(gitMetaEndMarker))
+	newBlock = append(newBlock, gitMetaBeginMarker...)
+	newBlock = append(newBlock, block...)
+	newBlock = append(newBlock, gitMetaEndMarker...)
+
+	if beginIndex := bytes.Index(contents, []byte(gitMetaBeginMarker)); beginIndex != -1 {
+		if endIndex := bytes.Index(contents[beginIndex:], []byte(gitMetaEndMarker)); endIndex != -1 {
+			endIndex += beginIndex + len(gitMetaEndMarker)
+			newContents := make([]byte, 0, len(contents)-(endIndex-beginIndex)+len(newBlock))
+			newContents = append(newContents, contents[:beginIndex]...)
+			newContents = append(newContents, newBlock...)
+			return append(newContents, contents[endIndex:]...)
+		}
+	}
+
+	newContents := make([]byte, 0, len(contents)+2+len(newBlock))
+	newContents = append(newContents, contents...)
+	switch {
+	case len(contents) == 0:
+		// Do nothing.
+	case contents[len(contents)-1] != '\n':
+		newContents = append(newContents, '\n', '\n')
+	default:
+		newContents = append(newContents, '\n')
+	}
+	return append(newContents, newBlock...)
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/twpayne/chezmoi/v2/internal/chezmoitest"
+)
+
+func TestReplaceGitMetaBlock(t *testing.T) {
+	block := []byte("block\n")
+	for _, tc := range []struct {
+		name     string
+		contents string
+		expected string
+	}{
+		{
+			name: "empty",
+			expected: chezmoitest.JoinLines(
+				"# BEGIN chezmoi generate git-meta",
+				"block",
+				"# END chezmoi generate git-meta",
+			),
+		},
+		{
+			name:     "append",
+			contents: "user\n",
+			expected: chezmoitest.JoinLines(
+				"user",
+				"",
+				"# BEGIN chezmoi generate git-meta",
+				"block",
+				"# END chezmoi generate git-meta",
+			),
+		},
+		{
+			name:     "append_no_trailing_newline",
+			contents: "user",
+			expected: chezmoitest.JoinLines(
+				"user",
+				"",
+				"# BEGIN chezmoi generate git-meta",
+				"block",
+				"# END chezmoi generate git-meta",
+			),
+		},
+		{
+			name: "replace",
+			contents: chezmoitest.JoinLines(
+				"user1",
+				"# BEGIN chezmoi generate git-meta",
+				"old",
+				"# END chezmoi generate git-meta",
+				"user2",
+			),
+			expected: chezmoitest.JoinLines(
+				"user1",
+				"# BEGIN chezmoi generate git-meta",
+				"block",
+				"# END chezmoi generate git-meta",
+				"user2",
+			),
+		},
+		{
+			name: "missing_end_marker",
+			contents: chezmoitest.JoinLines(
+				"# BEGIN chezmoi generate git-meta",
+				"old",
+			),
+			expected: chezmoitest.JoinLines(
+				"# BEGIN chezmoi generate git-meta",
+				"old",
+				"",
+				"# BEGIN chezmoi generate git-meta",
+				"block",
+				"# END chezmoi generate git-meta",
+			),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, string(replaceGitMetaBlock([]byte(tc.contents), block)))
+		})
+	}
+}
@@ -5,18 +5,20 @@ import (
 )
 
 type gitCmdConfig struct {
-	Command                   string `json:"command"                   mapstructure:"command"                   yaml:"command"`
-	AutoAdd                   bool   `json:"autoadd"                   mapstructure:"autoadd"                   yaml:"autoadd"`
-	AutoCommit                bool   `json:"autocommit"                mapstructure:"autocommit"                yaml:"autocommit"`
-	AutoPush                  bool   `json:"autopush"                  mapstructure:"autopush"                  yaml:"autopush"`
-	CommitMessageTemplate     string `json:"commitMessageTemplate"     mapstructure:"commitMessageTemplate"     yaml:"commitMessageTemplate"`
-	CommitMessageTemplateFile string `json:"commitMessageTemplateFile" mapstructure:"commitMessageTemplateFile" yaml:"commitMessageTemplateFile"`
+	Command                   string   `json:"command"                   mapstructure:"command"                   yaml:"command"`
+	AutoAdd                   bool     `json:"autoadd"                   mapstructure:"autoadd"                   yaml:"autoadd"`
+	AutoCommit                bool     `json:"autocommit"                mapstructure:"autocommit"                yaml:"autocommit"`
+	AutoPush                  bool     `json:"autopush"                  mapstructure:"autopush"                  yaml:"autopush"`
+	CommitMessageTemplate     string   `json:"commitMessageTemplate"     mapstructure:"commitMessageTemplate"     yaml:"commitMessageTemplate"`
+	CommitMessageTemplateFile string   `json:"commitMessageTemplateFile" mapstructure:"commitMessageTemplateFile" yaml:"commitMessageTemplateFile"`
+	PushRemotes               []string `json:"pushRemotes"               mapstructure:"pushRemotes"               yaml:"pushRemotes"`
+	RequireClean              bool     `json:"requireClean"              mapstructure:"requireClean"              yaml:"requireClean"`
 }
 
 func (c *Config) newGitCmd() *cobra.Command {
 	gitCmd := &cobra.Command{
 		Use:     "git [arg]...",
-		Short:   "Run git in the source directory",
+		Short:   mustShortHelp("git"),
 		Long:    mustLongHelp("git"),
 		Example: example("git"),
 		RunE:    c.runGitCmd,
@@ -31,5 +33,5 @@ func (c *Config) newGitCmd() *cobra.Command {
 }
 
 func (c *Config) runGitCmd(cmd *cobra.Command, args []string) error {
-	return c.run(c.WorkingTreeAbsPath, c.Git.Command, args)
+	return c.runPassthroughCmd("git", c.Git.Command, args)
 }
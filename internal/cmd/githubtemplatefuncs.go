@@ -13,7 +13,14 @@ import (
 )
 
 type gitHubConfig struct {
-	RefreshPeriod time.Duration `json:"refreshPeriod" mapstructure:"refreshPeriod" yaml:"refreshPeriod"`
+	RefreshPeriod time.Duration            `json:"refreshPeriod" mapstructure:"refreshPeriod" yaml:"refreshPeriod"`
+	Token         string                   `json:"token"         mapstructure:"token"         yaml:"token"`
+	TokenKeyring  gitHubTokenKeyringConfig `json:"tokenKeyring"  mapstructure:"tokenKeyring"  yaml:"tokenKeyring"`
+}
+
+type gitHubTokenKeyringConfig struct {
+	Service string `json:"service" mapstructure:"service" yaml:"service"`
+	User    string `json:"user"    mapstructure:"user"    yaml:"user"`
 }
 
 type gitHubKeysState struct {
@@ -52,6 +59,50 @@ type gitHubData struct {
 	tagsCache          map[string]map[string][]*github.RepositoryTag
 }
 
+// setKeys caches keys for user for the rest of the run, so that the persistent
+// state is only read once for each user.
+func (d *gitHubData) setKeys(user string, keys []*github.Key) {
+	if d.keysCache == nil {
+		d.keysCache = make(map[string][]*github.Key)
+	}
+	d.keysCache[user] = keys
+}
+
+// setLatestRelease caches release as the latest release of owner/repo for the
+// rest of the run.
+func (d *gitHubData) setLatestRelease(owner, repo string, release *github.RepositoryRelease) {
+	if d.latestReleaseCache == nil {
+		d.latestReleaseCache = make(map[string]map[string]*github.RepositoryRelease)
+	}
+	if d.latestReleaseCache[owner] == nil {
+		d.latestReleaseCache[owner] = make(map[string]*github.RepositoryRelease)
+	}
+	d.latestReleaseCache[owner][repo] = release
+}
+
+// setReleases caches releases as the releases of owner/repo for the rest of the
+// run.
+func (d *gitHubData) setReleases(owner, repo string, releases []*github.RepositoryRelease) {
+	if d.releasesCache == nil {
+		d.releasesCache = make(map[string]map[string][]*github.RepositoryRelease)
+	}
+	if d.releasesCache[owner] == nil {
+		d.releasesCache[owner] = make(map[string][]*github.RepositoryRelease)
+	}
+	d.releasesCache[owner][repo] = releases
+}
+
+// setTags caches tags as the tags of owner/repo for the rest of the run.
+func (d *gitHubData) setTags(owner, repo string, tags []*github.RepositoryTag) {
+	if d.tagsCache == nil {
+		d.tagsCache = make(map[string]map[string][]*github.RepositoryTag)
+	}
+	if d.tagsCache[owner] == nil {
+		d.tagsCache[owner] = make(map[string][]*github.RepositoryTag)
+	}
+	d.tagsCache[owner][repo] = tags
+}
+
 func (c *Config) gitHubKeysTemplateFunc(user string) []*github.Key {
 	if keys, ok := c.gitHub.keysCache[user]; ok {
 		return keys
@@ -65,6 +116,7 @@ func (c *Config) gitHubKeysTemplateFunc(user string) []*github.Key {
 		case err != nil:
 			panic(err)
 		case ok && now.Before(gitHubKeysValue.RequestedAt.Add(c.GitHub.RefreshPeriod)):
+			c.gitHub.setKeys(user, gitHubKeysValue.Keys)
 			return gitHubKeysValue.Keys
 		}
 	}
@@ -100,10 +152,7 @@ func (c *Config) gitHubKeysTemplateFunc(user string) []*github.Key {
 		panic(err)
 	}
 
-	if c.gitHub.keysCache == nil {
-		c.gitHub.keysCache = make(map[string][]*github.Key)
-	}
-	c.gitHub.keysCache[user] = allKeys
+	c.gitHub.setKeys(user, allKeys)
 
 	return allKeys
 }
@@ -145,6 +194,7 @@ func (c *Config) gitHubLatestRelease(ownerRepo string) (*github.RepositoryReleas
 		case err != nil:
 			return nil, err
 		case ok && now.Before(gitHubLatestReleaseStateValue.RequestedAt.Add(c.GitHub.RefreshPeriod)):
+			c.gitHub.setLatestRelease(owner, repo, gitHubLatestReleaseStateValue.Release)
 			return gitHubLatestReleaseStateValue.Release, nil
 		}
 	}
@@ -169,13 +219,7 @@ func (c *Config) gitHubLatestRelease(ownerRepo string) (*github.RepositoryReleas
 		return nil, err
 	}
 
-	if c.gitHub.latestReleaseCache == nil {
-		c.gitHub.latestReleaseCache = make(map[string]map[string]*github.RepositoryRelease)
-	}
-	if c.gitHub.latestReleaseCache[owner] == nil {
-		c.gitHub.latestReleaseCache[owner] = make(map[string]*github.RepositoryRelease)
-	}
-	c.gitHub.latestReleaseCache[owner][repo] = release
+	c.gitHub.setLatestRelease(owner, repo, release)
 
 	return release, nil
 }
@@ -219,6 +263,7 @@ func (c *Config) gitHubReleasesTemplateFunc(ownerRepo string) []*github.Reposito
 		case err != nil:
 			panic(err)
 		case ok && now.Before(gitHubReleasesStateValue.RequestedAt.Add(c.GitHub.RefreshPeriod)):
+			c.gitHub.setReleases(owner, repo, gitHubReleasesStateValue.Releases)
 			return gitHubReleasesStateValue.Releases
 		}
 	}
@@ -243,13 +288,7 @@ func (c *Config) gitHubReleasesTemplateFunc(ownerRepo string) []*github.Reposito
 		panic(err)
 	}
 
-	if c.gitHub.releasesCache == nil {
-		c.gitHub.releasesCache = make(map[string]map[string][]*github.RepositoryRelease)
-	}
-	if c.gitHub.releasesCache[owner] == nil {
-		c.gitHub.releasesCache[owner] = make(map[string][]*github.RepositoryRelease)
-	}
-	c.gitHub.releasesCache[owner][repo] = releases
+	c.gitHub.setReleases(owner, repo, releases)
 
 	return releases
 }
@@ -281,6 +320,7 @@ func (c *Config) getGitHubTags(ownerRepo string) ([]*github.RepositoryTag, error
 		case err != nil:
 			return nil, err
 		case ok && now.Before(gitHubTagsStateValue.RequestedAt.Add(c.GitHub.RefreshPeriod)):
+			c.gitHub.setTags(owner, repo, gitHubTagsStateValue.Tags)
 			return gitHubTagsStateValue.Tags, nil
 		}
 	}
@@ -305,13 +345,7 @@ func (c *Config) getGitHubTags(ownerRepo string) ([]*github.RepositoryTag, error
 		return nil, err
 	}
 
-	if c.gitHub.tagsCache == nil {
-		c.gitHub.tagsCache = make(map[string]map[string][]*github.RepositoryTag)
-	}
-	if c.gitHub.tagsCache[owner] == nil {
-		c.gitHub.tagsCache[owner] = make(map[string][]*github.RepositoryTag)
-	}
-	c.gitHub.tagsCache[owner][repo] = tags
+	c.gitHub.setTags(owner, repo, tags)
 
 	return tags, nil
 }
@@ -327,10 +361,36 @@ func (c *Config) getGitHubClient(ctx context.Context) (*github.Client, error) {
 		return nil, err
 	}
 
-	c.gitHub.client = chezmoi.NewGitHubClient(ctx, httpClient)
+	accessToken, err := c.gitHubAccessToken()
+	if err != nil {
+		c.gitHub.clientErr = err
+		return nil, err
+	}
+
+	c.gitHub.client = chezmoi.NewGitHubClientWithAccessToken(ctx, httpClient, accessToken)
 	return c.gitHub.client, nil
 }
 
+// gitHubAccessToken returns the GitHub access token. Tokens set in the
+// environment take precedence over gitHub.token, which takes precedence over
+// the token stored in the keyring under gitHub.tokenKeyring.
+func (c *Config) gitHubAccessToken() (string, error) {
+	if accessToken := chezmoi.GitHubAccessToken(); accessToken != "" {
+		return accessToken, nil
+	}
+	if c.GitHub.Token != "" {
+		return c.GitHub.Token, nil
+	}
+	if c.GitHub.TokenKeyring.Service == "" {
+		return "", nil
+	}
+	accessToken, err := keyringGet(c.GitHub.TokenKeyring.Service, c.GitHub.TokenKeyring.User)
+	if err != nil {
+		return "", fmt.Errorf("gitHub.tokenKeyring: %s %s: %w", c.GitHub.TokenKeyring.Service, c.GitHub.TokenKeyring.User, err)
+	}
+	return accessToken, nil
+}
+
 func gitHubSplitOwnerRepo(ownerRepo string) (string, string, error) {
 	owner, repo, ok := strings.Cut(ownerRepo, "/")
 	if !ok {
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/twpayne/go-vfs/v5"
+
+	"github.com/twpayne/chezmoi/v2/internal/chezmoitest"
+)
+
+func TestGitHubAccessToken(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		env      map[string]string
+		token    string
+		expected string
+	}{
+		{
+			name: "none",
+		},
+		{
+			name:     "config",
+			token:    "config-token",
+			expected: "config-token",
+		},
+		{
+			name: "env",
+			env: map[string]string{
+				"GITHUB_TOKEN": "env-token",
+			},
+			token:    "config-token",
+			expected: "env-token",
+		},
+		{
+			name: "env_precedence",
+			env: map[string]string{
+				"CHEZMOI_GITHUB_ACCESS_TOKEN": "chezmoi-token",
+				"GITHUB_TOKEN":                "env-token",
+			},
+			expected: "chezmoi-token",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, key := range []string{
+				"CHEZMOI_GITHUB_ACCESS_TOKEN",
+				"CHEZMOI_GITHUB_TOKEN",
+				"GITHUB_ACCESS_TOKEN",
+				"GITHUB_TOKEN",
+			} {
+				t.Setenv(key, tc.env[key])
+			}
+			chezmoitest.WithTestFS(t, nil, func(fileSystem vfs.FS) {
+				config := newTestConfig(t, fileSystem)
+				config.GitHub.Token = tc.token
+				actual, err := config.gitHubAccessToken()
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expected, actual)
+			})
+		})
+	}
+}
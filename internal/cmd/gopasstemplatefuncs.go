@@ -7,8 +7,6 @@ import (
 	"regexp"
 
 	"github.com/coreos/go-semver/semver"
-
-	"github.com/twpayne/chezmoi/v2/internal/chezmoilog"
 )
 
 var (
@@ -72,7 +70,7 @@ func (c *Config) gopassOutput(args ...string) ([]byte, error) {
 	cmd := exec.Command(name, args...)
 	cmd.Stdin = os.Stdin
 	cmd.Stderr = os.Stderr
-	output, err := chezmoilog.LogCmdOutput(c.logger, cmd)
+	output, err := c.secretCmdOutput(cmd)
 	if err != nil {
 		return nil, newCmdOutputError(cmd, output, err)
 	}
@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/twpayne/chezmoi/v2/internal/chezmoi"
+	"github.com/twpayne/chezmoi/v2/internal/chezmoilog"
+	"github.com/twpayne/chezmoi/v2/internal/chezmoimaps"
+	"github.com/twpayne/chezmoi/v2/internal/chezmoiset"
+)
+
+// importGPGKeys ensures that the GPG keys in sourceState are in the user's GPG
+// keyring and have the declared owner trust.
+func (c *Config) importGPGKeys(sourceState *chezmoi.SourceState) error {
+	gpgKeys := sourceState.GPGKeys()
+	if len(gpgKeys) == 0 || c.dryRun {
+		return nil
+	}
+
+	fingerprints, err := c.gpgFingerprints()
+	if err != nil {
+		return err
+	}
+	ownerTrust, err := c.gpgOwnerTrust()
+	if err != nil {
+		return err
+	}
+
+	var ownerTrustBuilder strings.Builder
+	for _, fingerprint := range chezmoimaps.SortedKeys(gpgKeys) {
+		gpgKey := gpgKeys[fingerprint]
+		if !fingerprints.Contains(fingerprint) {
+			var args []string
+			switch {
+			case gpgKey.File != "":
+				args = []string{"--import", gpgKey.FileAbsPath().String()}
+			case gpgKey.Keyserver != "":
+				args = []string{"--keyserver", gpgKey.Keyserver, "--recv-keys", fingerprint}
+			default:
+				args = []string{"--recv-keys", fingerprint}
+			}
+			if _, err := c.runGPG(nil, args...); err != nil {
+				return fmt.Errorf("%s: %w", fingerprint, err)
+			}
+		}
+		if gpgKey.Trust != "" {
+			if level := chezmoi.GPGOwnerTrustLevels[gpgKey.Trust]; ownerTrust[fingerprint] != level {
+				fmt.Fprintf(&ownerTrustBuilder, "%s:%d:\n", fingerprint, level)
+			}
+		}
+	}
+
+	// Check that every key was imported, as gpg --import does not check the
+	// fingerprints of the keys that it imports.
+	fingerprints, err = c.gpgFingerprints()
+	if err != nil {
+		return err
+	}
+	for _, fingerprint := range chezmoimaps.SortedKeys(gpgKeys) {
+		if !fingerprints.Contains(fingerprint) {
+			return fmt.Errorf("%s: GPG key not found after import", fingerprint)
+		}
+	}
+
+	if ownerTrustBuilder.Len() > 0 {
+		if _, err := c.runGPG([]byte(ownerTrustBuilder.String()), "--import-ownertrust"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// gpgFingerprints returns the fingerprints of all keys in the user's GPG
+// keyring.
+func (c *Config) gpgFingerprints() (chezmoiset.Set[string], error) {
+	args := []string{"--with-colons", "--fingerprint", "--list-keys"}
+	output, err := c.runGPG(nil, args...)
+	if err != nil {
+		return nil, err
+	}
+	fingerprints := chezmoiset.New[string]()
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		if fields := strings.Split(scanner.Text(), ":"); len(fields) > 9 && fields[0] == "fpr" {
+			fingerprints.Add(fields[9])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, newParseCmdOutputError(c.GPG.Command, args, output, err)
+	}
+	return fingerprints, nil
+}
+
+// gpgOwnerTrust returns the owner trust levels of the keys in the user's GPG
+// keyring.
+func (c *Config) gpgOwnerTrust() (map[string]int, error) {
+	output, err := c.runGPG(nil, "--export-ownertrust")
+	if err != nil {
+		return nil, err
+	}
+	ownerTrust := make(map[string]int)
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, ":")
+		if len(fields) < 2 {
+			continue
+		}
+		level, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, newParseCmdOutputError(c.GPG.Command, []string{"--export-ownertrust"}, output, err)
+		}
+		ownerTrust[fields[0]] = level
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, newParseCmdOutputError(c.GPG.Command, []string{"--export-ownertrust"}, output, err)
+	}
+	return ownerTrust, nil
+}
+
+// runGPG runs gpg with args and returns its output.
+func (c *Config) runGPG(stdin []byte, args ...string) ([]byte, error) {
+	cmdArgs := append(slices.Clone(c.GPG.Args), "--batch")
+	cmdArgs = append(cmdArgs, args...)
+	cmd := exec.Command(c.GPG.Command, cmdArgs...) //nolint:gosec
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	cmd.Stderr = os.Stderr
+	output, err := chezmoilog.LogCmdOutput(c.logger, cmd)
+	if err != nil {
+		return nil, newCmdOutputError(cmd, output, err)
+	}
+	return output, nil
+}
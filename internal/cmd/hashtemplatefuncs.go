@@ -0,0 +1,225 @@
+package cmd
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Parameters for argon2id, as recommended by RFC 9106 section 4.
+const (
+	argon2Time    = 3
+	argon2Memory  = 64 * 1024
+	argon2Threads = 4
+	argon2KeyLen  = 32
+	argon2SaltLen = 16
+)
+
+var hashStateBucket = []byte("hashState")
+
+type hashState struct {
+	Hash string `json:"hash" yaml:"hash"`
+}
+
+// A hashCallSites identifies the calls to hash template functions in a single
+// execution of a template. The nth call with an algorithm is identified by the
+// template's name, the algorithm, and n, so that the persistent state never
+// contains anything derived from a password.
+type hashCallSites struct {
+	name   string
+	counts map[string]int
+}
+
+// hashTemplateFuncs returns the hash template functions for an execution of
+// the template name, which store their hashes in the persistent state.
+func (c *Config) hashTemplateFuncs(name string) template.FuncMap {
+	callSites := &hashCallSites{
+		name:   name,
+		counts: make(map[string]int),
+	}
+	return template.FuncMap{
+		"argon2": func(password string) string {
+			return c.argon2Hash(callSites, password)
+		},
+		"bcrypt": func(password string, args ...int) string {
+			return c.bcryptHash(callSites, password, bcryptCost(args))
+		},
+		"htpasswd": func(username, password string) string {
+			return htpasswdEntry(username, func() string {
+				return c.bcryptHash(callSites, password, bcrypt.DefaultCost)
+			})
+		},
+	}
+}
+
+func (c *Config) argon2TemplateFunc(password string) string {
+	return c.argon2Hash(nil, password)
+}
+
+func (c *Config) bcryptTemplateFunc(password string, args ...int) string {
+	return c.bcryptHash(nil, password, bcryptCost(args))
+}
+
+func (c *Config) htpasswdTemplateFunc(username, password string) string {
+	return htpasswdEntry(username, func() string {
+		return c.bcryptHash(nil, password, bcrypt.DefaultCost)
+	})
+}
+
+func (c *Config) uuidv4TemplateFunc(args ...string) string {
+	switch len(args) {
+	case 0:
+		return uuid.New().String()
+	case 1:
+		mac := hmac.New(sha256.New, c.getMachineSecret())
+		mac.Write([]byte("uuidv4\x00" + args[0]))
+		sum := mac.Sum(nil)
+		sum[6] = sum[6]&0x0f | 0x40 // Version 4.
+		sum[8] = sum[8]&0x3f | 0x80 // Variant RFC 4122.
+		return uuid.Must(uuid.FromBytes(sum[:16])).String()
+	default:
+		panic(fmt.Errorf("want 0 or 1 arguments, got %d", len(args)))
+	}
+}
+
+// argon2Hash returns the argon2id hash of password, reusing the hash stored for
+// the current call site in callSites if it is still a hash of password.
+func (c *Config) argon2Hash(callSites *hashCallSites, password string) string {
+	return c.persistedHash(callSites.key("argon2id"), password, argon2HashMatches, func() (string, error) {
+		salt := make([]byte, argon2SaltLen)
+		if _, err := rand.Read(salt); err != nil {
+			return "", err
+		}
+		key := argon2.IDKey([]byte(password), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen)
+		return fmt.Sprintf(
+			"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+			argon2.Version,
+			argon2Memory,
+			argon2Time,
+			argon2Threads,
+			base64.RawStdEncoding.EncodeToString(salt),
+			base64.RawStdEncoding.EncodeToString(key),
+		), nil
+	})
+}
+
+// bcryptHash returns the bcrypt hash of password with cost, reusing the hash
+// stored for the current call site in callSites if it is still a hash of
+// password.
+func (c *Config) bcryptHash(callSites *hashCallSites, password string, cost int) string {
+	bcryptHashMatches := func(hash, password string) bool {
+		hashCost, err := bcrypt.Cost([]byte(hash))
+		return err == nil && hashCost == cost && bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+	}
+	return c.persistedHash(callSites.key("bcrypt"), password, bcryptHashMatches, func() (string, error) {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), cost)
+		return string(hash), err
+	})
+}
+
+// persistedHash returns the hash of password computed by hashFunc. Salted
+// hashes differ every time they are computed, which would cause the target
+// state to change on every apply, so the hash is stored in the persistent
+// state under key and reused for as long as hashMatchesFunc reports that it
+// is a hash of password. If key is empty, or the persistent state is
+// read-only, then the hash is not stored.
+func (c *Config) persistedHash(
+	key, password string,
+	hashMatchesFunc func(hash, password string) bool,
+	hashFunc func() (string, error),
+) string {
+	if key == "" {
+		hash, err := hashFunc()
+		if err != nil {
+			panic(err)
+		}
+		return hash
+	}
+
+	var state hashState
+	switch ok, err := c.readPersistentState(hashStateBucket, []byte(key), &state); {
+	case err != nil:
+		panic(err)
+	case ok && hashMatchesFunc(state.Hash, password):
+		return state.Hash
+	}
+
+	hash, err := hashFunc()
+	if err != nil {
+		panic(err)
+	}
+	state.Hash = hash
+	if err := c.writePersistentState(hashStateBucket, []byte(key), &state); err != nil {
+		panic(err)
+	}
+	return hash
+}
+
+// key returns the persistent state key of the next call with algorithm, or
+// the empty string if s is nil.
+func (s *hashCallSites) key(algorithm string) string {
+	if s == nil {
+		return ""
+	}
+	s.counts[algorithm]++
+	return algorithm + ":" + s.name + ":" + strconv.Itoa(s.counts[algorithm])
+}
+
+// argon2HashMatches returns if hash is an argon2id hash of password in PHC
+// string format.
+func argon2HashMatches(hash, password string) bool {
+	fields := strings.Split(hash, "$")
+	if len(fields) != 6 || fields[0] != "" || fields[1] != "argon2id" {
+		return false
+	}
+	var version int
+	if _, err := fmt.Sscanf(fields[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false
+	}
+	var memory, time uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(fields[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil {
+		return false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(fields[4])
+	if err != nil {
+		return false
+	}
+	key, err := base64.RawStdEncoding.DecodeString(fields[5])
+	if err != nil || len(key) == 0 {
+		return false
+	}
+	actualKey := argon2.IDKey([]byte(password), salt, time, memory, threads, uint32(len(key)))
+	return subtle.ConstantTimeCompare(key, actualKey) == 1
+}
+
+// bcryptCost returns the bcrypt cost from the optional arguments args.
+func bcryptCost(args []int) int {
+	switch len(args) {
+	case 0:
+		return bcrypt.DefaultCost
+	case 1:
+		return args[0]
+	default:
+		panic(fmt.Errorf("want 1 or 2 arguments, got %d", len(args)+1))
+	}
+}
+
+// htpasswdEntry returns an htpasswd entry for username with the hash returned
+// by hashFunc.
+func htpasswdEntry(username string, hashFunc func() string) string {
+	if strings.Contains(username, ":") {
+		panic(fmt.Errorf("%s: invalid username", username))
+	}
+	return username + ":" + hashFunc()
+}
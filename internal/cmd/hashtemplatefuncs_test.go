@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/twpayne/chezmoi/v2/internal/chezmoi"
+)
+
+func newHashTestConfig() *Config {
+	return &Config{
+		machineSecret:   []byte("secret"),
+		persistentState: chezmoi.NewMockPersistentState(),
+	}
+}
+
+func TestArgon2TemplateFunc(t *testing.T) {
+	c := newHashTestConfig()
+	argon2Func := func(password string) string {
+		return c.hashTemplateFuncs("name")["argon2"].(func(string) string)(password)
+	}
+	hash := argon2Func("password")
+	assert.Equal(t, hash, argon2Func("password"))
+	assert.True(t, argon2HashMatches(hash, "password"))
+	assert.False(t, argon2HashMatches(hash, "other password"))
+	assert.NotEqual(t, hash, argon2Func("other password"))
+	assert.NotEqual(t, c.argon2TemplateFunc("password"), c.argon2TemplateFunc("password"))
+
+	fields := strings.Split(hash, "$")
+	assert.Equal(t, 6, len(fields))
+	assert.Equal(t, "argon2id", fields[1])
+	assert.Equal(t, "v=19", fields[2])
+	assert.Equal(t, "m=65536,t=3,p=4", fields[3])
+	salt, err := base64.RawStdEncoding.DecodeString(fields[4])
+	assert.NoError(t, err)
+	key, err := base64.RawStdEncoding.DecodeString(fields[5])
+	assert.NoError(t, err)
+	assert.Equal(t, key, argon2.IDKey([]byte("password"), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen))
+}
+
+func TestBcryptTemplateFunc(t *testing.T) {
+	c := newHashTestConfig()
+	bcryptFunc := func(password string, args ...int) string {
+		return c.hashTemplateFuncs("name")["bcrypt"].(func(string, ...int) string)(password, args...)
+	}
+	hash := bcryptFunc("password", bcrypt.MinCost)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(hash), []byte("password")))
+	assert.Equal(t, hash, bcryptFunc("password", bcrypt.MinCost))
+	assert.NotEqual(t, hash, bcryptFunc("password", bcrypt.MinCost+1))
+	newHash := bcryptFunc("other password", bcrypt.MinCost)
+	assert.NotEqual(t, hash, newHash)
+	assert.Equal(t, newHash, bcryptFunc("other password", bcrypt.MinCost))
+}
+
+func TestHashTemplateFuncsCallSites(t *testing.T) {
+	c := newHashTestConfig()
+	bcryptFunc := c.hashTemplateFuncs("name")["bcrypt"].(func(string, ...int) string)
+	hash1 := bcryptFunc("password", bcrypt.MinCost)
+	hash2 := bcryptFunc("password", bcrypt.MinCost)
+	assert.NotEqual(t, hash1, hash2)
+	otherBcryptFunc := c.hashTemplateFuncs("other name")["bcrypt"].(func(string, ...int) string)
+	assert.NotEqual(t, hash1, otherBcryptFunc("password", bcrypt.MinCost))
+
+	// The persistent state is keyed by call site, never by password.
+	var keys []string
+	assert.NoError(t, c.persistentState.ForEach(hashStateBucket, func(k, v []byte) error {
+		keys = append(keys, string(k))
+		return nil
+	}))
+	slices.Sort(keys)
+	assert.Equal(t, []string{
+		"bcrypt:name:1",
+		"bcrypt:name:2",
+		"bcrypt:other name:1",
+	}, keys)
+}
+
+func TestHashTemplateFuncsReadOnlyPersistentState(t *testing.T) {
+	c := newHashTestConfig()
+	c.persistentStateReadOnly = true
+	bcryptFunc := c.hashTemplateFuncs("name")["bcrypt"].(func(string, ...int) string)
+	hash := bcryptFunc("password", bcrypt.MinCost)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(hash), []byte("password")))
+
+	// Nothing is written to a read-only persistent state.
+	assert.NoError(t, c.persistentState.ForEach(hashStateBucket, func(k, v []byte) error {
+		t.Errorf("unexpected key %q", k)
+		return nil
+	}))
+}
+
+func TestHtpasswdTemplateFunc(t *testing.T) {
+	c := newHashTestConfig()
+	htpasswd := func(username, password string) string {
+		return c.hashTemplateFuncs("name")["htpasswd"].(func(string, string) string)(username, password)
+	}
+	entry := htpasswd("user", "password")
+	username, hash, ok := strings.Cut(entry, ":")
+	assert.True(t, ok)
+	assert.Equal(t, "user", username)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(hash), []byte("password")))
+	assert.Equal(t, entry, htpasswd("user", "password"))
+	assert.Panics(t, func() {
+		htpasswd("invalid:user", "password")
+	})
+}
+
+func TestUUIDv4TemplateFunc(t *testing.T) {
+	uuidv4Rx := regexp.MustCompile(`\A[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}\z`)
+	c := newHashTestConfig()
+	assert.True(t, uuidv4Rx.MatchString(c.uuidv4TemplateFunc()))
+	assert.NotEqual(t, c.uuidv4TemplateFunc(), c.uuidv4TemplateFunc())
+	stableUUID := c.uuidv4TemplateFunc("key")
+	assert.True(t, uuidv4Rx.MatchString(stableUUID))
+	assert.Equal(t, stableUUID, c.uuidv4TemplateFunc("key"))
+	assert.NotEqual(t, stableUUID, c.uuidv4TemplateFunc("other key"))
+}
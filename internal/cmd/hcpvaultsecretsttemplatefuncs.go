@@ -9,8 +9,6 @@ import (
 	"strings"
 
 	"github.com/coreos/go-semver/semver"
-
-	"github.com/twpayne/chezmoi/v2/internal/chezmoilog"
 )
 
 type hcpVaultSecretConfig struct {
@@ -96,7 +94,7 @@ func (c *Config) vltOutput(args []string) ([]byte, error) {
 	cmd.Dir = c.DestDirAbsPath.String()
 	cmd.Stdin = os.Stdin
 	cmd.Stderr = os.Stderr
-	output, err := chezmoilog.LogCmdOutput(c.logger, cmd)
+	output, err := c.secretCmdOutput(cmd)
 	if err != nil {
 		return nil, newCmdOutputError(cmd, output, err)
 	}
@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+type hgCmdConfig struct {
+	Command string `json:"command" mapstructure:"command" yaml:"command"`
+}
+
+func (c *Config) newHgCmd() *cobra.Command {
+	hgCmd := &cobra.Command{
+		Use:     "hg [arg]...",
+		Short:   mustShortHelp("hg"),
+		Long:    mustLongHelp("hg"),
+		Example: example("hg"),
+		RunE:    c.runHgCmd,
+		Annotations: newAnnotations(
+			createSourceDirectoryIfNeeded,
+			requiresWorkingTree,
+			runsCommands,
+		),
+	}
+
+	return hgCmd
+}
+
+func (c *Config) runHgCmd(cmd *cobra.Command, args []string) error {
+	return c.runPassthroughCmd("hg", c.Hg.Command, args)
+}
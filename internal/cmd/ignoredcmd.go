@@ -13,7 +13,7 @@ type ignoredCmdConfig struct {
 func (c *Config) newIgnoredCmd() *cobra.Command {
 	ignoredCmd := &cobra.Command{
 		Use:         "ignored",
-		Short:       "Print ignored targets",
+		Short:       mustShortHelp("ignored"),
 		Long:        mustLongHelp("ignored"),
 		Example:     example("ignored"),
 		Args:        cobra.NoArgs,
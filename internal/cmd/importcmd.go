@@ -1,7 +1,10 @@
 package cmd
 
 import (
+	"errors"
+	"fmt"
 	"io"
+	"io/fs"
 
 	"github.com/spf13/cobra"
 
@@ -12,14 +15,16 @@ type importCmdConfig struct {
 	destination       chezmoi.AbsPath
 	exact             bool
 	filter            *chezmoi.EntryTypeFilter
+	format            chezmoi.ArchiveFormat
+	from              string
 	removeDestination bool
 	stripComponents   int
 }
 
 func (c *Config) newImportCmd() *cobra.Command {
 	importCmd := &cobra.Command{
-		Use:     "import archive",
-		Short:   "Import an archive into the source state",
+		Use:     "import [archive]",
+		Short:   mustShortHelp("import"),
 		Long:    mustLongHelp("import"),
 		Example: example("import"),
 		Args:    cobra.MaximumNArgs(1),
@@ -31,25 +36,41 @@ func (c *Config) newImportCmd() *cobra.Command {
 		),
 	}
 
-	importCmd.Flags().VarP(&c._import.destination, "destination", "d", "Set destination prefix")
-	importCmd.Flags().BoolVar(&c._import.exact, "exact", c._import.exact, "Set exact_ attribute on imported directories")
+	importCmd.Flags().VarP(&c._import.destination, "destination", "d", "")
+	importCmd.Flags().BoolVar(&c._import.exact, "exact", c._import.exact, "")
 	importCmd.Flags().VarP(c._import.filter.Exclude, "exclude", "x", "Exclude entry types")
+	importCmd.Flags().Var(&c._import.format, "format", "")
+	importCmd.Flags().StringVar(&c._import.from, "from", c._import.from, "")
 	importCmd.Flags().VarP(c._import.filter.Include, "include", "i", "Include entry types")
 	importCmd.Flags().
-		BoolVarP(&c._import.removeDestination, "remove-destination", "r", c._import.removeDestination, "Remove destination before import")
+		BoolVarP(&c._import.removeDestination, "remove-destination", "r", c._import.removeDestination, "")
 	importCmd.Flags().
-		IntVar(&c._import.stripComponents, "strip-components", c._import.stripComponents, "Strip leading path components")
+		IntVar(&c._import.stripComponents, "strip-components", c._import.stripComponents, "")
 
 	return importCmd
 }
 
 func (c *Config) runImportCmd(cmd *cobra.Command, args []string, sourceState *chezmoi.SourceState) error {
+	switch c._import.from {
+	case "", "archive":
+		return c.runImportArchive(args, sourceState)
+	case "stow":
+		return c.runImportStow(cmd, args, sourceState)
+	default:
+		return fmt.Errorf("%s: invalid --from, expected archive or stow", c._import.from)
+	}
+}
+
+// runImportArchive imports the archive named by args, or read from the
+// standard input, into sourceState.
+func (c *Config) runImportArchive(args []string, sourceState *chezmoi.SourceState) error {
 	var (
 		name string
 		data []byte
 	)
 	if len(args) == 0 {
-		name = ".tar"
+		// Archives read from stdin have no name, so their format is guessed
+		// from their contents.
 		var err error
 		data, err = io.ReadAll(c.stdin)
 		if err != nil {
@@ -67,7 +88,7 @@ func (c *Config) runImportCmd(cmd *cobra.Command, args []string, sourceState *ch
 		}
 	}
 	archiveReaderSystem, err := chezmoi.NewArchiveReaderSystem(
-		name, data, chezmoi.ArchiveFormatUnknown, chezmoi.ArchiveReaderSystemOptions{
+		name, data, c._import.format, chezmoi.ArchiveReaderSystemOptions{
 			RootAbsPath:     c._import.destination,
 			StripComponents: c._import.stripComponents,
 		},
@@ -75,8 +96,42 @@ func (c *Config) runImportCmd(cmd *cobra.Command, args []string, sourceState *ch
 	if err != nil {
 		return err
 	}
+	return c.importSystem(sourceState, archiveReaderSystem, archiveReaderSystem.FileInfos())
+}
+
+// runImportStow imports the packages in the GNU Stow directory named by args
+// into sourceState.
+func (c *Config) runImportStow(cmd *cobra.Command, args []string, sourceState *chezmoi.SourceState) error {
+	if len(args) == 0 {
+		return errors.New("--from=stow requires a stow directory")
+	}
+	for _, name := range []string{"format", "strip-components"} {
+		if cmd.Flags().Changed(name) {
+			return fmt.Errorf("--%s cannot be used with --from=stow", name)
+		}
+	}
+	stowDirAbsPath, err := chezmoi.NewAbsPathFromExtPath(args[0], c.homeDirAbsPath)
+	if err != nil {
+		return err
+	}
+	stowReaderSystem, err := chezmoi.NewStowReaderSystem(c.baseSystem, stowDirAbsPath, chezmoi.StowReaderSystemOptions{
+		RootAbsPath: c._import.destination,
+	})
+	if err != nil {
+		return err
+	}
+	return c.importSystem(sourceState, stowReaderSystem, stowReaderSystem.FileInfos())
+}
+
+// importSystem adds the entries in fileInfos from system to sourceState.
+func (c *Config) importSystem(
+	sourceState *chezmoi.SourceState,
+	system chezmoi.System,
+	fileInfos map[chezmoi.AbsPath]fs.FileInfo,
+) error {
 	var removeDir chezmoi.RelPath
 	if c._import.removeDestination {
+		var err error
 		removeDir, err = c._import.destination.TrimDirPrefix(c.DestDirAbsPath)
 		if err != nil {
 			return err
@@ -85,8 +140,8 @@ func (c *Config) runImportCmd(cmd *cobra.Command, args []string, sourceState *ch
 	return sourceState.Add(
 		c.sourceSystem,
 		c.persistentState,
-		archiveReaderSystem,
-		archiveReaderSystem.FileInfos(),
+		system,
+		fileInfos,
 		&chezmoi.AddOptions{
 			Errorf:    c.errorf,
 			Exact:     c._import.exact,
@@ -3,7 +3,6 @@ package cmd
 import (
 	"errors"
 	"fmt"
-	"io/fs"
 	"log/slog"
 	"regexp"
 	"strconv"
@@ -19,13 +18,16 @@ import (
 )
 
 type initCmdConfig struct {
+	allowDirty        bool
 	apply             bool
 	branch            string
 	configPath        chezmoi.AbsPath
 	data              bool
 	depth             int
 	filter            *chezmoi.EntryTypeFilter
+	from              string
 	guessRepoURL      bool
+	guided            bool
 	oneShot           bool
 	purge             bool
 	purgeBinary       bool
@@ -88,12 +90,13 @@ func (c *Config) newInitCmd() *cobra.Command {
 	initCmd := &cobra.Command{
 		Args:    cobra.MaximumNArgs(1),
 		Use:     "init [repo]",
-		Short:   "Setup the source directory and update the destination directory to match the target state",
+		Short:   mustShortHelp("init"),
 		Long:    mustLongHelp("init"),
 		Example: example("init"),
 		RunE:    c.runInitCmd,
 		Annotations: newAnnotations(
 			createSourceDirectoryIfNeeded,
+			modifiesConfigFile,
 			modifiesDestinationDirectory,
 			persistentStateModeReadWrite,
 			requiresWorkingTree,
@@ -102,13 +105,16 @@ func (c *Config) newInitCmd() *cobra.Command {
 	}
 
 	c.addInteractiveTemplateFuncFlags(initCmd.Flags())
+	initCmd.Flags().BoolVar(&c.init.allowDirty, "allow-dirty", c.init.allowDirty, "Apply even if the source directory is not clean")
 	initCmd.Flags().BoolVarP(&c.init.apply, "apply", "a", c.init.apply, "Update destination directory")
 	initCmd.Flags().StringVar(&c.init.branch, "branch", c.init.branch, "Set initial branch to checkout")
 	initCmd.Flags().VarP(&c.init.configPath, "config-path", "C", "Path to write generated config file")
 	initCmd.Flags().BoolVar(&c.init.data, "data", c.init.data, "Include existing template data")
 	initCmd.Flags().IntVarP(&c.init.depth, "depth", "d", c.init.depth, "Create a shallow clone")
 	initCmd.Flags().VarP(c.init.filter.Exclude, "exclude", "x", "Exclude entry types")
+	initCmd.Flags().StringVar(&c.init.from, "from", c.init.from, "Import dotfiles from git-bare or yadm")
 	initCmd.Flags().BoolVarP(&c.init.guessRepoURL, "guess-repo-url", "g", c.init.guessRepoURL, "Guess the repo URL")
+	initCmd.Flags().BoolVar(&c.init.guided, "guided", c.init.guided, "Guide the user through setting up chezmoi")
 	initCmd.Flags().VarP(c.init.filter.Include, "include", "i", "Include entry types")
 	initCmd.Flags().BoolVar(&c.init.oneShot, "one-shot", c.init.oneShot, "Run in one-shot mode")
 	initCmd.Flags().BoolVarP(&c.init.purge, "purge", "p", c.init.purge, "Purge config and source directories after running")
@@ -129,10 +135,28 @@ func (c *Config) runInitCmd(cmd *cobra.Command, args []string) error {
 		c.init.purgeBinary = true
 	}
 
+	// When importing dotfiles, the argument is the repo to import from, and
+	// the working tree is initialized empty.
+	var fromArgs []string
+	switch c.init.from {
+	case "":
+	case initFromGitBare, initFromYADM:
+		fromArgs, args = args, nil
+	default:
+		return fmt.Errorf("%s: invalid --from, expected %s or %s", c.init.from, initFromGitBare, initFromYADM)
+	}
+
 	// If we're not in a working tree then init it or clone it.
-	gitDirAbsPath := c.WorkingTreeAbsPath.JoinString(git.GitDirName)
-	switch _, err := c.baseSystem.Stat(gitDirAbsPath); {
-	case errors.Is(err, fs.ErrNotExist):
+	switch workingTreeVCSName, err := c.workingTreeVCSName(); {
+	case err != nil:
+		return err
+	case workingTreeVCSName == "":
+		if c.init.guided {
+			if args, err = c.guidedInitArgs(args); err != nil {
+				return err
+			}
+		}
+
 		workingTreeRawPath, err := c.baseSystem.RawPath(c.WorkingTreeAbsPath)
 		if err != nil {
 			return err
@@ -141,25 +165,37 @@ func (c *Config) runInitCmd(cmd *cobra.Command, args []string) error {
 		useBuiltinGit := c.UseBuiltinGit.Value(c.useBuiltinGitAutoFunc)
 
 		if len(args) == 0 {
-			if useBuiltinGit {
+			switch vcsName := c.defaultVCSName(); {
+			case vcsName != gitVCSName:
+				if err := c.vcsInit(vcsName); err != nil {
+					return err
+				}
+			case useBuiltinGit:
 				if err := c.builtinGitInit(workingTreeRawPath); err != nil {
 					return err
 				}
-			} else if err := c.run(c.WorkingTreeAbsPath, c.Git.Command, []string{"init", "--quiet"}); err != nil {
-				return err
+			default:
+				if err := c.runVCS(gitVCSName, c.WorkingTreeAbsPath, c.Git.Command, []string{"init", "--quiet"}); err != nil {
+					return err
+				}
 			}
 		} else {
-			var repoURLStr string
-			if c.init.guessRepoURL {
-				repoURLStr = guessRepoURL(args[0], c.init.ssh)
-			} else {
-				repoURLStr = args[0]
+			vcsName, repoURLStr := c.repoURLVCSName(args[0])
+			// Repo URLs are guessed for git only, as the guesses are for git
+			// hosting services.
+			if c.init.guessRepoURL && vcsName == gitVCSName {
+				repoURLStr = guessRepoURL(repoURLStr, c.init.ssh)
 			}
-			if useBuiltinGit {
+			switch {
+			case vcsName != gitVCSName:
+				if err := c.vcsClone(vcsName, repoURLStr, workingTreeRawPath); err != nil {
+					return err
+				}
+			case useBuiltinGit:
 				if err := c.builtinGitClone(repoURLStr, workingTreeRawPath); err != nil {
 					return err
 				}
-			} else {
+			default:
 				args := []string{
 					"clone",
 				}
@@ -179,13 +215,11 @@ func (c *Config) runInitCmd(cmd *cobra.Command, args []string) error {
 					)
 				}
 				args = append(args, repoURLStr, workingTreeRawPath.String())
-				if err := c.run(chezmoi.EmptyAbsPath, c.Git.Command, args); err != nil {
+				if err := c.runVCS(gitVCSName, chezmoi.EmptyAbsPath, c.Git.Command, args); err != nil {
 					return err
 				}
 			}
 		}
-	case err != nil:
-		return err
 	}
 
 	if err := c.checkVersion(); err != nil {
@@ -196,14 +230,33 @@ func (c *Config) runInitCmd(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	// Guided setup.
+	if c.init.guided && len(args) == 0 {
+		if err := c.runGuidedInit(cmd); err != nil {
+			return err
+		}
+	}
+
+	// Import dotfiles.
+	if c.init.from != "" {
+		c.makeInitSourceSystemWritable()
+		if err := c.initFrom(cmd, fromArgs); err != nil {
+			return err
+		}
+	}
+
 	// Apply.
 	if c.init.apply {
 		if err := c.applyArgs(cmd.Context(), c.destSystem, c.DestDirAbsPath, noArgs, applyArgsOptions{
-			cmd:          cmd,
-			filter:       c.init.filter,
-			recursive:    false,
-			umask:        c.Umask,
-			preApplyFunc: c.defaultPreApplyFunc,
+			cmd:           cmd,
+			filter:        c.init.filter,
+			importGPGKeys: true,
+			recursive:     false,
+			umask:         c.Umask,
+			mtimeFunc:     c.applyMtimeFunc(),
+			preApplyFunc:  c.defaultPreApplyFunc,
+			requireClean:  !c.init.allowDirty,
+			targetHooks:   true,
 		}); err != nil {
 			return err
 		}
@@ -338,3 +391,14 @@ func guessRepoURL(arg string, ssh bool) string {
 	}
 	return arg
 }
+
+// makeInitSourceSystemWritable makes the source system writable. chezmoi init
+// is not annotated as modifying the source directory, so that its changes are
+// not automatically committed, but guided setup and importing dotfiles write to
+// the source directory.
+func (c *Config) makeInitSourceSystemWritable() {
+	c.sourceSystem = c.baseSystem
+	if c.dryRun {
+		c.sourceSystem = chezmoi.NewDryRunSystem(c.sourceSystem)
+	}
+}
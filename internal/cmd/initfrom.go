@@ -0,0 +1,480 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/twpayne/chezmoi/v2/internal/chezmoi"
+	"github.com/twpayne/chezmoi/v2/internal/chezmoimaps"
+)
+
+// Dotfiles managers that chezmoi init --from can import from.
+const (
+	initFromGitBare = "git-bare"
+	initFromYADM    = "yadm"
+)
+
+// yadmAlternateSeparator separates a yadm alternate's target name from its
+// conditions.
+const yadmAlternateSeparator = "##"
+
+var (
+	// yadmConditionAliases maps yadm's short condition names to their long
+	// names.
+	yadmConditionAliases = map[string]string{
+		"a": "arch",
+		"c": "class",
+		"d": "distro",
+		"e": "extension",
+		"f": "distro_family",
+		"h": "hostname",
+		"o": "os",
+		"t": "template",
+		"u": "user",
+	}
+
+	// yadmConditionWeights are the weights that yadm gives each condition when
+	// choosing between alternates. More specific conditions have higher
+	// weights.
+	yadmConditionWeights = map[string]int{
+		"arch":     1,
+		"os":       2,
+		"distro":   4,
+		"class":    16,
+		"hostname": 32,
+		"user":     64,
+	}
+
+	// yadmArchs maps the output of uname -m, which yadm uses for the arch
+	// condition, to Go architectures.
+	yadmArchs = map[string]string{
+		"aarch64": "arm64",
+		"armv7l":  "arm",
+		"i386":    "386",
+		"i686":    "386",
+		"x86_64":  "amd64",
+	}
+
+	// templateDelimiterRx matches text/template's default delimiters.
+	templateDelimiterRx = regexp.MustCompile(`\{\{|\}\}`)
+)
+
+// A yadmAlternate is one of the alternate files for a target.
+type yadmAlternate struct {
+	absPath   chezmoi.AbsPath
+	condition string
+	score     int
+}
+
+// An initFromSystem is a chezmoi.System that replaces the targets of yadm
+// alternates with files generated from the alternates.
+type initFromSystem struct {
+	chezmoi.System
+	fileInfos map[chezmoi.AbsPath]fs.FileInfo
+	contents  map[chezmoi.AbsPath][]byte
+}
+
+// A renamedFileInfo is an fs.FileInfo with a different name.
+type renamedFileInfo struct {
+	fs.FileInfo
+	name string
+}
+
+// Lstat implements chezmoi.System.Lstat.
+func (s *initFromSystem) Lstat(name chezmoi.AbsPath) (fs.FileInfo, error) {
+	if fileInfo, ok := s.fileInfos[name]; ok {
+		return fileInfo, nil
+	}
+	return s.System.Lstat(name)
+}
+
+// ReadFile implements chezmoi.System.ReadFile.
+func (s *initFromSystem) ReadFile(name chezmoi.AbsPath) ([]byte, error) {
+	if contents, ok := s.contents[name]; ok {
+		return contents, nil
+	}
+	return s.System.ReadFile(name)
+}
+
+// Name implements fs.FileInfo.Name.
+func (i renamedFileInfo) Name() string {
+	return i.name
+}
+
+// initFrom adds the files tracked by the bare git repo or yadm repo named by
+// args to the source state.
+func (c *Config) initFrom(cmd *cobra.Command, args []string) error {
+	var gitDirAbsPath chezmoi.AbsPath
+	switch {
+	case len(args) > 0:
+		var err error
+		if gitDirAbsPath, err = chezmoi.NewAbsPathFromExtPath(args[0], c.homeDirAbsPath); err != nil {
+			return err
+		}
+	case c.init.from == initFromYADM:
+		dataHomeAbsPath, err := chezmoi.NewAbsPathFromExtPath(c.bds.DataHome, c.homeDirAbsPath)
+		if err != nil {
+			return err
+		}
+		gitDirAbsPath = dataHomeAbsPath.JoinString("yadm", "repo.git")
+	default:
+		return fmt.Errorf("--from=%s requires a repo", c.init.from)
+	}
+
+	output, err := c.vcsOutput(gitVCSName, chezmoi.EmptyAbsPath, c.Git.Command, []string{
+		"--git-dir", gitDirAbsPath.String(),
+		"--work-tree", c.DestDirAbsPath.String(),
+		"ls-files", "-z",
+	})
+	if err != nil {
+		return err
+	}
+	var trackedRelPaths []string
+	for _, relPath := range strings.Split(string(output), "\x00") {
+		if relPath != "" {
+			trackedRelPaths = append(trackedRelPaths, relPath)
+		}
+	}
+
+	system := &initFromSystem{
+		System:    c.destSystem,
+		fileInfos: make(map[chezmoi.AbsPath]fs.FileInfo),
+		contents:  make(map[chezmoi.AbsPath][]byte),
+	}
+	var plainAbsPaths, templateAbsPaths, encryptedAbsPaths []chezmoi.AbsPath
+	if c.init.from == initFromYADM {
+		if plainAbsPaths, templateAbsPaths, err = c.initFromYADMAlternates(system, trackedRelPaths); err != nil {
+			return err
+		}
+		if encryptedAbsPaths, err = c.initFromYADMEncrypted(); err != nil {
+			return err
+		}
+		// Files that yadm encrypts should not also be tracked, but if they are
+		// then only add their encrypted versions.
+		plainAbsPaths = slices.DeleteFunc(plainAbsPaths, func(absPath chezmoi.AbsPath) bool {
+			return slices.Contains(encryptedAbsPaths, absPath)
+		})
+	} else {
+		for _, relPath := range trackedRelPaths {
+			plainAbsPaths = append(plainAbsPaths, c.DestDirAbsPath.JoinString(relPath))
+		}
+	}
+
+	if err := c.initFromAdd(cmd, system, plainAbsPaths, false, false); err != nil {
+		return err
+	}
+	if err := c.initFromAdd(cmd, system, templateAbsPaths, false, true); err != nil {
+		return err
+	}
+	return c.initFromAdd(cmd, system, encryptedAbsPaths, true, false)
+}
+
+// initFromAdd adds absPaths from system to a newly read source state, so that
+// the entries added by previous calls are included.
+func (c *Config) initFromAdd(
+	cmd *cobra.Command,
+	system chezmoi.System,
+	absPaths []chezmoi.AbsPath,
+	encrypt, template bool,
+) error {
+	if len(absPaths) == 0 {
+		return nil
+	}
+	sourceState, err := c.newSourceState(cmd.Context(), cmd)
+	if err != nil {
+		return err
+	}
+	destAbsPathInfos := make(map[chezmoi.AbsPath]fs.FileInfo)
+	for _, absPath := range absPaths {
+		if err := sourceState.AddDestAbsPathInfos(destAbsPathInfos, system, absPath, nil); err != nil {
+			return err
+		}
+	}
+	addOptions, err := c.addOptions()
+	if err != nil {
+		return err
+	}
+	addOptions.AutoTemplate = false
+	addOptions.Create = false
+	addOptions.Encrypt = encrypt
+	addOptions.Exact = false
+	addOptions.Template = template
+	if encrypt {
+		addOptions.PreAddFunc = nil
+	} else {
+		addOptions.PreAddFunc = func(targetRelPath chezmoi.RelPath, fileInfo fs.FileInfo) error {
+			return c.preAdd(system, targetRelPath, fileInfo)
+		}
+	}
+	return sourceState.Add(c.sourceSystem, c.persistentState, system, destAbsPathInfos, addOptions)
+}
+
+// initFromYADMAlternates returns the targets of trackedRelPaths, replacing
+// yadm alternates with their targets in system. Targets with conditional
+// alternates are returned as templates.
+func (c *Config) initFromYADMAlternates(
+	system *initFromSystem,
+	trackedRelPaths []string,
+) (plainAbsPaths, templateAbsPaths []chezmoi.AbsPath, err error) {
+	configHomeAbsPath, err := chezmoi.NewAbsPathFromExtPath(c.bds.ConfigHome, c.homeDirAbsPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	dataHomeAbsPath, err := chezmoi.NewAbsPathFromExtPath(c.bds.DataHome, c.homeDirAbsPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	yadmConfigDirAbsPath := configHomeAbsPath.JoinString("yadm")
+	yadmAltDirAbsPath := yadmConfigDirAbsPath.JoinString("alt")
+	yadmDataDirAbsPath := dataHomeAbsPath.JoinString("yadm")
+
+	alternates := make(map[chezmoi.AbsPath][]yadmAlternate)
+TRACKED_REL_PATH:
+	for _, relPath := range trackedRelPaths {
+		absPath := c.DestDirAbsPath.JoinString(relPath)
+
+		// Alternates can also be stored in yadm's alt directory, relative to
+		// the home directory.
+		targetAbsPath := absPath
+		if altRelPath, err := absPath.TrimDirPrefix(yadmAltDirAbsPath); err == nil {
+			targetAbsPath = c.DestDirAbsPath.Join(altRelPath)
+		}
+
+		dirName, baseName := path.Split(targetAbsPath.String())
+		if strings.Contains(dirName, yadmAlternateSeparator) {
+			c.errorf("%s: directory alternates are not supported, skipping\n", absPath)
+			continue
+		}
+		name, conditions, ok := strings.Cut(baseName, yadmAlternateSeparator)
+		if !ok {
+			// Skip yadm's own files.
+			for _, yadmDirAbsPath := range []chezmoi.AbsPath{yadmConfigDirAbsPath, yadmDataDirAbsPath} {
+				if _, err := absPath.TrimDirPrefix(yadmDirAbsPath); err == nil {
+					continue TRACKED_REL_PATH
+				}
+			}
+			plainAbsPaths = append(plainAbsPaths, absPath)
+			continue
+		}
+
+		condition, score, err := parseYADMConditions(conditions)
+		if err != nil {
+			c.errorf("%s: %v, skipping\n", absPath, err)
+			continue
+		}
+		targetAbsPath = chezmoi.NewAbsPath(dirName + name)
+		alternates[targetAbsPath] = append(alternates[targetAbsPath], yadmAlternate{
+			absPath:   absPath,
+			condition: condition,
+			score:     score,
+		})
+	}
+
+	// yadm replaces targets with links to their alternates, so the targets
+	// themselves should not be tracked.
+	plainAbsPaths = slices.DeleteFunc(plainAbsPaths, func(absPath chezmoi.AbsPath) bool {
+		_, ok := alternates[absPath]
+		return ok
+	})
+
+	for _, targetAbsPath := range chezmoimaps.SortedKeys(alternates) {
+		targetAlternates := alternates[targetAbsPath]
+		// yadm chooses the alternate with the highest score.
+		slices.SortStableFunc(targetAlternates, func(a, b yadmAlternate) int {
+			return b.score - a.score
+		})
+
+		fileInfo, err := c.destSystem.Lstat(targetAlternates[0].absPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !fileInfo.Mode().IsRegular() {
+			c.errorf("%s: only regular file alternates are supported, skipping\n", targetAlternates[0].absPath)
+			continue
+		}
+		system.fileInfos[targetAbsPath] = renamedFileInfo{
+			FileInfo: fileInfo,
+			name:     targetAbsPath.Base(),
+		}
+
+		// Default alternates are not templates.
+		if targetAlternates[0].condition == "" {
+			if system.contents[targetAbsPath], err = c.destSystem.ReadFile(targetAlternates[0].absPath); err != nil {
+				return nil, nil, err
+			}
+			plainAbsPaths = append(plainAbsPaths, targetAbsPath)
+			continue
+		}
+
+		if system.contents[targetAbsPath], err = c.yadmAlternatesTemplate(targetAlternates); err != nil {
+			return nil, nil, err
+		}
+		templateAbsPaths = append(templateAbsPaths, targetAbsPath)
+	}
+
+	return plainAbsPaths, templateAbsPaths, nil
+}
+
+// yadmAlternatesTemplate returns a template that evaluates to the contents of
+// the first of alternates whose condition is true.
+func (c *Config) yadmAlternatesTemplate(alternates []yadmAlternate) ([]byte, error) {
+	var builder bytes.Buffer
+	for i, alternate := range alternates {
+		switch {
+		case i == 0:
+			fmt.Fprintf(&builder, "{{ if %s -}}\n", alternate.condition)
+		case alternate.condition == "":
+			builder.WriteString("{{ else -}}\n")
+		default:
+			fmt.Fprintf(&builder, "{{ else if %s -}}\n", alternate.condition)
+		}
+		contents, err := c.destSystem.ReadFile(alternate.absPath)
+		if err != nil {
+			return nil, err
+		}
+		builder.WriteString(escapeTemplateDelimiters(string(contents)))
+		// Only the first default alternate can be chosen.
+		if alternate.condition == "" {
+			break
+		}
+	}
+	builder.WriteString("{{ end -}}\n")
+	return builder.Bytes(), nil
+}
+
+// initFromYADMEncrypted returns the files matched by the patterns in yadm's
+// encrypt file.
+func (c *Config) initFromYADMEncrypted() ([]chezmoi.AbsPath, error) {
+	configHomeAbsPath, err := chezmoi.NewAbsPathFromExtPath(c.bds.ConfigHome, c.homeDirAbsPath)
+	if err != nil {
+		return nil, err
+	}
+	data, err := c.baseSystem.ReadFile(configHomeAbsPath.JoinString("yadm", "encrypt"))
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+
+	var absPaths []chezmoi.AbsPath
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		pattern := strings.TrimSpace(scanner.Text())
+		if pattern == "" || strings.HasPrefix(pattern, "#") {
+			continue
+		}
+		pattern, exclude := strings.CutPrefix(pattern, "!")
+		pattern = strings.TrimSpace(pattern)
+
+		matches, err := c.baseSystem.Glob(c.DestDirAbsPath.JoinString(pattern).String())
+		if err != nil {
+			return nil, err
+		}
+		var matchedAbsPaths []chezmoi.AbsPath
+		for _, match := range matches {
+			if err := chezmoi.Walk(c.destSystem, chezmoi.NewAbsPath(match), func(absPath chezmoi.AbsPath, fileInfo fs.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+				if !fileInfo.IsDir() {
+					matchedAbsPaths = append(matchedAbsPaths, absPath)
+				}
+				return nil
+			}); err != nil {
+				return nil, err
+			}
+		}
+
+		if exclude {
+			absPaths = slices.DeleteFunc(absPaths, func(absPath chezmoi.AbsPath) bool {
+				return slices.Contains(matchedAbsPaths, absPath)
+			})
+		} else {
+			for _, absPath := range matchedAbsPaths {
+				if !slices.Contains(absPaths, absPath) {
+					absPaths = append(absPaths, absPath)
+				}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return absPaths, nil
+}
+
+// parseYADMConditions returns the template condition and yadm's score for
+// the conditions of a yadm alternate. Default alternates have an empty
+// condition.
+func parseYADMConditions(conditions string) (string, int, error) {
+	var exprs []string
+	score := 0
+	for _, condition := range strings.Split(conditions, ",") {
+		condition, negated := strings.CutPrefix(condition, "~")
+		attribute, value, _ := strings.Cut(condition, ".")
+		if longAttribute, ok := yadmConditionAliases[attribute]; ok {
+			attribute = longAttribute
+		}
+
+		var expr string
+		switch attribute {
+		case "default", "extension":
+			continue
+		case "arch":
+			arch := strings.ToLower(value)
+			if goArch, ok := yadmArchs[arch]; ok {
+				arch = goArch
+			}
+			expr = "eq .chezmoi.arch " + strconv.Quote(arch)
+		case "class":
+			expr = "hasRole " + strconv.Quote(value)
+		case "distro":
+			expr = "and (hasKey .chezmoi \"osRelease\") (eq .chezmoi.osRelease.id " + strconv.Quote(strings.ToLower(value)) + ")"
+		case "hostname":
+			expr = "eq .chezmoi.hostname " + strconv.Quote(value)
+		case "os":
+			if value == "WSL" {
+				return "", 0, fmt.Errorf("%s: unsupported condition", condition)
+			}
+			expr = "eq .chezmoi.os " + strconv.Quote(strings.ToLower(value))
+		case "user":
+			expr = "eq .chezmoi.username " + strconv.Quote(value)
+		default:
+			return "", 0, fmt.Errorf("%s: unsupported condition", condition)
+		}
+
+		if negated {
+			expr = "not (" + expr + ")"
+		}
+		exprs = append(exprs, expr)
+		score += 1000 + yadmConditionWeights[attribute]
+	}
+
+	switch len(exprs) {
+	case 0:
+		return "", 0, nil
+	case 1:
+		return exprs[0], score, nil
+	default:
+		return "and (" + strings.Join(exprs, ") (") + ")", score, nil
+	}
+}
+
+// escapeTemplateDelimiters returns s with text/template's delimiters escaped,
+// so that s is a template that evaluates to s.
+func escapeTemplateDelimiters(s string) string {
+	return templateDelimiterRx.ReplaceAllStringFunc(s, func(delimiter string) string {
+		return `{{ "` + delimiter + `" }}`
+	})
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestParseYADMConditions(t *testing.T) {
+	for _, tc := range []struct {
+		conditions        string
+		expectedCondition string
+		expectedScore     int
+		expectedErr       bool
+	}{
+		{
+			conditions: "default",
+		},
+		{
+			conditions: "e.sh",
+		},
+		{
+			conditions:        "os.Darwin",
+			expectedCondition: `eq .chezmoi.os "darwin"`,
+			expectedScore:     1002,
+		},
+		{
+			conditions:        "a.x86_64",
+			expectedCondition: `eq .chezmoi.arch "amd64"`,
+			expectedScore:     1001,
+		},
+		{
+			conditions:        "d.Ubuntu",
+			expectedCondition: `and (hasKey .chezmoi "osRelease") (eq .chezmoi.osRelease.id "ubuntu")`,
+			expectedScore:     1004,
+		},
+		{
+			conditions:        "~class.work",
+			expectedCondition: `not (hasRole "work")`,
+			expectedScore:     1016,
+		},
+		{
+			conditions:        "u.alice,h.host,e.txt",
+			expectedCondition: `and (eq .chezmoi.username "alice") (eq .chezmoi.hostname "host")`,
+			expectedScore:     2096,
+		},
+		{
+			conditions:  "os.WSL",
+			expectedErr: true,
+		},
+		{
+			conditions:  "template",
+			expectedErr: true,
+		},
+		{
+			conditions:  "unknown.value",
+			expectedErr: true,
+		},
+	} {
+		t.Run(tc.conditions, func(t *testing.T) {
+			actualCondition, actualScore, err := parseYADMConditions(tc.conditions)
+			if tc.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedCondition, actualCondition)
+			assert.Equal(t, tc.expectedScore, actualScore)
+		})
+	}
+}
+
+func TestEscapeTemplateDelimiters(t *testing.T) {
+	assert.Equal(t, `a {{ "{{" }} b {{ "}}" }} c`, escapeTemplateDelimiters("a {{ b }} c"))
+}
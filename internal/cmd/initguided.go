@@ -0,0 +1,283 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"time"
+
+	"filippo.io/age"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/spf13/cobra"
+
+	"github.com/twpayne/chezmoi/v2/internal/chezmoi"
+)
+
+// guidedInitDotfiles are the dotfiles that chezmoi init --guided offers to
+// add, if they exist. Sensitive dotfiles are only added by default if they can
+// be encrypted.
+var guidedInitDotfiles = []struct {
+	relPath   chezmoi.RelPath
+	sensitive bool
+}{
+	{relPath: chezmoi.NewRelPath(".aws/credentials"), sensitive: true},
+	{relPath: chezmoi.NewRelPath(".bash_profile")},
+	{relPath: chezmoi.NewRelPath(".bashrc")},
+	{relPath: chezmoi.NewRelPath(".config/fish/config.fish")},
+	{relPath: chezmoi.NewRelPath(".config/git/config")},
+	{relPath: chezmoi.NewRelPath(".config/nvim/init.lua")},
+	{relPath: chezmoi.NewRelPath(".config/nvim/init.vim")},
+	{relPath: chezmoi.NewRelPath(".gitconfig")},
+	{relPath: chezmoi.NewRelPath(".inputrc")},
+	{relPath: chezmoi.NewRelPath(".netrc"), sensitive: true},
+	{relPath: chezmoi.NewRelPath(".profile")},
+	{relPath: chezmoi.NewRelPath(".pypirc"), sensitive: true},
+	{relPath: chezmoi.NewRelPath(".ssh/config")},
+	{relPath: chezmoi.NewRelPath(".tmux.conf")},
+	{relPath: chezmoi.NewRelPath(".vimrc")},
+	{relPath: chezmoi.NewRelPath(".zprofile")},
+	{relPath: chezmoi.NewRelPath(".zshrc")},
+}
+
+// guidedInitArgs prompts the user for an existing repo to clone, returning the
+// arguments that chezmoi init should use.
+func (c *Config) guidedInitArgs(args []string) ([]string, error) {
+	if len(args) != 0 {
+		return args, nil
+	}
+	switch existingRepo, err := c.promptBool("Do you already have a dotfiles repo", false); {
+	case err != nil:
+		return nil, err
+	case !existingRepo:
+		return nil, nil
+	}
+	repo, err := c.promptString("Repo URL or GitHub username")
+	if err != nil {
+		return nil, err
+	}
+	if repo == "" {
+		return nil, errors.New("no repo given")
+	}
+	switch apply, err := c.promptBool("Apply the dotfiles after cloning", false); {
+	case err != nil:
+		return nil, err
+	case apply:
+		c.init.apply = true
+	}
+	return []string{repo}, nil
+}
+
+// runGuidedInit walks the user through setting up a new source directory:
+// adding a remote, setting up encryption, adding common dotfiles, and making
+// the first commit.
+func (c *Config) runGuidedInit(cmd *cobra.Command) error {
+	// Guided setup commits its own changes to the source directory.
+	c.makeInitSourceSystemWritable()
+
+	remote, err := c.promptString("Remote repo URL or GitHub username (leave empty to skip)", "")
+	if err != nil {
+		return err
+	}
+	if remote != "" {
+		if c.init.guessRepoURL {
+			remote = guessRepoURL(remote, c.init.ssh)
+		}
+		if err := c.guidedInitAddRemote(remote); err != nil {
+			return err
+		}
+	}
+
+	if err := c.guidedInitEncryption(cmd); err != nil {
+		return err
+	}
+	_, encrypt := c.encryption.(*chezmoi.AgeEncryption)
+
+	sourceState, err := c.getSourceState(cmd.Context(), cmd)
+	if err != nil {
+		return err
+	}
+
+	var plainArgs, encryptedArgs []string
+	for _, dotfile := range guidedInitDotfiles {
+		targetRelPath := dotfile.relPath
+		if sourceState.Get(targetRelPath) != nil {
+			continue
+		}
+		destAbsPath := c.DestDirAbsPath.Join(targetRelPath)
+		switch fileInfo, err := c.destSystem.Lstat(destAbsPath); {
+		case errors.Is(err, fs.ErrNotExist):
+			continue
+		case err != nil:
+			return err
+		case !fileInfo.Mode().IsRegular():
+			continue
+		}
+		prompt := "Add ~/" + targetRelPath.String()
+		if dotfile.sensitive && encrypt {
+			prompt += " encrypted"
+		}
+		switch add, err := c.promptBool(prompt, !dotfile.sensitive || encrypt); {
+		case err != nil:
+			return err
+		case !add:
+			continue
+		case dotfile.sensitive && encrypt:
+			encryptedArgs = append(encryptedArgs, destAbsPath.String())
+		default:
+			plainArgs = append(plainArgs, destAbsPath.String())
+		}
+	}
+
+	if len(plainArgs) != 0 {
+		if err := c.runAddCmd(cmd, plainArgs, sourceState); err != nil {
+			return err
+		}
+	}
+	if len(encryptedArgs) != 0 {
+		addEncrypt := c.Add.Encrypt
+		c.Add.Encrypt = true
+		err := c.runAddCmd(cmd, encryptedArgs, sourceState)
+		c.Add.Encrypt = addEncrypt
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.dryRun {
+		return nil
+	}
+	status, err := c.gitAutoAdd()
+	if err != nil {
+		return err
+	}
+	if err := c.gitAutoCommit(cmd, status); err != nil {
+		return err
+	}
+	if remote != "" && !status.Empty() {
+		_, err := fmt.Fprintf(c.stdout, "chezmoi: run `chezmoi git push -- --set-upstream origin HEAD` to push to %s\n", remote)
+		return err
+	}
+	return nil
+}
+
+// guidedInitAddRemote adds remote as the origin of the working tree.
+func (c *Config) guidedInitAddRemote(remote string) error {
+	if c.dryRun {
+		return nil
+	}
+	if c.UseBuiltinGit.Value(c.useBuiltinGitAutoFunc) {
+		repo, _, err := c.builtinGitWorktree()
+		if err != nil {
+			return err
+		}
+		_, err = repo.CreateRemote(&config.RemoteConfig{
+			Name: "origin",
+			URLs: []string{remote},
+		})
+		return err
+	}
+	return c.runVCS(gitVCSName, c.WorkingTreeAbsPath, c.Git.Command, []string{"remote", "add", "origin", remote})
+}
+
+// guidedInitEncryption offers to set up age encryption by creating or reusing
+// an age identity and writing a config file template that uses it.
+func (c *Config) guidedInitEncryption(cmd *cobra.Command) error {
+	if c.Encryption != "" {
+		return nil
+	}
+	switch configTemplate, err := c.findConfigTemplate(); {
+	case err != nil:
+		return err
+	case configTemplate != nil:
+		return nil
+	}
+	configFileAbsPath := c.getConfigFileAbsPath()
+	switch _, err := c.baseSystem.Stat(configFileAbsPath); {
+	case err == nil:
+		c.warnf("%s: config file already exists, skipping encryption setup\n", configFileAbsPath)
+		return nil
+	case !errors.Is(err, fs.ErrNotExist):
+		return err
+	}
+
+	switch encrypt, err := c.promptBool("Set up age encryption for sensitive files", true); {
+	case err != nil:
+		return err
+	case !encrypt:
+		return nil
+	}
+
+	identityAbsPath := configFileAbsPath.Dir().JoinString("key.txt")
+	recipient, err := c.guidedInitAgeRecipient(identityAbsPath)
+	if err != nil {
+		return err
+	}
+
+	identityStr := identityAbsPath.String()
+	if relPath, err := identityAbsPath.TrimDirPrefix(c.homeDirAbsPath); err == nil {
+		identityStr = "~/" + relPath.String()
+	}
+	configTemplateContents := fmt.Sprintf(""+
+		"encryption = \"age\"\n"+
+		"[age]\n"+
+		"    identity = %s\n"+
+		"    recipient = %s\n",
+		strconv.Quote(identityStr),
+		strconv.Quote(recipient),
+	)
+	configTemplateAbsPath := c.SourceDirAbsPath.JoinString(".chezmoi.toml.tmpl")
+	if err := c.sourceSystem.WriteFile(configTemplateAbsPath, []byte(configTemplateContents), 0o666&^c.Umask); err != nil {
+		return err
+	}
+
+	return c.createAndReloadConfigFile(cmd)
+}
+
+// guidedInitAgeRecipient returns the recipient of the age identity in
+// identityAbsPath, generating a new identity if none exists.
+func (c *Config) guidedInitAgeRecipient(identityAbsPath chezmoi.AbsPath) (string, error) {
+	switch data, err := c.baseSystem.ReadFile(identityAbsPath); {
+	case err == nil:
+		identities, err := age.ParseIdentities(bytes.NewReader(data))
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", identityAbsPath, err)
+		}
+		for _, identity := range identities {
+			if x25519Identity, ok := identity.(*age.X25519Identity); ok {
+				return x25519Identity.Recipient().String(), nil
+			}
+		}
+		return "", fmt.Errorf("%s: no X25519 identity", identityAbsPath)
+	case !errors.Is(err, fs.ErrNotExist):
+		return "", err
+	}
+
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		return "", err
+	}
+	recipient := identity.Recipient().String()
+	if c.dryRun {
+		return recipient, nil
+	}
+	identityContents := fmt.Sprintf(""+
+		"# created: %s\n"+
+		"# public key: %s\n"+
+		"%s\n",
+		time.Now().Format(time.RFC3339),
+		recipient,
+		identity.String(),
+	)
+	if err := chezmoi.MkdirAll(c.baseSystem, identityAbsPath.Dir(), fs.ModePerm); err != nil {
+		return "", err
+	}
+	if err := c.baseSystem.WriteFile(identityAbsPath, []byte(identityContents), 0o600); err != nil {
+		return "", err
+	}
+	if _, err := fmt.Fprintf(c.stdout, "chezmoi: created age identity %s, back it up somewhere safe\n", identityAbsPath); err != nil {
+		return "", err
+	}
+	return recipient, nil
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"math"
 
 	"github.com/spf13/pflag"
 
@@ -181,7 +182,7 @@ func (c *Config) promptIntOnceInteractiveTemplateFunc(m map[string]any, path any
 	}
 	if !c.interactiveTemplateFuncs.forcePromptOnce {
 		if value, ok := nestedMap[lastKey]; ok {
-			if intValue, ok := value.(int64); ok {
+			if intValue, err := anyToInt64(value); err == nil {
 				return intValue
 			}
 		}
@@ -228,6 +229,29 @@ func (c *Config) promptStringOnceInteractiveTemplateFunc(m map[string]any, path
 	return c.promptStringInteractiveTemplateFunc(prompt, args...)
 }
 
+// anyToInt64 converts v to an int64. Config file formats decode integers to
+// different types, for example JSON decodes all numbers to float64s.
+func anyToInt64(v any) (int64, error) {
+	switch v := v.(type) {
+	case int:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case uint64:
+		if v > math.MaxInt64 {
+			return 0, fmt.Errorf("%d: out of range", v)
+		}
+		return int64(v), nil
+	case float64:
+		if v != math.Trunc(v) || v < math.MinInt64 || v >= math.MaxInt64 {
+			return 0, fmt.Errorf("%v: not an integer", v)
+		}
+		return int64(v), nil
+	default:
+		return 0, fmt.Errorf("%v: not an integer", v)
+	}
+}
+
 func anyToString(v any) (string, error) {
 	switch v := v.(type) {
 	case []byte:
@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"encoding"
+	"io/fs"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"github.com/twpayne/chezmoi/v2/internal/chezmoi"
+)
+
+const jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+var (
+	pflagValueType          = reflect.TypeOf((*pflag.Value)(nil)).Elem()
+	textUnmarshalerType     = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	jsonSchemaTypeOverrides = map[reflect.Type]map[string]any{
+		reflect.TypeOf(autoBool{}): {
+			"anyOf": []any{
+				map[string]any{"type": "boolean"},
+				map[string]any{"enum": []any{"auto"}},
+			},
+		},
+		reflect.TypeOf(chezmoi.EntryTypeSet{}): {
+			"type": []any{"array", "string"},
+			"items": map[string]any{
+				"type": "string",
+			},
+		},
+		reflect.TypeOf(chezmoi.ExternalType("")): {
+			"enum": []any{
+				chezmoi.ExternalTypeArchive,
+				chezmoi.ExternalTypeArchiveFile,
+				chezmoi.ExternalTypeFile,
+				chezmoi.ExternalTypeGitRepo,
+			},
+		},
+		reflect.TypeOf(chezmoi.Mode("")): {
+			"enum": []any{
+				chezmoi.ModeFile,
+				chezmoi.ModeSymlink,
+			},
+		},
+		reflect.TypeOf(fs.FileMode(0)): {
+			"type": "integer",
+		},
+		reflect.TypeOf(time.Duration(0)): {
+			"type": "string",
+		},
+	}
+)
+
+// jsonSchemas returns the JSON Schemas that chezmoi can generate, keyed by
+// name.
+func jsonSchemas() map[string]map[string]any {
+	return map[string]map[string]any{
+		"config": newJSONSchema(
+			"chezmoi config file",
+			jsonSchemaForType(reflect.TypeOf(ConfigFile{})),
+		),
+		"data": newJSONSchema(
+			"chezmoi .chezmoidata file",
+			map[string]any{
+				"type": "object",
+			},
+		),
+		"external": newJSONSchema(
+			"chezmoi .chezmoiexternal file",
+			map[string]any{
+				"type":                 "object",
+				"additionalProperties": jsonSchemaForType(reflect.TypeOf(chezmoi.External{})),
+			},
+		),
+	}
+}
+
+// newJSONSchema returns a top level JSON Schema with title describing values
+// matching schema.
+func newJSONSchema(title string, schema map[string]any) map[string]any {
+	result := map[string]any{
+		"$schema": jsonSchemaDraft,
+		"title":   title,
+	}
+	for key, value := range schema {
+		result[key] = value
+	}
+	return result
+}
+
+// jsonSchemaForType returns a JSON Schema describing values of type t. Struct
+// fields are described by their json tags, and types that are set from strings,
+// like paths, are described as strings.
+func jsonSchemaForType(t reflect.Type) map[string]any {
+	if override, ok := jsonSchemaTypeOverrides[t]; ok {
+		return override
+	}
+
+	if t.Kind() != reflect.Pointer {
+		pointerType := reflect.PointerTo(t)
+		if pointerType.Implements(pflagValueType) || pointerType.Implements(textUnmarshalerType) {
+			return map[string]any{
+				"type": "string",
+			}
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{
+			"type": "boolean",
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{
+			"type": "integer",
+		}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{
+			"type": "number",
+		}
+	case reflect.String:
+		return map[string]any{
+			"type": "string",
+		}
+	case reflect.Interface:
+		return map[string]any{}
+	case reflect.Map:
+		return map[string]any{
+			"type":                 "object",
+			"additionalProperties": jsonSchemaForType(t.Elem()),
+		}
+	case reflect.Pointer:
+		return jsonSchemaForType(t.Elem())
+	case reflect.Slice, reflect.Array:
+		return map[string]any{
+			"type":  "array",
+			"items": jsonSchemaForType(t.Elem()),
+		}
+	case reflect.Struct:
+		properties := make(map[string]any)
+		addJSONSchemaProperties(properties, t)
+		return map[string]any{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+	default:
+		return map[string]any{}
+	}
+}
+
+// addJSONSchemaProperties adds the JSON Schemas of the exported fields of the
+// struct type t to properties.
+func addJSONSchemaProperties(properties map[string]any, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			addJSONSchemaProperties(properties, field.Type)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		switch name {
+		case "-":
+			continue
+		case "":
+			name = field.Name
+		}
+		properties[name] = jsonSchemaForType(field.Type)
+	}
+}
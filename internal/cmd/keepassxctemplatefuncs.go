@@ -61,6 +61,7 @@ func (c *Config) keepassxcAttachmentTemplateFunc(entry, name string) string {
 		return data
 	}
 
+	var data string
 	switch c.Keepassxc.Mode {
 	case keepassxcModeCachePassword:
 		// In cache password mode use --stdout to read the attachment data directly.
@@ -68,7 +69,7 @@ func (c *Config) keepassxcAttachmentTemplateFunc(entry, name string) string {
 		if err != nil {
 			panic(err)
 		}
-		return string(output)
+		data = string(output)
 	case keepassxcModeOpen:
 		// In open mode write the attachment data to a temporary file.
 		tempDir, err := c.tempDir("chezmoi-keepassxc")
@@ -79,17 +80,27 @@ func (c *Config) keepassxcAttachmentTemplateFunc(entry, name string) string {
 		if _, err := c.keepassxcOutputOpen("attachment-export", "--quiet", entry, name, tempFilename); err != nil {
 			panic(err)
 		}
-		data, err := os.ReadFile(tempFilename)
+		output, err := os.ReadFile(tempFilename)
 		if err != nil {
 			panic(err)
 		}
 		if err := os.Remove(tempFilename); err != nil {
 			panic(err)
 		}
-		return string(data)
+		data = string(output)
 	default:
 		panic(fmt.Sprintf("%s: invalid mode", c.Keepassxc.Mode))
 	}
+
+	if c.Keepassxc.attachmentCache == nil {
+		c.Keepassxc.attachmentCache = make(map[string]map[string]string)
+	}
+	if c.Keepassxc.attachmentCache[entry] == nil {
+		c.Keepassxc.attachmentCache[entry] = make(map[string]string)
+	}
+	c.Keepassxc.attachmentCache[entry][name] = data
+
+	return data
 }
 
 func (c *Config) keepassxcTemplateFunc(entry string) map[string]string {
@@ -179,7 +190,7 @@ func (c *Config) keepassxcOutputCachePassword(command string, args ...string) ([
 	}
 	cmd.Stderr = os.Stderr
 
-	output, err := chezmoilog.LogCmdOutput(c.logger, cmd)
+	output, err := c.secretCmdOutput(cmd)
 	if err != nil {
 		return nil, newCmdOutputError(cmd, output, err)
 	}
@@ -211,6 +222,7 @@ func (c *Config) keepassxcOutputOpen(command string, args ...string) ([]byte, er
 		cmd.Stdin = console.Tty()
 		cmd.Stdout = console.Tty()
 		cmd.Stderr = console.Tty()
+		c.verbosef(chezmoi.VerbosityActions, "secrets", "running %s\n", shellQuoteCommand(c.Keepassxc.Command, cmdArgs))
 		if err := chezmoilog.LogCmdStart(c.logger, cmd); err != nil {
 			return nil, err
 		}
@@ -25,7 +25,7 @@ func (c *Config) keyringTemplateFunc(service, user string) string {
 	if password, ok := c.keyring.cache[key]; ok {
 		return password
 	}
-	password, err := keyring.Get(service, user)
+	password, err := keyringGet(service, user)
 	if err != nil {
 		panic(fmt.Errorf("%s %s: %w", service, user, err))
 	}
@@ -37,3 +37,9 @@ func (c *Config) keyringTemplateFunc(service, user string) string {
 	c.keyring.cache[key] = password
 	return password
 }
+
+// keyringGet returns the password for service and user from the system
+// keyring.
+func keyringGet(service, user string) (string, error) {
+	return keyring.Get(service, user)
+}
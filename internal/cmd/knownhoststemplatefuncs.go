@@ -0,0 +1,247 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1" //nolint:gosec
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+
+	"github.com/twpayne/chezmoi/v2/internal/chezmoi"
+	"github.com/twpayne/chezmoi/v2/internal/chezmoilog"
+	"github.com/twpayne/chezmoi/v2/internal/chezmoimaps"
+)
+
+type knownHostsConfig struct {
+	Command      string   `json:"command" mapstructure:"command" yaml:"command"`
+	Args         []string `json:"args"    mapstructure:"args"    yaml:"args"`
+	Confirm      bool     `json:"confirm" mapstructure:"confirm" yaml:"confirm"`
+	keyscanCache map[string][]string
+	// unconfirmedKeys contains the newly-scanned keys of each host that have
+	// not yet been confirmed by the user.
+	unconfirmedKeys map[string][]string
+}
+
+// A knownHost is the declaration of a host in the inventory passed to the
+// knownHosts template function.
+type knownHost struct {
+	Hash bool     `mapstructure:"hash"`
+	Keys []string `mapstructure:"keys"`
+}
+
+type knownHostSaltState struct {
+	Salt []byte `json:"salt" yaml:"salt"`
+}
+
+type sshKeyscanState struct {
+	ScannedAt time.Time `json:"scannedAt" yaml:"scannedAt"`
+	Keys      []string  `json:"keys"      yaml:"keys"`
+}
+
+var (
+	knownHostSaltStateBucket = []byte("knownHostSaltState")
+	sshKeyscanStateBucket    = []byte("sshKeyscanState")
+)
+
+func (c *Config) hashKnownHostTemplateFunc(host string) string {
+	return hashKnownHost(host, c.knownHostSalt(host))
+}
+
+func (c *Config) knownHostsTemplateFunc(inventory map[string]any) string {
+	var builder strings.Builder
+	for _, host := range chezmoimaps.SortedKeys(inventory) {
+		var knownHost knownHost
+		if err := mapstructure.Decode(inventory[host], &knownHost); err != nil {
+			panic(fmt.Errorf("%s: %w", host, err))
+		}
+		keys := knownHost.Keys
+		if len(keys) == 0 {
+			keys = c.sshKeyscanTemplateFunc(host)
+		}
+		hostField := host
+		if knownHost.Hash {
+			hostField = hashKnownHost(host, c.knownHostSalt(host))
+		}
+		for _, key := range keys {
+			fmt.Fprintf(&builder, "%s %s\n", hostField, key)
+		}
+	}
+	return builder.String()
+}
+
+func (c *Config) sshKeyscanTemplateFunc(host string) []string {
+	if keys, ok := c.KnownHosts.keyscanCache[host]; ok {
+		return keys
+	}
+
+	// Once scanned, a host's keys are pinned in the persistent state so that a
+	// change in the host's keys is not silently accepted.
+	var state sshKeyscanState
+	switch ok, err := c.readPersistentState(sshKeyscanStateBucket, []byte(host), &state); {
+	case err != nil:
+		panic(err)
+	case ok && len(state.Keys) != 0:
+		c.cacheSSHKeyscanKeys(host, state.Keys)
+		return state.Keys
+	}
+
+	args := append(slices.Clone(c.KnownHosts.Args), host)
+	cmd := exec.Command(c.KnownHosts.Command, args...) //nolint:gosec
+	cmd.Stderr = os.Stderr
+	output, err := chezmoilog.LogCmdOutput(c.logger, cmd)
+	if err != nil {
+		panic(newCmdOutputError(cmd, output, err))
+	}
+	keys, err := parseSSHKeyscanOutput(output)
+	switch {
+	case err != nil:
+		panic(newParseCmdOutputError(c.KnownHosts.Command, args, output, err))
+	case len(keys) == 0:
+		panic(fmt.Errorf("%s: no host keys found", host))
+	}
+	c.cacheSSHKeyscanKeys(host, keys)
+
+	// Templates are executed non-interactively, so newly-scanned keys are only
+	// pinned once the user has confirmed them, before the first target is
+	// applied. See confirmSSHKeyscanKeys.
+	if c.KnownHosts.Confirm && !c.force {
+		if c.KnownHosts.unconfirmedKeys == nil {
+			c.KnownHosts.unconfirmedKeys = make(map[string][]string)
+		}
+		c.KnownHosts.unconfirmedKeys[host] = keys
+		return keys
+	}
+
+	if err := c.writePersistentState(sshKeyscanStateBucket, []byte(host), &sshKeyscanState{
+		ScannedAt: time.Now(),
+		Keys:      keys,
+	}); err != nil {
+		panic(err)
+	}
+	return keys
+}
+
+func (c *Config) cacheSSHKeyscanKeys(host string, keys []string) {
+	if c.KnownHosts.keyscanCache == nil {
+		c.KnownHosts.keyscanCache = make(map[string][]string)
+	}
+	c.KnownHosts.keyscanCache[host] = keys
+}
+
+// confirmSSHKeyscanKeys prompts the user to confirm the keys of each host that
+// have been scanned but not yet confirmed. Confirmed keys are pinned in the
+// persistent state. If the user does not confirm a host's keys then they are
+// forgotten and fs.SkipDir is returned so that the current target is not
+// applied.
+func (c *Config) confirmSSHKeyscanKeys() error {
+	for _, host := range chezmoimaps.SortedKeys(c.KnownHosts.unconfirmedKeys) {
+		keys := c.KnownHosts.unconfirmedKeys[host]
+		if c.KnownHosts.Confirm {
+			fingerprints := make([]string, 0, len(keys))
+			for _, key := range keys {
+				fingerprints = append(fingerprints, sshKeyFingerprint(key))
+			}
+			prompt := fmt.Sprintf("Trust host keys %s of %s", strings.Join(fingerprints, ", "), host)
+			switch choice, err := c.promptChoice(prompt, choicesYesNoAllQuit); {
+			case err != nil:
+				return err
+			case choice == "yes":
+				// Do nothing.
+			case choice == "no":
+				delete(c.KnownHosts.keyscanCache, host)
+				delete(c.KnownHosts.unconfirmedKeys, host)
+				return fs.SkipDir
+			case choice == "all":
+				c.KnownHosts.Confirm = false
+			case choice == "quit":
+				return chezmoi.ExitCodeError(0)
+			default:
+				panic(choice + ": unexpected choice")
+			}
+		}
+		if err := c.writePersistentState(sshKeyscanStateBucket, []byte(host), &sshKeyscanState{
+			ScannedAt: time.Now(),
+			Keys:      keys,
+		}); err != nil {
+			return err
+		}
+		delete(c.KnownHosts.unconfirmedKeys, host)
+	}
+	return nil
+}
+
+// knownHostSalt returns the salt used to hash host. The salt is generated
+// randomly the first time that host is hashed and then stored in the persistent
+// state, so that the hashed host does not change on every apply.
+func (c *Config) knownHostSalt(host string) []byte {
+	var state knownHostSaltState
+	switch ok, err := c.readPersistentState(knownHostSaltStateBucket, []byte(host), &state); {
+	case err != nil:
+		panic(err)
+	case ok && len(state.Salt) == sha1.Size:
+		return state.Salt
+	}
+
+	state.Salt = make([]byte, sha1.Size)
+	if _, err := rand.Read(state.Salt); err != nil {
+		panic(err)
+	}
+	if err := c.writePersistentState(knownHostSaltStateBucket, []byte(host), &state); err != nil {
+		panic(err)
+	}
+	return state.Salt
+}
+
+// hashKnownHost returns host hashed with salt in the format used by OpenSSH's
+// HashKnownHosts option.
+func hashKnownHost(host string, salt []byte) string {
+	mac := hmac.New(sha1.New, salt)
+	mac.Write([]byte(host))
+	return "|1|" + base64.StdEncoding.EncodeToString(salt) + "|" + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// parseSSHKeyscanOutput returns the sorted keys, each as a key type and base64
+// encoded key separated by a space, in the output of ssh-keyscan.
+func parseSSHKeyscanOutput(output []byte) ([]string, error) {
+	var keys []string
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("%q: invalid line", line)
+		}
+		keys = append(keys, fields[1]+" "+fields[2])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	slices.Sort(keys)
+	return slices.Compact(keys), nil
+}
+
+// sshKeyFingerprint returns the SHA256 fingerprint of key, as displayed by
+// ssh-keygen -l.
+func sshKeyFingerprint(key string) string {
+	_, encodedKey, _ := strings.Cut(key, " ")
+	keyData, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil {
+		return key
+	}
+	sum := sha256.Sum256(keyData)
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
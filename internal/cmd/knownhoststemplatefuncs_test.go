@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/twpayne/chezmoi/v2/internal/chezmoitest"
+)
+
+const testSSHKey = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIDOApV0JKmnCv0jbUcPE9VGhcWvV7m/XH8obu4qJ81aW"
+
+func TestHashKnownHost(t *testing.T) {
+	salt, err := base64.StdEncoding.DecodeString("wiCKvelmjo6YFcNpCFXt0eY6vqw=")
+	assert.NoError(t, err)
+	assert.Equal(t, "|1|wiCKvelmjo6YFcNpCFXt0eY6vqw=|FR5zFx3rYqFETkli3gg82I7YPgg=", hashKnownHost("github.com", salt))
+}
+
+func TestParseSSHKeyscanOutput(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		output      string
+		expected    []string
+		expectedErr bool
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name: "keys",
+			output: chezmoitest.JoinLines(
+				"# example.com:22 SSH-2.0-OpenSSH_9.6",
+				"example.com ssh-rsa AAAAB3NzaC1yc2E=",
+				"example.com "+testSSHKey,
+				"example.com ssh-rsa AAAAB3NzaC1yc2E=",
+			),
+			expected: []string{
+				testSSHKey,
+				"ssh-rsa AAAAB3NzaC1yc2E=",
+			},
+		},
+		{
+			name:        "invalid",
+			output:      "example.com\n",
+			expectedErr: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := parseSSHKeyscanOutput([]byte(tc.output))
+			if tc.expectedErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestSSHKeyFingerprint(t *testing.T) {
+	assert.Equal(t, "SHA256:X9+Z8f1Gwd/TNMbXOEL5wngx9fhH2Gn4wnibXYUN8wg", sshKeyFingerprint(testSSHKey))
+}
@@ -12,7 +12,7 @@ import (
 func (c *Config) newLicenseCmd() *cobra.Command {
 	licenseCmd := &cobra.Command{
 		Use:     "license",
-		Short:   "Print license",
+		Short:   mustShortHelp("license"),
 		Long:    mustLongHelp("license"),
 		Example: example("license"),
 		Args:    cobra.NoArgs,
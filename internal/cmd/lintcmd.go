@@ -0,0 +1,259 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/twpayne/chezmoi/v2/internal/chezmoi"
+)
+
+type lintCmdConfig struct {
+	renders int
+	shuffle bool
+}
+
+// nonDeterministicTemplateFuncNames are the names of template functions whose
+// output can differ each time that they are called.
+var nonDeterministicTemplateFuncNames = []string{
+	"genCA",
+	"genPrivateKey",
+	"genSelfSignedCert",
+	"genSignedCert",
+	"keys",
+	"now",
+	"randAlpha",
+	"randAlphaNum",
+	"randAscii",
+	"randBytes",
+	"randInt",
+	"randNumeric",
+	"shuffle",
+	"uuidv4",
+	"values",
+}
+
+// keyedTemplateFuncNames are the names of non-deterministic template functions
+// whose output is the same every time when they are called with a key.
+var keyedTemplateFuncNames = []string{
+	"uuidv4",
+}
+
+var (
+	templateActionRx           = regexp.MustCompile(`(?s)\{\{.*?\}\}`)
+	nonDeterministicFuncNameRx = regexp.MustCompile(`(?:^|[^$.\w])(` + strings.Join(nonDeterministicTemplateFuncNames, "|") + `)\b`)
+	templateFuncArgRx          = regexp.MustCompile(`^\s+[^\s|)}-]`)
+)
+
+func (c *Config) newLintCmd() *cobra.Command {
+	lintCmd := &cobra.Command{
+		Use:     "lint",
+		Short:   mustShortHelp("lint"),
+		Long:    mustLongHelp("lint"),
+		Example: example("lint"),
+		Args:    cobra.NoArgs,
+		RunE:    c.runLintCmd,
+		Annotations: newAnnotations(
+			persistentStateModeReadMockWrite,
+			requiresSourceDirectory,
+		),
+	}
+
+	lintCmd.Flags().IntVar(&c.lint.renders, "renders", c.lint.renders, "")
+	lintCmd.Flags().BoolVar(&c.lint.shuffle, "shuffle", c.lint.shuffle, "")
+
+	return lintCmd
+}
+
+func (c *Config) runLintCmd(cmd *cobra.Command, args []string) error {
+	if c.lint.renders < 2 {
+		return fmt.Errorf("%d: renders must be at least 2", c.lint.renders)
+	}
+
+	sourceState, err := c.newSourceState(cmd.Context(), cmd)
+	if err != nil {
+		return err
+	}
+
+	problems, invalidTemplateTargetRelPaths := c.lintTemplateSyntax(sourceState)
+	problems = append(problems, lintAttributes(sourceState)...)
+	ignorePatternProblems, err := lintIgnorePatterns(sourceState)
+	if err != nil {
+		return err
+	}
+	problems = append(problems, ignorePatternProblems...)
+	nonDeterministicTemplateProblems, err := c.lintNonDeterministicTemplates(cmd, invalidTemplateTargetRelPaths)
+	if err != nil {
+		return err
+	}
+	problems = append(problems, nonDeterministicTemplateProblems...)
+
+	if len(problems) == 0 {
+		return nil
+	}
+	for _, problem := range problems {
+		if _, err := fmt.Fprintln(c.stdout, problem); err != nil {
+			return err
+		}
+	}
+	return chezmoi.ExitCodeError(1)
+}
+
+// lintTemplateSyntax parses every template in sourceState and returns a
+// problem for every template with a syntax error, and the targets of those
+// templates.
+func (c *Config) lintTemplateSyntax(sourceState *chezmoi.SourceState) ([]string, map[chezmoi.RelPath]bool) {
+	var problems []string
+	invalidTemplateTargetRelPaths := make(map[chezmoi.RelPath]bool)
+	for _, targetRelPath := range sourceState.TargetRelPaths() {
+		sourceStateFile, ok := sourceState.MustEntry(targetRelPath).(*chezmoi.SourceStateFile)
+		if !ok || !sourceStateFile.Attr.Template {
+			continue
+		}
+		contents, err := sourceStateFile.Contents()
+		if err == nil {
+			_, err = chezmoi.ParseTemplate(sourceStateFile.SourceRelPath().String(), contents, c.templateFuncs, chezmoi.TemplateOptions{
+				Options: slices.Clone(c.Template.Options),
+			})
+		}
+		if err != nil {
+			// Parse errors already start with the template name and line
+			// number.
+			problems = append(problems, strings.TrimPrefix(err.Error(), "template: "))
+			invalidTemplateTargetRelPaths[targetRelPath] = true
+		}
+	}
+	return problems, invalidTemplateTargetRelPaths
+}
+
+// lintAttributes returns a problem for every entry in the source directory
+// whose target name starts with something that looks like an attribute, which
+// is usually caused by attributes in the wrong order or misspelled attributes.
+func lintAttributes(sourceState *chezmoi.SourceState) []string {
+	var problems []string
+	for _, targetRelPath := range sourceState.TargetRelPaths() {
+		sourceStateEntry := sourceState.MustEntry(targetRelPath)
+		if _, ok := sourceStateEntry.Origin().(chezmoi.SourceStateOriginAbsPath); !ok {
+			continue
+		}
+		var targetName string
+		switch sourceStateEntry := sourceStateEntry.(type) {
+		case *chezmoi.SourceStateDir:
+			targetName = sourceStateEntry.Attr.TargetName
+		case *chezmoi.SourceStateFile:
+			targetName = sourceStateEntry.Attr.TargetName
+		default:
+			continue
+		}
+		sourceRelPath := sourceStateEntry.SourceRelPath().RelPath()
+		if strings.Contains(sourceRelPath.Base(), "literal_") {
+			continue
+		}
+		if attribute, ok := chezmoi.LikelyAttribute(targetName); ok {
+			prefix, _, _ := strings.Cut(targetName, "_")
+			problem := fmt.Sprintf("%s: %s_ looks like the %s_ attribute but is part of the target name %s",
+				sourceRelPath, prefix, attribute, targetName)
+			problems = append(problems, problem)
+		}
+	}
+	return problems
+}
+
+// lintIgnorePatterns returns a problem for every pattern in a .chezmoiignore
+// file that does not match anything.
+func lintIgnorePatterns(sourceState *chezmoi.SourceState) ([]string, error) {
+	unusedIgnorePatterns, err := sourceState.UnusedIgnorePatterns()
+	if err != nil {
+		return nil, err
+	}
+	problems := make([]string, 0, len(unusedIgnorePatterns))
+	for _, ignorePattern := range unusedIgnorePatterns {
+		pattern := ignorePattern.Pattern
+		if ignorePattern.Exclude {
+			pattern = "!" + pattern
+		}
+		problem := fmt.Sprintf("%s:%d: %s: pattern does not match anything",
+			ignorePattern.SourceRelPath, ignorePattern.LineNumber, pattern)
+		problems = append(problems, problem)
+	}
+	return problems, nil
+}
+
+// lintNonDeterministicTemplates renders every template in the source state,
+// except those of skipTargetRelPaths, c.lint.renders times and returns a
+// problem for every template whose output is not the same every time.
+func (c *Config) lintNonDeterministicTemplates(
+	cmd *cobra.Command,
+	skipTargetRelPaths map[chezmoi.RelPath]bool,
+) ([]string, error) {
+	var sourceState *chezmoi.SourceState
+	contentsSHA256s := make(map[chezmoi.RelPath][]byte)
+	nonDeterministicTargetRelPaths := make(map[chezmoi.RelPath]bool)
+	for i := 0; i < c.lint.renders; i++ {
+		var err error
+		sourceState, err = c.newSourceState(cmd.Context(), cmd, chezmoi.WithShuffleTemplateData(c.lint.shuffle))
+		if err != nil {
+			return nil, err
+		}
+		for _, targetRelPath := range sourceState.TargetRelPaths() {
+			sourceStateFile, ok := sourceState.MustEntry(targetRelPath).(*chezmoi.SourceStateFile)
+			if !ok || !sourceStateFile.Attr.Template || skipTargetRelPaths[targetRelPath] {
+				continue
+			}
+			targetStateEntry, err := sourceStateFile.TargetStateEntry(c.destSystem, c.DestDirAbsPath.Join(targetRelPath))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", targetRelPath, err)
+			}
+			entryState, err := targetStateEntry.EntryState(c.Umask)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", targetRelPath, err)
+			}
+			contentsSHA256 := []byte(entryState.ContentsSHA256)
+			if previousContentsSHA256, ok := contentsSHA256s[targetRelPath]; !ok {
+				contentsSHA256s[targetRelPath] = contentsSHA256
+			} else if !bytes.Equal(contentsSHA256, previousContentsSHA256) {
+				nonDeterministicTargetRelPaths[targetRelPath] = true
+			}
+		}
+	}
+
+	var problems []string
+	for _, targetRelPath := range sourceState.TargetRelPaths() {
+		if !nonDeterministicTargetRelPaths[targetRelPath] {
+			continue
+		}
+		sourceStateFile := sourceState.MustEntry(targetRelPath).(*chezmoi.SourceStateFile) //nolint:forcetypeassert
+		problem := fmt.Sprintf("%s: template output differs between renders", sourceStateFile.SourceRelPath())
+		contents, err := sourceStateFile.Contents()
+		if err != nil {
+			return nil, err
+		}
+		if funcNames := nonDeterministicFuncNames(contents); len(funcNames) > 0 {
+			problem += " (uses " + strings.Join(funcNames, ", ") + ")"
+		}
+		problems = append(problems, problem)
+	}
+	return problems, nil
+}
+
+// nonDeterministicFuncNames returns the sorted names of the non-deterministic
+// template functions called in the actions of the template contents. Calls of
+// keyed template functions with an argument are not included.
+func nonDeterministicFuncNames(contents []byte) []string {
+	var funcNames []string
+	for _, action := range templateActionRx.FindAll(contents, -1) {
+		for _, match := range nonDeterministicFuncNameRx.FindAllSubmatchIndex(action, -1) {
+			funcName := string(action[match[2]:match[3]])
+			if slices.Contains(keyedTemplateFuncNames, funcName) && templateFuncArgRx.Match(action[match[3]:]) {
+				continue
+			}
+			funcNames = append(funcNames, funcName)
+		}
+	}
+	slices.Sort(funcNames)
+	return slices.Compact(funcNames)
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestNonDeterministicFuncNames(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		contents string
+		expected []string
+	}{
+		{
+			name:     "empty",
+			contents: "",
+		},
+		{
+			name:     "text",
+			contents: "now is not in an action",
+		},
+		{
+			name:     "now",
+			contents: "{{ now.Year }}",
+			expected: []string{"now"},
+		},
+		{
+			name:     "pipeline",
+			contents: "{{ keys .map | join \",\" }}{{randAlphaNum 8}}{{ now }}",
+			expected: []string{"keys", "now", "randAlphaNum"},
+		},
+		{
+			name:     "uuidv4",
+			contents: "{{ uuidv4 }}{{ uuidv4 | upper }}{{ (uuidv4) }}{{ uuidv4 -}}",
+			expected: []string{"uuidv4"},
+		},
+		{
+			name:     "uuidv4_keyed",
+			contents: "{{ uuidv4 \"key\" }}{{ uuidv4 .chezmoi.hostname }}{{ uuidv4 $key | upper }}",
+		},
+		{
+			name:     "fields_and_variables",
+			contents: "{{ .keys }}{{ $now := 1 }}{{ .chezmoi.values }}",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, nonDeterministicFuncNames([]byte(tc.contents)))
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package cmd
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+
+	"github.com/twpayne/go-vfs/v5"
+
+	"github.com/twpayne/chezmoi/v2/internal/chezmoi"
+	"github.com/twpayne/chezmoi/v2/internal/chezmoierrors"
+)
+
+const stableRandomDefaultCharset = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// machineSecretFileRelPath is the path of the file containing the machine's
+// secret, relative to the directory containing the persistent state. The
+// secret is stored in its own file, and not in the persistent state, so that it
+// is not printed by chezmoi state dump.
+var machineSecretFileRelPath = chezmoi.NewRelPath("machinesecret")
+
+func (c *Config) machineSecretTemplateFunc() string {
+	return hex.EncodeToString(c.getMachineSecret())
+}
+
+func (c *Config) stableRandomTemplateFunc(key string, length int, args ...string) string {
+	charset := stableRandomDefaultCharset
+	switch len(args) {
+	case 0:
+		// Do nothing.
+	case 1:
+		charset = args[0]
+	default:
+		panic(fmt.Errorf("want 2 or 3 arguments, got %d", len(args)+2))
+	}
+	switch {
+	case length < 0:
+		panic(fmt.Errorf("%d: invalid length", length))
+	case charset == "" || len(charset) > 256:
+		panic(fmt.Errorf("%q: invalid charset", charset))
+	}
+
+	// Generate a stream of pseudorandom bytes from the HMAC of the key and a
+	// counter, and map them to charset, rejecting bytes that would bias the
+	// result towards the start of charset.
+	limit := 256 - 256%len(charset)
+	var builder strings.Builder
+	builder.Grow(length)
+	for counter := uint64(0); builder.Len() < length; counter++ {
+		mac := hmac.New(sha256.New, c.getMachineSecret())
+		mac.Write([]byte(key))
+		mac.Write(binary.BigEndian.AppendUint64(nil, counter))
+		for _, b := range mac.Sum(nil) {
+			if int(b) >= limit {
+				continue
+			}
+			builder.WriteByte(charset[int(b)%len(charset)])
+			if builder.Len() == length {
+				break
+			}
+		}
+	}
+	return builder.String()
+}
+
+// getMachineSecret returns the machine's secret, creating it if needed. The
+// secret is random and is created the first time it is needed, and then stored
+// in a file that only the user can read so that it does not change. It is not
+// derived from a machine identifier like /etc/machine-id, as these are
+// readable by every user on the machine.
+//
+// The secret is only created by commands that write the persistent state and
+// that are not run with --dry-run. Other commands fail until it has been
+// created, rather than returning values that differ from those that would be
+// applied.
+func (c *Config) getMachineSecret() []byte {
+	if c.machineSecret != nil {
+		return c.machineSecret
+	}
+
+	persistentStateFileAbsPath, err := c.persistentStateFile()
+	if err != nil {
+		panic(err)
+	}
+	machineSecretFileAbsPath := persistentStateFileAbsPath.Dir().Join(machineSecretFileRelPath)
+	machineSecret, err := c.readMachineSecret(machineSecretFileAbsPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		if c.machineSecretWritable {
+			machineSecret, err = c.createMachineSecret(machineSecretFileAbsPath)
+		} else {
+			err = fmt.Errorf(
+				"%s: machine secret does not exist, run chezmoi apply or chezmoi execute-template '{{ machineSecret }}' to create it",
+				machineSecretFileAbsPath,
+			)
+		}
+	}
+	if err != nil {
+		panic(err)
+	}
+	c.machineSecret = machineSecret
+	return c.machineSecret
+}
+
+// createMachineSecret creates a new random machine secret in
+// machineSecretFileAbsPath. The secret is written to a temporary file which is
+// then hard linked into place, so machineSecretFileAbsPath is never partially
+// written and is never replaced. If another chezmoi process creates it first
+// then its secret is returned instead.
+func (c *Config) createMachineSecret(machineSecretFileAbsPath chezmoi.AbsPath) (_ []byte, err error) {
+	machineSecret, err := newMachineSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := vfs.MkdirAll(c.fileSystem, machineSecretFileAbsPath.Dir().String(), fs.ModePerm); err != nil {
+		return nil, err
+	}
+	tempSuffix := make([]byte, 8)
+	if _, err := rand.Read(tempSuffix); err != nil {
+		return nil, err
+	}
+	tempFileAbsPath := machineSecretFileAbsPath.Dir().JoinString(
+		machineSecretFileRelPath.String() + "." + hex.EncodeToString(tempSuffix) + ".tmp",
+	)
+	if err := c.fileSystem.WriteFile(tempFileAbsPath.String(), []byte(hex.EncodeToString(machineSecret)+"\n"), 0o600); err != nil {
+		return nil, err
+	}
+	defer chezmoierrors.CombineFunc(&err, func() error {
+		return c.fileSystem.Remove(tempFileAbsPath.String())
+	})
+	switch err := c.fileSystem.Link(tempFileAbsPath.String(), machineSecretFileAbsPath.String()); {
+	case errors.Is(err, fs.ErrExist):
+		return c.readMachineSecret(machineSecretFileAbsPath)
+	case err != nil:
+		return nil, err
+	}
+	return machineSecret, nil
+}
+
+// newMachineSecret returns a new random machine secret.
+func newMachineSecret() ([]byte, error) {
+	machineSecret := make([]byte, sha256.Size)
+	if _, err := rand.Read(machineSecret); err != nil {
+		return nil, err
+	}
+	return machineSecret, nil
+}
+
+// readMachineSecret reads the machine secret from machineSecretFileAbsPath.
+func (c *Config) readMachineSecret(machineSecretFileAbsPath chezmoi.AbsPath) ([]byte, error) {
+	data, err := c.fileSystem.ReadFile(machineSecretFileAbsPath.String())
+	if err != nil {
+		return nil, err
+	}
+	machineSecret, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(machineSecret) == 0 {
+		return nil, fmt.Errorf("%s: invalid machine secret", machineSecretFileAbsPath)
+	}
+	return machineSecret, nil
+}
+
+// readPersistentState gets the value associated with key in bucket in the
+// persistent state. Commands that do not use the persistent state still need
+// to read values stored by other commands, for example in template functions,
+// so the persistent state is opened read-only for them.
+func (c *Config) readPersistentState(bucket, key []byte, value any) (bool, error) {
+	if _, ok := c.persistentState.(chezmoi.NullPersistentState); !ok {
+		return chezmoi.PersistentStateGet(c.persistentState, bucket, key, value)
+	}
+	persistentStateFileAbsPath, err := c.persistentStateFile()
+	if err != nil {
+		return false, err
+	}
+	persistentState, err := chezmoi.NewBoltPersistentState(
+		c.baseSystem,
+		persistentStateFileAbsPath,
+		chezmoi.BoltPersistentStateReadOnly,
+	)
+	if err != nil {
+		return false, err
+	}
+	defer persistentState.Close()
+	return chezmoi.PersistentStateGet(persistentState, bucket, key, value)
+}
+
+// writePersistentState sets the value associated with key in bucket in the
+// persistent state. Template functions are also executed by commands that open
+// the persistent state read-only, so nothing is written for them.
+func (c *Config) writePersistentState(bucket, key []byte, value any) error {
+	if c.persistentStateReadOnly {
+		return nil
+	}
+	return chezmoi.PersistentStateSet(c.persistentState, bucket, key, value)
+}
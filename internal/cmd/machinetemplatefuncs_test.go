@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestStableRandomTemplateFunc(t *testing.T) {
+	c := &Config{
+		machineSecret: []byte("secret"),
+	}
+	for _, tc := range []struct {
+		name    string
+		key     string
+		length  int
+		charset string
+	}{
+		{
+			name:   "empty",
+			key:    "key",
+			length: 0,
+		},
+		{
+			name:   "default_charset",
+			key:    "key",
+			length: 16,
+		},
+		{
+			name:   "long",
+			key:    "key",
+			length: 100,
+		},
+		{
+			name:    "digits",
+			key:     "port",
+			length:  4,
+			charset: "0123456789",
+		},
+		{
+			name:    "single_char",
+			key:     "key",
+			length:  8,
+			charset: "x",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var args []string
+			charset := stableRandomDefaultCharset
+			if tc.charset != "" {
+				args = append(args, tc.charset)
+				charset = tc.charset
+			}
+			actual := c.stableRandomTemplateFunc(tc.key, tc.length, args...)
+			assert.Equal(t, tc.length, len(actual))
+			for _, r := range actual {
+				assert.True(t, strings.ContainsRune(charset, r))
+			}
+			assert.Equal(t, actual, c.stableRandomTemplateFunc(tc.key, tc.length, args...))
+		})
+	}
+
+	assert.NotEqual(t, c.stableRandomTemplateFunc("key1", 16), c.stableRandomTemplateFunc("key2", 16))
+	otherMachine := &Config{
+		machineSecret: []byte("other secret"),
+	}
+	assert.NotEqual(t, c.stableRandomTemplateFunc("key", 16), otherMachine.stableRandomTemplateFunc("key", 16))
+}
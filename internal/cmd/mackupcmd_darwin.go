@@ -94,7 +94,7 @@ func (c *Config) runMackupAddCmd(cmd *cobra.Command, args []string, sourceState
 		}
 	}
 
-	destAbsPathInfos, err := c.destAbsPathInfos(sourceState, addArgs, destAbsPathInfosOptions{
+	destAbsPathInfos, err := c.destAbsPathInfos(cmd.Context(), sourceState, addArgs, destAbsPathInfosOptions{
 		follow:         c.Add.follow,
 		ignoreNotExist: true,
 		onIgnoreFunc:   c.defaultOnIgnoreFunc,
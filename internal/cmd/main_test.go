@@ -55,6 +55,7 @@ func TestScript(t *testing.T) {
 		Cmds: map[string]func(*testscript.TestScript, bool, []string){
 			"appendline":     cmdAppendLine,
 			"chhome":         cmdChHome,
+			"chtimes":        cmdChTimes,
 			"cmpmod":         cmdCmpMod,
 			"edit":           cmdEdit,
 			"expandenv":      cmdExpandEnv,
@@ -136,6 +137,27 @@ func cmdChHome(ts *testscript.TestScript, neg bool, args []string) {
 	}
 }
 
+// cmdChTimes sets the access and modification times of its arguments to the
+// given Unix time.
+func cmdChTimes(ts *testscript.TestScript, neg bool, args []string) {
+	if neg {
+		ts.Fatalf("unsupported: ! chtimes")
+	}
+	if len(args) < 2 {
+		ts.Fatalf("usage: chtimes unixtime path...")
+	}
+	unixTime, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		ts.Fatalf("invalid unix time: %s", args[0])
+	}
+	t := time.Unix(unixTime, 0)
+	for _, arg := range args[1:] {
+		if err := os.Chtimes(ts.MkAbs(arg), t, t); err != nil {
+			ts.Fatalf("%s: %v", arg, err)
+		}
+	}
+}
+
 // cmdCmpMod compares modes.
 func cmdCmpMod(ts *testscript.TestScript, neg bool, args []string) {
 	if len(args) != 2 {
@@ -353,7 +375,8 @@ func cmdMkGitConfig(ts *testscript.TestScript, neg bool, args []string) {
 	)), 0o666))
 }
 
-// cmdMkGPGConfig creates a GPG key and a chezmoi configuration file.
+// cmdMkGPGConfig creates a GPG key and a chezmoi configuration file and sets
+// $GPGFINGERPRINT to the key's fingerprint.
 func cmdMkGPGConfig(ts *testscript.TestScript, neg bool, args []string) {
 	if neg {
 		ts.Fatalf("unsupported: ! mkgpgconfig")
@@ -383,6 +406,9 @@ func cmdMkGPGConfig(ts *testscript.TestScript, neg bool, args []string) {
 
 	key, passphrase, err := chezmoitest.GPGGenerateKey(command, gpgHomeDir)
 	ts.Check(err)
+	fingerprint, err := chezmoitest.GPGKeyFingerprint(command, gpgHomeDir, key)
+	ts.Check(err)
+	ts.Setenv("GPGFINGERPRINT", fingerprint)
 
 	configFile := filepath.Join(ts.Getenv("HOME"), ".config", "chezmoi", "chezmoi.toml")
 	ts.Check(os.MkdirAll(filepath.Dir(configFile), fs.ModePerm))
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/spf13/cobra"
@@ -9,32 +10,52 @@ import (
 )
 
 type managedCmdConfig struct {
-	filter    *chezmoi.EntryTypeFilter
-	pathStyle chezmoi.PathStyle
-	tree      bool
+	filter         *chezmoi.EntryTypeFilter
+	format         writeDataFormat
+	formatTemplate string
+	pathStyle      chezmoi.PathStyle
+	tree           bool
+}
+
+// A managedEntry is a managed target, as passed to --format-template. Path is
+// the path in the requested path style, SourcePath and TargetPath are
+// absolute, and Mode is the target's permissions in octal, if it has any.
+type managedEntry struct {
+	Path       string
+	SourcePath string
+	TargetPath string
+	Type       string
+	Mode       string
 }
 
 func (c *Config) newManagedCmd() *cobra.Command {
 	managedCmd := &cobra.Command{
-		Use:         "managed [path]...",
-		Aliases:     []string{"list"},
-		Short:       "List the managed entries in the destination directory",
-		Long:        mustLongHelp("managed"),
-		Example:     example("managed"),
-		Args:        cobra.ArbitraryArgs,
-		RunE:        c.makeRunEWithSourceState(c.runManagedCmd),
-		Annotations: newAnnotations(),
+		Use:               "managed [path]...",
+		Aliases:           []string{"list"},
+		Short:             mustShortHelp("managed"),
+		Long:              mustLongHelp("managed"),
+		Example:           example("managed"),
+		Args:              cobra.ArbitraryArgs,
+		ValidArgsFunction: c.targetValidArgs,
+		RunE:              c.makeRunEWithSourceState(c.runManagedCmd),
+		Annotations:       newAnnotations(),
 	}
 
 	managedCmd.Flags().VarP(c.managed.filter.Exclude, "exclude", "x", "Exclude entry types")
 	managedCmd.Flags().VarP(c.managed.filter.Include, "include", "i", "Include entry types")
-	managedCmd.Flags().VarP(&c.managed.pathStyle, "path-style", "p", "Path style")
-	managedCmd.Flags().BoolVarP(&c.managed.tree, "tree", "t", c.managed.tree, "Print paths as a tree")
+	managedCmd.Flags().VarP(&c.managed.format, "format", "f", "")
+	managedCmd.Flags().StringVar(&c.managed.formatTemplate, "format-template", c.managed.formatTemplate, "")
+	managedCmd.Flags().VarP(&c.managed.pathStyle, "path-style", "p", "")
+	managedCmd.Flags().BoolVarP(&c.managed.tree, "tree", "t", c.managed.tree, "")
 
 	return managedCmd
 }
 
 func (c *Config) runManagedCmd(cmd *cobra.Command, args []string, sourceState *chezmoi.SourceState) error {
+	if c.managed.formatTemplate != "" && (c.managed.format != "" || c.managed.tree) {
+		return errors.New("the --format-template flag cannot be used with --format or --tree")
+	}
+
 	// Build queued relPaths. When there are no arguments, start from root,
 	// otherwise start from arguments.
 	var relPaths chezmoi.RelPaths
@@ -48,8 +69,13 @@ func (c *Config) runManagedCmd(cmd *cobra.Command, args []string, sourceState *c
 		}
 	}
 
+	managedPath := func(targetRelPath chezmoi.RelPath, sourceStateEntry chezmoi.SourceStateEntry) fmt.Stringer {
+		return c.managedPath(sourceState, targetRelPath, sourceStateEntry)
+	}
+
 	var paths []fmt.Stringer
-	_ = sourceState.ForEach(
+	var entries []managedEntry
+	err := sourceState.ForEach(
 		func(targetRelPath chezmoi.RelPath, sourceStateEntry chezmoi.SourceStateEntry) error {
 			if !c.managed.filter.IncludeSourceStateEntry(sourceStateEntry) {
 				return nil
@@ -77,23 +103,77 @@ func (c *Config) runManagedCmd(cmd *cobra.Command, args []string, sourceState *c
 				}
 			}
 
-			var path fmt.Stringer
-			switch c.managed.pathStyle {
-			case chezmoi.PathStyleAbsolute:
-				path = c.DestDirAbsPath.Join(targetRelPath)
-			case chezmoi.PathStyleRelative:
-				path = targetRelPath
-			case chezmoi.PathStyleSourceAbsolute:
-				path = c.SourceDirAbsPath.Join(sourceStateEntry.SourceRelPath().RelPath())
-			case chezmoi.PathStyleSourceRelative:
-				path = sourceStateEntry.SourceRelPath().RelPath()
+			path := managedPath(targetRelPath, sourceStateEntry)
+			if c.managed.formatTemplate != "" {
+				entryState, err := targetStateEntry.EntryState(c.Umask)
+				if err != nil {
+					return err
+				}
+				var mode string
+				if entryState.Type != chezmoi.EntryStateTypeSymlink && entryState.Type != chezmoi.EntryStateTypeScript {
+					mode = fmt.Sprintf("%04o", entryState.Mode.Perm())
+				}
+				entries = append(entries, managedEntry{
+					Path:       path.String(),
+					SourcePath: sourceState.SourceAbsPath(sourceStateEntry).String(),
+					TargetPath: c.DestDirAbsPath.Join(targetRelPath).String(),
+					Type:       string(entryState.Type),
+					Mode:       mode,
+				})
 			}
 			paths = append(paths, path)
 			return nil
 		},
 	)
 
+	if c.managed.formatTemplate != "" {
+		if err != nil {
+			return err
+		}
+		return writeFormatTemplate(c, c.managed.formatTemplate, entries)
+	}
+
 	return c.writePaths(stringersToStrings(paths), writePathsOptions{
-		tree: c.managed.tree,
+		format: c.managed.format,
+		tree:   c.managed.tree,
+		treeOptions: pathListTreeOptions{
+			annotations: dirMetadataAnnotations(sourceState, managedPath),
+			counts:      true,
+		},
 	})
 }
+
+// managedPath returns the path of targetRelPath in the managed path style.
+func (c *Config) managedPath(
+	sourceState *chezmoi.SourceState,
+	targetRelPath chezmoi.RelPath,
+	sourceStateEntry chezmoi.SourceStateEntry,
+) fmt.Stringer {
+	switch c.managed.pathStyle {
+	case chezmoi.PathStyleAbsolute:
+		return c.DestDirAbsPath.Join(targetRelPath)
+	case chezmoi.PathStyleSourceAbsolute:
+		return sourceState.SourceAbsPath(sourceStateEntry)
+	case chezmoi.PathStyleSourceRelative:
+		return sourceStateEntry.SourceRelPath().RelPath()
+	default:
+		return targetRelPath
+	}
+}
+
+// dirMetadataAnnotations returns the descriptions of the target directories in
+// sourceState, keyed by the paths returned by pathFunc.
+func dirMetadataAnnotations(
+	sourceState *chezmoi.SourceState,
+	pathFunc func(chezmoi.RelPath, chezmoi.SourceStateEntry) fmt.Stringer,
+) map[string]string {
+	annotations := make(map[string]string)
+	for targetRelPath, dirMetadata := range sourceState.DirMetadata() {
+		sourceStateEntry := sourceState.Get(targetRelPath)
+		if dirMetadata.Description == "" || sourceStateEntry == nil {
+			continue
+		}
+		annotations[pathFunc(targetRelPath, sourceStateEntry).String()] = dirMetadata.Description
+	}
+	return annotations
+}
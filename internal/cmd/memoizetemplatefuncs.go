@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/twpayne/chezmoi/v2/internal/chezmoi"
+	"github.com/twpayne/chezmoi/v2/internal/chezmoiset"
+)
+
+// networkTemplateFuncNames are the names of the template functions that make
+// network requests.
+var networkTemplateFuncNames = chezmoiset.New(
+	"gitHubKeys",
+	"gitHubLatestRelease",
+	"gitHubLatestReleaseAssetURL",
+	"gitHubLatestTag",
+	"gitHubReleases",
+	"gitHubTags",
+	"sshKeyscan",
+)
+
+var templateFuncCacheStateBucket = []byte("templateFuncCacheState")
+
+type templateCacheConfig struct {
+	Functions []string      `json:"functions" mapstructure:"functions" yaml:"functions"`
+	TTL       time.Duration `json:"ttl"       mapstructure:"ttl"       yaml:"ttl"`
+}
+
+// A templateFuncCacheState is the results of a call to a template function,
+// cached in the persistent state.
+type templateFuncCacheState struct {
+	RequestedAt time.Time         `json:"requestedAt" yaml:"requestedAt"`
+	Results     []json.RawMessage `json:"results"     yaml:"results"`
+}
+
+// templateFuncResults records the results of calls to template functions for
+// the rest of the run, keyed by the function's name and arguments.
+type templateFuncResults struct {
+	sync.Mutex
+	results map[string][]reflect.Value
+}
+
+// get returns the recorded results of call.
+func (r *templateFuncResults) get(call string) ([]reflect.Value, bool) {
+	r.Lock()
+	defer r.Unlock()
+	results, ok := r.results[call]
+	return results, ok
+}
+
+// reset forgets all recorded results.
+func (r *templateFuncResults) reset() {
+	r.Lock()
+	defer r.Unlock()
+	r.results = nil
+}
+
+// set records results as the results of call.
+func (r *templateFuncResults) set(call string, results []reflect.Value) {
+	r.Lock()
+	defer r.Unlock()
+	if r.results == nil {
+		r.results = make(map[string][]reflect.Value)
+	}
+	r.results[call] = results
+}
+
+// memoizeTemplateFuncs replaces the secret and network template functions, and
+// the template functions listed in template.cache.functions, with functions
+// that call them at most once for each set of arguments in each run. The
+// results of the functions listed in template.cache.functions are also cached
+// in the persistent state for template.cache.ttl. Secret template functions
+// cannot be cached in the persistent state, as it is not encrypted.
+func (c *Config) memoizeTemplateFuncs() error {
+	persistentNames := chezmoiset.New(c.Template.Cache.Functions...)
+	for name := range persistentNames {
+		switch _, ok := c.templateFuncs[name]; {
+		case !ok:
+			return fmt.Errorf("template.cache.functions: %s: unknown template function", name)
+		case c.secretTemplateFuncNames.Contains(name):
+			return fmt.Errorf("template.cache.functions: %s: secret template functions cannot be cached", name)
+		}
+	}
+	for name, templateFunc := range c.templateFuncs {
+		if persistentNames.Contains(name) || c.secretTemplateFuncNames.Contains(name) ||
+			networkTemplateFuncNames.Contains(name) {
+			persistent := c.Template.Cache.TTL > 0 && persistentNames.Contains(name)
+			c.templateFuncs[name] = c.memoizeTemplateFunc(name, templateFunc, persistent)
+		}
+	}
+	return nil
+}
+
+// memoizeTemplateFunc returns a function with the same signature as
+// templateFunc that calls templateFunc at most once for each set of arguments,
+// and, if persistent is true, caches its results in the persistent state.
+// Results are only recorded if templateFunc succeeds.
+func (c *Config) memoizeTemplateFunc(name string, templateFunc any, persistent bool) any {
+	funcValue := reflect.ValueOf(templateFunc)
+	funcType := funcValue.Type()
+	return reflect.MakeFunc(funcType, func(args []reflect.Value) []reflect.Value {
+		call := formatTemplateFuncCall(name, args)
+		if results, ok := c.templateFuncResults.get(call); ok {
+			return results
+		}
+		if persistent {
+			if results, ok := c.getPersistentTemplateFuncResults(call, funcType); ok {
+				c.templateFuncResults.set(call, results)
+				return results
+			}
+		}
+
+		var results []reflect.Value
+		if funcType.IsVariadic() {
+			results = funcValue.CallSlice(args)
+		} else {
+			results = funcValue.Call(args)
+		}
+		for _, result := range results {
+			if err, ok := result.Interface().(error); ok && err != nil {
+				return results
+			}
+		}
+
+		c.templateFuncResults.set(call, results)
+		if persistent {
+			c.setPersistentTemplateFuncResults(call, results)
+		}
+		return results
+	}).Interface()
+}
+
+// getPersistentTemplateFuncResults returns the results of call cached in the
+// persistent state, if they are present and have not expired.
+func (c *Config) getPersistentTemplateFuncResults(call string, funcType reflect.Type) ([]reflect.Value, bool) {
+	var state templateFuncCacheState
+	switch ok, err := chezmoi.PersistentStateGet(c.persistentState, templateFuncCacheStateBucket, []byte(call), &state); {
+	case err != nil:
+		panic(err)
+	case !ok:
+		return nil, false
+	case !time.Now().Before(state.RequestedAt.Add(c.Template.Cache.TTL)):
+		return nil, false
+	case len(state.Results) != funcType.NumOut():
+		return nil, false
+	}
+	results := make([]reflect.Value, 0, funcType.NumOut())
+	for i, data := range state.Results {
+		value := reflect.New(funcType.Out(i))
+		if err := json.Unmarshal(data, value.Interface()); err != nil {
+			return nil, false
+		}
+		results = append(results, value.Elem())
+	}
+	return results, true
+}
+
+// setPersistentTemplateFuncResults caches results as the results of call in
+// the persistent state. Results that cannot be represented as JSON are not
+// cached.
+func (c *Config) setPersistentTemplateFuncResults(call string, results []reflect.Value) {
+	state := templateFuncCacheState{
+		RequestedAt: time.Now(),
+		Results:     make([]json.RawMessage, 0, len(results)),
+	}
+	for _, result := range results {
+		data, err := json.Marshal(result.Interface())
+		if err != nil {
+			return
+		}
+		state.Results = append(state.Results, data)
+	}
+	if err := chezmoi.PersistentStateSet(c.persistentState, templateFuncCacheStateBucket, []byte(call), &state); err != nil {
+		panic(err)
+	}
+}
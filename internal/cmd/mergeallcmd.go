@@ -16,7 +16,7 @@ type mergeAllCmdConfig struct {
 func (c *Config) newMergeAllCmd() *cobra.Command {
 	mergeAllCmd := &cobra.Command{
 		Use:     "merge-all",
-		Short:   "Perform a three-way merge for each modified file",
+		Short:   mustShortHelp("merge-all"),
 		Long:    mustLongHelp("merge-all"),
 		Example: example("merge-all"),
 		RunE:    c.runMergeAllCmd,
@@ -59,7 +59,7 @@ func (c *Config) runMergeAllCmd(cmd *cobra.Command, args []string) error {
 
 	for _, targetRelPath := range targetRelPaths {
 		sourceStateEntry := sourceState.MustEntry(targetRelPath)
-		if err := c.doMerge(targetRelPath, sourceStateEntry); err != nil {
+		if err := c.doMerge(sourceState, targetRelPath, sourceStateEntry); err != nil {
 			return err
 		}
 	}
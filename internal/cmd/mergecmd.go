@@ -22,7 +22,7 @@ func (c *Config) newMergeCmd() *cobra.Command {
 	mergeCmd := &cobra.Command{
 		Use:               "merge target...",
 		Args:              cobra.MinimumNArgs(1),
-		Short:             "Perform a three-way merge between the destination state, the source state, and the target state",
+		Short:             mustShortHelp("merge"),
 		Long:              mustLongHelp("merge"),
 		Example:           example("merge"),
 		ValidArgsFunction: c.targetValidArgs,
@@ -47,7 +47,7 @@ func (c *Config) runMergeCmd(cmd *cobra.Command, args []string, sourceState *che
 
 	for _, targetRelPath := range targetRelPaths {
 		sourceStateEntry := sourceState.MustEntry(targetRelPath)
-		if err := c.doMerge(targetRelPath, sourceStateEntry); err != nil {
+		if err := c.doMerge(sourceState, targetRelPath, sourceStateEntry); err != nil {
 			return err
 		}
 	}
@@ -58,8 +58,12 @@ func (c *Config) runMergeCmd(cmd *cobra.Command, args []string, sourceState *che
 // doMerge is the core merge functionality. It invokes the merge tool to do a
 // three-way merge between the destination, source, and target, including
 // transparently decrypting the file in the source state.
-func (c *Config) doMerge(targetRelPath chezmoi.RelPath, sourceStateEntry chezmoi.SourceStateEntry) (err error) {
-	sourceAbsPath := c.SourceDirAbsPath.Join(sourceStateEntry.SourceRelPath().RelPath())
+func (c *Config) doMerge(
+	sourceState *chezmoi.SourceState,
+	targetRelPath chezmoi.RelPath,
+	sourceStateEntry chezmoi.SourceStateEntry,
+) (err error) {
+	sourceAbsPath := sourceState.SourceAbsPath(sourceStateEntry)
 
 	// If the source state entry is an encrypted file, then decrypt it to a
 	// temporary directory and pass the plaintext to the merge command
@@ -169,6 +173,11 @@ func (c *Config) doMerge(targetRelPath chezmoi.RelPath, sourceStateEntry chezmoi
 		args = append(args, templateData.Destination, templateData.Source, templateData.Target)
 	}
 
+	if c.dryRun {
+		c.verbosef(chezmoi.VerbosityChanges, "merge", "not running %s on dry run\n", shellQuoteCommand(c.Merge.Command, args))
+		return
+	}
+
 	if err = c.persistentState.Close(); err != nil {
 		return
 	}
@@ -185,7 +194,7 @@ func (c *Config) doMerge(targetRelPath chezmoi.RelPath, sourceStateEntry chezmoi
 		if encryptedContents, err = c.encryption.EncryptFile(plaintextAbsPath); err != nil {
 			return
 		}
-		if err = c.baseSystem.WriteFile(c.SourceDirAbsPath.Join(sourceStateEntry.SourceRelPath().RelPath()), encryptedContents, 0o644); err != nil {
+		if err = c.baseSystem.WriteFile(sourceState.SourceAbsPath(sourceStateEntry), encryptedContents, 0o644); err != nil {
 			return
 		}
 	}
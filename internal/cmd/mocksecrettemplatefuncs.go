@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"encoding/json"
+	"reflect"
+	"text/template"
+
+	"github.com/twpayne/chezmoi/v2/internal/chezmoiset"
+)
+
+// mockSecretPlaceholderKey is the key of a mockSecretValue's placeholder. It
+// cannot be a field name in a template.
+const mockSecretPlaceholderKey = "\x00placeholder"
+
+// A mockSecretValue is a placeholder for a structured value returned by a
+// secret template function. It has an entry for every name referenced by the
+// template that it is returned to, each of which is the mockSecretValue itself,
+// so any chain of fields on it is valid. It prints as its placeholder.
+type mockSecretValue map[string]any
+
+// String implements fmt.Stringer.String.
+func (v mockSecretValue) String() string {
+	placeholder, _ := v[mockSecretPlaceholderKey].(string)
+	return placeholder
+}
+
+// MarshalJSON implements encoding/json.Marshaler.MarshalJSON.
+func (v mockSecretValue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.String())
+}
+
+// mockSecretTemplateFuncs replaces the secret template functions with
+// functions that return placeholder values, so that the target state can be
+// computed without access to any password manager. Templates in the source
+// state are executed with mockSecretTemplateFuncsFor instead.
+func (c *Config) mockSecretTemplateFuncs() {
+	c.mockedSecrets = chezmoiset.New[string]()
+	for name := range c.secretTemplateFuncNames {
+		if templateFunc, ok := c.templateFuncs[name]; ok {
+			c.templateFuncs[name] = mockSecretTemplateFunc(name, templateFunc, nil, nil)
+		}
+	}
+}
+
+// mockSecretTemplateFuncsFor returns the mocked secret template functions for
+// an execution of the template for target, which returns placeholders for every
+// name in referencedNames. The first call of each function for target is
+// reported.
+func (c *Config) mockSecretTemplateFuncsFor(target string, referencedNames chezmoiset.Set[string]) template.FuncMap {
+	templateFuncs := make(template.FuncMap, len(c.secretTemplateFuncNames))
+	for name := range c.secretTemplateFuncNames {
+		templateFunc, ok := c.templateFuncs[name]
+		if !ok {
+			continue
+		}
+		templateFuncs[name] = mockSecretTemplateFunc(name, templateFunc, referencedNames, func() {
+			if key := target + "\x00" + name; !c.mockedSecrets.Contains(key) {
+				c.mockedSecrets.Add(key)
+				c.warnf("%s: %s returned a mock secret\n", target, name)
+			}
+		})
+	}
+	return templateFuncs
+}
+
+// mockSecretTemplateFunc returns a function with the same signature as
+// templateFunc that calls calledFunc, if it is not nil, and returns a
+// placeholder value containing name. Strings are replaced with the placeholder,
+// maps with maps containing the placeholder for every name in referencedNames,
+// and interfaces with mockSecretValues.
+func mockSecretTemplateFunc(name string, templateFunc any, referencedNames chezmoiset.Set[string], calledFunc func()) any {
+	placeholder := "<mock " + name + ">"
+	funcType := reflect.TypeOf(templateFunc)
+	return reflect.MakeFunc(funcType, func([]reflect.Value) []reflect.Value {
+		if calledFunc != nil {
+			calledFunc()
+		}
+		value := mockSecretValue{
+			mockSecretPlaceholderKey: placeholder,
+		}
+		for referencedName := range referencedNames {
+			value[referencedName] = value
+		}
+		results := make([]reflect.Value, 0, funcType.NumOut())
+		for i := 0; i < funcType.NumOut(); i++ {
+			outType := funcType.Out(i)
+			var result reflect.Value
+			switch outType.Kind() {
+			case reflect.String:
+				result = reflect.ValueOf(placeholder).Convert(outType)
+			case reflect.Map:
+				result = reflect.MakeMap(outType)
+				if outType.Key().Kind() == reflect.String && outType.Elem().Kind() == reflect.String {
+					for referencedName := range referencedNames {
+						result.SetMapIndex(reflect.ValueOf(referencedName).Convert(outType.Key()), reflect.ValueOf(placeholder).Convert(outType.Elem()))
+					}
+				}
+			case reflect.Interface:
+				result = reflect.New(outType).Elem()
+				if mockValue := reflect.ValueOf(value); mockValue.Type().Implements(outType) {
+					result.Set(mockValue)
+				}
+			default:
+				result = reflect.Zero(outType)
+			}
+			results = append(results, result)
+		}
+		return results
+	}).Interface()
+}
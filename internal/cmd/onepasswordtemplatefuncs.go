@@ -10,8 +10,6 @@ import (
 	"strings"
 
 	"github.com/coreos/go-semver/semver"
-
-	"github.com/twpayne/chezmoi/v2/internal/chezmoilog"
 )
 
 type onepasswordMode string
@@ -184,7 +182,7 @@ func (c *Config) onepasswordGetOrRefreshSessionToken(args *onepasswordArgs) (str
 	cmd := exec.Command(c.Onepassword.Command, commandArgs...) //nolint:gosec
 	cmd.Stdin = os.Stdin
 	cmd.Stderr = os.Stderr
-	output, err := chezmoilog.LogCmdOutput(c.logger, cmd)
+	output, err := c.secretCmdOutput(cmd)
 	if err != nil {
 		return "", newCmdOutputError(cmd, output, err)
 	}
@@ -238,7 +236,7 @@ func (c *Config) onepasswordOutput(args *onepasswordArgs, withSessionToken withS
 	cmd := exec.Command(c.Onepassword.Command, commandArgs...) //nolint:gosec
 	cmd.Stdin = os.Stdin
 	cmd.Stderr = os.Stderr
-	output, err := chezmoilog.LogCmdOutput(c.logger, cmd)
+	output, err := c.secretCmdOutput(cmd)
 	if err != nil {
 		return nil, newCmdOutputError(cmd, output, err)
 	}
@@ -0,0 +1,273 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/twpayne/chezmoi/v2/internal/chezmoi"
+	"github.com/twpayne/chezmoi/v2/internal/chezmoilog"
+	"github.com/twpayne/chezmoi/v2/internal/chezmoimaps"
+)
+
+// packagesDataKey is the key in the template data that lists the packages to
+// install with each package manager. It is prefixed with chezmoi so that it
+// does not conflict with users' own package lists.
+const packagesDataKey = "chezmoiPackages"
+
+type packagesCmdConfig struct {
+	Managers map[string]packageManagerConfig `json:"managers" mapstructure:"managers" yaml:"managers"`
+}
+
+// A packageManagerConfig describes how to check whether a package is installed
+// and how to install packages with a package manager.
+type packageManagerConfig struct {
+	CheckCommand   string   `json:"checkCommand"   mapstructure:"checkCommand"   yaml:"checkCommand"`
+	CheckArgs      []string `json:"checkArgs"      mapstructure:"checkArgs"      yaml:"checkArgs"`
+	CheckOutput    string   `json:"checkOutput"    mapstructure:"checkOutput"    yaml:"checkOutput"`
+	InstallCommand string   `json:"installCommand" mapstructure:"installCommand" yaml:"installCommand"`
+	InstallArgs    []string `json:"installArgs"    mapstructure:"installArgs"    yaml:"installArgs"`
+}
+
+// defaultPackageManagers are the builtin package managers, which can be
+// overridden in the config file.
+var defaultPackageManagers = map[string]packageManagerConfig{
+	// dpkg --status also succeeds for packages that have been removed but
+	// whose config files remain, so check the package's status too.
+	"apt": {
+		CheckCommand:   "dpkg",
+		CheckArgs:      []string{"--status"},
+		CheckOutput:    "Status: install ok installed",
+		InstallCommand: "sudo",
+		InstallArgs:    []string{"apt-get", "install", "--yes"},
+	},
+	"brew": {
+		CheckCommand:   "brew",
+		CheckArgs:      []string{"list"},
+		InstallCommand: "brew",
+		InstallArgs:    []string{"install"},
+	},
+	"pacman": {
+		CheckCommand:   "pacman",
+		CheckArgs:      []string{"--query"},
+		InstallCommand: "sudo",
+		InstallArgs:    []string{"pacman", "--sync", "--needed", "--noconfirm"},
+	},
+	"winget": {
+		CheckCommand:   "winget",
+		CheckArgs:      []string{"list", "--exact", "--id"},
+		InstallCommand: "winget",
+		InstallArgs:    []string{"install", "--exact", "--silent", "--id"},
+	},
+}
+
+// A missingPackages is a list of packages that are not installed by a package
+// manager.
+type missingPackages struct {
+	manager  string
+	packages []string
+}
+
+func (c *Config) newPackagesCmd() *cobra.Command {
+	packagesCmd := &cobra.Command{
+		Use:     "packages",
+		Args:    cobra.NoArgs,
+		Short:   mustShortHelp("packages"),
+		Long:    mustLongHelp("packages"),
+		Example: example("packages"),
+	}
+
+	packagesDiffCmd := &cobra.Command{
+		Use:         "diff [manager]...",
+		Args:        cobra.ArbitraryArgs,
+		Short:       "Print the packages that are not installed",
+		RunE:        c.runPackagesDiffCmd,
+		Annotations: newAnnotations(),
+	}
+	packagesCmd.AddCommand(packagesDiffCmd)
+
+	packagesInstallCmd := &cobra.Command{
+		Use:   "install [manager]...",
+		Args:  cobra.ArbitraryArgs,
+		Short: "Install the packages that are not installed",
+		RunE:  c.runPackagesInstallCmd,
+		Annotations: newAnnotations(
+			dryRun,
+			runsCommands,
+		),
+	}
+	packagesCmd.AddCommand(packagesInstallCmd)
+
+	return packagesCmd
+}
+
+func (c *Config) runPackagesDiffCmd(cmd *cobra.Command, args []string) error {
+	allMissingPackages, err := c.findMissingPackages(cmd, args)
+	if err != nil {
+		return err
+	}
+	for _, missingPackages := range allMissingPackages {
+		for _, pkg := range missingPackages.packages {
+			if _, err := fmt.Fprintf(c.stdout, "%s: %s\n", missingPackages.manager, pkg); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Config) runPackagesInstallCmd(cmd *cobra.Command, args []string) error {
+	allMissingPackages, err := c.findMissingPackages(cmd, args)
+	if err != nil {
+		return err
+	}
+	packageManagers := c.packageManagers()
+	for _, missingPackages := range allMissingPackages {
+		packageManager := packageManagers[missingPackages.manager]
+		installArgs := append(slices.Clone(packageManager.InstallArgs), missingPackages.packages...)
+		if c.dryRun {
+			c.verbosef(
+				chezmoi.VerbosityChanges, "packages", "not running %s on dry run\n",
+				shellQuoteCommand(packageManager.InstallCommand, installArgs),
+			)
+			continue
+		}
+		if err := c.run(chezmoi.EmptyAbsPath, packageManager.InstallCommand, installArgs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// packageManagers returns all package managers, including the builtin package
+// managers.
+func (c *Config) packageManagers() map[string]packageManagerConfig {
+	packageManagers := make(map[string]packageManagerConfig, len(defaultPackageManagers)+len(c.Packages.Managers))
+	for name, packageManager := range defaultPackageManagers {
+		packageManagers[name] = packageManager
+	}
+	for name, packageManager := range c.Packages.Managers {
+		packageManagers[name] = packageManager
+	}
+	return packageManagers
+}
+
+// findMissingPackages returns the packages listed in the template data that
+// are not installed by the package managers named by managerNames, or by all
+// available package managers if managerNames is empty. Package managers whose
+// check command is not found are skipped, so the same list of packages can be
+// used on machines with different package managers.
+func (c *Config) findMissingPackages(cmd *cobra.Command, managerNames []string) ([]missingPackages, error) {
+	sourceState, err := c.newSourceState(cmd.Context(), cmd,
+		chezmoi.WithTemplateDataOnly(true),
+	)
+	if err != nil {
+		return nil, err
+	}
+	packagesByManager, err := parsePackagesData(sourceState.TemplateData()[packagesDataKey])
+	if err != nil {
+		return nil, err
+	}
+
+	packageManagers := c.packageManagers()
+	for _, name := range managerNames {
+		if _, ok := packageManagers[name]; !ok {
+			return nil, fmt.Errorf("%s: unknown package manager", name)
+		}
+	}
+	if len(managerNames) == 0 {
+		managerNames = chezmoimaps.SortedKeys(packagesByManager)
+	}
+
+	var allMissingPackages []missingPackages
+	for _, name := range managerNames {
+		packages := packagesByManager[name]
+		if len(packages) == 0 {
+			continue
+		}
+		packageManager, ok := packageManagers[name]
+		if !ok {
+			return nil, fmt.Errorf("%s.%s: unknown package manager", packagesDataKey, name)
+		}
+		if _, err := chezmoi.LookPath(packageManager.CheckCommand); err != nil {
+			c.verbosef(chezmoi.VerbosityActions, "packages", "skipping %s as %s was not found\n", name, packageManager.CheckCommand)
+			continue
+		}
+		var missing []string
+		for _, pkg := range packages {
+			switch installed, err := c.packageInstalled(packageManager, pkg); {
+			case err != nil:
+				return nil, err
+			case !installed:
+				missing = append(missing, pkg)
+			}
+		}
+		if len(missing) > 0 {
+			allMissingPackages = append(allMissingPackages, missingPackages{
+				manager:  name,
+				packages: missing,
+			})
+		}
+	}
+	return allMissingPackages, nil
+}
+
+// packageInstalled returns whether pkg is installed by packageManager, by
+// running its check command, which exits with a non-zero status if pkg is not
+// installed. If packageManager has a check output then the check command's
+// output must also contain a line equal to it.
+func (c *Config) packageInstalled(packageManager packageManagerConfig, pkg string) (bool, error) {
+	args := append(slices.Clone(packageManager.CheckArgs), pkg)
+	cmd := exec.Command(packageManager.CheckCommand, args...)
+	c.verboseCmdf("packages", packageManager.CheckCommand, args)
+	output, err := chezmoilog.LogCmdOutput(c.logger, cmd)
+	var exitError *exec.ExitError
+	switch {
+	case errors.As(err, &exitError):
+		return false, nil
+	case err != nil:
+		return false, err
+	case packageManager.CheckOutput == "":
+		return true, nil
+	default:
+		for _, line := range strings.Split(string(output), "\n") {
+			if strings.TrimSpace(line) == packageManager.CheckOutput {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+}
+
+// parsePackagesData parses the value of packagesDataKey in the template data,
+// which maps package manager names to lists of packages.
+func parsePackagesData(data any) (map[string][]string, error) {
+	if data == nil {
+		return nil, nil
+	}
+	dataMap, ok := data.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s: expected a map of package managers to lists of packages, got %T", packagesDataKey, data)
+	}
+	packagesByManager := make(map[string][]string, len(dataMap))
+	for name, value := range dataMap {
+		values, ok := value.([]any)
+		if !ok {
+			return nil, fmt.Errorf("%s.%s: expected a list of packages, got %T", packagesDataKey, name, value)
+		}
+		packages := make([]string, 0, len(values))
+		for _, value := range values {
+			pkg, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s.%s: expected a package name, got %T", packagesDataKey, name, value)
+			}
+			packages = append(packages, pkg)
+		}
+		packagesByManager[name] = packages
+	}
+	return packagesByManager, nil
+}
@@ -8,8 +8,6 @@ import (
 	"slices"
 
 	"github.com/coreos/go-semver/semver"
-
-	"github.com/twpayne/chezmoi/v2/internal/chezmoilog"
 )
 
 type passholeCacheKey struct {
@@ -66,7 +64,7 @@ func (c *Config) passholeOutput(name string, args []string, stdin io.Reader) (st
 	cmd := exec.Command(name, args...)
 	cmd.Stdin = stdin
 	cmd.Stderr = os.Stderr
-	output, err := chezmoilog.LogCmdOutput(c.logger, cmd)
+	output, err := c.secretCmdOutput(cmd)
 	if err != nil {
 		return "", newCmdOutputError(cmd, output, err)
 	}
@@ -4,8 +4,6 @@ import (
 	"bytes"
 	"os"
 	"os/exec"
-
-	"github.com/twpayne/chezmoi/v2/internal/chezmoilog"
 )
 
 type passConfig struct {
@@ -28,8 +26,10 @@ func (c *Config) passFieldsTemplateFunc(id string) map[string]string {
 		panic(err)
 	}
 
+	// The first line contains the password, so only parse the following lines.
+	_, body, _ := bytes.Cut(output, []byte{'\n'})
 	result := make(map[string]string)
-	for _, line := range bytes.Split(output, []byte{'\n'}) {
+	for _, line := range bytes.Split(body, []byte{'\n'}) {
 		if key, value, ok := bytes.Cut(line, []byte{':'}); ok {
 			result[string(bytes.TrimSpace(key))] = string(bytes.TrimSpace(value))
 		}
@@ -54,7 +54,7 @@ func (c *Config) passOutput(id string) ([]byte, error) {
 	cmd := exec.Command(c.Pass.Command, args...) //nolint:gosec
 	cmd.Stdin = os.Stdin
 	cmd.Stderr = os.Stderr
-	output, err := chezmoilog.LogCmdOutput(c.logger, cmd)
+	output, err := c.secretCmdOutput(cmd)
 	if err != nil {
 		return nil, newCmdOutputError(cmd, output, err)
 	}
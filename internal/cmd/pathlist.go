@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/twpayne/chezmoi/v2/internal/chezmoimaps"
@@ -8,42 +9,81 @@ import (
 
 type pathListTreeNode struct {
 	component string
+	path      string
+	listed    bool
 	children  map[string]*pathListTreeNode
 }
 
-func newPathListTreeNode(component string) *pathListTreeNode {
+// pathListTreeOptions are options for writing a pathListTreeNode.
+type pathListTreeOptions struct {
+	// annotations maps paths to annotations written after them.
+	annotations map[string]string
+	// counts, if set, writes the number of listed descendants of each
+	// directory.
+	counts bool
+	// statuses, if non-nil, maps paths to two character statuses written
+	// before them.
+	statuses map[string]string
+}
+
+func newPathListTreeNode(component, path string) *pathListTreeNode {
 	return &pathListTreeNode{
 		component: component,
+		path:      path,
 		children:  make(map[string]*pathListTreeNode),
 	}
 }
 
 func newPathListTreeFromPathsSlice(paths []string) *pathListTreeNode {
-	root := newPathListTreeNode("")
+	root := newPathListTreeNode("", "")
 	for _, path := range paths {
 		n := root
-		for _, component := range strings.Split(path, "/") {
+		components := strings.Split(path, "/")
+		for i, component := range components {
 			child, ok := n.children[component]
 			if !ok {
-				child = newPathListTreeNode(component)
+				child = newPathListTreeNode(component, strings.Join(components[:i+1], "/"))
 				n.children[component] = child
 			}
 			n = child
 		}
+		n.listed = true
 	}
 	return root
 }
 
-func (n *pathListTreeNode) write(sb *strings.Builder, prefix, indent string) {
+// count returns the number of listed descendants of n.
+func (n *pathListTreeNode) count() int {
+	count := 0
+	for _, child := range n.children {
+		if child.listed {
+			count++
+		}
+		count += child.count()
+	}
+	return count
+}
+
+func (n *pathListTreeNode) write(sb *strings.Builder, prefix, indent string, options *pathListTreeOptions) {
+	if options.statuses != nil {
+		fmt.Fprintf(sb, "%-2s ", options.statuses[n.path])
+	}
 	sb.WriteString(prefix)
 	sb.WriteString(n.component)
+	if options.counts && len(n.children) != 0 {
+		fmt.Fprintf(sb, " (%d)", n.count())
+	}
+	if annotation, ok := options.annotations[n.path]; ok {
+		sb.WriteString("  # ")
+		sb.WriteString(annotation)
+	}
 	sb.WriteByte('\n')
-	n.writeChildren(sb, prefix+indent, indent)
+	n.writeChildren(sb, prefix+indent, indent, options)
 }
 
-func (n *pathListTreeNode) writeChildren(sb *strings.Builder, prefix, indent string) {
+func (n *pathListTreeNode) writeChildren(sb *strings.Builder, prefix, indent string, options *pathListTreeOptions) {
 	for _, key := range chezmoimaps.SortedKeys(n.children) {
 		child := n.children[key]
-		child.write(sb, prefix, indent)
+		child.write(sb, prefix, indent, options)
 	}
 }
@@ -68,7 +68,77 @@ func TestNewNodeFromPathsSlice(t *testing.T) {
 	} {
 		t.Run(tc.name, func(t *testing.T) {
 			var sb strings.Builder
-			newPathListTreeFromPathsSlice(tc.paths).writeChildren(&sb, "", "  ")
+			newPathListTreeFromPathsSlice(tc.paths).writeChildren(&sb, "", "  ", &pathListTreeOptions{})
+			assert.Equal(t, chezmoitest.JoinLines(tc.expected...), sb.String())
+		})
+	}
+}
+
+func TestPathListTreeOptions(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		paths    []string
+		options  pathListTreeOptions
+		expected []string
+	}{
+		{
+			name: "counts",
+			paths: []string{
+				"a",
+				"a/a",
+				"a/b/a",
+				"b",
+			},
+			options: pathListTreeOptions{
+				counts: true,
+			},
+			expected: []string{
+				"a (2)",
+				"  a",
+				"  b (1)",
+				"    a",
+				"b",
+			},
+		},
+		{
+			name: "annotations",
+			paths: []string{
+				"a/a",
+				"b",
+			},
+			options: pathListTreeOptions{
+				annotations: map[string]string{
+					"a": "annotation",
+				},
+			},
+			expected: []string{
+				"a  # annotation",
+				"  a",
+				"b",
+			},
+		},
+		{
+			name: "statuses",
+			paths: []string{
+				"a/a",
+				"b",
+			},
+			options: pathListTreeOptions{
+				statuses: map[string]string{
+					"a/a": " M",
+					"b":   "MM",
+				},
+			},
+			expected: []string{
+				"   a",
+				" M   a",
+				"MM b",
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var sb strings.Builder
+			newPathListTreeFromPathsSlice(tc.paths).writeChildren(&sb, "", "  ", &tc.options)
 			assert.Equal(t, chezmoitest.JoinLines(tc.expected...), sb.String())
 		})
 	}
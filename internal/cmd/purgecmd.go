@@ -11,6 +11,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/twpayne/chezmoi/v2/internal/chezmoi"
+	"github.com/twpayne/chezmoi/v2/internal/chezmoiset"
 )
 
 type purgeCmdConfig struct {
@@ -20,7 +21,7 @@ type purgeCmdConfig struct {
 func (c *Config) newPurgeCmd() *cobra.Command {
 	purgeCmd := &cobra.Command{
 		Use:     "purge",
-		Short:   "Purge chezmoi's configuration and data",
+		Short:   mustShortHelp("purge"),
 		Long:    mustLongHelp("purge"),
 		Example: example("purge"),
 		Args:    cobra.NoArgs,
@@ -31,7 +32,7 @@ func (c *Config) newPurgeCmd() *cobra.Command {
 		),
 	}
 
-	purgeCmd.Flags().BoolVarP(&c.purge.binary, "binary", "P", c.purge.binary, "Purge chezmoi binary")
+	purgeCmd.Flags().BoolVarP(&c.purge.binary, "binary", "P", c.purge.binary, "")
 
 	return purgeCmd
 }
@@ -102,8 +103,15 @@ func (c *Config) doPurge(options *doPurgeOptions) error {
 		}
 	}
 
-	// Remove all paths that exist.
+	// Remove all paths that exist. The working tree and the source directory
+	// are often the same, so only consider each path once.
+	seenAbsPaths := chezmoiset.NewWithCapacity[chezmoi.AbsPath](len(absPaths))
 	for _, absPath := range absPaths {
+		if seenAbsPaths.Contains(absPath) {
+			continue
+		}
+		seenAbsPaths.Add(absPath)
+
 		switch _, err := c.destSystem.Stat(absPath); {
 		case errors.Is(err, fs.ErrNotExist):
 			continue
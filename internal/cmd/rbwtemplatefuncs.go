@@ -7,8 +7,6 @@ import (
 	"strings"
 
 	"github.com/coreos/go-semver/semver"
-
-	"github.com/twpayne/chezmoi/v2/internal/chezmoilog"
 )
 
 type rbwConfig struct {
@@ -61,7 +59,7 @@ func (c *Config) rbwOutput(args []string) ([]byte, error) {
 	cmd := exec.Command(c.RBW.Command, args...) //nolint:gosec
 	cmd.Stdin = os.Stdin
 	cmd.Stderr = os.Stderr
-	output, err := chezmoilog.LogCmdOutput(c.logger, cmd)
+	output, err := c.secretCmdOutput(cmd)
 	if err != nil {
 		return nil, newCmdOutputError(cmd, output, err)
 	}
@@ -19,7 +19,7 @@ type reAddCmdConfig struct {
 func (c *Config) newReAddCmd() *cobra.Command {
 	reAddCmd := &cobra.Command{
 		Use:               "re-add",
-		Short:             "Re-add modified files",
+		Short:             mustShortHelp("re-add"),
 		Long:              mustLongHelp("re-add"),
 		Example:           example("re-add"),
 		ValidArgsFunction: c.targetValidArgs,
@@ -34,7 +34,7 @@ func (c *Config) newReAddCmd() *cobra.Command {
 
 	reAddCmd.Flags().VarP(c.reAdd.filter.Exclude, "exclude", "x", "Exclude entry types")
 	reAddCmd.Flags().VarP(c.reAdd.filter.Include, "include", "i", "Include entry types")
-	reAddCmd.Flags().BoolVarP(&c.reAdd.recursive, "recursive", "r", c.reAdd.recursive, "Recurse into subdirectories")
+	reAddCmd.Flags().BoolVarP(&c.reAdd.recursive, "recursive", "r", c.reAdd.recursive, "")
 
 	return reAddCmd
 }
@@ -105,7 +105,11 @@ TARGET_REL_PATH:
 		if err != nil {
 			return err
 		}
-		if bytes.Equal(actualContents, targetContents) && actualStateFile.Perm() == targetStateFile.Perm(c.Umask) {
+		targetPerm, err := targetStateFile.Perm(c.Umask)
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(actualContents, targetContents) && actualStateFile.Perm() == targetPerm {
 			continue
 		}
 
@@ -122,7 +126,7 @@ TARGET_REL_PATH:
 				case err != nil:
 					return err
 				case choice == "diff":
-					if err := c.diffFile(targetRelPath, targetContents, targetStateFile.Perm(c.Umask), actualContents, actualStateFile.Perm()); err != nil {
+					if err := c.diffFile(targetRelPath, targetContents, targetPerm, actualContents, actualStateFile.Perm()); err != nil {
 						return err
 					}
 				case choice == "yes":
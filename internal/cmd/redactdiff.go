@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/twpayne/chezmoi/v2/internal/chezmoi"
+	"github.com/twpayne/chezmoi/v2/internal/chezmoiset"
+)
+
+// minRedactedSecretLen is the minimum length of a secret value that is
+// redacted from diffs. Shorter values would redact too much unrelated text.
+const minRedactedSecretLen = 4
+
+// redactedSecret replaces secret values in diffs.
+const redactedSecret = "<redacted>"
+
+// secretValues records the values returned by secret template functions.
+type secretValues struct {
+	sync.Mutex
+	values   chezmoiset.Set[string]
+	replacer *strings.Replacer
+}
+
+// recordSecretTemplateFuncs replaces the secret template functions with
+// functions that record the values that they return, so that they can be
+// redacted from diffs.
+func (c *Config) recordSecretTemplateFuncs() {
+	for name := range c.secretTemplateFuncNames {
+		if templateFunc, ok := c.templateFuncs[name]; ok {
+			c.templateFuncs[name] = c.secretValues.recordTemplateFunc(templateFunc)
+		}
+	}
+}
+
+// redactDiff implements chezmoi.DiffRedactFunc. The values returned by secret
+// template functions are always redacted. The contents of targets that are
+// encrypted in the source state are hidden, unless the user asked to show them.
+func (c *Config) redactDiff(absPath chezmoi.AbsPath, data []byte) ([]byte, bool) {
+	data = c.secretValues.redact(data)
+	if c.Diff.showEncrypted || c.sourceState == nil {
+		return data, false
+	}
+	targetRelPath, err := absPath.TrimDirPrefix(c.DestDirAbsPath)
+	if err != nil {
+		return data, false
+	}
+	sourceStateFile, ok := c.sourceState.Get(targetRelPath).(*chezmoi.SourceStateFile)
+	return data, ok && sourceStateFile.Attr.Encrypted
+}
+
+// recordTemplateFunc returns a function with the same signature as
+// templateFunc that calls templateFunc and records every string that it
+// returns in s.
+func (s *secretValues) recordTemplateFunc(templateFunc any) any {
+	funcValue := reflect.ValueOf(templateFunc)
+	funcType := funcValue.Type()
+	return reflect.MakeFunc(funcType, func(args []reflect.Value) []reflect.Value {
+		var results []reflect.Value
+		if funcType.IsVariadic() {
+			results = funcValue.CallSlice(args)
+		} else {
+			results = funcValue.Call(args)
+		}
+		s.Lock()
+		defer s.Unlock()
+		for _, result := range results {
+			s.addValue(result)
+		}
+		return results
+	}).Interface()
+}
+
+// addValue records all strings in value. s must be locked.
+func (s *secretValues) addValue(value reflect.Value) {
+	switch value.Kind() {
+	case reflect.String:
+		if str := value.String(); len(str) >= minRedactedSecretLen {
+			if s.values == nil {
+				s.values = chezmoiset.New[string]()
+			}
+			s.values.Add(str)
+			s.replacer = nil
+		}
+	case reflect.Interface, reflect.Pointer:
+		if !value.IsNil() {
+			s.addValue(value.Elem())
+		}
+	case reflect.Slice:
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			s.addValue(reflect.ValueOf(string(value.Bytes())))
+			return
+		}
+		fallthrough
+	case reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			s.addValue(value.Index(i))
+		}
+	case reflect.Map:
+		iter := value.MapRange()
+		for iter.Next() {
+			s.addValue(iter.Value())
+		}
+	}
+}
+
+// redact returns data with all recorded secret values replaced.
+func (s *secretValues) redact(data []byte) []byte {
+	s.Lock()
+	defer s.Unlock()
+	if len(s.values) == 0 {
+		return data
+	}
+	if s.replacer == nil {
+		// Replace longer values first so that secrets that contain other
+		// secrets are completely replaced.
+		values := s.values.Elements()
+		slices.SortFunc(values, func(a, b string) int {
+			return len(b) - len(a)
+		})
+		oldnew := make([]string, 0, 2*len(values))
+		for _, value := range values {
+			oldnew = append(oldnew, value, redactedSecret)
+		}
+		s.replacer = strings.NewReplacer(oldnew...)
+	}
+	return []byte(s.replacer.Replace(string(data)))
+}
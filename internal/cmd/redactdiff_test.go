@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestSecretValuesRedact(t *testing.T) {
+	var s secretValues
+	assert.Equal(t, []byte("password = hunter42\n"), s.redact([]byte("password = hunter42\n")))
+
+	pass := s.recordTemplateFunc(func(args ...string) string {
+		return args[0]
+	}).(func(...string) string) //nolint:forcetypeassert
+	fields := s.recordTemplateFunc(func(string) map[string]any {
+		return map[string]any{
+			"login":    "user",
+			"password": "hunter42-long",
+			"short":    "abc",
+			"nested":   []any{[]byte("bytes-secret")},
+		}
+	}).(func(string) map[string]any) //nolint:forcetypeassert
+
+	assert.Equal(t, "hunter42", pass("hunter42"))
+	assert.Equal(t, "hunter42-long", fields("item")["password"])
+
+	assert.Equal(t, []byte(""+
+		"login = <redacted>\n"+
+		"password = <redacted>\n"+
+		"other = <redacted>\n"+
+		"short = abc\n"+
+		"bytes = <redacted>\n",
+	), s.redact([]byte(""+
+		"login = user\n"+
+		"password = hunter42\n"+
+		"other = hunter42-long\n"+
+		"short = abc\n"+
+		"bytes = bytes-secret\n",
+	)))
+}
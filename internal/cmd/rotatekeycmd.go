@@ -0,0 +1,256 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+
+	"filippo.io/age"
+	"github.com/go-git/go-git/v5"
+	"github.com/spf13/cobra"
+
+	"github.com/twpayne/chezmoi/v2/internal/chezmoi"
+	"github.com/twpayne/chezmoi/v2/internal/chezmoierrors"
+)
+
+type rotateKeyCmdConfig struct {
+	from chezmoi.AbsPath
+	to   chezmoi.AbsPath
+}
+
+func (c *Config) newRotateKeyCmd() *cobra.Command {
+	rotateKeyCmd := &cobra.Command{
+		Use:     "rotate-key",
+		Short:   mustShortHelp("rotate-key"),
+		Long:    mustLongHelp("rotate-key"),
+		Example: example("rotate-key"),
+		Args:    cobra.NoArgs,
+		RunE:    c.runRotateKeyCmd,
+		Annotations: newAnnotations(
+			modifiesSourceDirectory,
+			requiresSourceDirectory,
+		),
+	}
+
+	rotateKeyCmd.Flags().VarP(&c.rotateKey.from, "from", "f", "")
+	rotateKeyCmd.Flags().VarP(&c.rotateKey.to, "to", "t", "")
+	markFlagsRequired(rotateKeyCmd, "to")
+
+	return rotateKeyCmd
+}
+
+func (c *Config) runRotateKeyCmd(cmd *cobra.Command, args []string) error {
+	if c.Encryption != "age" {
+		return errors.New("rotate-key requires encryption to be set to age")
+	}
+	useBuiltinAge := c.UseBuiltinAge.Value(c.useBuiltinAgeAutoFunc)
+
+	// Decrypt with the configured encryption, unless an old identity is given.
+	oldEncryption := c.encryption
+	if !c.rotateKey.from.Empty() {
+		oldAgeEncryption := c.Age
+		oldAgeEncryption.UseBuiltin = useBuiltinAge
+		oldAgeEncryption.Identity = c.rotateKey.from
+		oldAgeEncryption.Identities = nil
+		oldEncryption = &oldAgeEncryption
+	}
+
+	// Encrypt to the new identity only.
+	recipient, err := c.ageIdentityFileRecipient(c.rotateKey.to)
+	if err != nil {
+		return err
+	}
+	newEncryption := c.Age
+	newEncryption.UseBuiltin = useBuiltinAge
+	newEncryption.Identity = c.rotateKey.to
+	newEncryption.Identities = nil
+	newEncryption.Passphrase = false
+	newEncryption.Recipient = recipient
+	newEncryption.Recipients = nil
+	newEncryption.RecipientsFile = chezmoi.EmptyAbsPath
+	newEncryption.RecipientsFiles = nil
+	newEncryption.Symmetric = false
+
+	oldSuffix := oldEncryption.EncryptedSuffix()
+	encryptedSourceAbsPaths, err := chezmoi.EncryptedSourceAbsPaths(c.sourceSystem, c.SourceDirAbsPath, oldSuffix)
+	if err != nil {
+		return err
+	}
+	if len(encryptedSourceAbsPaths) == 0 {
+		return errors.New("no encrypted files in source state")
+	}
+
+	// Re-encrypt and verify every file before writing any of them, so that the
+	// source state is left unchanged if any file cannot be rotated.
+	type rotatedFile struct {
+		oldAbsPath  chezmoi.AbsPath
+		newAbsPath  chezmoi.AbsPath
+		tempAbsPath chezmoi.AbsPath
+		perm        fs.FileMode
+		ciphertext  []byte
+	}
+	rotatedFiles := make([]rotatedFile, 0, len(encryptedSourceAbsPaths))
+	for _, sourceAbsPath := range encryptedSourceAbsPaths {
+		fileInfo, err := c.sourceSystem.Lstat(sourceAbsPath)
+		if err != nil {
+			return err
+		}
+		oldCiphertext, err := c.sourceSystem.ReadFile(sourceAbsPath)
+		if err != nil {
+			return err
+		}
+		plaintext, err := oldEncryption.Decrypt(oldCiphertext)
+		if err != nil {
+			return fmt.Errorf("%s: %w", sourceAbsPath, err)
+		}
+		newCiphertext, err := newEncryption.Encrypt(plaintext)
+		if err != nil {
+			return fmt.Errorf("%s: %w", sourceAbsPath, err)
+		}
+		switch roundTripPlaintext, err := newEncryption.Decrypt(newCiphertext); {
+		case err != nil:
+			return fmt.Errorf("%s: %w", sourceAbsPath, err)
+		case !bytes.Equal(roundTripPlaintext, plaintext):
+			return fmt.Errorf("%s: round trip failed", sourceAbsPath)
+		}
+		newAbsPath := chezmoi.NewAbsPath(strings.TrimSuffix(sourceAbsPath.String(), oldSuffix) + newEncryption.Suffix)
+		rotatedFiles = append(rotatedFiles, rotatedFile{
+			oldAbsPath: sourceAbsPath,
+			newAbsPath: newAbsPath,
+			// The temporary file's name starts with a dot so that it is
+			// ignored if it is left behind.
+			tempAbsPath: newAbsPath.Dir().JoinString("." + newAbsPath.Base() + ".rotate-key"),
+			perm:        fileInfo.Mode().Perm(),
+			ciphertext:  newCiphertext,
+		})
+	}
+
+	// Write all the new files to temporary files and only then rename them
+	// into place, so that an error, for example a full disk, leaves the old
+	// files intact.
+	for i, rotatedFile := range rotatedFiles {
+		if err := c.sourceSystem.WriteFile(rotatedFile.tempAbsPath, rotatedFile.ciphertext, rotatedFile.perm); err != nil {
+			for _, rotatedFile := range rotatedFiles[:i+1] {
+				err = chezmoierrors.Combine(err, c.sourceSystem.RemoveAll(rotatedFile.tempAbsPath))
+			}
+			return err
+		}
+	}
+
+	// Renames cannot be undone, so if one fails then remove the remaining
+	// temporary files and report the files that have already been rotated.
+	rotatedRelPaths := make([]chezmoi.RelPath, 0, len(rotatedFiles))
+	var removedRelPaths []chezmoi.RelPath
+	for i, rotatedFile := range rotatedFiles {
+		err := c.sourceSystem.Rename(rotatedFile.tempAbsPath, rotatedFile.newAbsPath)
+		if err == nil {
+			rotatedRelPaths = append(rotatedRelPaths, rotatedFile.newAbsPath.MustTrimDirPrefix(c.SourceDirAbsPath))
+			if rotatedFile.newAbsPath != rotatedFile.oldAbsPath {
+				if err = c.sourceSystem.Remove(rotatedFile.oldAbsPath); err == nil {
+					removedRelPaths = append(removedRelPaths, rotatedFile.oldAbsPath.MustTrimDirPrefix(c.SourceDirAbsPath))
+				}
+			}
+		}
+		if err != nil {
+			for _, rotatedFile := range rotatedFiles[i:] {
+				err = chezmoierrors.Combine(err, c.sourceSystem.RemoveAll(rotatedFile.tempAbsPath))
+			}
+			if len(rotatedRelPaths) == 0 {
+				return err
+			}
+			rotatedRelPathStrs := make([]string, 0, len(rotatedRelPaths))
+			for _, rotatedRelPath := range rotatedRelPaths {
+				rotatedRelPathStrs = append(rotatedRelPathStrs, rotatedRelPath.String())
+			}
+			return fmt.Errorf(
+				"%w: only %s were re-encrypted with %s",
+				err, strings.Join(rotatedRelPathStrs, ", "), c.rotateKey.to,
+			)
+		}
+	}
+
+	if err := c.warnAgeConfig(c.rotateKey.to, recipient); err != nil {
+		return err
+	}
+
+	return c.gitCommitRotatedFiles(cmd, append(rotatedRelPaths, removedRelPaths...), recipient)
+}
+
+// gitCommitRotatedFiles stages the changes to rotatedRelPaths, relative to the
+// source directory, and commits them in a single commit, if the source
+// directory is in a git working tree. Other changes in the working tree are
+// not staged, but changes that were already staged are also committed.
+func (c *Config) gitCommitRotatedFiles(cmd *cobra.Command, rotatedRelPaths []chezmoi.RelPath, recipient string) error {
+	if c.dryRun {
+		return nil
+	}
+	switch _, err := c.baseSystem.Stat(c.WorkingTreeAbsPath.JoinString(git.GitDirName)); {
+	case errors.Is(err, fs.ErrNotExist):
+		return nil
+	case err != nil:
+		return err
+	}
+
+	sourceDirRelPath, err := c.SourceDirAbsPath.TrimDirPrefix(c.WorkingTreeAbsPath)
+	if err != nil {
+		return err
+	}
+	workingTreeRelPaths := make([]chezmoi.RelPath, 0, len(rotatedRelPaths))
+	for _, rotatedRelPath := range rotatedRelPaths {
+		workingTreeRelPaths = append(workingTreeRelPaths, sourceDirRelPath.Join(rotatedRelPath))
+	}
+	if err := c.gitAdd(workingTreeRelPaths); err != nil {
+		return err
+	}
+	if err := c.gitCommit(cmd, []byte("Re-encrypt files to age recipient "+recipient+"\n")); err != nil {
+		return err
+	}
+	// The changes are already committed, so they are not pushed by
+	// git.autoPush after the command has run.
+	if c.Git.AutoPush {
+		return c.gitPushRemotes()
+	}
+	return nil
+}
+
+// ageIdentityFileRecipient returns the recipient of the single X25519
+// identity in the age identity file at identityAbsPath.
+func (c *Config) ageIdentityFileRecipient(identityAbsPath chezmoi.AbsPath) (string, error) {
+	data, err := c.baseSystem.ReadFile(identityAbsPath)
+	if err != nil {
+		return "", err
+	}
+	identities, err := age.ParseIdentities(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", identityAbsPath, err)
+	}
+	if len(identities) != 1 {
+		return "", fmt.Errorf("%s: expected exactly one identity, got %d", identityAbsPath, len(identities))
+	}
+	x25519Identity, ok := identities[0].(*age.X25519Identity)
+	if !ok {
+		return "", fmt.Errorf("%s: not an X25519 identity", identityAbsPath)
+	}
+	return x25519Identity.Recipient().String(), nil
+}
+
+// warnAgeConfig prints a warning telling the user to set age.identity and
+// age.recipient to identityAbsPath and recipient. The config file, or the
+// config file template that generates it, is not modified, as rewriting it
+// would lose its comments, key order, and formatting.
+func (c *Config) warnAgeConfig(identityAbsPath chezmoi.AbsPath, recipient string) error {
+	configAbsPath := c.getConfigFileAbsPath()
+	switch configTemplate, err := c.findConfigTemplate(); {
+	case err != nil:
+		return err
+	case configTemplate != nil:
+		configAbsPath = configTemplate.sourceAbsPath
+	}
+	c.warnf(
+		"%s: set age.identity to %s and age.recipient to %s, and remove any other age identities and recipients\n",
+		configAbsPath, identityAbsPath, recipient,
+	)
+	return nil
+}
@@ -3,14 +3,15 @@ package cmd
 import "github.com/spf13/cobra"
 
 type secretCmdConfig struct {
-	keyring secretKeyringCmdConfig
+	keyring     secretKeyringCmdConfig
+	passthrough secretPassthroughCmdConfig
 }
 
 func (c *Config) newSecretCmd() *cobra.Command {
 	secretCmd := &cobra.Command{
 		Use:     "secret",
 		Args:    cobra.NoArgs,
-		Short:   "Interact with a secret manager",
+		Short:   mustShortHelp("secret"),
 		Long:    mustLongHelp("secret"),
 		Example: example("secret"),
 	}
@@ -18,6 +19,7 @@ func (c *Config) newSecretCmd() *cobra.Command {
 	if secretKeyringCmd := c.newSecretKeyringCmd(); secretKeyringCmd != nil {
 		secretCmd.AddCommand(secretKeyringCmd)
 	}
+	secretCmd.AddCommand(c.newSecretPassthroughCmds()...)
 
 	return secretCmd
 }
@@ -3,6 +3,8 @@
 package cmd
 
 import (
+	"fmt"
+
 	"github.com/spf13/cobra"
 	"github.com/zalando/go-keyring"
 )
@@ -83,13 +85,18 @@ func (c *Config) newSecretKeyringCmd() *cobra.Command {
 }
 
 func (c *Config) runSecretKeyringDeleteCmdE(cmd *cobra.Command, args []string) error {
-	return keyring.Delete(c.secret.keyring.delete.service, c.secret.keyring.delete.user)
+	service, user := c.secret.keyring.delete.service, c.secret.keyring.delete.user
+	if err := keyring.Delete(service, user); err != nil {
+		return fmt.Errorf("%s %s: %w", service, user, err)
+	}
+	return nil
 }
 
 func (c *Config) runSecretKeyringGetCmdE(cmd *cobra.Command, args []string) error {
-	value, err := keyring.Get(c.secret.keyring.get.service, c.secret.keyring.get.user)
+	service, user := c.secret.keyring.get.service, c.secret.keyring.get.user
+	value, err := keyring.Get(service, user)
 	if err != nil {
-		return err
+		return fmt.Errorf("%s %s: %w", service, user, err)
 	}
 	return c.writeOutputString(value)
 }
@@ -103,5 +110,9 @@ func (c *Config) runSecretKeyringSetCmdE(cmd *cobra.Command, args []string) erro
 			return err
 		}
 	}
-	return keyring.Set(c.secret.keyring.set.service, c.secret.keyring.set.user, value)
+	service, user := c.secret.keyring.set.service, c.secret.keyring.set.user
+	if err := keyring.Set(service, user, value); err != nil {
+		return fmt.Errorf("%s %s: %w", service, user, err)
+	}
+	return nil
 }
@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"encoding/json"
+
+	"github.com/spf13/cobra"
+)
+
+type secretPassthroughCmdConfig struct {
+	format writeDataFormat
+}
+
+// A secretPassthrough is a password manager whose CLI can be run with
+// arbitrary arguments by chezmoi secret.
+type secretPassthrough struct {
+	name       string
+	short      string
+	command    func() string
+	outputFunc func(args []string) ([]byte, error)
+}
+
+// secretPassthroughs returns the password managers whose CLIs can be run by
+// chezmoi secret.
+func (c *Config) secretPassthroughs() []secretPassthrough {
+	return []secretPassthrough{
+		{
+			name:       "bitwarden",
+			short:      "Run the Bitwarden CLI",
+			command:    func() string { return c.Bitwarden.Command },
+			outputFunc: c.bitwardenOutput,
+		},
+		{
+			name:       "bitwarden-secrets",
+			short:      "Run the Bitwarden Secrets CLI",
+			command:    func() string { return c.BitwardenSecrets.Command },
+			outputFunc: c.bitwardenSecretsOutput,
+		},
+		{
+			name:    "dashlane",
+			short:   "Run the Dashlane CLI",
+			command: func() string { return c.Dashlane.Command },
+			outputFunc: func(args []string) ([]byte, error) {
+				return c.dashlaneOutput(args...)
+			},
+		},
+		{
+			name:       "doppler",
+			short:      "Run the Doppler CLI",
+			command:    func() string { return c.Doppler.Command },
+			outputFunc: c.dopplerOutput,
+		},
+		{
+			name:    "gopass",
+			short:   "Run the gopass CLI",
+			command: func() string { return c.Gopass.Command },
+			outputFunc: func(args []string) ([]byte, error) {
+				return c.gopassOutput(args...)
+			},
+		},
+		{
+			name:       "hcp-vault-secrets",
+			short:      "Run the HCP Vault Secrets CLI",
+			command:    func() string { return c.HCPVaultSecrets.Command },
+			outputFunc: c.vltOutput,
+		},
+		{
+			name:       "keeper",
+			short:      "Run the Keeper CLI",
+			command:    func() string { return c.Keeper.Command },
+			outputFunc: c.keeperOutput,
+		},
+		{
+			name:    "lastpass",
+			short:   "Run the LastPass CLI",
+			command: func() string { return c.Lastpass.Command },
+			outputFunc: func(args []string) ([]byte, error) {
+				return c.lastpassOutput(args...)
+			},
+		},
+		{
+			name:       "rbw",
+			short:      "Run the rbw CLI",
+			command:    func() string { return c.RBW.Command },
+			outputFunc: c.rbwOutput,
+		},
+	}
+}
+
+// newSecretPassthroughCmds returns commands that run each password manager's
+// CLI in the same way as chezmoi's template functions, so users can check what
+// the template functions will return.
+func (c *Config) newSecretPassthroughCmds() []*cobra.Command {
+	secretPassthroughs := c.secretPassthroughs()
+	cmds := make([]*cobra.Command, 0, len(secretPassthroughs))
+	for _, secretPassthrough := range secretPassthroughs {
+		secretPassthrough := secretPassthrough
+		secretPassthroughCmd := &cobra.Command{
+			Use:   secretPassthrough.name + " [arg]...",
+			Args:  cobra.ArbitraryArgs,
+			Short: secretPassthrough.short,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return c.runSecretPassthroughCmd(secretPassthrough, args)
+			},
+			Annotations: newAnnotations(),
+		}
+		secretPassthroughCmd.Flags().VarP(&c.secret.passthrough.format, "format", "f", "Output format")
+		cmds = append(cmds, secretPassthroughCmd)
+	}
+	return cmds
+}
+
+func (c *Config) runSecretPassthroughCmd(secretPassthrough secretPassthrough, args []string) error {
+	output, err := secretPassthrough.outputFunc(args)
+	if err != nil {
+		return err
+	}
+	if c.secret.passthrough.format == "" {
+		return c.writeOutput(output)
+	}
+	var value any
+	if err := json.Unmarshal(output, &value); err != nil {
+		return newParseCmdOutputError(secretPassthrough.command(), args, output, err)
+	}
+	return c.marshal(c.secret.passthrough.format, value)
+}
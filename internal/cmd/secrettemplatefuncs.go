@@ -3,12 +3,11 @@ package cmd
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"os"
 	"os/exec"
 	"slices"
 	"strings"
-
-	"github.com/twpayne/chezmoi/v2/internal/chezmoilog"
 )
 
 type secretConfig struct {
@@ -39,6 +38,10 @@ func (c *Config) secretJSONTemplateFunc(args ...string) any {
 }
 
 func (c *Config) secretOutput(args []string) ([]byte, error) {
+	if c.Secret.Command == "" {
+		return nil, errors.New("secret.command not set")
+	}
+
 	key := strings.Join(args, "\x00")
 	if output, ok := c.Secret.cache[key]; ok {
 		return output, nil
@@ -48,7 +51,7 @@ func (c *Config) secretOutput(args []string) ([]byte, error) {
 	cmd := exec.Command(c.Secret.Command, args...) //nolint:gosec
 	cmd.Stdin = os.Stdin
 	cmd.Stderr = os.Stderr
-	output, err := chezmoilog.LogCmdOutput(c.logger, cmd)
+	output, err := c.secretCmdOutput(cmd)
 	if err != nil {
 		return nil, newCmdOutputError(cmd, output, err)
 	}
@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+
+	"github.com/twpayne/chezmoi/v2/internal/chezmoimaps"
+)
+
+// A shellConfig is a shell-agnostic declaration of aliases, environment
+// variables, and functions.
+type shellConfig struct {
+	Aliases   map[string]string            `mapstructure:"aliases"`
+	Env       map[string]string            `mapstructure:"env"`
+	Functions map[string]map[string]string `mapstructure:"functions"`
+}
+
+// A shellConfigWriter renders a shellConfig for a particular shell.
+type shellConfigWriter struct {
+	// functionShells are the keys of a function's bodies to use, in order of
+	// preference.
+	functionShells []string
+	writeAlias     func(builder *strings.Builder, name, value string)
+	writeEnv       func(builder *strings.Builder, name, value string)
+	writeFunction  func(builder *strings.Builder, name, body string)
+}
+
+var (
+	envVarNameRx = regexp.MustCompile(`\A[A-Za-z_][0-9A-Za-z_]*\z`)
+	// shellNameRx matches alias and function names that are valid in every
+	// shell and that do not need quoting.
+	shellNameRx = regexp.MustCompile(`\A[.0-9A-Za-z_][-+.0-9A-Za-z_]*\z`)
+
+	posixShellConfigWriter = func(shell string) *shellConfigWriter {
+		return &shellConfigWriter{
+			functionShells: []string{shell, "posix"},
+			writeAlias: func(builder *strings.Builder, name, value string) {
+				fmt.Fprintf(builder, "alias %s=%s\n", name, shellQuote(value))
+			},
+			writeEnv: func(builder *strings.Builder, name, value string) {
+				fmt.Fprintf(builder, "export %s=%s\n", name, shellQuote(value))
+			},
+			writeFunction: func(builder *strings.Builder, name, body string) {
+				fmt.Fprintf(builder, "%s() {\n%s\n}\n", name, body)
+			},
+		}
+	}
+
+	fishShellConfigWriter = &shellConfigWriter{
+		functionShells: []string{"fish"},
+		writeAlias: func(builder *strings.Builder, name, value string) {
+			fmt.Fprintf(builder, "alias %s %s\n", name, fishQuote(value))
+		},
+		writeEnv: func(builder *strings.Builder, name, value string) {
+			fmt.Fprintf(builder, "set -gx %s %s\n", name, fishQuote(value))
+		},
+		writeFunction: func(builder *strings.Builder, name, body string) {
+			fmt.Fprintf(builder, "function %s\n%s\nend\n", name, body)
+		},
+	}
+
+	powerShellConfigWriter = &shellConfigWriter{
+		functionShells: []string{"powershell"},
+		// PowerShell aliases cannot include arguments, so aliases are written
+		// as functions that pass on their arguments. Functions take precedence
+		// over applications, so the aliased command is looked up as an
+		// application to prevent an alias like grep = "grep --color" from
+		// calling itself. Built-in aliases take precedence over functions, so
+		// any built-in alias with the same name is removed. Remove-Item is used
+		// instead of Remove-Alias, which is not available in Windows
+		// PowerShell.
+		writeAlias: func(builder *strings.Builder, name, value string) {
+			command, args, _ := strings.Cut(strings.TrimSpace(value), " ")
+			fmt.Fprintf(builder, "if (Test-Path Alias:%s) { Remove-Item Alias:%s -Force }\n", name, name)
+			fmt.Fprintf(builder, "function %s { & (Get-Command -CommandType Application -Name %s | Select-Object -First 1)", name, powerShellQuote(command))
+			if args = strings.TrimSpace(args); args != "" {
+				fmt.Fprintf(builder, " %s", args)
+			}
+			builder.WriteString(" @args }\n")
+		},
+		writeEnv: func(builder *strings.Builder, name, value string) {
+			fmt.Fprintf(builder, "$env:%s = %s\n", name, powerShellQuote(value))
+		},
+		writeFunction: func(builder *strings.Builder, name, body string) {
+			fmt.Fprintf(builder, "function %s {\n%s\n}\n", name, body)
+		},
+	}
+
+	shellConfigWriters = map[string]*shellConfigWriter{
+		"bash":       posixShellConfigWriter("bash"),
+		"fish":       fishShellConfigWriter,
+		"powershell": powerShellConfigWriter,
+		"pwsh":       powerShellConfigWriter,
+		"sh":         posixShellConfigWriter("sh"),
+		"zsh":        posixShellConfigWriter("zsh"),
+	}
+)
+
+func (c *Config) shellConfigTemplateFunc(shell string, data map[string]any) string {
+	writer, ok := shellConfigWriters[shell]
+	if !ok {
+		panic(fmt.Errorf("%s: unsupported shell, expected one of %s", shell, englishList(chezmoimaps.SortedKeys(shellConfigWriters))))
+	}
+
+	var shellConfig shellConfig
+	if err := mapstructure.Decode(data, &shellConfig); err != nil {
+		panic(err)
+	}
+
+	var builder strings.Builder
+	for _, name := range chezmoimaps.SortedKeys(shellConfig.Env) {
+		if !envVarNameRx.MatchString(name) {
+			panic(fmt.Errorf("%s: invalid environment variable name", name))
+		}
+		writer.writeEnv(&builder, name, shellConfig.Env[name])
+	}
+	for _, name := range chezmoimaps.SortedKeys(shellConfig.Aliases) {
+		if !shellNameRx.MatchString(name) {
+			panic(fmt.Errorf("%s: invalid alias name", name))
+		}
+		writer.writeAlias(&builder, name, shellConfig.Aliases[name])
+	}
+	for _, name := range chezmoimaps.SortedKeys(shellConfig.Functions) {
+		if !shellNameRx.MatchString(name) {
+			panic(fmt.Errorf("%s: invalid function name", name))
+		}
+		bodies := shellConfig.Functions[name]
+		for _, functionShell := range writer.functionShells {
+			if body, ok := bodies[functionShell]; ok {
+				writer.writeFunction(&builder, name, strings.TrimSuffix(body, "\n"))
+				break
+			}
+		}
+	}
+	return builder.String()
+}
+
+// fishQuote returns s quoted as a fish argument.
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+// powerShellQuote returns s quoted as a PowerShell string literal.
+func powerShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/twpayne/chezmoi/v2/internal/chezmoitest"
+)
+
+func TestShellConfigTemplateFunc(t *testing.T) {
+	data := map[string]any{
+		"aliases": map[string]any{
+			"grep": "grep --color",
+			"gs":   "git status",
+			"ll":   "ls -l",
+			"tree": "tree",
+		},
+		"env": map[string]any{
+			"EDITOR": "vim",
+			"QUOTE":  `it's`,
+		},
+		"functions": map[string]any{
+			"mkcd": map[string]any{
+				"posix":      "mkdir -p \"$1\" && cd \"$1\"\n",
+				"fish":       "mkdir -p $argv[1]; and cd $argv[1]",
+				"powershell": "New-Item -ItemType Directory -Force $args[0]; Set-Location $args[0]",
+			},
+			"zshOnly": map[string]any{
+				"zsh": "print zsh",
+			},
+		},
+	}
+
+	for _, tc := range []struct {
+		shell    string
+		expected string
+	}{
+		{
+			shell: "bash",
+			expected: chezmoitest.JoinLines(
+				`export EDITOR=vim`,
+				`export QUOTE='it'\''s'`,
+				`alias grep='grep --color'`,
+				`alias gs='git status'`,
+				`alias ll='ls -l'`,
+				`alias tree=tree`,
+				`mkcd() {`,
+				`mkdir -p "$1" && cd "$1"`,
+				`}`,
+			),
+		},
+		{
+			shell: "fish",
+			expected: chezmoitest.JoinLines(
+				`set -gx EDITOR 'vim'`,
+				`set -gx QUOTE 'it\'s'`,
+				`alias grep 'grep --color'`,
+				`alias gs 'git status'`,
+				`alias ll 'ls -l'`,
+				`alias tree 'tree'`,
+				`function mkcd`,
+				`mkdir -p $argv[1]; and cd $argv[1]`,
+				`end`,
+			),
+		},
+		{
+			shell: "powershell",
+			expected: chezmoitest.JoinLines(
+				`$env:EDITOR = 'vim'`,
+				`$env:QUOTE = 'it''s'`,
+				`if (Test-Path Alias:grep) { Remove-Item Alias:grep -Force }`,
+				`function grep { & (Get-Command -CommandType Application -Name 'grep' | Select-Object -First 1) --color @args }`,
+				`if (Test-Path Alias:gs) { Remove-Item Alias:gs -Force }`,
+				`function gs { & (Get-Command -CommandType Application -Name 'git' | Select-Object -First 1) status @args }`,
+				`if (Test-Path Alias:ll) { Remove-Item Alias:ll -Force }`,
+				`function ll { & (Get-Command -CommandType Application -Name 'ls' | Select-Object -First 1) -l @args }`,
+				`if (Test-Path Alias:tree) { Remove-Item Alias:tree -Force }`,
+				`function tree { & (Get-Command -CommandType Application -Name 'tree' | Select-Object -First 1) @args }`,
+				`function mkcd {`,
+				`New-Item -ItemType Directory -Force $args[0]; Set-Location $args[0]`,
+				`}`,
+			),
+		},
+		{
+			shell: "zsh",
+			expected: chezmoitest.JoinLines(
+				`export EDITOR=vim`,
+				`export QUOTE='it'\''s'`,
+				`alias grep='grep --color'`,
+				`alias gs='git status'`,
+				`alias ll='ls -l'`,
+				`alias tree=tree`,
+				`mkcd() {`,
+				`mkdir -p "$1" && cd "$1"`,
+				`}`,
+				`zshOnly() {`,
+				`print zsh`,
+				`}`,
+			),
+		},
+	} {
+		t.Run(tc.shell, func(t *testing.T) {
+			c, err := newConfig()
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, c.shellConfigTemplateFunc(tc.shell, data))
+		})
+	}
+}
+
+func TestShellConfigTemplateFuncErrors(t *testing.T) {
+	c, err := newConfig()
+	assert.NoError(t, err)
+	assert.Panics(t, func() {
+		c.shellConfigTemplateFunc("csh", nil)
+	})
+	assert.Panics(t, func() {
+		c.shellConfigTemplateFunc("bash", map[string]any{
+			"env": map[string]any{
+				"INVALID-NAME": "value",
+			},
+		})
+	})
+	assert.Panics(t, func() {
+		c.shellConfigTemplateFunc("bash", map[string]any{
+			"aliases": map[string]any{
+				"invalid name; rm -rf": "value",
+			},
+		})
+	})
+	assert.Panics(t, func() {
+		c.shellConfigTemplateFunc("powershell", map[string]any{
+			"functions": map[string]any{
+				"invalid}name": map[string]any{
+					"powershell": "body",
+				},
+			},
+		})
+	})
+}
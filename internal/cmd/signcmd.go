@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/twpayne/chezmoi/v2/internal/chezmoi"
+	"github.com/twpayne/chezmoi/v2/internal/chezmoilog"
+)
+
+// gpgFingerprintRx matches v4 and v5 OpenPGP key fingerprints. Key IDs are not
+// accepted because they are not unique.
+var gpgFingerprintRx = regexp.MustCompile(`\A(?:[0-9A-F]{40}|[0-9A-F]{64})\z`)
+
+type signCmdConfig struct {
+	Key string `json:"key" mapstructure:"key" yaml:"key"`
+}
+
+func (c *Config) newSignCmd() *cobra.Command {
+	signCmd := &cobra.Command{
+		Use:     "sign",
+		Short:   mustShortHelp("sign"),
+		Long:    mustLongHelp("sign"),
+		Example: example("sign"),
+		Args:    cobra.NoArgs,
+		RunE:    c.runSignCmd,
+		Annotations: newAnnotations(
+			modifiesSourceDirectory,
+			requiresSourceDirectory,
+			runsCommands,
+		),
+	}
+
+	signCmd.Flags().StringVar(&c.Sign.Key, "key", c.Sign.Key, "")
+
+	return signCmd
+}
+
+func (c *Config) runSignCmd(cmd *cobra.Command, args []string) error {
+	sourceLayerDirAbsPaths, err := c.getSourceLayerDirAbsPaths()
+	if err != nil {
+		return err
+	}
+	manifest, err := chezmoi.NewManifest(c.baseSystem, c.SourceDirAbsPath, sourceLayerDirAbsPaths)
+	if err != nil {
+		return err
+	}
+	manifestData := manifest.Bytes()
+
+	gpgArgs := append(slices.Clone(c.GPG.Args), "--armor", "--detach-sign")
+	if c.Sign.Key != "" {
+		gpgArgs = append(gpgArgs, "--local-user", c.Sign.Key)
+	}
+	gpgCmd := exec.Command(c.GPG.Command, gpgArgs...) //nolint:gosec
+	gpgCmd.Stdin = bytes.NewReader(manifestData)
+	gpgCmd.Stderr = c.stderr
+	c.verboseCmdf("sign", c.GPG.Command, gpgArgs)
+	signature, err := chezmoilog.LogCmdOutput(c.logger, gpgCmd)
+	if err != nil {
+		return fmt.Errorf("%s: %w", c.GPG.Command, err)
+	}
+
+	if err := c.sourceSystem.WriteFile(c.SourceDirAbsPath.JoinString(chezmoi.ManifestName), manifestData, 0o666&^c.Umask); err != nil {
+		return err
+	}
+	return c.sourceSystem.WriteFile(c.SourceDirAbsPath.JoinString(chezmoi.ManifestSignatureName), signature, 0o666&^c.Umask)
+}
+
+// verifySignature checks that the source directory's manifest is signed by the
+// key whose fingerprint is sign.key and that the source directory and source
+// layers match it.
+// Every change since the source directory was signed is reported.
+//
+// The fingerprint must come from the config file and not from the source
+// directory, otherwise anyone who can change the source directory, for example
+// by adding a key to .chezmoigpgkeys, can also change which key is trusted.
+func (c *Config) verifySignature() error {
+	trustedFingerprint := normalizeGPGFingerprint(c.Sign.Key)
+	if !gpgFingerprintRx.MatchString(trustedFingerprint) {
+		return errors.New("sign.key must be set to the fingerprint of a trusted key in the config file")
+	}
+	configFileAbsPath := c.getConfigFileAbsPath()
+	if _, err := configFileAbsPath.TrimDirPrefix(c.SourceDirAbsPath); err == nil {
+		return fmt.Errorf("%s: config file is in the source directory", configFileAbsPath)
+	}
+
+	manifestAbsPath := c.SourceDirAbsPath.JoinString(chezmoi.ManifestName)
+	signatureAbsPath := c.SourceDirAbsPath.JoinString(chezmoi.ManifestSignatureName)
+	manifestData, err := c.baseSystem.ReadFile(manifestAbsPath)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return fmt.Errorf("%s: source state is not signed, run chezmoi sign", c.SourceDirAbsPath)
+	case err != nil:
+		return err
+	}
+	signature, err := c.baseSystem.ReadFile(signatureAbsPath)
+	if err != nil {
+		return err
+	}
+
+	// Verify exactly the manifest and signature that were read, so that they
+	// cannot be replaced between verifying and comparing the manifest. The
+	// manifest is passed on gpg's standard input and the signature is copied
+	// to a private temporary directory.
+	tempDirAbsPath, err := c.tempDir("chezmoi-sign")
+	if err != nil {
+		return err
+	}
+	tempSignatureAbsPath := tempDirAbsPath.JoinString(chezmoi.ManifestSignatureName)
+	if err := c.baseSystem.WriteFile(tempSignatureAbsPath, signature, 0o600); err != nil {
+		return err
+	}
+	gpgArgs := append(
+		slices.Clone(c.GPG.Args),
+		"--status-fd", "1",
+		"--verify", tempSignatureAbsPath.String(), "-",
+	)
+	gpgCmd := exec.Command(c.GPG.Command, gpgArgs...) //nolint:gosec
+	gpgCmd.Stdin = bytes.NewReader(manifestData)
+	c.verboseCmdf("sign", c.GPG.Command, gpgArgs)
+	status, err := chezmoilog.LogCmdOutput(c.logger, gpgCmd)
+	fingerprints := gpgValidSigFingerprints(status)
+	if err != nil || len(fingerprints) == 0 {
+		return fmt.Errorf("%s: bad signature", signatureAbsPath)
+	}
+	if !slices.Contains(fingerprints, trustedFingerprint) {
+		return fmt.Errorf("%s: signed by %s, not %s", signatureAbsPath, fingerprints[0], trustedFingerprint)
+	}
+
+	signedManifest, err := chezmoi.ParseManifest(manifestData)
+	if err != nil {
+		return fmt.Errorf("%s: %w", manifestAbsPath, err)
+	}
+	sourceLayerDirAbsPaths, err := c.getSourceLayerDirAbsPaths()
+	if err != nil {
+		return err
+	}
+	actualManifest, err := chezmoi.NewManifest(c.baseSystem, c.SourceDirAbsPath, sourceLayerDirAbsPaths)
+	if err != nil {
+		return err
+	}
+	changes := signedManifest.Changes(actualManifest)
+	if len(changes) == 0 {
+		return nil
+	}
+	for _, change := range changes {
+		if change.Layer == 0 {
+			c.errorf("%s: %s since signing\n", change.RelPath, change.Change)
+		} else {
+			c.errorf("source layer %d: %s: %s since signing\n", change.Layer, change.RelPath, change.Change)
+		}
+	}
+	return chezmoi.ExitCodeError(1)
+}
+
+// gpgValidSigFingerprints returns the fingerprints of the signing key and its
+// primary key from the VALIDSIG lines in gpg's status output.
+func gpgValidSigFingerprints(status []byte) []string {
+	var fingerprints []string
+	for _, line := range strings.Split(string(status), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || fields[0] != "[GNUPG:]" || fields[1] != "VALIDSIG" {
+			continue
+		}
+		fingerprints = append(fingerprints, fields[2])
+		if len(fields) >= 12 && fields[11] != fields[2] {
+			fingerprints = append(fingerprints, fields[11])
+		}
+	}
+	return fingerprints
+}
+
+// normalizeGPGFingerprint returns fingerprint in the format that gpg uses in
+// its status output.
+func normalizeGPGFingerprint(fingerprint string) string {
+	fingerprint = strings.ToUpper(strings.ReplaceAll(fingerprint, " ", ""))
+	return strings.TrimPrefix(fingerprint, "0X")
+}
@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestGPGValidSigFingerprints(t *testing.T) {
+	status := []byte("[GNUPG:] NEWSIG\n" +
+		"[GNUPG:] GOODSIG B76017BDF63072D8 chezmoi-test-gpg-key\n" +
+		"[GNUPG:] VALIDSIG 1111111111111111111111111111111111111111 2026-10-16 1792166400 0 4 0 1 10 00 979A6D80FA0C3291F5122C64B76017BDF63072D8\n" +
+		"[GNUPG:] TRUST_ULTIMATE 0 pgp\n")
+	fingerprints := gpgValidSigFingerprints(status)
+	assert.Equal(t, []string{
+		"1111111111111111111111111111111111111111",
+		"979A6D80FA0C3291F5122C64B76017BDF63072D8",
+	}, fingerprints)
+
+	assert.Equal(t, "979A6D80FA0C3291F5122C64B76017BDF63072D8", normalizeGPGFingerprint("979a 6d80 fa0c 3291 f512 2c64 b760 17bd f630 72d8"))
+	assert.Equal(t, "979A6D80FA0C3291F5122C64B76017BDF63072D8", normalizeGPGFingerprint("0x979A6D80FA0C3291F5122C64B76017BDF63072D8"))
+	assert.True(t, gpgFingerprintRx.MatchString("979A6D80FA0C3291F5122C64B76017BDF63072D8"))
+	assert.False(t, gpgFingerprintRx.MatchString("B76017BDF63072D8"))
+	assert.False(t, gpgFingerprintRx.MatchString("chezmoi-test-gpg-key"))
+
+	assert.Equal(t, 0, len(gpgValidSigFingerprints([]byte("[GNUPG:] BADSIG B76017BDF63072D8 chezmoi-test-gpg-key\n"))))
+}
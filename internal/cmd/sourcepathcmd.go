@@ -10,7 +10,7 @@ import (
 func (c *Config) newSourcePathCmd() *cobra.Command {
 	sourcePathCmd := &cobra.Command{
 		Use:               "source-path [target]...",
-		Short:             "Print the source path of a target",
+		Short:             mustShortHelp("source-path"),
 		Long:              mustLongHelp("source-path"),
 		Example:           example("source-path"),
 		ValidArgsFunction: c.targetValidArgs,
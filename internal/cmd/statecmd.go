@@ -45,7 +45,7 @@ type stateSetCmdConfig struct {
 func (c *Config) newStateCmd() *cobra.Command {
 	stateCmd := &cobra.Command{
 		Use:     "state",
-		Short:   "Manipulate the persistent state",
+		Short:   mustShortHelp("state"),
 		Long:    mustLongHelp("state"),
 		Example: example("state"),
 	}
@@ -171,6 +171,7 @@ func (c *Config) runStateDeleteBucketCmd(cmd *cobra.Command, args []string) erro
 
 func (c *Config) runStateDumpCmd(cmd *cobra.Command, args []string) error {
 	data, err := chezmoi.PersistentStateData(c.persistentState, map[string][]byte{
+		"applyState":               applyStateBucket,
 		"configState":              chezmoi.ConfigStateBucket,
 		"entryState":               chezmoi.EntryStateBucket,
 		"gitHubKeysState":          gitHubKeysStateBucket,
@@ -178,7 +179,10 @@ func (c *Config) runStateDumpCmd(cmd *cobra.Command, args []string) error {
 		"gitHubReleasesState":      gitHubReleasesStateBucket,
 		"gitHubTagsState":          gitHubTagsStateBucket,
 		"gitRepoExternalState":     chezmoi.GitRepoExternalStateBucket,
+		"hashState":                hashStateBucket,
+		"knownHostSaltState":       knownHostSaltStateBucket,
 		"scriptState":              chezmoi.ScriptStateBucket,
+		"sshKeyscanState":          sshKeyscanStateBucket,
 	})
 	if err != nil {
 		return err
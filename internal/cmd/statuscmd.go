@@ -14,17 +14,33 @@ import (
 )
 
 type statusCmdConfig struct {
-	Exclude   *chezmoi.EntryTypeSet `json:"exclude"   mapstructure:"exclude"   yaml:"exclude"`
-	PathStyle *chezmoi.PathStyle    `json:"pathStyle" mapstructure:"pathStyle" yaml:"pathStyle"`
-	include   *chezmoi.EntryTypeSet
-	init      bool
-	recursive bool
+	Exclude        *chezmoi.EntryTypeSet `json:"exclude"   mapstructure:"exclude"   yaml:"exclude"`
+	PathStyle      *chezmoi.PathStyle    `json:"pathStyle" mapstructure:"pathStyle" yaml:"pathStyle"`
+	format         writeDataFormat
+	formatTemplate string
+	include        *chezmoi.EntryTypeSet
+	init           bool
+	recursive      bool
+	tree           bool
+}
+
+// A statusEntry is the machine-readable status of a target, as written with
+// --format or passed to --format-template. Actual is the difference between the
+// last written state and the actual state, and Target is the difference between
+// the actual state and the target state, using the same letters as the columns
+// of the plain output. Error is set if the status of the target could not be
+// determined.
+type statusEntry struct {
+	Path   string `json:"path"            yaml:"path"`
+	Actual string `json:"actual"          yaml:"actual"`
+	Target string `json:"target"          yaml:"target"`
+	Error  string `json:"error,omitempty" yaml:"error,omitempty"`
 }
 
 func (c *Config) newStatusCmd() *cobra.Command {
 	statusCmd := &cobra.Command{
 		Use:               "status [target]...",
-		Short:             "Show the status of targets",
+		Short:             mustShortHelp("status"),
 		Long:              mustLongHelp("status"),
 		Example:           example("status"),
 		ValidArgsFunction: c.targetValidArgs,
@@ -36,17 +52,55 @@ func (c *Config) newStatusCmd() *cobra.Command {
 		),
 	}
 
-	statusCmd.Flags().VarP(c.Status.Exclude, "exclude", "x", "Exclude entry types")
+	statusCmd.Flags().VarP(c.Status.Exclude, "exclude", "x", "")
+	statusCmd.Flags().VarP(&c.Status.format, "format", "f", "")
+	statusCmd.Flags().StringVar(&c.Status.formatTemplate, "format-template", c.Status.formatTemplate, "")
 	statusCmd.Flags().VarP(c.Status.PathStyle, "path-style", "p", "Path style")
-	statusCmd.Flags().VarP(c.Status.include, "include", "i", "Include entry types")
+	statusCmd.Flags().VarP(c.Status.include, "include", "i", "")
 	statusCmd.Flags().BoolVar(&c.Status.init, "init", c.Status.init, "Recreate config file from template")
+	statusCmd.Flags().BoolVar(&c.mockSecrets, "mock-secrets", c.mockSecrets, "")
 	statusCmd.Flags().BoolVarP(&c.Status.recursive, "recursive", "r", c.Status.recursive, "Recurse into subdirectories")
+	statusCmd.Flags().BoolVarP(&c.Status.tree, "tree", "t", c.Status.tree, "")
 
 	return statusCmd
 }
 
 func (c *Config) runStatusCmd(cmd *cobra.Command, args []string) error {
+	if c.Status.format != "" && c.Status.tree {
+		return errors.New("the --format and --tree flags are mutually exclusive")
+	}
+	if c.Status.formatTemplate != "" && (c.Status.format != "" || c.Status.tree) {
+		return errors.New("the --format-template flag cannot be used with --format or --tree")
+	}
+
 	builder := strings.Builder{}
+	entries := []statusEntry{}
+	var paths []string
+	statuses := make(map[string]string)
+	addStatus := func(targetRelPath chezmoi.RelPath, x, y rune, statusErr error) error {
+		path, err := c.statusPath(targetRelPath)
+		if err != nil {
+			return err
+		}
+		switch {
+		case c.Status.format != "" || c.Status.formatTemplate != "":
+			entry := statusEntry{
+				Path:   path.String(),
+				Actual: strings.TrimSpace(string(x)),
+				Target: strings.TrimSpace(string(y)),
+			}
+			if statusErr != nil {
+				entry.Error = statusErr.Error()
+			}
+			entries = append(entries, entry)
+		case c.Status.tree:
+			paths = append(paths, path.String())
+			statuses[path.String()] = string([]rune{x, y})
+		default:
+			fmt.Fprintf(&builder, "%c%c %s\n", x, y, path)
+		}
+		return nil
+	}
 	preApplyFunc := func(targetRelPath chezmoi.RelPath, targetEntryState, lastWrittenEntryState, actualEntryState *chezmoi.EntryState) error {
 		c.logger.Info("statusPreApplyFunc",
 			chezmoilog.Stringer("targetRelPath", targetRelPath),
@@ -68,33 +122,79 @@ func (c *Config) runStatusCmd(cmd *cobra.Command, args []string) error {
 		}
 
 		if x != ' ' || y != ' ' {
-			var path string
-			switch *c.Status.PathStyle {
-			case chezmoi.PathStyleAbsolute:
-				path = c.DestDirAbsPath.Join(targetRelPath).String()
-			case chezmoi.PathStyleRelative:
-				path = targetRelPath.String()
-			case chezmoi.PathStyleSourceAbsolute:
-				return errors.New("source-absolute not supported for status")
-			case chezmoi.PathStyleSourceRelative:
-				return errors.New("source-relative not supported for status")
+			if err := addStatus(targetRelPath, x, y, nil); err != nil {
+				return err
 			}
-
-			fmt.Fprintf(&builder, "%c%c %s\n", x, y, path)
 		}
 		return fs.SkipDir
 	}
-	if err := c.applyArgs(cmd.Context(), c.destSystem, c.DestDirAbsPath, args, applyArgsOptions{
+	// With --keep-going, targets whose status cannot be determined are
+	// reported with an E in the second column.
+	errorFunc := func(targetRelPath chezmoi.RelPath, err error) error {
+		return addStatus(targetRelPath, ' ', 'E', err)
+	}
+	err := c.applyArgs(cmd.Context(), c.destSystem, c.DestDirAbsPath, args, applyArgsOptions{
 		cmd:          cmd,
 		filter:       chezmoi.NewEntryTypeFilter(c.Status.include.Bits(), c.Status.Exclude.Bits()),
 		init:         c.Status.init,
 		recursive:    c.Status.recursive,
 		umask:        c.Umask,
 		preApplyFunc: preApplyFunc,
-	}); err != nil {
+		errorFunc:    errorFunc,
+	})
+	// With --keep-going, errors for individual targets have already been
+	// reported, so still write the status of all targets.
+	if err != nil && !c.keepGoing {
+		return err
+	}
+	if c.Status.tree {
+		sourceState, err := c.getSourceState(cmd.Context(), cmd)
+		if err != nil {
+			return err
+		}
+		newPathListTreeFromPathsSlice(paths).writeChildren(&builder, "", "  ", &pathListTreeOptions{
+			annotations: dirMetadataAnnotations(sourceState, func(targetRelPath chezmoi.RelPath, _ chezmoi.SourceStateEntry) fmt.Stringer {
+				if path, err := c.statusPath(targetRelPath); err == nil {
+					return path
+				}
+				return targetRelPath
+			}),
+			counts:   true,
+			statuses: statuses,
+		})
+	}
+	if c.Status.formatTemplate != "" {
+		if err := writeFormatTemplate(c, c.Status.formatTemplate, entries); err != nil {
+			return err
+		}
 		return err
 	}
-	return c.writeOutputString(builder.String())
+	if c.Status.format != "" {
+		if err := c.marshal(c.Status.format, entries); err != nil {
+			return err
+		}
+		return err
+	}
+	if err := c.writeOutputString(builder.String()); err != nil {
+		return err
+	}
+	return err
+}
+
+// statusPath returns the path of targetRelPath in the status path style.
+func (c *Config) statusPath(targetRelPath chezmoi.RelPath) (fmt.Stringer, error) {
+	switch *c.Status.PathStyle {
+	case chezmoi.PathStyleAbsolute:
+		return c.DestDirAbsPath.Join(targetRelPath), nil
+	case chezmoi.PathStyleRelative:
+		return targetRelPath, nil
+	case chezmoi.PathStyleSourceAbsolute:
+		return nil, errors.New("source-absolute not supported for status")
+	case chezmoi.PathStyleSourceRelative:
+		return nil, errors.New("source-relative not supported for status")
+	default:
+		return nil, fmt.Errorf("%s: unsupported path style", *c.Status.PathStyle)
+	}
 }
 
 func statusRune(fromState, toState *chezmoi.EntryState) rune {
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+
+	"github.com/twpayne/chezmoi/v2/internal/chezmoi"
+)
+
+type targetHookConfig struct {
+	Pattern string            `json:"pattern" mapstructure:"pattern" yaml:"pattern"`
+	Pre     hookCommandConfig `json:"pre"     mapstructure:"pre"     yaml:"pre"`
+	Post    hookCommandConfig `json:"post"    mapstructure:"post"    yaml:"post"`
+}
+
+// A targetChange is a change to a target.
+type targetChange struct {
+	targetAbsPath chezmoi.AbsPath
+	oldSHA256     string
+	newSHA256     string
+}
+
+// A targetHooksRunner runs the target hooks for the targets changed while
+// applying. Each hook's pre command is run once, before the first target that
+// matches its pattern is changed, and its post command is run once, after all
+// targets have been applied.
+type targetHooksRunner struct {
+	c                   *Config
+	preRun              []bool
+	targetChangesByHook [][]targetChange
+}
+
+func newTargetHooksRunner(c *Config) *targetHooksRunner {
+	return &targetHooksRunner{
+		c:                   c,
+		preRun:              make([]bool, len(c.TargetHooks)),
+		targetChangesByHook: make([][]targetChange, len(c.TargetHooks)),
+	}
+}
+
+// newTargetChange returns the change to targetRelPath from oldEntryState to
+// newEntryState.
+func (r *targetHooksRunner) newTargetChange(targetRelPath chezmoi.RelPath, oldEntryState, newEntryState *chezmoi.EntryState) targetChange {
+	return targetChange{
+		targetAbsPath: r.c.DestDirAbsPath.Join(targetRelPath),
+		oldSHA256:     entryStateContentsSHA256(oldEntryState),
+		newSHA256:     entryStateContentsSHA256(newEntryState),
+	}
+}
+
+// preChangeFunc runs the pre commands of the hooks that match targetRelPath and
+// that have not been run yet.
+func (r *targetHooksRunner) preChangeFunc(targetRelPath chezmoi.RelPath, oldEntryState, newEntryState *chezmoi.EntryState) error {
+	return r.forEachMatchingHook(targetRelPath, func(i int, targetHook targetHookConfig) error {
+		if r.preRun[i] {
+			return nil
+		}
+		r.preRun[i] = true
+		hook := "targetHooks[" + targetHook.Pattern + "].pre"
+		targetChanges := []targetChange{r.newTargetChange(targetRelPath, oldEntryState, newEntryState)}
+		return r.runHookCommand(hook, targetHook.Pre, targetChanges)
+	})
+}
+
+// postChangeFunc records that targetRelPath was changed.
+func (r *targetHooksRunner) postChangeFunc(targetRelPath chezmoi.RelPath, oldEntryState, newEntryState *chezmoi.EntryState) error {
+	return r.forEachMatchingHook(targetRelPath, func(i int, targetHook targetHookConfig) error {
+		targetChange := r.newTargetChange(targetRelPath, oldEntryState, newEntryState)
+		r.targetChangesByHook[i] = append(r.targetChangesByHook[i], targetChange)
+		return nil
+	})
+}
+
+// forEachMatchingHook calls f for each hook whose pattern matches
+// targetRelPath.
+func (r *targetHooksRunner) forEachMatchingHook(targetRelPath chezmoi.RelPath, f func(int, targetHookConfig) error) error {
+	for i, targetHook := range r.c.TargetHooks {
+		switch ok, err := doublestar.Match(targetHook.Pattern, targetRelPath.String()); {
+		case err != nil:
+			return fmt.Errorf("%s: %w", targetHook.Pattern, err)
+		case ok:
+			if err := f(i, targetHook); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// runPost runs the post commands of the hooks that matched any changed
+// targets.
+func (r *targetHooksRunner) runPost() error {
+	for i, targetHook := range r.c.TargetHooks {
+		if len(r.targetChangesByHook[i]) == 0 {
+			continue
+		}
+		hook := "targetHooks[" + targetHook.Pattern + "].post"
+		if err := r.runHookCommand(hook, targetHook.Post, r.targetChangesByHook[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runHookCommand runs hookCommand with $CHEZMOI_TARGET_PATHS set to the
+// newline-separated paths of targetChanges and $CHEZMOI_TARGET_CHANGES set to
+// the newline-separated old contents hash, new contents hash, and path of
+// each of targetChanges.
+func (r *targetHooksRunner) runHookCommand(hook string, hookCommand hookCommandConfig, targetChanges []targetChange) error {
+	targetPaths := make([]string, 0, len(targetChanges))
+	targetChangeLines := make([]string, 0, len(targetChanges))
+	for _, targetChange := range targetChanges {
+		targetPaths = append(targetPaths, targetChange.targetAbsPath.String())
+		targetChangeLines = append(targetChangeLines, strings.Join([]string{
+			targetChange.oldSHA256,
+			targetChange.newSHA256,
+			targetChange.targetAbsPath.String(),
+		}, " "))
+	}
+	os.Setenv("CHEZMOI_TARGET_PATHS", strings.Join(targetPaths, "\n"))
+	defer os.Unsetenv("CHEZMOI_TARGET_PATHS")
+	os.Setenv("CHEZMOI_TARGET_CHANGES", strings.Join(targetChangeLines, "\n"))
+	defer os.Unsetenv("CHEZMOI_TARGET_CHANGES")
+	return r.c.runHookCommand(hook, hookCommand)
+}
+
+// entryStateContentsSHA256 returns the hex-encoded SHA256 sum of the contents
+// of entryState, or - if entryState has no contents, for example if it is a
+// directory or absent.
+func entryStateContentsSHA256(entryState *chezmoi.EntryState) string {
+	if entryState == nil || len(entryState.ContentsSHA256) == 0 {
+		return "-"
+	}
+	return entryState.ContentsSHA256.String()
+}
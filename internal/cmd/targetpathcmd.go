@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"errors"
+	"io/fs"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -11,7 +13,7 @@ import (
 func (c *Config) newTargetPathCmd() *cobra.Command {
 	targetPathCmd := &cobra.Command{
 		Use:         "target-path [source-path]...",
-		Short:       "Print the target path of a source path",
+		Short:       mustShortHelp("target-path"),
 		Long:        mustLongHelp("target-path"),
 		Example:     example("target-path"),
 		RunE:        c.runTargetPathCmd,
@@ -39,8 +41,12 @@ func (c *Config) runTargetPathCmd(cmd *cobra.Command, args []string) error {
 			return err
 		}
 
+		// Source paths that do not exist yet are treated as files so that
+		// editors and scripts can compute the target path of a new file.
 		var sourceRelPath chezmoi.SourceRelPath
 		switch fileInfo, err := c.sourceSystem.Stat(argAbsPath); {
+		case errors.Is(err, fs.ErrNotExist):
+			sourceRelPath = chezmoi.NewSourceRelPath(argRelPath.String())
 		case err != nil:
 			return err
 		case fileInfo.IsDir():
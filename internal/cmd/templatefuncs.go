@@ -235,6 +235,19 @@ func (c *Config) globTemplateFunc(pattern string) []string {
 	return matches
 }
 
+// hasRoleTemplateFunc returns whether the machine has any of roles.
+func (c *Config) hasRoleTemplateFunc(role string, roles ...string) bool {
+	if slices.Contains(c.Roles, role) {
+		return true
+	}
+	for _, role := range roles {
+		if slices.Contains(c.Roles, role) {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *Config) hexDecodeTemplateFunc(s string) string {
 	result, err := hex.DecodeString(s)
 	if err != nil {
@@ -389,6 +402,7 @@ func (c *Config) mozillaInstallHashTemplateFunc(path string) string {
 }
 
 func (c *Config) outputTemplateFunc(name string, args ...string) string {
+	c.verbosef(chezmoi.VerbosityActions, "templates", "running %s\n", shellQuoteCommand(name, args))
 	cmd := exec.Command(name, args...)
 	cmd.Stderr = os.Stderr
 	output, err := chezmoilog.LogCmdOutput(c.logger, cmd)
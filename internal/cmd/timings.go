@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/twpayne/chezmoi/v2/internal/chezmoi"
+)
+
+// A timings records how long it takes to apply each target and to call each
+// secret template function.
+type timings struct {
+	sync.Mutex
+	targets     []*targetTiming
+	secretCalls map[string]*secretCallTiming
+}
+
+// A targetTiming is the time taken to apply a target.
+type targetTiming struct {
+	targetRelPath  chezmoi.RelPath
+	renderDuration time.Duration
+	ioDuration     time.Duration
+}
+
+// A secretCallTiming is the total time taken by all calls to a secret template
+// function with the same arguments.
+type secretCallTiming struct {
+	call     string
+	count    int
+	duration time.Duration
+}
+
+func newTimings() *timings {
+	return &timings{
+		secretCalls: make(map[string]*secretCallTiming),
+	}
+}
+
+// applyTimingsFunc records the time taken to apply targetRelPath. It
+// implements chezmoi.ApplyTimingsFunc.
+func (t *timings) applyTimingsFunc(targetRelPath chezmoi.RelPath, renderDuration, ioDuration time.Duration) {
+	t.Lock()
+	defer t.Unlock()
+	t.targets = append(t.targets, &targetTiming{
+		targetRelPath:  targetRelPath,
+		renderDuration: renderDuration,
+		ioDuration:     ioDuration,
+	})
+}
+
+// timeSecretTemplateFuncs replaces the secret template functions with functions
+// that record the time taken by each call in t.
+func (c *Config) timeSecretTemplateFuncs(t *timings) {
+	for name := range c.secretTemplateFuncNames {
+		if templateFunc, ok := c.templateFuncs[name]; ok {
+			c.templateFuncs[name] = t.timeTemplateFunc(name, templateFunc)
+		}
+	}
+}
+
+// timeTemplateFunc returns a function with the same signature as templateFunc
+// that calls templateFunc and records the time taken in t.
+func (t *timings) timeTemplateFunc(name string, templateFunc any) any {
+	funcValue := reflect.ValueOf(templateFunc)
+	funcType := funcValue.Type()
+	return reflect.MakeFunc(funcType, func(args []reflect.Value) []reflect.Value {
+		startTime := time.Now()
+		defer func() {
+			t.addSecretCall(formatTemplateFuncCall(name, args), time.Since(startTime))
+		}()
+		if funcType.IsVariadic() {
+			return funcValue.CallSlice(args)
+		}
+		return funcValue.Call(args)
+	}).Interface()
+}
+
+func (t *timings) addSecretCall(call string, duration time.Duration) {
+	t.Lock()
+	defer t.Unlock()
+	secretCall, ok := t.secretCalls[call]
+	if !ok {
+		secretCall = &secretCallTiming{
+			call: call,
+		}
+		t.secretCalls[call] = secretCall
+	}
+	secretCall.count++
+	secretCall.duration += duration
+}
+
+// write writes the recorded timings to w, slowest first.
+func (t *timings) write(w io.Writer) error {
+	t.Lock()
+	defer t.Unlock()
+
+	targets := slices.Clone(t.targets)
+	slices.SortStableFunc(targets, func(a, b *targetTiming) int {
+		return cmp.Compare(b.renderDuration+b.ioDuration, a.renderDuration+a.ioDuration)
+	})
+	secretCalls := make([]*secretCallTiming, 0, len(t.secretCalls))
+	for _, secretCall := range t.secretCalls {
+		secretCalls = append(secretCalls, secretCall)
+	}
+	slices.SortFunc(secretCalls, func(a, b *secretCallTiming) int {
+		if c := cmp.Compare(b.duration, a.duration); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.call, b.call)
+	})
+
+	tabWriter := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tabWriter, "TARGET\tRENDER\tIO\tTOTAL")
+	for _, target := range targets {
+		fmt.Fprintf(tabWriter, "%s\t%s\t%s\t%s\n",
+			target.targetRelPath,
+			formatTimingDuration(target.renderDuration),
+			formatTimingDuration(target.ioDuration),
+			formatTimingDuration(target.renderDuration+target.ioDuration),
+		)
+	}
+	if len(secretCalls) > 0 {
+		fmt.Fprintln(tabWriter)
+		fmt.Fprintln(tabWriter, "SECRET CALL\tCOUNT\tTOTAL")
+		for _, secretCall := range secretCalls {
+			fmt.Fprintf(tabWriter, "%s\t%d\t%s\n",
+				secretCall.call,
+				secretCall.count,
+				formatTimingDuration(secretCall.duration),
+			)
+		}
+	}
+	return tabWriter.Flush()
+}
+
+// formatTemplateFuncCall returns a string representation of a call to the
+// template function name with args.
+func formatTemplateFuncCall(name string, args []reflect.Value) string {
+	elems := []string{name}
+	appendArg := func(arg reflect.Value) {
+		if arg.Kind() == reflect.String {
+			elems = append(elems, fmt.Sprintf("%q", arg.String()))
+		} else {
+			elems = append(elems, fmt.Sprintf("%v", arg.Interface()))
+		}
+	}
+	for _, arg := range args {
+		if arg.Kind() == reflect.Slice && arg.Type().Elem().Kind() == reflect.String {
+			for i := 0; i < arg.Len(); i++ {
+				appendArg(arg.Index(i))
+			}
+		} else {
+			appendArg(arg)
+		}
+	}
+	return strings.Join(elems, " ")
+}
+
+// formatTimingDuration returns d rounded to the nearest millisecond.
+func formatTimingDuration(d time.Duration) string {
+	return d.Round(time.Millisecond).String()
+}
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"errors"
+	"io/fs"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/twpayne/chezmoi/v2/internal/chezmoi"
+	"github.com/twpayne/chezmoi/v2/internal/chezmoibubbles"
+)
+
+type uiCmdConfig struct {
+	filter    *chezmoi.EntryTypeFilter
+	recursive bool
+}
+
+func (c *Config) newUICmd() *cobra.Command {
+	uiCmd := &cobra.Command{
+		Use:               "ui [target]...",
+		Short:             mustShortHelp("ui"),
+		Long:              mustLongHelp("ui"),
+		Example:           example("ui"),
+		ValidArgsFunction: c.targetValidArgs,
+		RunE:              c.runUICmd,
+		Annotations: newAnnotations(
+			modifiesDestinationDirectory,
+			persistentStateModeReadWrite,
+			requiresSourceDirectory,
+		),
+	}
+
+	uiCmd.Flags().VarP(c.ui.filter.Exclude, "exclude", "x", "")
+	uiCmd.Flags().VarP(c.ui.filter.Include, "include", "i", "")
+	uiCmd.Flags().BoolVarP(&c.ui.recursive, "recursive", "r", c.ui.recursive, "")
+
+	return uiCmd
+}
+
+func (c *Config) runUICmd(cmd *cobra.Command, args []string) error {
+	changes, err := c.uiChanges(cmd, args)
+	switch {
+	case err != nil:
+		return err
+	case len(changes) == 0:
+		return nil
+	case c.noTTY:
+		return errors.New("cannot review changes without a TTY")
+	}
+
+	changesModel, err := runCancelableModel(chezmoibubbles.NewChangesModel(changes))
+	if err != nil {
+		return err
+	}
+	selectedChanges := changesModel.Selected()
+	if len(selectedChanges) == 0 {
+		return nil
+	}
+
+	targetArgs := make([]string, 0, len(selectedChanges))
+	for _, change := range selectedChanges {
+		targetArgs = append(targetArgs, c.DestDirAbsPath.JoinString(change.Name).String())
+	}
+	return c.applyArgs(cmd.Context(), c.destSystem, c.DestDirAbsPath, targetArgs, applyArgsOptions{
+		cmd:          cmd,
+		filter:       c.ui.filter,
+		recursive:    false,
+		umask:        c.Umask,
+		mtimeFunc:    c.applyMtimeFunc(),
+		preApplyFunc: c.defaultPreApplyFunc,
+		targetHooks:  true,
+	})
+}
+
+// uiChanges returns the pending changes to args, with their diffs, by applying
+// them to a dry run system with a copy of the persistent state.
+func (c *Config) uiChanges(cmd *cobra.Command, args []string) ([]chezmoibubbles.Change, error) {
+	persistentState := c.persistentState
+	dryRunPersistentState := chezmoi.NewMockPersistentState()
+	if err := persistentState.CopyTo(dryRunPersistentState); err != nil {
+		return nil, err
+	}
+	c.persistentState = dryRunPersistentState
+	defer func() {
+		c.persistentState = persistentState
+	}()
+
+	var changes []chezmoibubbles.Change
+	preApplyFunc := func(targetRelPath chezmoi.RelPath, targetEntryState, lastWrittenEntryState, actualEntryState *chezmoi.EntryState) error {
+		var (
+			x = ' '
+			y = ' '
+		)
+		switch {
+		case targetEntryState.Type == chezmoi.EntryStateTypeScript:
+			y = 'R'
+		case !targetEntryState.Equivalent(actualEntryState):
+			x = statusRune(lastWrittenEntryState, actualEntryState)
+			y = statusRune(actualEntryState, targetEntryState)
+		}
+		if x == ' ' && y == ' ' {
+			return fs.SkipDir
+		}
+		changes = append(changes, chezmoibubbles.Change{
+			Name:   targetRelPath.String(),
+			Status: string([]rune{x, y}),
+		})
+		return nil
+	}
+
+	// The diff is not colored so that it can be split into the diffs of each
+	// target.
+	var diff strings.Builder
+	diffSystem := chezmoi.NewGitDiffSystem(chezmoi.NewDryRunSystem(c.destSystem), &diff, c.DestDirAbsPath, &chezmoi.GitDiffSystemOptions{
+		Filter:         c.ui.filter,
+		ScriptContents: c.Diff.ScriptContents,
+		TextConvFunc:   c.TextConv.convert,
+		RedactFunc:     c.redactDiff,
+	})
+	if err := c.applyArgs(cmd.Context(), diffSystem, c.DestDirAbsPath, args, applyArgsOptions{
+		cmd:          cmd,
+		filter:       c.ui.filter,
+		recursive:    c.ui.recursive,
+		umask:        c.Umask,
+		preApplyFunc: preApplyFunc,
+	}); err != nil {
+		return nil, err
+	}
+
+	diffs := make(map[string]string)
+	for _, diffEntry := range parseGitDiffEntries(diff.String()) {
+		diffs[diffEntry.Path] = diffEntry.Diff
+	}
+	for i := range changes {
+		changes[i].Diff = diffs[changes[i].Name]
+	}
+	return changes, nil
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io/fs"
 	"sort"
+	"sync"
 
 	"github.com/spf13/cobra"
 
@@ -12,6 +13,7 @@ import (
 )
 
 type unmanagedCmdConfig struct {
+	format    writeDataFormat
 	pathStyle chezmoi.PathStyle
 	tree      bool
 }
@@ -19,7 +21,7 @@ type unmanagedCmdConfig struct {
 func (c *Config) newUnmanagedCmd() *cobra.Command {
 	unmanagedCmd := &cobra.Command{
 		Use:         "unmanaged [path]...",
-		Short:       "List the unmanaged files in the destination directory",
+		Short:       mustShortHelp("unmanaged"),
 		Long:        mustLongHelp("unmanaged"),
 		Example:     example("unmanaged"),
 		Args:        cobra.ArbitraryArgs,
@@ -27,13 +29,24 @@ func (c *Config) newUnmanagedCmd() *cobra.Command {
 		Annotations: newAnnotations(),
 	}
 
-	unmanagedCmd.Flags().VarP(&c.unmanaged.pathStyle, "path-style", "p", "Path style")
+	unmanagedCmd.Flags().VarP(&c.unmanaged.format, "format", "f", "")
+	unmanagedCmd.Flags().VarP(&c.unmanaged.pathStyle, "path-style", "p", "")
 	unmanagedCmd.Flags().BoolVarP(&c.unmanaged.tree, "tree", "t", c.unmanaged.tree, "Print paths as a tree")
 
 	return unmanagedCmd
 }
 
 func (c *Config) runUnmanagedCmd(cmd *cobra.Command, args []string, sourceState *chezmoi.SourceState) error {
+	// Unmanaged files are not in the source state, so they do not have a
+	// source path.
+	switch c.unmanaged.pathStyle {
+	case chezmoi.PathStyleAbsolute, chezmoi.PathStyleRelative:
+	case chezmoi.PathStyleSourceAbsolute, chezmoi.PathStyleSourceRelative:
+		return fmt.Errorf("%s not supported for unmanaged", c.unmanaged.pathStyle)
+	default:
+		return fmt.Errorf("%s: unsupported path style", c.unmanaged.pathStyle)
+	}
+
 	var absPaths chezmoi.AbsPaths
 	if len(args) == 0 {
 		absPaths = append(absPaths, c.DestDirAbsPath)
@@ -50,10 +63,15 @@ func (c *Config) runUnmanagedCmd(cmd *cobra.Command, args []string, sourceState
 		sort.Sort(absPaths)
 	}
 
+	// Directories are walked concurrently, so mutex protects
+	// unmanagedRelPaths and the output of errors.
+	var mutex sync.Mutex
 	unmanagedRelPaths := chezmoiset.New[chezmoi.RelPath]()
 	walkFunc := func(destAbsPath chezmoi.AbsPath, fileInfo fs.FileInfo, err error) error {
 		if err != nil {
+			mutex.Lock()
 			c.errorf("%s: %v\n", destAbsPath, err)
+			mutex.Unlock()
 			if fileInfo == nil || fileInfo.IsDir() {
 				return fs.SkipDir
 			}
@@ -68,10 +86,15 @@ func (c *Config) runUnmanagedCmd(cmd *cobra.Command, args []string, sourceState
 		}
 		sourceStateEntry := sourceState.Get(targetRelPath)
 		managed := sourceStateEntry != nil
-		ignored := sourceState.Ignore(targetRelPath)
+		ignored := sourceState.Ignore(targetRelPath) || sourceState.AddIgnored(targetRelPath)
 		if !managed && !ignored {
+			mutex.Lock()
 			unmanagedRelPaths.Add(targetRelPath)
+			mutex.Unlock()
 		}
+		// Unmanaged directories are reported without descending into them,
+		// so only managed directories are walked. Managed directories are
+		// never pruned, so that no unmanaged entries are hidden.
 		if fileInfo.IsDir() {
 			switch {
 			case !managed:
@@ -89,7 +112,7 @@ func (c *Config) runUnmanagedCmd(cmd *cobra.Command, args []string, sourceState
 		return nil
 	}
 	for _, absPath := range absPaths {
-		if err := chezmoi.Walk(c.destSystem, absPath, walkFunc); err != nil {
+		if err := chezmoi.ConcurrentWalk(cmd.Context(), c.destSystem, absPath, walkFunc); err != nil {
 			return err
 		}
 	}
@@ -106,6 +129,7 @@ func (c *Config) runUnmanagedCmd(cmd *cobra.Command, args []string, sourceState
 	}
 
 	return c.writePaths(stringersToStrings(paths), writePathsOptions{
-		tree: c.unmanaged.tree,
+		format: c.unmanaged.format,
+		tree:   c.unmanaged.tree,
 	})
 }
@@ -14,6 +14,7 @@ type updateCmdConfig struct {
 	Args              []string `json:"args"              mapstructure:"args"              yaml:"args"`
 	Apply             bool     `json:"apply"             mapstructure:"apply"             yaml:"apply"`
 	RecurseSubmodules bool     `json:"recurseSubmodules" mapstructure:"recurseSubmodules" yaml:"recurseSubmodules"`
+	allowDirty        bool
 	filter            *chezmoi.EntryTypeFilter
 	init              bool
 	recursive         bool
@@ -22,7 +23,7 @@ type updateCmdConfig struct {
 func (c *Config) newUpdateCmd() *cobra.Command {
 	updateCmd := &cobra.Command{
 		Use:     "update",
-		Short:   "Pull and apply any changes",
+		Short:   mustShortHelp("update"),
 		Long:    mustLongHelp("update"),
 		Example: example("update"),
 		Args:    cobra.NoArgs,
@@ -36,37 +37,40 @@ func (c *Config) newUpdateCmd() *cobra.Command {
 		),
 	}
 
+	updateCmd.Flags().BoolVar(&c.Update.allowDirty, "allow-dirty", c.Update.allowDirty, "")
 	updateCmd.Flags().BoolVarP(&c.Update.Apply, "apply", "a", c.Update.Apply, "Apply after pulling")
-	updateCmd.Flags().VarP(c.Update.filter.Exclude, "exclude", "x", "Exclude entry types")
-	updateCmd.Flags().VarP(c.Update.filter.Include, "include", "i", "Include entry types")
+	updateCmd.Flags().VarP(c.Update.filter.Exclude, "exclude", "x", "")
+	updateCmd.Flags().VarP(c.Update.filter.Include, "include", "i", "")
 	updateCmd.Flags().BoolVar(&c.Update.init, "init", c.Update.init, "Recreate config file from template")
 	updateCmd.Flags().
-		BoolVar(&c.Update.RecurseSubmodules, "recurse-submodules", c.Update.RecurseSubmodules, "Recursively update submodules")
+		BoolVar(&c.Update.RecurseSubmodules, "recurse-submodules", c.Update.RecurseSubmodules, "")
+	updateCmd.Flags().Var(&c.Mtime, "mtime", "")
 	updateCmd.Flags().BoolVarP(&c.Update.recursive, "recursive", "r", c.Update.recursive, "Recurse into subdirectories")
 
 	return updateCmd
 }
 
 func (c *Config) runUpdateCmd(cmd *cobra.Command, args []string) error {
+	workingTreeVCSName, err := c.workingTreeVCSName()
+	if err != nil {
+		return err
+	}
+
 	switch {
 	case c.Update.Command != "":
 		if err := c.run(c.WorkingTreeAbsPath, c.Update.Command, c.Update.Args); err != nil {
 			return err
 		}
-	case c.UseBuiltinGit.Value(c.useBuiltinGitAutoFunc):
-		rawWorkingTreeAbsPath, err := c.baseSystem.RawPath(c.WorkingTreeAbsPath)
-		if err != nil {
-			return err
-		}
-		repo, err := git.PlainOpen(rawWorkingTreeAbsPath.String())
-		if err != nil {
+	case workingTreeVCSName != "" && workingTreeVCSName != gitVCSName:
+		if err := c.vcsPull(workingTreeVCSName); err != nil {
 			return err
 		}
-		wt, err := repo.Worktree()
+	case c.UseBuiltinGit.Value(c.useBuiltinGitAutoFunc):
+		_, worktree, err := c.builtinGitWorktree()
 		if err != nil {
 			return err
 		}
-		if err := wt.Pull(&git.PullOptions{
+		if err := worktree.Pull(&git.PullOptions{
 			RecurseSubmodules: git.DefaultSubmoduleRecursionDepth,
 		}); err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
 			return err
@@ -82,19 +86,23 @@ func (c *Config) runUpdateCmd(cmd *cobra.Command, args []string) error {
 				"--recurse-submodules",
 			)
 		}
-		if err := c.run(c.WorkingTreeAbsPath, c.Git.Command, gitArgs); err != nil {
+		if err := c.runVCS(gitVCSName, c.WorkingTreeAbsPath, c.Git.Command, gitArgs); err != nil {
 			return err
 		}
 	}
 
 	if c.Update.Apply {
 		if err := c.applyArgs(cmd.Context(), c.destSystem, c.DestDirAbsPath, args, applyArgsOptions{
-			cmd:          cmd,
-			filter:       c.Update.filter,
-			init:         c.Update.init,
-			recursive:    c.Update.recursive,
-			umask:        c.Umask,
-			preApplyFunc: c.defaultPreApplyFunc,
+			cmd:           cmd,
+			filter:        c.Update.filter,
+			importGPGKeys: true,
+			init:          c.Update.init,
+			recursive:     c.Update.recursive,
+			umask:         c.Umask,
+			mtimeFunc:     c.applyMtimeFunc(),
+			preApplyFunc:  c.defaultPreApplyFunc,
+			requireClean:  !c.Update.allowDirty,
+			targetHooks:   true,
 		}); err != nil {
 			return err
 		}
@@ -31,6 +31,7 @@ import (
 const (
 	upgradeMethodBrewUpgrade       = "brew-upgrade"
 	upgradeMethodReplaceExecutable = "replace-executable"
+	upgradeMethodScoopUpdate       = "scoop-update"
 	upgradeMethodSnapRefresh       = "snap-refresh"
 	upgradeMethodUpgradePackage    = "upgrade-package"
 	upgradeMethodSudoPrefix        = "sudo-"
@@ -50,7 +51,7 @@ type upgradeCmdConfig struct {
 func (c *Config) newUpgradeCmd() *cobra.Command {
 	upgradeCmd := &cobra.Command{
 		Use:     "upgrade",
-		Short:   "Upgrade chezmoi to the latest released version",
+		Short:   mustShortHelp("upgrade"),
 		Long:    mustLongHelp("upgrade"),
 		Example: example("upgrade"),
 		Args:    cobra.NoArgs,
@@ -60,8 +61,8 @@ func (c *Config) newUpgradeCmd() *cobra.Command {
 		),
 	}
 
-	upgradeCmd.Flags().StringVar(&c.upgrade.executable, "executable", c.upgrade.method, "Set executable to replace")
-	upgradeCmd.Flags().StringVar(&c.upgrade.method, "method", c.upgrade.method, "Set upgrade method")
+	upgradeCmd.Flags().StringVar(&c.upgrade.executable, "executable", c.upgrade.method, "")
+	upgradeCmd.Flags().StringVar(&c.upgrade.method, "method", c.upgrade.method, "")
 
 	return upgradeCmd
 }
@@ -79,7 +80,11 @@ func (c *Config) runUpgradeCmd(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
-	client := chezmoi.NewGitHubClient(ctx, httpClient)
+	accessToken, err := c.gitHubAccessToken()
+	if err != nil {
+		return err
+	}
+	client := chezmoi.NewGitHubClientWithAccessToken(ctx, httpClient, accessToken)
 
 	// Get the latest release.
 	rr, _, err := client.Repositories.GetLatestRelease(ctx, "twpayne", "chezmoi")
@@ -119,6 +124,11 @@ func (c *Config) runUpgradeCmd(cmd *cobra.Command, args []string) error {
 	}
 	c.logger.Info("upgradeMethod", slog.String("executable", c.upgrade.executable), slog.String("method", method))
 
+	if c.dryRun {
+		c.verbosef(chezmoi.VerbosityChanges, "upgrade", "not upgrading to %s with %s on dry run\n", version, method)
+		return nil
+	}
+
 	// Replace the executable with the updated version.
 	switch method {
 	case upgradeMethodBrewUpgrade:
@@ -129,6 +139,10 @@ func (c *Config) runUpgradeCmd(cmd *cobra.Command, args []string) error {
 		if err := c.replaceExecutable(ctx, executableAbsPath, version, rr); err != nil {
 			return err
 		}
+	case upgradeMethodScoopUpdate:
+		if err := c.scoopUpdate(); err != nil {
+			return err
+		}
 	case upgradeMethodSnapRefresh:
 		if err := c.snapRefresh(); err != nil {
 			return err
@@ -322,3 +336,9 @@ func getReleaseAssetByName(rr *github.RepositoryRelease, name string) *github.Re
 	}
 	return nil
 }
+
+// isScoopInstall returns whether executableAbsPath was installed by Scoop, which
+// installs apps in $SCOOP/apps/<app>/<version>.
+func isScoopInstall(executableAbsPath chezmoi.AbsPath) bool {
+	return strings.Contains(strings.ToLower(executableAbsPath.String()), "/scoop/apps/chezmoi/")
+}
@@ -8,6 +8,8 @@ import (
 	"github.com/alecthomas/assert/v2"
 	"github.com/coreos/go-semver/semver"
 	"github.com/twpayne/go-vfs/v5"
+
+	"github.com/twpayne/chezmoi/v2/internal/chezmoi"
 )
 
 func TestConfigGetPackageFilename(t *testing.T) {
@@ -81,3 +83,31 @@ func TestConfigGetPackageFilename(t *testing.T) {
 		})
 	}
 }
+
+func TestIsScoopInstall(t *testing.T) {
+	for _, tc := range []struct {
+		executable string
+		expected   bool
+	}{
+		{
+			executable: "C:/Users/user/scoop/apps/chezmoi/current/chezmoi.exe",
+			expected:   true,
+		},
+		{
+			executable: "C:/ProgramData/Scoop/Apps/chezmoi/2.0.0/chezmoi.exe",
+			expected:   true,
+		},
+		{
+			executable: "C:/Users/user/bin/chezmoi.exe",
+			expected:   false,
+		},
+		{
+			executable: "/usr/local/bin/chezmoi",
+			expected:   false,
+		},
+	} {
+		t.Run(tc.executable, func(t *testing.T) {
+			assert.Equal(t, tc.expected, isScoopInstall(chezmoi.NewAbsPath(tc.executable)))
+		})
+	}
+}
@@ -84,6 +84,10 @@ func (c *Config) getPackageFilename(packageType string, version *semver.Version,
 	}
 }
 
+func (c *Config) scoopUpdate() error {
+	return errUnsupportedUpgradeMethod
+}
+
 func (c *Config) snapRefresh() error {
 	return c.run(chezmoi.EmptyAbsPath, "snap", []string{"refresh", "chezmoi"})
 }
@@ -81,6 +81,10 @@ func isWinGetInstall(fileSystem vfs.Stater, executableAbsPath string) (bool, err
 	return false, nil
 }
 
+func (c *Config) scoopUpdate() error {
+	return c.run(chezmoi.EmptyAbsPath, "scoop", []string{"update", "chezmoi"})
+}
+
 func (c *Config) snapRefresh() error {
 	return errUnsupportedUpgradeMethod
 }
@@ -114,6 +118,12 @@ func getUpgradeMethod(fileSystem vfs.Stater, executableAbsPath chezmoi.AbsPath)
 		return upgradeMethodWinGetUpgrade, nil
 	}
 
+	// Scoop installs apps in the user's home directory by default, so check
+	// for it before falling back to replace-executable.
+	if isScoopInstall(executableAbsPath) {
+		return upgradeMethodScoopUpdate, nil
+	}
+
 	// If the executable is in the user's home directory, then always use
 	// replace-executable.
 	switch userHomeDir, err := chezmoi.UserHomeDir(); {
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"path"
 	"strings"
 	"unicode"
 
@@ -89,6 +90,20 @@ func englishListWithNoun(ss []string, singular, plural string) string {
 	}
 }
 
+// matchesAnyPattern returns whether name matches any of the path.Match
+// patterns.
+func matchesAnyPattern(patterns []string, name string) (bool, error) {
+	for _, pattern := range patterns {
+		switch ok, err := path.Match(pattern, name); {
+		case err != nil:
+			return false, err
+		case ok:
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 // firstNonEmptyString returns its first non-empty argument, or "" if all
 // arguments are empty.
 func firstNonEmptyString(ss ...string) string {
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/bmatcuk/doublestar/v4"
+
+	"github.com/twpayne/chezmoi/v2/internal/chezmoi"
+	"github.com/twpayne/chezmoi/v2/internal/chezmoierrors"
+	"github.com/twpayne/chezmoi/v2/internal/chezmoilog"
+)
+
+type validateElement struct {
+	Pattern string   `json:"pattern" mapstructure:"pattern" yaml:"pattern"`
+	Command string   `json:"command" mapstructure:"command" yaml:"command"`
+	Args    []string `json:"args"    mapstructure:"args"    yaml:"args"`
+}
+
+type validate []*validateElement
+
+// match returns the element of v with the longest pattern that matches
+// targetRelPath, or nil if there is no match.
+func (v validate) match(targetRelPath chezmoi.RelPath) (*validateElement, error) {
+	var longestPatternElement *validateElement
+	for _, element := range v {
+		ok, err := doublestar.Match(element.Pattern, targetRelPath.String())
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		if longestPatternElement == nil || len(element.Pattern) > len(longestPatternElement.Pattern) {
+			longestPatternElement = element
+		}
+	}
+	return longestPatternElement, nil
+}
+
+// validateTarget runs the validation command for targetRelPath, if any, on
+// contents. contents are written to a temporary file with the same name as the
+// target. The command's arguments are templates which can refer to the path of
+// the temporary file as .Path. If no argument is changed by executing it as a
+// template then the path is appended to the arguments. An error is returned if
+// the command fails.
+func (c *Config) validateTarget(targetRelPath chezmoi.RelPath, contents []byte) (err error) {
+	element, err := c.Validate.match(targetRelPath)
+	if err != nil || element == nil {
+		return err
+	}
+
+	tempDirAbsPath, err := c.tempDir("chezmoi-validate")
+	if err != nil {
+		return err
+	}
+	tempFileAbsPath := tempDirAbsPath.JoinString(targetRelPath.Base())
+	if err := os.WriteFile(tempFileAbsPath.String(), contents, 0o600); err != nil {
+		return err
+	}
+	defer chezmoierrors.CombineFunc(&err, func() error {
+		return os.Remove(tempFileAbsPath.String())
+	})
+
+	templateData := struct {
+		Path string
+	}{
+		Path: tempFileAbsPath.String(),
+	}
+	args := make([]string, 0, len(element.Args)+1)
+	anyTemplateArgs := false
+	for i, arg := range element.Args {
+		tmpl, err := template.New("validate.args[" + strconv.Itoa(i) + "]").Parse(arg)
+		if err != nil {
+			return err
+		}
+		builder := strings.Builder{}
+		if err := tmpl.Execute(&builder, templateData); err != nil {
+			return err
+		}
+		args = append(args, builder.String())
+		if arg != builder.String() {
+			anyTemplateArgs = true
+		}
+	}
+	if !anyTemplateArgs {
+		args = append(args, templateData.Path)
+	}
+
+	cmd := exec.Command(element.Command, args...) //nolint:gosec
+	if output, err := chezmoilog.LogCmdCombinedOutput(c.logger, cmd); err != nil {
+		return fmt.Errorf("%s: validation failed: %w", targetRelPath, newCmdOutputError(cmd, output, err))
+	}
+	return nil
+}
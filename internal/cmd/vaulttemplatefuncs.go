@@ -4,8 +4,6 @@ import (
 	"encoding/json"
 	"os"
 	"os/exec"
-
-	"github.com/twpayne/chezmoi/v2/internal/chezmoilog"
 )
 
 type vaultConfig struct {
@@ -22,7 +20,7 @@ func (c *Config) vaultTemplateFunc(key string) any {
 	cmd := exec.Command(c.Vault.Command, args...) //nolint:gosec
 	cmd.Stdin = os.Stdin
 	cmd.Stderr = os.Stderr
-	output, err := chezmoilog.LogCmdOutput(c.logger, cmd)
+	output, err := c.secretCmdOutput(cmd)
 	if err != nil {
 		panic(newCmdOutputError(cmd, output, err))
 	}
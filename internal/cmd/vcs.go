@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+
+	"github.com/twpayne/chezmoi/v2/internal/chezmoi"
+	"github.com/twpayne/chezmoi/v2/internal/chezmoimaps"
+)
+
+// gitVCSName is the name of the git VCS, which is handled separately from
+// other VCSes as chezmoi has builtin support for it.
+const gitVCSName = "git"
+
+type vcsConfig struct {
+	Type     string                      `json:"type"     mapstructure:"type"     yaml:"type"`
+	Backends map[string]vcsBackendConfig `json:"backends" mapstructure:"backends" yaml:"backends"`
+}
+
+// A vcsBackendConfig describes how to run a VCS other than git to create,
+// clone, and update the working tree.
+type vcsBackendConfig struct {
+	Command   string   `json:"command"   mapstructure:"command"   yaml:"command"`
+	Dir       string   `json:"dir"       mapstructure:"dir"       yaml:"dir"`
+	InitArgs  []string `json:"initArgs"  mapstructure:"initArgs"  yaml:"initArgs"`
+	CloneArgs []string `json:"cloneArgs" mapstructure:"cloneArgs" yaml:"cloneArgs"`
+	PullArgs  []string `json:"pullArgs"  mapstructure:"pullArgs"  yaml:"pullArgs"`
+}
+
+// vcsBackends returns all VCS backends, including the builtin Mercurial
+// backend, which can be overridden.
+func (c *Config) vcsBackends() map[string]vcsBackendConfig {
+	vcsBackends := map[string]vcsBackendConfig{
+		"hg": {
+			Command:   c.Hg.Command,
+			Dir:       ".hg",
+			InitArgs:  []string{"init"},
+			CloneArgs: []string{"clone"},
+			PullArgs:  []string{"pull", "--update"},
+		},
+	}
+	for name, vcsBackend := range c.VCS.Backends {
+		vcsBackends[name] = vcsBackend
+	}
+	return vcsBackends
+}
+
+// getVCSBackend returns the VCS backend called name.
+func (c *Config) getVCSBackend(name string) (vcsBackendConfig, error) {
+	vcsBackend, ok := c.vcsBackends()[name]
+	switch {
+	case !ok:
+		return vcsBackendConfig{}, fmt.Errorf("%s: unknown VCS", name)
+	case vcsBackend.Command == "":
+		return vcsBackendConfig{}, fmt.Errorf("%s: VCS command not set", name)
+	default:
+		return vcsBackend, nil
+	}
+}
+
+// defaultVCSName returns the name of the VCS to use for new working trees when
+// it cannot be determined otherwise.
+func (c *Config) defaultVCSName() string {
+	if c.VCS.Type != "" {
+		return c.VCS.Type
+	}
+	return gitVCSName
+}
+
+// repoURLVCSName returns the name of the VCS to use to clone repoURLStr and
+// the URL to pass to it. A repo URL can select a VCS with a prefix of the VCS's
+// name followed by a plus sign, for example hg+https://example.com/dotfiles.
+func (c *Config) repoURLVCSName(repoURLStr string) (string, string) {
+	if name, url, ok := strings.Cut(repoURLStr, "+"); ok {
+		if _, ok := c.vcsBackends()[name]; ok || name == gitVCSName {
+			return name, url
+		}
+	}
+	return c.defaultVCSName(), repoURLStr
+}
+
+// workingTreeVCSName returns the name of the VCS that manages the working tree,
+// or the empty string if the working tree is not managed by a VCS.
+func (c *Config) workingTreeVCSName() (string, error) {
+	// Check git first, then the other VCSes in a stable order.
+	vcsBackends := c.vcsBackends()
+	names := append([]string{gitVCSName}, chezmoimaps.SortedKeys(vcsBackends)...)
+	for _, name := range names {
+		dir := git.GitDirName
+		if name != gitVCSName {
+			dir = vcsBackends[name].Dir
+		}
+		if dir == "" {
+			continue
+		}
+		switch _, err := c.baseSystem.Stat(c.WorkingTreeAbsPath.JoinString(dir)); {
+		case errors.Is(err, fs.ErrNotExist):
+			continue
+		case err != nil:
+			return "", err
+		default:
+			return name, nil
+		}
+	}
+	return "", nil
+}
+
+// vcsClone clones repoURLStr into the working tree with the VCS called name.
+func (c *Config) vcsClone(name, repoURLStr string, workingTreeRawPath chezmoi.AbsPath) error {
+	vcsBackend, err := c.getVCSBackend(name)
+	if err != nil {
+		return err
+	}
+	if c.init.depth != 0 {
+		return fmt.Errorf("%s: --depth is only supported by git", name)
+	}
+	args := slices.Clone(vcsBackend.CloneArgs)
+	if c.init.branch != "" {
+		args = append(args, "--branch", c.init.branch)
+	}
+	args = append(args, repoURLStr, workingTreeRawPath.String())
+	return c.runVCS(name, chezmoi.EmptyAbsPath, vcsBackend.Command, args)
+}
+
+// vcsInit creates a new repo in the working tree with the VCS called name.
+func (c *Config) vcsInit(name string) error {
+	vcsBackend, err := c.getVCSBackend(name)
+	if err != nil {
+		return err
+	}
+	return c.runVCS(name, c.WorkingTreeAbsPath, vcsBackend.Command, vcsBackend.InitArgs)
+}
+
+// vcsPull pulls changes into the working tree with the VCS called name.
+func (c *Config) vcsPull(name string) error {
+	vcsBackend, err := c.getVCSBackend(name)
+	if err != nil {
+		return err
+	}
+	return c.runVCS(name, c.WorkingTreeAbsPath, vcsBackend.Command, vcsBackend.PullArgs)
+}
@@ -1,22 +1,39 @@
 package cmd
 
 import (
+	"io/fs"
+
 	"github.com/spf13/cobra"
 
 	"github.com/twpayne/chezmoi/v2/internal/chezmoi"
 )
 
 type verifyCmdConfig struct {
-	Exclude   *chezmoi.EntryTypeSet `json:"exclude" mapstructure:"exclude" yaml:"exclude"`
-	include   *chezmoi.EntryTypeSet
-	init      bool
-	recursive bool
+	Exclude    *chezmoi.EntryTypeSet `json:"exclude" mapstructure:"exclude" yaml:"exclude"`
+	include    *chezmoi.EntryTypeSet
+	afterApply bool
+	format     writeDataFormat
+	init       bool
+	recursive  bool
+	signature  bool
+}
+
+// A verifyTarget is a target that does not match its target state.
+type verifyTarget struct {
+	Path       string                 `json:"path"       yaml:"path"`
+	TargetType chezmoi.EntryStateType `json:"targetType" yaml:"targetType"`
+	ActualType chezmoi.EntryStateType `json:"actualType" yaml:"actualType"`
+}
+
+// A verifyResult is the machine-readable result of verify.
+type verifyResult struct {
+	Targets []verifyTarget `json:"targets" yaml:"targets"`
 }
 
 func (c *Config) newVerifyCmd() *cobra.Command {
 	verifyCmd := &cobra.Command{
 		Use:               "verify [target]...",
-		Short:             "Exit with success if the destination state matches the target state, fail otherwise",
+		Short:             mustShortHelp("verify"),
 		Long:              mustLongHelp("verify"),
 		Example:           example("verify"),
 		ValidArgsFunction: c.targetValidArgs,
@@ -27,15 +44,54 @@ func (c *Config) newVerifyCmd() *cobra.Command {
 		),
 	}
 
-	verifyCmd.Flags().VarP(c.Verify.Exclude, "exclude", "x", "Exclude entry types")
-	verifyCmd.Flags().VarP(c.Verify.include, "include", "i", "Include entry types")
+	verifyCmd.Flags().BoolVar(&c.Verify.afterApply, "after-apply", c.Verify.afterApply, "")
+	// verify --after-apply modifies the destination directory in the same way
+	// as apply.
+	annotateFlag(verifyCmd.Flags(), "after-apply", newAnnotations(
+		modifiesDestinationDirectory,
+		persistentStateModeReadWrite,
+	))
+	verifyCmd.Flags().VarP(c.Verify.Exclude, "exclude", "x", "")
+	verifyCmd.Flags().VarP(&c.Verify.format, "format", "f", "")
+	verifyCmd.Flags().VarP(c.Verify.include, "include", "i", "")
 	verifyCmd.Flags().BoolVar(&c.Verify.init, "init", c.Verify.init, "Recreate config file from template")
+	verifyCmd.Flags().BoolVar(&c.mockSecrets, "mock-secrets", c.mockSecrets, "")
 	verifyCmd.Flags().BoolVarP(&c.Verify.recursive, "recursive", "r", c.Verify.recursive, "Recurse into subdirectories")
+	verifyCmd.Flags().BoolVar(&c.Verify.signature, "signature", c.Verify.signature, "")
 
 	return verifyCmd
 }
 
 func (c *Config) runVerifyCmd(cmd *cobra.Command, args []string) error {
+	if c.Verify.signature {
+		return c.verifySignature()
+	}
+
+	if c.Verify.afterApply {
+		return c.runVerifyAfterApply(cmd, args)
+	}
+
+	if c.Verify.format != "" {
+		var targets []verifyTarget
+		if err := c.applyArgs(cmd.Context(), chezmoi.NewDryRunSystem(c.destSystem), c.DestDirAbsPath, args, applyArgsOptions{
+			cmd:       cmd,
+			filter:    chezmoi.NewEntryTypeFilter(c.Verify.include.Bits(), c.Verify.Exclude.Bits()),
+			init:      c.Verify.init,
+			recursive: c.Verify.recursive,
+			umask:     c.Umask,
+			preApplyFunc: func(
+				targetRelPath chezmoi.RelPath,
+				targetEntryState, lastWrittenEntryState, actualEntryState *chezmoi.EntryState,
+			) error {
+				targets = appendVerifyTarget(targets, targetRelPath, targetEntryState, actualEntryState)
+				return fs.SkipDir
+			},
+		}); err != nil {
+			return err
+		}
+		return c.writeVerifyResult(targets)
+	}
+
 	errorOnWriteSystem := chezmoi.NewErrorOnWriteSystem(c.destSystem, chezmoi.ExitCodeError(1))
 	return c.applyArgs(cmd.Context(), errorOnWriteSystem, c.DestDirAbsPath, args, applyArgsOptions{
 		cmd:       cmd,
@@ -45,3 +101,91 @@ func (c *Config) runVerifyCmd(cmd *cobra.Command, args []string) error {
 		umask:     c.Umask,
 	})
 }
+
+// runVerifyAfterApply applies the target state and then checks that a freshly
+// computed target state matches the destination state. Differences are usually
+// caused by templates or scripts that are not deterministic.
+func (c *Config) runVerifyAfterApply(cmd *cobra.Command, args []string) error {
+	filter := chezmoi.NewEntryTypeFilter(c.Verify.include.Bits(), c.Verify.Exclude.Bits())
+	if err := c.applyArgs(cmd.Context(), c.destSystem, c.DestDirAbsPath, args, applyArgsOptions{
+		cmd:           cmd,
+		filter:        filter,
+		importGPGKeys: true,
+		init:          c.Verify.init,
+		recursive:     c.Verify.recursive,
+		umask:         c.Umask,
+		preApplyFunc:  c.defaultPreApplyFunc,
+	}); err != nil {
+		return err
+	}
+
+	// Read the source state again so that templates are executed again.
+	c.sourceState, c.sourceStateErr = nil, nil
+
+	var changedTargets []verifyTarget
+	preApplyFunc := func(
+		targetRelPath chezmoi.RelPath,
+		targetEntryState, lastWrittenEntryState, actualEntryState *chezmoi.EntryState,
+	) error {
+		// Scripts that are always run are not expected to be idempotent.
+		if sourceStateFile, ok := c.sourceState.MustEntry(targetRelPath).(*chezmoi.SourceStateFile); ok &&
+			sourceStateFile.Attr.Condition == chezmoi.ScriptConditionAlways {
+			return fs.SkipDir
+		}
+		changedTargets = appendVerifyTarget(changedTargets, targetRelPath, targetEntryState, actualEntryState)
+		return fs.SkipDir
+	}
+	if err := c.applyArgs(cmd.Context(), chezmoi.NewDryRunSystem(c.destSystem), c.DestDirAbsPath, args, applyArgsOptions{
+		cmd:          cmd,
+		filter:       filter,
+		recursive:    c.Verify.recursive,
+		umask:        c.Umask,
+		preApplyFunc: preApplyFunc,
+	}); err != nil {
+		return err
+	}
+
+	if c.Verify.format != "" {
+		return c.writeVerifyResult(changedTargets)
+	}
+	if len(changedTargets) == 0 {
+		return nil
+	}
+	for _, changedTarget := range changedTargets {
+		c.errorf("%s: target state changed after apply\n", changedTarget.Path)
+	}
+	return chezmoi.ExitCodeError(1)
+}
+
+// appendVerifyTarget appends targetRelPath to targets if targetEntryState is
+// not equivalent to actualEntryState.
+func appendVerifyTarget(
+	targets []verifyTarget,
+	targetRelPath chezmoi.RelPath,
+	targetEntryState, actualEntryState *chezmoi.EntryState,
+) []verifyTarget {
+	if targetEntryState.Equivalent(actualEntryState) {
+		return targets
+	}
+	return append(targets, verifyTarget{
+		Path:       targetRelPath.String(),
+		TargetType: targetEntryState.Type,
+		ActualType: actualEntryState.Type,
+	})
+}
+
+// writeVerifyResult writes targets in c.Verify.format and returns an error
+// with exit code 1 if there are any targets.
+func (c *Config) writeVerifyResult(targets []verifyTarget) error {
+	result := verifyResult{
+		Targets: make([]verifyTarget, 0, len(targets)),
+	}
+	result.Targets = append(result.Targets, targets...)
+	if err := c.marshal(c.Verify.format, result); err != nil {
+		return err
+	}
+	if len(targets) > 0 {
+		return chezmoi.ExitCodeError(1)
+	}
+	return nil
+}
@@ -0,0 +1,600 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	extensionast "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/text"
+
+	"github.com/twpayne/chezmoi/v2/assets/chezmoi.io/docs/reference/commands"
+)
+
+// Locations of pages in the documentation, used to resolve relative links.
+const (
+	commandsDir    = "reference/commands"
+	globalFlagsDir = "reference/command-line-flags"
+	siteURL        = "https://chezmoi.io"
+)
+
+var (
+	globalFlags       = flag.String("g", "assets/chezmoi.io/docs/reference/command-line-flags/global.md", "global flags documentation")
+	markdownOutputDir = flag.String("m", "", "Markdown output directory, if any")
+	outputDir         = flag.String("o", "man", "output directory")
+)
+
+var (
+	admonitionRx = regexp.MustCompile(`^!!! (\w+)(?: "(.*)")?$`)
+	linkRx       = regexp.MustCompile(`\]\(([^)\s]+)\)`)
+	sentenceRx   = regexp.MustCompile(`\.\s`)
+	titleRx      = regexp.MustCompile("^# `([^`]+)`(.*)$")
+)
+
+var markdown = goldmark.New(goldmark.WithExtensions(extension.Table))
+
+// A manPageWriter converts the Markdown used in chezmoi's command reference
+// into a roff man page.
+type manPageWriter struct {
+	buf            bytes.Buffer
+	sectionOptions bool
+	inOption       bool
+	examples       bool
+}
+
+// parse parses the Markdown in lines.
+func parse(lines []string) ([]byte, ast.Node) {
+	source := []byte(strings.Join(lines, "\n"))
+	return source, markdown.Parser().Parse(text.NewReader(source))
+}
+
+// escape escapes text for roff.
+func escape(text string) string {
+	text = strings.ReplaceAll(text, `\`, `\e`)
+	text = strings.ReplaceAll(text, "-", `\-`)
+	return text
+}
+
+// inline returns the inline children of node converted to roff.
+func inline(source []byte, node ast.Node) string {
+	var builder strings.Builder
+	for child := node.FirstChild(); child != nil; child = child.NextSibling() {
+		switch child := child.(type) {
+		case *ast.Text:
+			builder.WriteString(escape(string(child.Segment.Value(source))))
+			if child.SoftLineBreak() || child.HardLineBreak() {
+				builder.WriteByte(' ')
+			}
+		case *ast.String:
+			builder.WriteString(escape(string(child.Value)))
+		case *ast.CodeSpan:
+			builder.WriteString(`\fB` + escape(plain(source, child)) + `\fR`)
+		case *ast.Emphasis:
+			font := `\fI`
+			if child.Level == 2 {
+				font = `\fB`
+			}
+			builder.WriteString(font + inline(source, child) + `\fR`)
+		case *ast.AutoLink:
+			builder.WriteString(escape(string(child.URL(source))))
+		case *ast.RawHTML:
+			// Inline HTML has no equivalent in roff, so drop it.
+		default:
+			builder.WriteString(inline(source, child))
+		}
+	}
+	return builder.String()
+}
+
+// inlineMarkdown returns the first paragraph of the Markdown in s converted to
+// roff.
+func inlineMarkdown(s string) string {
+	source, document := parse([]string{s})
+	if document.FirstChild() == nil {
+		return ""
+	}
+	return inline(source, document.FirstChild())
+}
+
+// plain returns the text of node without any markup.
+func plain(source []byte, node ast.Node) string {
+	var builder strings.Builder
+	for child := node.FirstChild(); child != nil; child = child.NextSibling() {
+		switch child := child.(type) {
+		case *ast.Text:
+			builder.Write(child.Segment.Value(source))
+			if child.SoftLineBreak() || child.HardLineBreak() {
+				builder.WriteByte(' ')
+			}
+		case *ast.String:
+			builder.Write(child.Value)
+		case *ast.AutoLink:
+			builder.Write(child.URL(source))
+		case *ast.RawHTML:
+		default:
+			builder.WriteString(plain(source, child))
+		}
+	}
+	return builder.String()
+}
+
+// textLine writes a line of text, protecting leading control characters.
+func (w *manPageWriter) textLine(line string) {
+	line = strings.TrimSpace(line)
+	if strings.HasPrefix(line, ".") || strings.HasPrefix(line, "'") {
+		w.buf.WriteString(`\&`)
+	}
+	w.buf.WriteString(line)
+	w.buf.WriteByte('\n')
+}
+
+// request writes a roff request.
+func (w *manPageWriter) request(format string, args ...any) {
+	fmt.Fprintf(&w.buf, format+"\n", args...)
+}
+
+// startParagraph starts a new paragraph, indented if it is part of an option.
+func (w *manPageWriter) startParagraph() {
+	switch {
+	case w.inOption:
+		w.inOption = false
+	case w.sectionOptions:
+		w.request(".IP")
+	default:
+		w.request(".PP")
+	}
+}
+
+// nested writes the output of f as a nested block, continuing the current
+// paragraph if continueParagraph is true.
+func (w *manPageWriter) nested(continueParagraph bool, f func()) {
+	sectionOptions, inOption := w.sectionOptions, w.inOption
+	w.sectionOptions, w.inOption = continueParagraph, continueParagraph
+	f()
+	w.sectionOptions, w.inOption = sectionOptions, inOption
+}
+
+// preformatted writes lines without filling.
+func (w *manPageWriter) preformatted(lines []string) {
+	w.startParagraph()
+	w.request(".nf")
+	for _, line := range lines {
+		w.textLine(escape(line))
+	}
+	w.request(".fi")
+}
+
+// table returns rows of cells as aligned columns of text.
+func table(rows [][]string) []string {
+	var widths []int
+	for _, row := range rows {
+		for i, cell := range row {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], len(cell))
+		}
+	}
+	lines := make([]string, 0, len(rows))
+	for _, row := range rows {
+		cells := slices.Clone(row)
+		for i, cell := range cells[:len(cells)-1] {
+			cells[i] = cell + strings.Repeat(" ", widths[i]-len(cell))
+		}
+		lines = append(lines, strings.TrimRight(strings.Join(cells, "  "), " "))
+	}
+	return lines
+}
+
+// writeMarkdown writes the Markdown lines in lines, which may contain
+// admonitions.
+func (w *manPageWriter) writeMarkdown(lines []string) {
+	var markdownLines []string
+	flush := func() {
+		if len(markdownLines) != 0 {
+			source, document := parse(markdownLines)
+			w.writeBlocks(source, document)
+			markdownLines = nil
+		}
+	}
+	for i := 0; i < len(lines); i++ {
+		m := admonitionRx.FindStringSubmatch(lines[i])
+		if m == nil {
+			markdownLines = append(markdownLines, lines[i])
+			continue
+		}
+		flush()
+		var body []string
+		for i++; i < len(lines) && (strings.TrimSpace(lines[i]) == "" || strings.HasPrefix(lines[i], "    ")); i++ {
+			body = append(body, strings.TrimPrefix(lines[i], "    "))
+		}
+		i--
+		w.writeAdmonition(m[1], m[2], body)
+	}
+	flush()
+}
+
+// writeAdmonition writes an admonition of kind with title and body.
+func (w *manPageWriter) writeAdmonition(kind, title string, body []string) {
+	if kind == "example" {
+		if !w.examples {
+			w.request(".SH EXAMPLES")
+			w.examples = true
+		}
+		w.sectionOptions = false
+		w.inOption = false
+		w.writeMarkdown(body)
+		return
+	}
+	if title == "" {
+		title = strings.ToUpper(kind[:1]) + kind[1:]
+	}
+	w.startParagraph()
+	w.textLine(`\fB` + escape(title) + `:\fR`)
+	w.request(".RS 4")
+	w.nested(false, func() {
+		w.writeMarkdown(body)
+	})
+	w.request(".RE")
+}
+
+// writeBlocks writes the block children of node.
+func (w *manPageWriter) writeBlocks(source []byte, node ast.Node) {
+	for child := node.FirstChild(); child != nil; child = child.NextSibling() {
+		w.writeBlock(source, child)
+	}
+}
+
+// writeBlock writes the block node.
+func (w *manPageWriter) writeBlock(source []byte, node ast.Node) {
+	switch node := node.(type) {
+	case *ast.Heading:
+		if node.Level != 2 {
+			w.startParagraph()
+			w.textLine(`\fB` + inline(source, node) + `\fR`)
+			return
+		}
+		if !w.sectionOptions {
+			w.request(".SH OPTIONS")
+			w.sectionOptions = true
+		}
+		w.request(".TP")
+		w.textLine(inline(source, node))
+		w.inOption = true
+	case *ast.Paragraph, *ast.TextBlock:
+		w.startParagraph()
+		w.textLine(inline(source, node))
+	case *ast.FencedCodeBlock, *ast.CodeBlock:
+		lines := node.Lines()
+		code := make([]string, 0, lines.Len())
+		for i := 0; i < lines.Len(); i++ {
+			segment := lines.At(i)
+			code = append(code, strings.TrimSuffix(string(segment.Value(source)), "\n"))
+		}
+		w.preformatted(code)
+	case *ast.List:
+		w.writeList(source, node)
+	case *ast.Blockquote:
+		w.startParagraph()
+		w.request(".RS 4")
+		w.nested(true, func() {
+			w.writeBlocks(source, node)
+		})
+		w.request(".RE")
+	case *extensionast.Table:
+		var rows [][]string
+		for row := node.FirstChild(); row != nil; row = row.NextSibling() {
+			var cells []string
+			for cell := row.FirstChild(); cell != nil; cell = cell.NextSibling() {
+				cells = append(cells, strings.TrimSpace(plain(source, cell)))
+			}
+			rows = append(rows, cells)
+		}
+		w.preformatted(table(rows))
+	case *ast.HTMLBlock, *ast.ThematicBreak:
+		// HTML and thematic breaks have no equivalent in roff, so drop them.
+	default:
+		w.writeBlocks(source, node)
+	}
+}
+
+// writeList writes list, indenting it if it is nested in another list.
+func (w *manPageWriter) writeList(source []byte, list *ast.List) {
+	_, isNested := list.Parent().(*ast.ListItem)
+	if isNested {
+		w.request(".RS 2")
+	}
+	index := list.Start
+	for item := list.FirstChild(); item != nil; item = item.NextSibling() {
+		if list.IsOrdered() {
+			w.request(".IP %d. 4", index)
+			index++
+		} else {
+			w.request(`.IP \(bu 2`)
+		}
+		w.nested(true, func() {
+			w.writeBlocks(source, item)
+		})
+	}
+	if isNested {
+		w.request(".RE")
+	}
+}
+
+// description returns the first sentence of the first paragraph of lines,
+// ignoring admonitions.
+func description(lines []string) string {
+	var markdownLines []string
+	for _, line := range lines {
+		if admonitionRx.MatchString(line) {
+			break
+		}
+		markdownLines = append(markdownLines, line)
+	}
+	source, document := parse(markdownLines)
+	var paragraph ast.Node
+	for child := document.FirstChild(); child != nil; child = child.NextSibling() {
+		if _, ok := child.(*ast.Paragraph); ok {
+			paragraph = child
+			break
+		}
+	}
+	if paragraph == nil {
+		return ""
+	}
+	text := strings.TrimSpace(plain(source, paragraph))
+	for _, loc := range sentenceRx.FindAllStringIndex(text, -1) {
+		if sentence := text[:loc[0]]; !strings.HasSuffix(sentence, ".") &&
+			!strings.HasSuffix(sentence, "e.g") && !strings.HasSuffix(sentence, "i.e") {
+			return sentence
+		}
+	}
+	return strings.TrimSuffix(text, ".")
+}
+
+// commandManPage returns the man page for command generated from data, the
+// Markdown documentation for command.
+func commandManPage(command string, data []byte) ([]byte, error) {
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	m := titleRx.FindStringSubmatch(lines[0])
+	if m == nil || m[1] != command {
+		return nil, fmt.Errorf("%s: missing or invalid title", command)
+	}
+	lines = lines[1:]
+
+	synopsis := `\fBchezmoi ` + escape(command) + `\fR`
+	if arguments := inlineMarkdown(m[2]); arguments != "" {
+		synopsis += " " + arguments
+	}
+
+	w := &manPageWriter{}
+	w.request(`.TH "CHEZMOI\-%s" "1" "" "chezmoi" "chezmoi Manual"`, strings.ToUpper(escape(command)))
+	w.request(".SH NAME")
+	w.textLine("chezmoi\\-" + escape(command) + ` \- ` + escape(description(lines)))
+	w.request(".SH SYNOPSIS")
+	w.textLine(synopsis)
+	w.request(".SH DESCRIPTION")
+	w.writeMarkdown(lines)
+	w.request(".SH SEE ALSO")
+	w.textLine(`\fBchezmoi\fR(1)`)
+	return w.buf.Bytes(), nil
+}
+
+// rootManPage returns the man page for chezmoi itself, listing commands and
+// documenting the global flags in globalFlagsData.
+func rootManPage(commandNames []string, globalFlagsData []byte) []byte {
+	w := &manPageWriter{}
+	w.request(`.TH "CHEZMOI" "1" "" "chezmoi" "chezmoi Manual"`)
+	w.request(".SH NAME")
+	w.textLine(`chezmoi \- manage your dotfiles across multiple diverse machines, securely`)
+	w.request(".SH SYNOPSIS")
+	w.textLine(`\fBchezmoi\fR [\fIflags\fR] \fIcommand\fR [\fIargs\fR...]`)
+	if globalFlagsData != nil {
+		lines := strings.Split(strings.TrimRight(string(globalFlagsData), "\n"), "\n")
+		w.writeMarkdown(lines[1:])
+	}
+	w.request(".SH COMMANDS")
+	for _, commandName := range commandNames {
+		w.request(".TP")
+		w.textLine(`\fB` + escape(commandName) + `\fR`)
+		w.textLine(`See \fBchezmoi\-` + escape(commandName) + `\fR(1).`)
+	}
+	return w.buf.Bytes()
+}
+
+// commandMarkdownPage returns the standalone Markdown page for command
+// generated from data, the Markdown documentation for command. Admonitions are
+// converted to block quotes and links are rewritten to point to the other
+// generated pages or to the website.
+func commandMarkdownPage(command string, data []byte) ([]byte, error) {
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	m := titleRx.FindStringSubmatch(lines[0])
+	if m == nil || m[1] != command {
+		return nil, fmt.Errorf("%s: missing or invalid title", command)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# `chezmoi %s`%s\n", command, m[2])
+	writeStandaloneMarkdown(&buf, commandsDir, lines[1:], 0)
+	return buf.Bytes(), nil
+}
+
+// rootMarkdownPage returns the standalone Markdown page for chezmoi itself,
+// listing commands with their descriptions in descriptions and documenting
+// the global flags in globalFlagsData.
+func rootMarkdownPage(commandNames []string, descriptions map[string]string, globalFlagsData []byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("# `chezmoi`\n")
+	buf.WriteString("\n")
+	buf.WriteString("Manage your dotfiles across multiple diverse machines, securely.\n")
+	if globalFlagsData != nil {
+		lines := strings.Split(strings.TrimRight(string(globalFlagsData), "\n"), "\n")
+		buf.WriteString("\n")
+		buf.WriteString("## Global flags\n")
+		writeStandaloneMarkdown(&buf, globalFlagsDir, lines[1:], 1)
+	}
+	buf.WriteString("\n")
+	buf.WriteString("## Commands\n")
+	buf.WriteString("\n")
+	for _, commandName := range commandNames {
+		fmt.Fprintf(&buf, "* [`%s`](chezmoi-%s.md): %s\n", commandName, commandName, descriptions[commandName])
+	}
+	return buf.Bytes()
+}
+
+// writeStandaloneMarkdown writes the Markdown lines in lines from the page in
+// the documentation directory dir to buf, converting admonitions to block
+// quotes, rewriting links, and demoting headings by demote levels.
+func writeStandaloneMarkdown(buf *bytes.Buffer, dir string, lines []string, demote int) {
+	inCode := false
+	writeLine := func(prefix, line string) {
+		switch {
+		case strings.HasPrefix(line, "```"):
+			inCode = !inCode
+		case inCode:
+		case strings.HasPrefix(line, "#"):
+			line = strings.Repeat("#", demote) + markdownLinks(dir, line)
+		default:
+			line = markdownLinks(dir, line)
+		}
+		if line == "" {
+			buf.WriteString(strings.TrimRight(prefix, " ") + "\n")
+		} else {
+			buf.WriteString(prefix + line + "\n")
+		}
+	}
+	for i := 0; i < len(lines); i++ {
+		m := admonitionRx.FindStringSubmatch(lines[i])
+		if m == nil || inCode {
+			writeLine("", lines[i])
+			continue
+		}
+		var body []string
+		for i++; i < len(lines) && (strings.TrimSpace(lines[i]) == "" || strings.HasPrefix(lines[i], "    ")); i++ {
+			body = append(body, strings.TrimPrefix(lines[i], "    "))
+		}
+		i--
+		for len(body) > 0 && strings.TrimSpace(body[len(body)-1]) == "" {
+			body = body[:len(body)-1]
+		}
+		title := m[2]
+		if title == "" {
+			title = strings.ToUpper(m[1][:1]) + m[1][1:]
+		}
+		writeLine("> ", "**"+title+":**")
+		for _, line := range body {
+			writeLine("> ", line)
+		}
+		if i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != "" {
+			buf.WriteString("\n")
+		}
+	}
+}
+
+// markdownLinks returns line with the destinations of links to other pages in
+// the documentation, relative to the documentation directory dir, rewritten.
+// Links to commands point to their generated Markdown pages and all other
+// links point to the website.
+func markdownLinks(dir, line string) string {
+	return linkRx.ReplaceAllStringFunc(line, func(match string) string {
+		dest := match[2 : len(match)-1]
+		if strings.Contains(dest, "://") || strings.HasPrefix(dest, "#") {
+			return match
+		}
+		docPath, fragment, _ := strings.Cut(dest, "#")
+		if !strings.HasSuffix(docPath, ".md") {
+			return match
+		}
+		if fragment != "" {
+			fragment = "#" + fragment
+		}
+		docPath = path.Join(dir, docPath)
+		if docDir, name := path.Split(docPath); docDir == commandsDir+"/" {
+			return "](chezmoi-" + name + fragment + ")"
+		}
+		url := strings.TrimSuffix(strings.TrimSuffix(docPath, ".md"), "index")
+		url = strings.TrimSuffix(url, "/") + "/"
+		return "](" + siteURL + "/" + url + fragment + ")"
+	})
+}
+
+func run() error {
+	flag.Parse()
+
+	if err := os.MkdirAll(*outputDir, 0o777); err != nil {
+		return err
+	}
+
+	dirEntries, err := fs.ReadDir(commands.FS, ".")
+	if err != nil {
+		return err
+	}
+	if *markdownOutputDir != "" {
+		if err := os.MkdirAll(*markdownOutputDir, 0o777); err != nil {
+			return err
+		}
+	}
+
+	commandNames := make([]string, 0, len(dirEntries))
+	descriptions := make(map[string]string, len(dirEntries))
+	for _, dirEntry := range dirEntries {
+		command := strings.TrimSuffix(dirEntry.Name(), ".md")
+		data, err := fs.ReadFile(commands.FS, dirEntry.Name())
+		if err != nil {
+			return err
+		}
+		manPage, err := commandManPage(command, data)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(*outputDir, "chezmoi-"+command+".1"), manPage, 0o666); err != nil {
+			return err
+		}
+		if *markdownOutputDir != "" {
+			markdownPage, err := commandMarkdownPage(command, data)
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(filepath.Join(*markdownOutputDir, "chezmoi-"+command+".md"), markdownPage, 0o666); err != nil {
+				return err
+			}
+		}
+		commandNames = append(commandNames, command)
+		descriptions[command] = description(strings.Split(string(data), "\n")[1:])
+	}
+	slices.Sort(commandNames)
+
+	var globalFlagsData []byte
+	if *globalFlags != "" {
+		if globalFlagsData, err = os.ReadFile(*globalFlags); err != nil {
+			return err
+		}
+	}
+	if err := os.WriteFile(filepath.Join(*outputDir, "chezmoi.1"), rootManPage(commandNames, globalFlagsData), 0o666); err != nil {
+		return err
+	}
+	if *markdownOutputDir != "" {
+		markdownPage := rootMarkdownPage(commandNames, descriptions, globalFlagsData)
+		if err := os.WriteFile(filepath.Join(*markdownOutputDir, "chezmoi.md"), markdownPage, 0o666); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
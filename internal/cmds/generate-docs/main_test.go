@@ -0,0 +1,196 @@
+package main
+
+import (
+	"flag"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/twpayne/chezmoi/v2/assets/chezmoi.io/docs/reference/commands"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestCommandManPage(t *testing.T) {
+	data := strings.Join([]string{
+		"# `example` *target*...",
+		"",
+		"Do something to *target*s. See [`apply`](apply.md).",
+		"",
+		"## `-f`, `--force`",
+		"",
+		"Do it anyway.",
+		"",
+		"!!! warning",
+		"",
+		"    Be careful.",
+		"",
+		"!!! example",
+		"",
+		"    ```console",
+		"    $ chezmoi example --force .file",
+		"    ```",
+		"",
+	}, "\n")
+	actual, err := commandManPage("example", []byte(data))
+	assert.NoError(t, err)
+	assert.Equal(t, strings.Join([]string{
+		`.TH "CHEZMOI\-EXAMPLE" "1" "" "chezmoi" "chezmoi Manual"`,
+		`.SH NAME`,
+		`chezmoi\-example \- Do something to targets`,
+		`.SH SYNOPSIS`,
+		`\fBchezmoi example\fR \fItarget\fR...`,
+		`.SH DESCRIPTION`,
+		`.PP`,
+		`Do something to \fItarget\fRs. See \fBapply\fR.`,
+		`.SH OPTIONS`,
+		`.TP`,
+		`\fB\-f\fR, \fB\-\-force\fR`,
+		`Do it anyway.`,
+		`.IP`,
+		`\fBWarning:\fR`,
+		`.RS 4`,
+		`.PP`,
+		`Be careful.`,
+		`.RE`,
+		`.SH EXAMPLES`,
+		`.PP`,
+		`.nf`,
+		`$ chezmoi example \-\-force .file`,
+		`.fi`,
+		`.SH SEE ALSO`,
+		`\fBchezmoi\fR(1)`,
+		``,
+	}, "\n"), string(actual))
+}
+
+func TestCommandPages(t *testing.T) {
+	dirEntries, err := fs.ReadDir(commands.FS, ".")
+	assert.NoError(t, err)
+	for _, dirEntry := range dirEntries {
+		command := strings.TrimSuffix(dirEntry.Name(), ".md")
+		t.Run(command, func(t *testing.T) {
+			data, err := fs.ReadFile(commands.FS, dirEntry.Name())
+			assert.NoError(t, err)
+			_, err = commandManPage(command, data)
+			assert.NoError(t, err)
+			_, err = commandMarkdownPage(command, data)
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestTable(t *testing.T) {
+	assert.Equal(t, []string{
+		"Key    Value",
+		"a      b",
+		"long   c",
+		"empty",
+	}, table([][]string{
+		{"Key", "Value"},
+		{"a", "b"},
+		{"long", "c"},
+		{"empty", ""},
+	}))
+}
+
+func TestCommandPagesGolden(t *testing.T) {
+	dirEntries, err := os.ReadDir("testdata")
+	assert.NoError(t, err)
+	for _, dirEntry := range dirEntries {
+		name, ok := strings.CutSuffix(dirEntry.Name(), ".md")
+		if !ok {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join("testdata", dirEntry.Name()))
+		assert.NoError(t, err)
+		for _, page := range []struct {
+			ext      string
+			pageFunc func(string, []byte) ([]byte, error)
+		}{
+			{ext: ".1", pageFunc: commandManPage},
+			{ext: ".markdown", pageFunc: commandMarkdownPage},
+		} {
+			t.Run(name+page.ext, func(t *testing.T) {
+				actual, err := page.pageFunc(name, data)
+				assert.NoError(t, err)
+				goldenPath := filepath.Join("testdata", name+page.ext)
+				if *update {
+					assert.NoError(t, os.WriteFile(goldenPath, actual, 0o666))
+				}
+				expected, err := os.ReadFile(goldenPath)
+				assert.NoError(t, err)
+				assert.Equal(t, string(expected), string(actual))
+			})
+		}
+	}
+}
+
+func TestMarkdownLinks(t *testing.T) {
+	for _, tc := range []struct {
+		dir      string
+		line     string
+		expected string
+	}{
+		{
+			dir:      commandsDir,
+			line:     "See [`apply`](apply.md) and [`add`](add.md#-encrypt).",
+			expected: "See [`apply`](chezmoi-apply.md) and [`add`](chezmoi-add.md#-encrypt).",
+		},
+		{
+			dir:      commandsDir,
+			line:     "Read the [guide](../../user-guide/templating.md#testing-templates).",
+			expected: "Read the [guide](https://chezmoi.io/user-guide/templating/#testing-templates).",
+		},
+		{
+			dir:      globalFlagsDir,
+			line:     "Read the [configuration](../configuration-file/index.md) and [`init`](../commands/init.md).",
+			expected: "Read the [configuration](https://chezmoi.io/reference/configuration-file/) and [`init`](chezmoi-init.md).",
+		},
+		{
+			dir:      commandsDir,
+			line:     "Unchanged [links](https://example.com/a.md), [anchors](#flags), and [images](image.png).",
+			expected: "Unchanged [links](https://example.com/a.md), [anchors](#flags), and [images](image.png).",
+		},
+	} {
+		t.Run(tc.line, func(t *testing.T) {
+			assert.Equal(t, tc.expected, markdownLinks(tc.dir, tc.line))
+		})
+	}
+}
+
+func TestRootMarkdownPage(t *testing.T) {
+	globalFlagsData := []byte(strings.Join([]string{
+		"# Global command line flags",
+		"",
+		"## `-v`, `--verbose`",
+		"",
+		"Be verbose, see [`diff`](../commands/diff.md).",
+		"",
+	}, "\n"))
+	actual := rootMarkdownPage([]string{"add", "apply"}, map[string]string{
+		"add":   "Add targets to the source state",
+		"apply": "Update the destination directory",
+	}, globalFlagsData)
+	assert.Equal(t, strings.Join([]string{
+		"# `chezmoi`",
+		"",
+		"Manage your dotfiles across multiple diverse machines, securely.",
+		"",
+		"## Global flags",
+		"",
+		"### `-v`, `--verbose`",
+		"",
+		"Be verbose, see [`diff`](chezmoi-diff.md).",
+		"",
+		"## Commands",
+		"",
+		"* [`add`](chezmoi-add.md): Add targets to the source state",
+		"* [`apply`](chezmoi-apply.md): Update the destination directory",
+		"",
+	}, "\n"), string(actual))
+}